@@ -6,15 +6,48 @@ import (
 	"fmt"
 	"github.com/archine/gin-plus/v3/application/middleware"
 	"github.com/archine/gin-plus/v3/banner"
+	"github.com/archine/gin-plus/v3/buildinfo"
+	"github.com/archine/gin-plus/v3/event"
 	"github.com/archine/gin-plus/v3/exception/interceptor"
 	"github.com/archine/gin-plus/v3/listener"
 	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/auth"
+	"github.com/archine/gin-plus/v3/plugin/cache"
+	"github.com/archine/gin-plus/v3/plugin/compress"
+	"github.com/archine/gin-plus/v3/plugin/dependency"
+	"github.com/archine/gin-plus/v3/plugin/discovery"
+	"github.com/archine/gin-plus/v3/plugin/gateway"
+	"github.com/archine/gin-plus/v3/plugin/idempotency"
+	"github.com/archine/gin-plus/v3/plugin/jobs"
+	"github.com/archine/gin-plus/v3/plugin/k8s"
 	"github.com/archine/gin-plus/v3/plugin/logger"
-	"github.com/archine/ioc"
+	"github.com/archine/gin-plus/v3/plugin/messaging"
+	"github.com/archine/gin-plus/v3/plugin/metrics"
+	"github.com/archine/gin-plus/v3/plugin/oidc"
+	"github.com/archine/gin-plus/v3/plugin/ratelimit"
+	"github.com/archine/gin-plus/v3/plugin/redis"
+	"github.com/archine/gin-plus/v3/plugin/rewrite"
+	"github.com/archine/gin-plus/v3/plugin/scheduler"
+	"github.com/archine/gin-plus/v3/plugin/scim"
+	"github.com/archine/gin-plus/v3/plugin/signature"
+	"github.com/archine/gin-plus/v3/plugin/static"
+	"github.com/archine/gin-plus/v3/plugin/tracing"
+	"github.com/archine/gin-plus/v3/plugin/wellknown"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/archine/gin-plus/v3/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"io/fs"
+	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -22,30 +55,46 @@ import (
 // App application instance
 type App struct {
 	e              *gin.Engine
+	clock          *phaseClock
+	bannerFS       fs.FS
+	customBanner   bool
+	deregistered   sync.Once // the instance is deregistered once by the pre-stop or the shutdown
 	exitDelay      time.Duration
+	features       map[string]bool
+	inflight       inflight
 	interceptors   []mvc.MethodInterceptor
 	ginMiddlewares []gin.HandlerFunc
 	listeners      []listener.ApplicationListener
+	modules        []Module
+	scimStore      scim.Store
+	scimHandlers   []gin.HandlerFunc
+	selfChecks     []SelfCheck
+	staticSites    []static.Site
+	templates      templates
+	wellKnownFS    fs.FS
 }
 
 // New Create a clean application, you can add some gin middlewares to the engine
 func New(listeners []listener.ApplicationListener, middlewares ...gin.HandlerFunc) *App {
 	app := &App{
+		clock:          newPhaseClock(),
 		exitDelay:      3 * time.Second,
 		ginMiddlewares: middlewares,
 	}
-	configured := false
+	var configListeners []listener.ConfigListener
 	for _, l := range listeners {
 		if cl, ok := l.(listener.ConfigListener); ok {
-			LoadApplicationConfigFile(cl)
-			configured = true
+			configListeners = append(configListeners, cl)
 			continue
 		}
 		app.listeners = append(app.listeners, l)
 	}
-	if !configured {
-		LoadApplicationConfigFile(nil)
-	}
+	LoadApplicationConfigFile(configListeners...)
+	app.clock.mark("config")
+	// the conditions are evaluated with the loaded configuration
+	app.listeners = slices.DeleteFunc(app.listeners, func(l listener.ApplicationListener) bool {
+		return !mvc.Enabled(l)
+	})
 	if Conf.Server.Env == Prod {
 		gin.SetMode(gin.ReleaseMode)
 	} else {
@@ -54,14 +103,44 @@ func New(listeners []listener.ApplicationListener, middlewares ...gin.HandlerFun
 	return app
 }
 
-// Default Create a default application with gin default logger, exception interception, and cross-domain middleware
+/*
+Default Create a default application with gin default logger, exception interception, and cross-domain middleware.
+The cross-domain policy is read from the cors configuration, when no origin is configured, every origin is allowed
+in dev and test environments and none in prod environment.
+*/
 func Default(listeners ...listener.ApplicationListener) *App {
-	return New(listeners, gin.Logger(), interceptor.GlobalExceptionInterceptor, middleware.Cors())
+	app := New(listeners, gin.Logger(), interceptor.GlobalExceptionInterceptor)
+	switch {
+	case len(Conf.Cors.AllowOrigins) > 0:
+		if err := Conf.Cors.Validate(); err != nil {
+			logger.Log.Fatalf("Invalid cors configuration, %s", err.Error())
+		}
+		app.ginMiddlewares = append(app.ginMiddlewares, middleware.CorsWith(Conf.Cors))
+	case Conf.Server.Env != Prod:
+		app.ginMiddlewares = append(app.ginMiddlewares, middleware.Cors())
+	}
+	return app
 }
 
-// Banner Sets the project startup banner
+// Banner Sets the project startup banner, it takes precedence over the banner file.
+// The placeholders such as ${app.name}, ${version}, ${port} and ${env} are substituted
 func (a *App) Banner(b string) *App {
 	banner.Banner = b
+	a.customBanner = true
+	return a
+}
+
+/*
+BannerFS Load the banner file of the banner.location configuration from the fs instead of the working directory,
+usually an embed.FS
+
+	//go:embed banner.txt
+	var bannerFS embed.FS
+
+	app.BannerFS(bannerFS)
+*/
+func (a *App) BannerFS(fsys fs.FS) *App {
+	a.bannerFS = fsys
 	return a
 }
 
@@ -72,76 +151,629 @@ func (a *App) Log(collector logger.AbstractLogger) *App {
 	return a
 }
 
-// Interceptor Add a global interceptor
+// Cache Sets the cache store, memory cache as default. It replaces the store of the configuration
+func (a *App) Cache(store cache.Cache) *App {
+	if c, ok := cache.Store.(io.Closer); ok {
+		_ = c.Close()
+	}
+	cache.Store = store
+	return a
+}
+
+/*
+Static Serve the static assets under the prefix, the assets are served only when no route matches the request.
+The config is optional, the embedded frontend is usually served like this:
+
+	//go:embed dist
+	var dist embed.FS
+
+	sub, _ := fs.Sub(dist, "dist")
+	app.Static("/", sub, static.Config{Spa: true, MaxAge: 24 * time.Hour})
+*/
+func (a *App) Static(prefix string, fsys fs.FS, conf ...static.Config) *App {
+	site := static.Site{FS: fsys}
+	if len(conf) > 0 {
+		site.Config = conf[0]
+	}
+	site.Prefix = prefix
+	a.staticSites = append(a.staticSites, site)
+	return a
+}
+
+// WellKnown Serve robots.txt, favicon.ico, security.txt and .well-known/* from the fs, usually an embed.FS.
+// The inline contents of the well_known configuration take precedence
+func (a *App) WellKnown(fsys fs.FS) *App {
+	a.wellKnownFS = fsys
+	return a
+}
+
+// SCIM Serve the SCIM 2.0 user provisioning endpoints backed by the store, such as /scim/v2/Users.
+// The middlewares authenticate the provisioning client, the scim.token configuration can be used instead,
+// the application refuses to start without both
+func (a *App) SCIM(store scim.Store, middlewares ...gin.HandlerFunc) *App {
+	a.scimStore = store
+	a.scimHandlers = middlewares
+	return a
+}
+
+/*
+Validation Register the validation of the tag on the binding validator, the messages of each language are
+the messages of the validation errors, see validation.RegisterValidation
+
+	app.Validation("mobile", func(fl validator.FieldLevel) bool {
+	    return mobileRegexp.MatchString(fl.Field().String())
+	}, map[string]string{"zh-CN": "{field} 不是有效的手机号", "en": "{field} is not a valid mobile number"})
+*/
+func (a *App) Validation(tag string, fn validator.Func, messages ...map[string]string) *App {
+	if err := validation.RegisterValidation(tag, fn, messages...); err != nil {
+		logger.Log.Fatalf("Register validation [%s] error, %s", tag, err.Error())
+	}
+	return a
+}
+
+// StructValidation Register the struct level validation of the types on the binding validator
+func (a *App) StructValidation(fn validator.StructLevelFunc, types ...any) *App {
+	validation.RegisterStructValidation(fn, types...)
+	return a
+}
+
+// TagNameFunc Set the function getting the name of the fields in the validation errors,
+// the validation.field_name configuration is enough for using the json or form names
+func (a *App) TagNameFunc(fn validator.TagNameFunc) *App {
+	validation.RegisterTagNameFunc(fn)
+	return a
+}
+
+// Interceptor Add a global interceptor, the interceptors whose conditions don't match are skipped, see mvc.ConditionalBean
 func (a *App) Interceptor(interceptor ...mvc.MethodInterceptor) *App {
-	a.interceptors = append(a.interceptors, interceptor...)
+	for _, i := range interceptor {
+		if mvc.Enabled(i) {
+			a.interceptors = append(a.interceptors, i)
+		}
+	}
 	return a
 }
 
+// InterceptorFor Add the interceptors only applied to the routes matching the ant-style pattern,
+// such as /admin/**, the Predicate of the interceptor is still checked after the pattern matches.
+// The pattern is matched against the route template, see mvc.PathPredicate
+func (a *App) InterceptorFor(pattern string, interceptor ...mvc.MethodInterceptor) *App {
+	match := mvc.PathPredicate(pattern)
+	for _, i := range interceptor {
+		if !mvc.Enabled(i) {
+			continue
+		}
+		a.interceptors = append(a.interceptors, &scopedInterceptor{MethodInterceptor: i, pattern: pattern, match: match})
+	}
+	return a
+}
+
+// scopedInterceptor the interceptor applied to the routes matching the pattern
+type scopedInterceptor struct {
+	mvc.MethodInterceptor
+	pattern string
+	match   func(ctx *gin.Context) bool
+}
+
+func (s *scopedInterceptor) Predicate(ctx *gin.Context) bool {
+	return s.match(ctx) && s.MethodInterceptor.Predicate(ctx)
+}
+
+func (s *scopedInterceptor) AfterCompletion(ctx *gin.Context, err any) {
+	if ci, ok := s.MethodInterceptor.(mvc.CompletionInterceptor); ok {
+		ci.AfterCompletion(ctx, err)
+	}
+}
+
 // Run the main program entry
 func (a *App) Run() {
 	if logger.Log == nil {
 		logger.Log = &logger.DefaultLog{}
 	}
+	a.clock.mark("setup")
+	if Conf.Kubernetes.Enabled && Conf.Kubernetes.PodLabels {
+		pod := k8s.CurrentPod()
+		if prefix := pod.String(); prefix != "" {
+			logger.Log = logger.WithPrefix(logger.Log, prefix+" ")
+		}
+		metrics.SetConstLabels(pod.Labels())
+	}
+	setupPlugins()
 	a.e = gin.New()
+	// no proxy is trusted by default, so the X-Forwarded-For can't forge the client ip
+	if err := a.e.SetTrustedProxies(Conf.Server.TrustedProxies); err != nil {
+		logger.Log.Fatalf("Parse trusted proxies error, %s", err.Error())
+	}
+	a.loadTemplates()
 	server := &http.Server{
 		Addr:                         fmt.Sprintf(":%d", Conf.Server.Port),
 		ReadTimeout:                  Conf.Server.ReadTimeout,
 		WriteTimeout:                 Conf.Server.WriteTimeout,
+		MaxHeaderBytes:               Conf.Server.MaxHeaderBytes + headerBytesSlack,
 		DisableGeneralOptionsHandler: true,
+		ErrorLog:                     log.New(&logWriter{}, "", 0),
 	}
 	server.Handler = a.e
+	a.e.Use(protocolGuard(Conf.Server.MaxHeaderBytes))
+	if Conf.Tracing.Enabled {
+		a.e.Use(tracing.Middleware(Conf.Tracing))
+	}
+	if Conf.Server.SecurityHeaders.Enabled {
+		a.e.Use(middleware.SecurityHeaders(Conf.Server.SecurityHeaders))
+	}
 	if len(a.ginMiddlewares) > 0 {
 		a.e.Use(a.ginMiddlewares...)
 	}
+	if len(Conf.IPFilter) > 0 {
+		filter, err := middleware.IPFilter(Conf.IPFilter)
+		if err != nil {
+			logger.Log.Fatalf("Parse ip filter error, %s", err.Error())
+		}
+		a.e.Use(filter)
+	}
+	if Conf.Signature.Enabled {
+		// verified before the idempotency, so the replayed responses are not returned to the unsigned requests
+		a.e.Use(signature.Middleware(Conf.Signature))
+	}
+	if Conf.Server.Compression.Enabled {
+		// added before the payload metrics, so they record the response size before the compression
+		a.e.Use(compress.Middleware(Conf.Server.Compression))
+	}
+	if Conf.Metrics.Enabled {
+		server.Handler = metrics.PayloadHandler(a.e)
+		metrics.SetSaturation(Conf.Metrics.Saturation)
+		a.e.Use(metrics.Payload(), metrics.Concurrency())
+		a.e.GET(Conf.Metrics.Path, metrics.Handler())
+	}
+	if Conf.Diagnostics.BodyLog.Enabled {
+		// added after the compression, so the uncompressed responses are logged
+		a.e.Use(middleware.BodyLog(Conf.Diagnostics.BodyLog))
+	}
+	if Conf.OpenAPI.Examples.Enabled {
+		if Conf.Server.Env == Prod {
+			logger.Log.Warn("OpenAPI examples recording is ignored in prod environment")
+			Conf.OpenAPI.Examples.Enabled = false
+		} else {
+			a.e.Use(mvc.RecordExamples(Conf.OpenAPI.Examples))
+		}
+	}
+	if Conf.Diagnostics.Allocation.Enabled {
+		if Conf.Server.Env == Prod {
+			logger.Log.Warn("Allocation diagnostics is ignored in prod environment")
+		} else {
+			a.e.Use(metrics.Allocation(Conf.Diagnostics.Allocation))
+			if Conf.Diagnostics.Allocation.Path != "" {
+				a.e.GET(Conf.Diagnostics.Allocation.Path, metrics.AllocationHandler())
+			}
+		}
+	}
+	if Conf.Rewrite.Watch || Conf.Rewrite.HttpsRedirect || Conf.Rewrite.TrailingSlash != "" || len(Conf.Rewrite.Rules) > 0 {
+		server.Handler = rewrite.Handler(server.Handler)
+	}
+	server.Handler = a.inflight.wrap(server.Handler)
+	if Conf.Auth.APIKey.Enabled {
+		a.e.Use(auth.APIKeyMiddleware(Conf.Auth.APIKey))
+	}
+	if Conf.OIDC.Client.Enabled {
+		rp, err := oidc.NewRelyingParty(Conf.OIDC.Client)
+		if err != nil {
+			logger.Log.Fatalf("Init oidc client error, %s", err.Error())
+		}
+		oidc.DefaultRelyingParty = rp
+		mvc.SetBeans(rp)
+		// the replicas are expected when the instance runs in the cluster or is registered
+		if cache.Local(cache.Store) && (Conf.Kubernetes.Enabled || discovery.Default.Enabled()) {
+			logger.Log.Warn("The oidc sessions are stored in the memory cache, they're lost when the requests reach other replicas, use the shared cache.store such as redis")
+		}
+		// the principal of the session is set before the authentication
+		a.e.Use(rp.Middleware())
+	}
+	if Conf.Auth.HasKeys() {
+		authenticator, err := auth.New(Conf.Auth)
+		if err != nil {
+			logger.Log.Fatalf("Init auth error, %s", err.Error())
+		}
+		auth.Default = authenticator
+		mvc.SetBeans(authenticator)
+		if Conf.Auth.Enabled {
+			a.e.Use(authenticator.Middleware())
+		}
+	}
+	if Conf.Idempotency.Enabled {
+		// added after the authentication, so the keys are scoped by the principal
+		a.e.Use(idempotency.Middleware(Conf.Idempotency))
+	}
+	if len(Conf.RateLimit.Rules) > 0 {
+		a.e.Use(ratelimit.Rules(Conf.RateLimit.Rules))
+	}
+	if Conf.RateLimit.Tenant.Enabled {
+		a.e.Use(ratelimit.Tenant(Conf.RateLimit.Tenant))
+	}
+	if Conf.Spool.Enabled {
+		a.e.Use(mvc.Spool(Conf.Spool))
+	}
+	if Conf.Checksum.Enabled {
+		a.e.Use(mvc.VerifyChecksum(false))
+	}
+	a.e.Use(mvc.Timeout(Conf.Timeout))
 	a.e.MaxMultipartMemory = Conf.Server.MaxFileSize
 	a.e.RemoveExtraSlash = true
-	ioc.SetBeans(a.e)
-	if banner.Banner != "" {
-		fmt.Print(banner.Banner)
+	mvc.SetBeans(a.e)
+	a.printBanner()
+	if Conf.Cache.Invalidation.Enabled {
+		if client := sharedRedis("redis", Conf.Cache.Invalidation.Addr); client != nil {
+			c := cache.NewInvalidatingCache(cache.Store, cache.NewRedisBus(client, Conf.Cache.Invalidation.Channel))
+			c.Listen()
+			cache.Store = c
+		} else {
+			cache.Store = cache.NewRedisInvalidation(cache.Store, Conf.Cache.Invalidation)
+		}
+	}
+	if Conf.OIDC.Provider.Enabled {
+		provider, err := oidc.NewProvider(Conf.OIDC.Provider)
+		if err != nil {
+			logger.Log.Fatalf("Init oidc provider error, %s", err.Error())
+		}
+		oidc.DefaultProvider = provider
+		mvc.SetBeans(provider)
 	}
+	if Conf.OIDC.Introspection.Enabled {
+		oidc.DefaultIntrospector = oidc.NewIntrospector(Conf.OIDC.Introspection)
+		mvc.SetBeans(oidc.DefaultIntrospector)
+		mvc.RegisterMiddleware(oidc.IntrospectionMiddleware, oidc.DefaultIntrospector.Middleware())
+	}
+	if Conf.Mock.Enabled && Conf.Server.Env == Prod {
+		logger.Log.Warn("Mock mode is ignored in prod environment")
+		Conf.Mock.Enabled = false
+	}
+	mvc.SetMockConfig(Conf.Mock)
+	a.clock.mark("middlewares")
+	// the listeners of the beans set before are discovered in time for PreApply
+	a.discover(mvc.Beans(), true)
 	listener.DoPreApply(a.listeners)
+	a.clock.mark("pre_apply")
+	if err := configureModules(a.modules, GetConfReader()); err != nil {
+		logger.Log.Fatalf("Application start error, %s", err.Error())
+	}
+	if len(a.modules) > 0 && !Conf.Mock.Enabled {
+		begin := time.Now()
+		timeline, err := startModules(a.modules, Conf.Startup.Parallelism, Conf.Startup.Timeout)
+		if err != nil {
+			logger.Log.Fatalf("Application start error, %s", err.Error())
+		}
+		moduleTimeline = timeline
+		printModuleTimeline(timeline, time.Since(begin))
+		addModuleStatus(a.modules, timeline)
+		for _, m := range a.modules {
+			mvc.SetBeans(m)
+		}
+		a.clock.mark("modules")
+	}
+	newDependencyMonitor()
+	// the beans set by the PreApply listeners and the modules
+	a.discover(mvc.Beans(), true)
+	// the request scoped beans are disposed after the interceptors completed
+	a.e.Use(mvc.RequestScope())
 	if len(a.interceptors) > 0 {
-		a.e.Use(func(context *gin.Context) {
-			var is []mvc.MethodInterceptor
-			for _, ic := range a.interceptors {
-				if ic.Predicate(context) {
-					is = append(is, ic)
-					ic.PreHandle(context)
-				}
-				if context.IsAborted() {
-					return
-				}
-			}
-			context.Next()
-			for _, i := range is {
-				i.PostHandle(context)
-				if context.IsAborted() {
-					return
-				}
-			}
-		})
+		a.e.Use(a.intercept)
 	}
+	mvc.SetWebSocketConfig(Conf.WebSocket)
+	mvc.SetOpenAPIConfig(Conf.OpenAPI)
+	mvc.SetBindingConfig(Conf.Binding)
+	mvc.SetPageConfig(Conf.Pagination)
 	mvc.Apply(a.e, true)
-	listener.DoPreStart(a.listeners)
+	if err := idempotency.CheckLockTTL(Conf.Idempotency, Conf.Timeout); err != nil {
+		logger.Log.Fatalf("Application start error, %s", err.Error())
+	}
+	mvc.Autowire(moduleBeans(a.modules)...)
+	printBeanTimeline(mvc.BeanTimeline(), Conf.Startup.SlowBean)
+	beans := mvc.Beans()
+	a.discover(beans, false)
+	event.Default.Register(beans...)
+	if err := scheduler.Default.Register(beans...); err != nil {
+		logger.Log.Fatalf("Register scheduled tasks error, %s", err.Error())
+	}
+	jobs.Default.Register(beans...)
+	if err := messaging.Default.Register(beans...); err != nil {
+		logger.Log.Fatalf("Register message listeners error, %s", err.Error())
+	}
+	if err := cache.Bind(beans...); err != nil {
+		logger.Log.Fatalf("Cache annotation error, %s", err.Error())
+	}
+	listener.DoRoutesMounted(a.listeners, mvc.Routes())
+	a.clock.mark("apply")
+	if Conf.Server.RoutesPath != "" {
+		a.e.GET(Conf.Server.RoutesPath, mvc.RoutesHandler())
+	}
+	if Conf.Server.ErrorsPath != "" {
+		a.e.GET(Conf.Server.ErrorsPath, resp.ErrorCatalogHandler())
+	}
+	if Conf.Server.InfoPath != "" {
+		a.e.GET(Conf.Server.InfoPath, buildinfo.Handler())
+	}
+	if Conf.Kubernetes.Enabled {
+		if Conf.Kubernetes.ReadinessPath != "" {
+			a.e.GET(Conf.Kubernetes.ReadinessPath, k8s.ReadinessHandler())
+		}
+		if Conf.Kubernetes.PreStopPath != "" {
+			a.e.GET(Conf.Kubernetes.PreStopPath, k8s.PreStopHandler(Conf.Kubernetes.PreStopDelay, Conf.Kubernetes.PreStopToken))
+		}
+	}
+	if Conf.OpenAPI.Enabled {
+		a.e.GET(Conf.OpenAPI.Path, mvc.OpenAPIHandler())
+		if Conf.OpenAPI.SwaggerUI != "" {
+			a.e.GET(Conf.OpenAPI.SwaggerUI, mvc.SwaggerUIHandler())
+		}
+	}
+	if dependency.Default != nil && Conf.Dependencies.Path != "" {
+		a.e.GET(Conf.Dependencies.Path, dependency.Default.Handler())
+	}
+	for _, m := range a.modules {
+		if em, ok := m.(EndpointModule); ok {
+			em.Mount(a.e)
+		}
+	}
+	if len(Conf.Gateway.Routes) > 0 {
+		gateway.Mount(a.e, Conf.Gateway.Routes)
+	}
+	if oidc.DefaultProvider != nil {
+		oidc.DefaultProvider.Mount(a.e)
+	}
+	if oidc.DefaultRelyingParty != nil {
+		oidc.DefaultRelyingParty.Mount(a.e)
+	}
+	if a.scimStore != nil {
+		if err := scim.Mount(a.e, Conf.SCIM, a.scimStore, a.scimHandlers...); err != nil {
+			logger.Log.Fatalf("Mount scim error, %s", err.Error())
+		}
+	}
+	if a.wellKnownFS == nil && Conf.WellKnown.Dir != "" {
+		a.wellKnownFS = os.DirFS(Conf.WellKnown.Dir)
+	}
+	if a.wellKnownFS != nil || !Conf.WellKnown.Empty() {
+		wellknown.Mount(a.e, Conf.WellKnown, a.wellKnownFS)
+	}
+	for _, c := range Conf.Static {
+		a.staticSites = append(a.staticSites, static.Site{Config: c, FS: os.DirFS(c.Dir)})
+	}
+	if len(a.staticSites) > 0 {
+		static.Mount(a.e, a.staticSites)
+	}
+	a.clock.mark("endpoints")
+	if err := listener.DoPreStartE(a.listeners); err != nil {
+		logger.Log.Fatalf("Application start error, %s", err.Error())
+	}
+	a.clock.mark("pre_start")
+	if dependency.Default != nil {
+		dependency.Default.Start()
+	}
+	a.listen(server)
+	if err := a.selfTest(); err != nil {
+		logger.Log.Error(err.Error())
+		a.shutdown(server)
+		logger.Log.Fatalf("Application start failure, self test not passed")
+	}
+	asyncFailed := a.startAsyncListeners()
+	if err := listener.DoPostStart(a.listeners); err != nil {
+		logger.Log.Error(err.Error())
+		a.shutdown(server)
+		logger.Log.Fatalf("Application start failure, PostStart failed")
+	}
+	scheduler.Default.Start()
+	jobs.Default.Start()
+	messaging.Default.Start()
+	a.setServing(true)
+	// registered after the server is serving, so the clients never discover the instance which can't serve
+	if err := discovery.Default.Register(context.Background()); err != nil {
+		logger.Log.Errorf("Register instance error, %s", err.Error())
+		a.shutdown(server)
+		logger.Log.Fatalf("Application start failure, instance not registered")
+	}
+	a.clock.mark("post_start")
+	logger.Log.Debugf("Application %s start success on Ports:[%d]", buildinfo.Get(), Conf.Server.Port)
+	if banner.Banner != "" {
+		fmt.Print(a.summary())
+	} else {
+		logger.Log.Infof("Startup summary:\n%s", a.summary())
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case <-quit:
+		a.prepareStop(server)
+		a.shutdown(server)
+		logger.Log.Debug("Server exiting ...")
+	case err := <-asyncFailed:
+		a.shutdown(server)
+		logger.Log.Fatalf("Application start failure, %s", err.Error())
+	}
+}
+
+// trigger the PreStart event of the async listeners, the readiness fails until they finish.
+// The failure is sent to the returned channel to stop the application unless the policy is log and continue
+func (a *App) startAsyncListeners() <-chan error {
+	failed := make(chan error, 1)
+	group := listener.StartAsync(a.listeners)
+	k8s.SetStarting(true)
 	go func() {
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Log.Fatalf("Application start error, %s", err.Error())
+		errs := group.Wait()
+		for _, err := range errs {
+			logger.Log.Errorf("Async listener failed, %s", err.Error())
 		}
+		if len(errs) > 0 && Conf.Listener.AsyncFailure != listener.LogAndContinue {
+			failed <- fmt.Errorf("%d async listeners failed", len(errs))
+			return
+		}
+		k8s.SetStarting(false)
 	}()
-	logger.Log.Debugf("Application start success on Ports:[%d]", Conf.Server.Port)
-	quit := make(chan os.Signal)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
+	return failed
+}
+
+// print the banner with the placeholders substituted, the banner file is used instead of the default banner
+func (a *App) printBanner() {
+	if !Conf.Banner.Enabled {
+		banner.Banner = ""
+		return
+	}
+	if !a.customBanner && Conf.Banner.Location != "" {
+		fsys := a.bannerFS
+		if fsys == nil {
+			fsys = os.DirFS(".")
+		}
+		text, err := banner.Load(fsys, strings.TrimPrefix(Conf.Banner.Location, "./"))
+		switch {
+		case err == nil:
+			banner.Banner = text
+		case !errors.Is(err, fs.ErrNotExist):
+			logger.Log.Warnf("Load banner %s error, %s", Conf.Banner.Location, err.Error())
+		}
+	}
+	if banner.Banner == "" {
+		return
+	}
+	conf := GetConfReader()
+	banner.Banner = banner.Render(banner.Banner, func(name string) (string, bool) {
+		switch name {
+		case "port":
+			return strconv.Itoa(Conf.Server.Port), true
+		case "env":
+			return Conf.Server.Env, true
+		case "version":
+			if conf.IsSet("app.version") {
+				return conf.GetString("app.version"), true
+			}
+			return buildinfo.Get().Version, buildinfo.Get().Version != ""
+		case "go.version":
+			return runtime.Version(), true
+		}
+		return conf.GetString(name), conf.IsSet(name)
+	})
+	fmt.Print(banner.Banner)
+}
+
+// the status lines of the application and its startup followed by the ones of the modules and the plugins
+func (a *App) summary() string {
+	lines := []banner.StatusLine{
+		{Name: "Version", Status: buildinfo.Get().String()},
+		{Name: "Profile", Status: Conf.Server.Env},
+		{Name: "Port", Status: strconv.Itoa(Conf.Server.Port)},
+	}
+	lines = append(lines, a.startupLines()...)
+	if Conf.Metrics.Enabled {
+		lines = append(lines, banner.StatusLine{Name: "Metrics", Status: Conf.Metrics.Path})
+	}
+	if Conf.OpenAPI.Enabled {
+		lines = append(lines, banner.StatusLine{Name: "OpenAPI", Status: Conf.OpenAPI.Path})
+	}
+	if dependency.Default != nil {
+		lines = append(lines, banner.StatusLine{Name: "Dependencies", Status: strconv.Itoa(len(dependency.Default.Status()))})
+	}
+	if inst := discovery.Default.Instance(); inst != nil {
+		lines = append(lines, banner.StatusLine{Name: "Discovery", Status: fmt.Sprintf("%s (%s)", Conf.Discovery.Type, inst.Id)})
+	}
+	if redis.Default != nil {
+		mode := Conf.Redis.Mode
+		if mode == "" {
+			mode = "standalone"
+		}
+		lines = append(lines, banner.StatusLine{Name: "Redis", Status: mode})
+	}
+	return banner.Format(append(lines, banner.StatusLines()...))
+}
+
+// run the interceptors around the api, the ones whose predicates don't match are skipped
+func (a *App) intercept(context *gin.Context) {
+	var is []mvc.MethodInterceptor
+	defer func() {
+		r := recover()
+		afterCompletion(context, is, r)
+		// the panic is still handled by the global exception interceptor
+		if r != nil {
+			panic(r)
+		}
+	}()
+	for _, ic := range a.interceptors {
+		if ic.Predicate(context) {
+			is = append(is, ic)
+			ic.PreHandle(context)
+		}
+		if context.IsAborted() {
+			return
+		}
+	}
+	context.Next()
+	for _, i := range is {
+		i.PostHandle(context)
+		if context.IsAborted() {
+			return
+		}
+	}
+}
+
+// trigger AfterCompletion of the interceptors whose PreHandle was triggered, in reverse order
+func afterCompletion(ctx *gin.Context, interceptors []mvc.MethodInterceptor, r any) {
+	err := r
+	if err == nil {
+		if last := ctx.Errors.Last(); last != nil {
+			err = last.Err
+		}
+	}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ci, ok := interceptors[i].(mvc.CompletionInterceptor)
+		if !ok {
+			continue
+		}
+		func() {
+			// the panic of one interceptor doesn't skip the others
+			defer func() {
+				if p := recover(); p != nil {
+					logger.Log.Errorf("Interceptor AfterCompletion panic, %v", p)
+				}
+			}()
+			ci.AfterCompletion(ctx, err)
+		}()
+	}
+}
+
+// shutdown the server gracefully and trigger the stop events, the requests, the calls and the jobs share one deadline
+// of the exit delay, so the shutdown never takes longer than it
+func (a *App) shutdown(server *http.Server) {
 	logger.Log.Debug("Shutdown server ...")
 	listener.DoPreStop(a.listeners)
-	ctx, cancelFunc := context.WithTimeout(context.Background(), a.exitDelay)
+	a.deregister()
+	ctx, cancelFunc := context.WithTimeout(context.Background(), a.exitDelayOf())
 	defer cancelFunc()
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Log.Fatalf("Server shutdown failure, %s", err.Error())
+	a.drain(ctx, server)
+	a.stopServers(ctx)
+	a.stopScheduler(ctx)
+	a.stopListeners(ctx)
+	a.stopJobs(ctx)
+	// the async events published by the handlers are delivered before the modules are closed
+	_ = event.Default.Close()
+	mvc.DestroyBeans()
+	closeModules(a.modules, moduleTimeline)
+	if dependency.Default != nil {
+		_ = dependency.Default.Close()
+	}
+	if c, ok := cache.Store.(io.Closer); ok {
+		_ = c.Close()
+	}
+	if c, ok := ratelimit.Store.(io.Closer); ok {
+		_ = c.Close()
+	}
+	if c, ok := idempotency.Store.(io.Closer); ok {
+		_ = c.Close()
+	}
+	if c, ok := signature.Store.(io.Closer); ok {
+		_ = c.Close()
+	}
+	_ = jobs.Default.Close()
+	_ = messaging.Default.Close()
+	_ = redis.Close()
+	if k8s.Default != nil {
+		_ = k8s.Default.Close()
 	}
 	listener.DoPostStop(a.listeners)
-	logger.Log.Debug("Server exiting ...")
 }
 
 // ReadConfig Read configuration
@@ -163,7 +795,8 @@ func (a *App) ReadConfigSub(v any, sub string) *App {
 	return a
 }
 
-// ExitDelay Graceful exit time(default 3s), when reached to shut down the server and trigger PostStop().
+// ExitDelay Graceful exit time(default 3s) of the in-flight requests after the pre-stop delay, the requests still running
+// are aborted when reached, then the server is shut down and PostStop() is triggered.
 func (a *App) ExitDelay(time time.Duration) *App {
 	a.exitDelay = time
 	return a
@@ -1,11 +1,53 @@
 package application
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"github.com/archine/gin-plus/v3/application/middleware"
+	"github.com/archine/gin-plus/v3/banner"
+	configvalue "github.com/archine/gin-plus/v3/config"
+	"github.com/archine/gin-plus/v3/event"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/i18n"
 	"github.com/archine/gin-plus/v3/listener"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/auth"
+	"github.com/archine/gin-plus/v3/plugin/breaker"
+	"github.com/archine/gin-plus/v3/plugin/cache"
+	"github.com/archine/gin-plus/v3/plugin/compress"
+	"github.com/archine/gin-plus/v3/plugin/dependency"
+	"github.com/archine/gin-plus/v3/plugin/discovery"
+	"github.com/archine/gin-plus/v3/plugin/discovery/consul"
+	"github.com/archine/gin-plus/v3/plugin/discovery/etcd"
+	"github.com/archine/gin-plus/v3/plugin/discovery/nacos"
+	"github.com/archine/gin-plus/v3/plugin/gateway"
+	"github.com/archine/gin-plus/v3/plugin/httpclient"
+	"github.com/archine/gin-plus/v3/plugin/idempotency"
+	"github.com/archine/gin-plus/v3/plugin/jobs"
+	"github.com/archine/gin-plus/v3/plugin/k8s"
 	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/plugin/messaging"
+	"github.com/archine/gin-plus/v3/plugin/metrics"
+	"github.com/archine/gin-plus/v3/plugin/oidc"
+	"github.com/archine/gin-plus/v3/plugin/ratelimit"
+	"github.com/archine/gin-plus/v3/plugin/redis"
+	"github.com/archine/gin-plus/v3/plugin/rewrite"
+	"github.com/archine/gin-plus/v3/plugin/scheduler"
+	"github.com/archine/gin-plus/v3/plugin/scim"
+	"github.com/archine/gin-plus/v3/plugin/signature"
+	"github.com/archine/gin-plus/v3/plugin/static"
+	"github.com/archine/gin-plus/v3/plugin/tracing"
+	"github.com/archine/gin-plus/v3/plugin/wellknown"
+	"github.com/archine/gin-plus/v3/validation"
 	ioc "github.com/archine/ioc"
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin/binding"
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
+	"io/fs"
+	"net/http"
+	"strings"
 	"time"
 )
 
@@ -23,45 +65,428 @@ const (
 	Prod = "prod"
 )
 
+// Configuration properties cache, bound after the application configuration is loaded
+var propertiesCache []ConfigurationProperties
+
+// ConfigurationProperties Declares the structure to be bound to a section of the application configuration.
+// After binding, the structure is validated by the binding tags and set to the IOC container,
+// so beans can inject it directly or get it by ioc.GetBean() inside CreateBean().
+type ConfigurationProperties interface {
+	// Prefix the configuration key of the section, such as "redis". Empty means the whole configuration
+	Prefix() string
+}
+
 type config struct {
 	Server struct {
-		Port         int           `mapstructure:"port"`          // Application port
-		Env          string        `mapstructure:"env"`           // Application environment, default dev, you can set it to prod or test
-		MaxFileSize  int64         `mapstructure:"max_file_size"` // Maximum file size, default 100M
-		WriteTimeout time.Duration `mapstructure:"write_timeout"` // Write timeout, default 0 means no timeout
-		ReadTimeout  time.Duration `mapstructure:"read_timeout"`  // Read timeout, default 0 means no timeout
+		Port            int                              `mapstructure:"port"`             // Application port
+		Env             string                           `mapstructure:"env"`              // Application environment, default dev, you can set it to prod or test
+		MaxFileSize     int64                            `mapstructure:"max_file_size"`    // Maximum file size, default 100M
+		WriteTimeout    time.Duration                    `mapstructure:"write_timeout"`    // Write timeout, default 0 means no timeout
+		ReadTimeout     time.Duration                    `mapstructure:"read_timeout"`     // Read timeout, default 0 means no timeout
+		MaxHeaderBytes  int                              `mapstructure:"max_header_bytes"` // Maximum size of the request headers, default 1M
+		RoutesPath      string                           `mapstructure:"routes_path"`      // Endpoint exposing the route table as json, default empty means not exposed
+		ErrorsPath      string                           `mapstructure:"errors_path"`      // Endpoint exposing the error code catalog as json, default empty means not exposed
+		InfoPath        string                           `mapstructure:"info_path"`        // Endpoint exposing the build information as json, such as /actuator/info, default empty means not exposed
+		Compression     compress.Config                  `mapstructure:"compression"`      // Gzip and brotli compression of the responses
+		SecurityHeaders middleware.SecurityHeadersConfig `mapstructure:"security_headers"` // HSTS, X-Frame-Options and other security headers of the responses
+		TrustedProxies  []string                         `mapstructure:"trusted_proxies"`  // Ips or CIDR ranges of the proxies whose X-Forwarded-For is trusted, default none
 	}
+	SelfTest struct {
+		Enabled   bool               `mapstructure:"enabled"`   // Whether to request the endpoints after the server is listening, default false
+		Timeout   time.Duration      `mapstructure:"timeout"`   // Timeout of each request, default 5s
+		Endpoints []SelfTestEndpoint `mapstructure:"endpoints"` // Endpoints to request
+	} `mapstructure:"self_test"`
+	Shutdown struct {
+		PreStopDelay time.Duration `mapstructure:"pre_stop_delay"` // How long to keep serving after the readiness fails, kubernetes.pre_stop_delay is used when kubernetes is enabled, default 0
+		ExitDelay    time.Duration `mapstructure:"exit_delay"`     // How long to wait for the in-flight requests, overrides App.ExitDelay(), default 0 means the App one
+	} `mapstructure:"shutdown"`
+	Startup struct {
+		Parallelism int           `mapstructure:"parallelism"` // Max modules initialized at the same time, default 0 means unlimited
+		Timeout     time.Duration `mapstructure:"timeout"`     // Timeout of the modules initialization, default 0 means no timeout
+		SlowBean    time.Duration `mapstructure:"slow_bean"`   // The beans initialized slower than it are reported in the startup summary, default 100ms
+	} `mapstructure:"startup"`
+	Gateway struct {
+		Routes []gateway.Route `mapstructure:"routes"` // Proxy routes, forward the matched requests to the upstream
+	} `mapstructure:"gateway"`
+	Metrics struct {
+		Enabled    bool                     `mapstructure:"enabled"`    // Whether to expose the metrics, default false
+		Path       string                   `mapstructure:"path"`       // Metrics endpoint, default /metrics
+		Saturation metrics.SaturationConfig `mapstructure:"saturation"` // In-flight thresholds of the saturation alerts
+	} `mapstructure:"metrics"`
+	Diagnostics struct {
+		Allocation metrics.AllocationConfig `mapstructure:"allocation"` // Per-request allocation diagnostics, ignored in prod environment
+		BodyLog    middleware.BodyLogConfig `mapstructure:"body_log"`   // Log the request and response bodies at debug level with the redaction
+	} `mapstructure:"diagnostics"`
+	RateLimit struct {
+		Tenant ratelimit.TenantConfig `mapstructure:"tenant"` // Rate limits of tenants
+		Rules  []ratelimit.Rule       `mapstructure:"rules"`  // Rate limits of the ips, routes and api keys
+		Store  ratelimit.StoreConfig  `mapstructure:"store"`  // Storage of the limit state, default memory
+	} `mapstructure:"ratelimit"`
+	HttpClient httpclient.Config `mapstructure:"http_client"` // Outbound http client
+	Static     []static.Config   `mapstructure:"static"`      // Static assets served from the local directories
+	Rewrite    rewrite.Config    `mapstructure:"rewrite"`     // Redirect and rewrite rules evaluated before routing
+	Template   struct {
+		Pattern string `mapstructure:"pattern"` // Glob pattern of the html templates, such as templates/*.html
+	} `mapstructure:"template"`
+	WellKnown  wellknown.Config    `mapstructure:"well_known"` // robots.txt, favicon, security.txt and /.well-known/* documents
+	WebSocket  mvc.WebSocketConfig `mapstructure:"websocket"`  // Websocket upgrader
+	Mock       mvc.MockConfig      `mapstructure:"mock"`       // Respond the examples instead of calling the apis, ignored in prod environment
+	OpenAPI    mvc.OpenAPIConfig   `mapstructure:"openapi"`    // OpenAPI 3 document generated from the apis
+	Checksum   mvc.ChecksumConfig  `mapstructure:"checksum"`   // Verify the request bodies by the Content-MD5, X-Checksum and Content-Digest headers
+	Spool      mvc.SpoolConfig     `mapstructure:"spool"`      // Spool the large request bodies to the temp files
+	Binding    mvc.BindingConfig   `mapstructure:"binding"`    // Request binding, such as the strict json binding
+	Pagination mvc.PageConfig      `mapstructure:"pagination"` // Query parameters and the limits of the Pageable
+	Timeout    mvc.TimeoutConfig   `mapstructure:"timeout"`    // Timeouts of the handlers, @Timeout declares the timeout of the api method
+	OIDC       struct {
+		Provider      oidc.ProviderConfig      `mapstructure:"provider"`      // OpenID Connect authorization server
+		Introspection oidc.IntrospectionConfig `mapstructure:"introspection"` // Verify the opaque tokens by introspection
+		Client        oidc.RelyingPartyConfig  `mapstructure:"client"`        // Login the browser users against the OpenID Connect provider with the sessions
+	} `mapstructure:"oidc"`
+	Cache struct {
+		Store        cache.StoreConfig        `mapstructure:"store"`        // Storage of the cached values and responses, default memory, App.Cache() takes precedence
+		Invalidation cache.InvalidationConfig `mapstructure:"invalidation"` // Broadcast the cache evictions to all instances
+	} `mapstructure:"cache"`
+	SCIM           scim.Config           `mapstructure:"scim"`            // SCIM 2.0 user provisioning endpoints, mounted when a store is set by App.SCIM
+	Dependencies   dependency.Config     `mapstructure:"dependencies"`    // Dependency endpoints pinged on the schedule
+	CircuitBreaker breaker.Settings      `mapstructure:"circuit_breaker"` // Circuit breakers of the routes and the outbound calls
+	Tracing        tracing.Config        `mapstructure:"tracing"`         // Trace the requests by the samplers
+	Idempotency    idempotency.Config    `mapstructure:"idempotency"`     // Replay the responses of the retries with the same Idempotency-Key
+	Cors           middleware.CorsConfig `mapstructure:"cors"`            // Cross-domain policy of Default(), all origins are allowed in dev and test environments when no origin is configured
+	IPFilter       []middleware.IPRule   `mapstructure:"ip_filter"`       // Client ips allowed or denied on the routes, such as the admin endpoints
+	Kubernetes     k8s.Config            `mapstructure:"kubernetes"`      // Readiness, preStop delay, pod metadata and restart coordination in the kubernetes cluster
+	Auth           auth.Config           `mapstructure:"auth"`            // Jwt authentication, @Auth and @Anonymous declare the authentication of the api method
+	Signature      signature.Config      `mapstructure:"signature"`       // Verify the hmac signatures of the requests, @Signed declares the api method requires the signed request
+	I18n           i18n.Config           `mapstructure:"i18n"`            // Message bundles translating the business errors and the validation messages by Accept-Language
+	Validation     validation.Config     `mapstructure:"validation"`      // Field names and the language of the validation messages
+	Listener       listener.Config       `mapstructure:"listener"`        // Policy of the failed async listeners
+	Event          event.Config          `mapstructure:"event"`           // Workers of the application event bus
+	Banner         banner.Config         `mapstructure:"banner"`          // Startup banner loaded from the file with the placeholders substituted
+	Scheduler      scheduler.Config      `mapstructure:"scheduler"`       // Time zone and jitter of the scheduled tasks
+	Jobs           jobs.Config           `mapstructure:"jobs"`            // Workers, retries and storage of the background job queue
+	Redis          redis.Config          `mapstructure:"redis"`           // Redis client registered as the bean and shared by the redis stores without their own address
+	Messaging      messaging.Config      `mapstructure:"messaging"`       // Message listeners and the producer, retries and the dead letter topics
+	Discovery      struct {
+		discovery.Config `mapstructure:",squash"`
+		Consul           consul.Config `mapstructure:"consul"` // Consul agent of the consul type
+		Nacos            nacos.Config  `mapstructure:"nacos"`  // Nacos server of the nacos type
+		Etcd             etcd.Config   `mapstructure:"etcd"`   // Etcd cluster of the etcd type
+	} `mapstructure:"discovery"` // Register the instance to the service registry once serving and deregister it on PreStop
 }
 
-// LoadApplicationConfigFile load the application configuration file
-func LoadApplicationConfigFile(l listener.ConfigListener) {
+const defaultConfigFile = "app.yml"
+
+/*
+LoadApplicationConfigFile load the application configuration. The sources are merged in the precedence order, the later
+overrides the earlier:
+
+ 1. The defaults
+ 2. The files of the -c flag separated by commas, such as -c app.yml,app-prod.yml, default app.yml
+ 3. The config listeners in order, each reads into the viper of the application with the defaults, the env and the
+    configuration merged so far, the first file is set as the config file
+ 4. The environment variables
+
+When the config listeners exist, the absent default app.yml is ignored.
+*/
+func LoadApplicationConfigFile(listeners ...listener.ConfigListener) {
 	var v = viper.New()
-	var configFile string
-	flag.StringVar(&configFile, "c", "app.yml", "Absolute path to the project configuration file, default app.yml")
-	flag.Parse()
-	v.SetConfigFile(configFile)
+	files := configFiles()
+	v.SetConfigFile(files[0])
 	v.SetDefault("server.port", 4006)
 	v.SetDefault("server.env", Dev)
 	v.SetDefault("server.max_file_size", 104857600)
 	v.SetDefault("server.read_timeout", 0)  // 0 means no timeout
 	v.SetDefault("server.write_timeout", 0) // 0 means no timeout
+	v.SetDefault("server.max_header_bytes", http.DefaultMaxHeaderBytes)
+	v.SetDefault("server.compression.min_size", 1024)
+	v.SetDefault("server.security_headers.hsts_max_age", 365*24*time.Hour)
+	v.SetDefault("server.security_headers.content_type_options", "nosniff")
+	v.SetDefault("server.security_headers.frame_options", "DENY")
+	v.SetDefault("server.security_headers.referrer_policy", "strict-origin-when-cross-origin")
+	v.SetDefault("banner.enabled", true)
+	v.SetDefault("scheduler.enabled", true)
+	v.SetDefault("banner.location", "banner.txt")
+	v.SetDefault("self_test.timeout", 5*time.Second)
+	v.SetDefault("startup.slow_bean", 100*time.Millisecond)
+	v.SetDefault("metrics.path", "/metrics")
+	v.SetDefault("diagnostics.allocation.max_bytes", 10<<20)
+	v.SetDefault("well_known.max_age", 24*time.Hour)
+	v.SetDefault("scim.base_path", "/scim/v2")
+	v.SetDefault("scim.max_results", 100)
+	v.SetDefault("openapi.path", "/openapi.json")
+	v.SetDefault("spool.threshold", 1<<20)
+	v.SetDefault("dependencies.interval", 30*time.Second)
+	v.SetDefault("dependencies.timeout", 5*time.Second)
+	v.SetDefault("dependencies.failure_threshold", 3)
+	v.SetDefault("tracing.rate", 1)
+	v.SetDefault("tracing.parent_based", true)
+	v.SetDefault("tracing.sample_errors", true)
+	v.SetDefault("auth.header", "Authorization")
+	v.SetDefault("auth.scheme", "Bearer")
+	v.SetDefault("kubernetes.readiness_path", "/readyz")
+	v.SetDefault("kubernetes.pre_stop_delay", 5*time.Second)
 	v.AutomaticEnv()
-	var err error
-	if l != nil {
-		err = l.Read(v)
-	} else {
-		err = v.ReadInConfig()
+	var cls []listener.ConfigListener
+	for _, l := range listeners {
+		if l != nil {
+			cls = append(cls, l)
+		}
 	}
+	err := mergeConfig(v, files, cls)
 	if err != nil {
 		logger.Log.Fatalf("Init project config error, %s", err.Error())
 	}
 	if err = v.Unmarshal(&Conf); err != nil {
 		logger.Log.Fatalf("Parse project config error, %s", err.Error())
 	}
-	ioc.SetBeans(v)
+	mvc.SetBeans(v)
+	// the values converted from the previous configuration are stale
+	configvalue.Reset()
+	if err = rewrite.Load(Conf.Rewrite); err != nil {
+		logger.Log.Fatalf("Parse rewrite config error, %s", err.Error())
+	}
+	if Conf.I18n.Fallback != "" {
+		exception.DefaultLanguage = Conf.I18n.Fallback
+		i18n.Default.SetFallback(Conf.I18n.Fallback)
+	}
+	if Conf.I18n.Dir != "" {
+		if err = i18n.Default.LoadDir(Conf.I18n.Dir); err != nil {
+			logger.Log.Fatalf("Load message bundles error, %s", err.Error())
+		}
+	}
+	validation.SetConfig(Conf.Validation)
+	event.Default.SetConfig(Conf.Event)
+	if err = scheduler.Default.SetConfig(Conf.Scheduler); err != nil {
+		logger.Log.Fatalf("Parse scheduler config error, %s", err.Error())
+	}
+	jobs.Default.SetConfig(Conf.Jobs)
+	if err = messaging.Default.SetConfig(Conf.Messaging); err != nil {
+		logger.Log.Fatalf("Parse messaging config error, %s", err.Error())
+	}
+	// reloading replaces the merged configuration by the file, so only the single file is watched
+	if Conf.Rewrite.Watch && len(cls) == 0 && len(files) == 1 {
+		v.OnConfigChange(func(fsnotify.Event) {
+			configvalue.Reset()
+			var conf rewrite.Config
+			if err := v.UnmarshalKey("rewrite", &conf); err != nil {
+				logger.Log.Errorf("Reload rewrite config error, %s", err.Error())
+				return
+			}
+			if err := rewrite.Load(conf); err != nil {
+				logger.Log.Errorf("Reload rewrite config error, %s", err.Error())
+				return
+			}
+			logger.Log.Infof("Rewrite rules reloaded, %d rules", len(conf.Rules))
+		})
+		v.WatchConfig()
+	}
+	idempotency.SetConfig(Conf.Idempotency)
+	signature.SetConfig(Conf.Signature)
+	auth.SetAPIKeyConfig(Conf.Auth.APIKey)
+	breaker.Configure(Conf.CircuitBreaker)
+	bindProperties(v)
+}
+
+/*
+create the clients and the stores of the plugins from the configuration when the application runs after the logger is
+set, so loading the configuration has no side effects, such as connecting the redis or registering the instance
+*/
+func setupPlugins() {
+	if Conf.Kubernetes.Enabled && Conf.Kubernetes.Rollout.Enabled {
+		rollout, err := k8s.NewRollout(Conf.Kubernetes.Rollout, k8s.CurrentPod())
+		if err != nil {
+			logger.Log.Fatalf("Create rollout error, %s", err.Error())
+		}
+		k8s.Default = rollout
+	}
+	mvc.SetBeans(messaging.Default.Producer())
+	discoveryConf := Conf.Discovery.Config
+	if discoveryConf.Port == 0 {
+		discoveryConf.Port = Conf.Server.Port
+	}
+	if discoveryConf.Host == "" {
+		discoveryConf.Host = k8s.CurrentPod().IP
+	}
+	switch Conf.Discovery.Type {
+	case "":
+	case "consul":
+		discovery.Default.SetRegistry(consul.New(Conf.Discovery.Consul))
+	case "nacos":
+		// the nacos server marks the instance unhealthy after 15s without the beats
+		if discoveryConf.Heartbeat == 0 {
+			discoveryConf.Heartbeat = 5 * time.Second
+		}
+		discovery.Default.SetRegistry(nacos.New(Conf.Discovery.Nacos))
+	case "etcd":
+		discovery.Default.SetRegistry(etcd.New(Conf.Discovery.Etcd))
+	default:
+		logger.Log.Fatalf("Unknown discovery type %s, consul, nacos or etcd is expected", Conf.Discovery.Type)
+	}
+	discovery.Default.SetConfig(discoveryConf)
+	mvc.SetBeans(event.Default, jobs.Default)
+	if len(Conf.Redis.Addrs) > 0 {
+		var err error
+		if redis.Default, err = redis.New(Conf.Redis); err != nil {
+			logger.Log.Fatalf("Create redis client error, %s", err.Error())
+		}
+		mvc.SetBeans(redis.Default)
+		dependency.AddIndicator("redis", true, redis.Ping)
+	}
+	if client := sharedRedis(Conf.Cache.Store.Type, Conf.Cache.Store.Addr); client != nil {
+		cache.Store = cache.NewRedisCache(client, Conf.Cache.Store.Prefix)
+	} else if Conf.Cache.Store.Type != "" {
+		cache.Store = cache.NewStore(Conf.Cache.Store)
+	}
+	if client := sharedRedis(Conf.RateLimit.Store.Type, Conf.RateLimit.Store.Addr); client != nil {
+		ratelimit.Store = ratelimit.NewRedisStore(client, Conf.RateLimit.Store.Prefix)
+	} else if Conf.RateLimit.Store.Type != "" {
+		ratelimit.Store = ratelimit.NewStore(Conf.RateLimit.Store)
+	}
+	if client := sharedRedis(Conf.Idempotency.Store.Type, Conf.Idempotency.Store.Addr); client != nil {
+		idempotency.Store = idempotency.NewRedisStore(client, Conf.Idempotency.Store.Prefix)
+	} else if Conf.Idempotency.Store.Type != "" {
+		idempotency.Store = idempotency.NewStore(Conf.Idempotency.Store)
+	}
+	if client := sharedRedis(Conf.Signature.Store.Type, Conf.Signature.Store.Addr); client != nil {
+		signature.Store = signature.NewRedisStore(client, Conf.Signature.Store.Prefix)
+	} else if Conf.Signature.Store.Type != "" {
+		signature.Store = signature.NewStore(Conf.Signature.Store)
+	}
+	if client := sharedRedis(Conf.Jobs.Store.Type, Conf.Jobs.Store.Addr); client != nil {
+		jobs.Default.SetStore(jobs.NewRedisStore(client, Conf.Jobs.Store))
+	}
+	httpclient.Default = httpclient.New(Conf.HttpClient)
+	// the fail fast dependencies trip the breakers of their hosts
+	if Conf.CircuitBreaker.HttpClient || Conf.Dependencies.FailFast {
+		httpclient.Default.Transport = breaker.Transport(httpclient.Default.Transport)
+	}
+	mvc.SetBeans(httpclient.Default)
+}
+
+// create the dependency monitor after the modules added their health indicators
+func newDependencyMonitor() {
+	if len(Conf.Dependencies.Endpoints) == 0 && !dependency.HasIndicators() {
+		return
+	}
+	dependency.Default = dependency.NewMonitor(Conf.Dependencies)
+	mvc.SetBeans(dependency.Default)
+}
+
+// the client of the redis plugin shared by the redis store without its own address, nil means the store creates its client
+func sharedRedis(storeType, addr string) goredis.UniversalClient {
+	if storeType != "redis" || addr != "" {
+		return nil
+	}
+	return redis.Shared()
+}
+
+// RegisterProperties Register configuration properties, they will be bound when the configuration is loaded
+func RegisterProperties(properties ...ConfigurationProperties) {
+	propertiesCache = append(propertiesCache, properties...)
+}
+
+// bind all registered configuration properties
+func bindProperties(v *viper.Viper) {
+	for _, p := range propertiesCache {
+		var err error
+		if p.Prefix() == "" {
+			err = v.Unmarshal(p)
+		} else {
+			err = v.UnmarshalKey(p.Prefix(), p)
+		}
+		if err != nil {
+			logger.Log.Fatalf("Parse [%s] config error, %s", p.Prefix(), err.Error())
+		}
+		if err = binding.Validator.ValidateStruct(p); err != nil {
+			logger.Log.Fatalf("Validate [%s] config error, %s", p.Prefix(), err.Error())
+		}
+		mvc.SetBeans(p)
+	}
+	propertiesCache = nil // GC
 }
 
 // GetConfReader Get config reader of the application
 func GetConfReader() *viper.Viper {
 	return ioc.GetBeanByName("viper.Viper").(*viper.Viper)
 }
+
+// the files of the -c flag, the flag is defined once so the configuration can be loaded again
+func configFiles() []string {
+	f := flag.Lookup("c")
+	if f == nil {
+		flag.String("c", defaultConfigFile, "Paths to the project configuration files separated by commas, the later overrides the earlier, default app.yml")
+		f = flag.Lookup("c")
+	}
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	var files []string
+	for _, file := range strings.Split(f.Value.String(), ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		files = append(files, defaultConfigFile)
+	}
+	return files
+}
+
+// merge the files and the sources of the config listeners into the viper in order
+func mergeConfig(v *viper.Viper, files []string, listeners []listener.ConfigListener) error {
+	for _, file := range files {
+		v.SetConfigFile(file)
+		if err := v.MergeInConfig(); err != nil {
+			if len(listeners) > 0 && file == defaultConfigFile && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("%s, %w", file, err)
+		}
+	}
+	v.SetConfigFile(files[0])
+	for _, l := range listener.Sorted(toListeners(listeners)) {
+		// the listener reads with the defaults and the env, its configuration overrides the merged one
+		// even when it replaces the configuration by viper.ReadConfig
+		merged := configSettings(v)
+		if err := l.(listener.ConfigListener).Read(v); err != nil {
+			return fmt.Errorf("%T, %w", l, err)
+		}
+		read := configSettings(v)
+		if err := v.MergeConfigMap(merged); err != nil {
+			return fmt.Errorf("%T, %w", l, err)
+		}
+		if err := v.MergeConfigMap(read); err != nil {
+			return fmt.Errorf("%T, %w", l, err)
+		}
+	}
+	return nil
+}
+
+// the settings read from the configuration sources, the defaults are excluded
+func configSettings(v *viper.Viper) map[string]any {
+	settings := make(map[string]any)
+	for _, key := range v.AllKeys() {
+		if !v.InConfig(key) {
+			continue
+		}
+		path := strings.Split(key, ".")
+		m := settings
+		for _, k := range path[:len(path)-1] {
+			next, ok := m[k].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				m[k] = next
+			}
+			m = next
+		}
+		m[path[len(path)-1]] = v.Get(key)
+	}
+	return settings
+}
+
+func toListeners(cls []listener.ConfigListener) []listener.ApplicationListener {
+	listeners := make([]listener.ApplicationListener, len(cls))
+	for i, l := range cls {
+		listeners[i] = l
+	}
+	return listeners
+}
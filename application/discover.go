@@ -0,0 +1,55 @@
+package application
+
+import (
+	"github.com/archine/gin-plus/v3/listener"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"reflect"
+)
+
+/*
+discover Register the beans implementing the application listener or the method interceptor, so
+they don't need to be passed to New() or Interceptor(). The beans are the ones set by mvc.SetBeans, the controllers and
+the beans injected into them, see mvc.Beans(). It runs before PreApply, after the modules started and after the apis are
+mounted, so the listeners of the beans set by the PreApply listeners or only injected into the controllers miss the
+PreApply event. The method interceptors are only discovered before the apis are mounted, the ones found later are not
+applied, they should be set by mvc.SetBeans before Run() or passed to Interceptor(). The registered beans are skipped.
+*/
+func (a *App) discover(beans []any, interceptors bool) {
+	registered := make(map[any]bool)
+	for _, l := range a.listeners {
+		if hashable(l) {
+			registered[l] = true
+		}
+	}
+	for _, i := range a.interceptors {
+		if si, ok := i.(*scopedInterceptor); ok {
+			i = si.MethodInterceptor
+		}
+		if hashable(i) {
+			registered[i] = true
+		}
+	}
+	for _, bean := range beans {
+		if registered[bean] {
+			continue
+		}
+		if listener.IsListener(bean) {
+			a.listeners = append(a.listeners, bean)
+			logger.Log.Debugf("Listener %T is discovered", bean)
+		}
+		if i, ok := bean.(mvc.MethodInterceptor); ok {
+			if interceptors {
+				a.interceptors = append(a.interceptors, i)
+				logger.Log.Debugf("Interceptor %T is discovered", bean)
+			} else {
+				logger.Log.Warnf("Interceptor %T is not applied, it's found after the apis are mounted, set it by mvc.SetBeans before Run()", bean)
+			}
+		}
+	}
+}
+
+// whether the value can be the key of the map
+func hashable(v any) bool {
+	return v != nil && reflect.TypeOf(v).Comparable()
+}
@@ -0,0 +1,105 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/discovery"
+	"github.com/archine/gin-plus/v3/plugin/jobs"
+	"github.com/archine/gin-plus/v3/plugin/k8s"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/plugin/messaging"
+	"github.com/archine/gin-plus/v3/plugin/scheduler"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// inflight tracks the requests being served, so the requests aborted by the shutdown can be counted
+type inflight struct {
+	active atomic.Int64
+}
+
+func (f *inflight) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.active.Add(1)
+		defer f.active.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// the delay of serving after the readiness fails, the kubernetes one takes precedence
+func preStopDelay() time.Duration {
+	if Conf.Kubernetes.Enabled {
+		return Conf.Kubernetes.PreStopDelay
+	}
+	return Conf.Shutdown.PreStopDelay
+}
+
+// the time waiting for the in-flight requests and the running jobs, the configuration overrides App.ExitDelay()
+func (a *App) exitDelayOf() time.Duration {
+	if Conf.Shutdown.ExitDelay > 0 {
+		return Conf.Shutdown.ExitDelay
+	}
+	return a.exitDelay
+}
+
+// stop the scheduled tasks and wait for the running jobs until the shutdown deadline
+func (a *App) stopScheduler(ctx context.Context) {
+	if err := scheduler.Default.Stop(ctx); err != nil {
+		logger.Log.Warnf("Scheduler stop timeout, %s", err.Error())
+	}
+}
+
+// stop the job workers after the ready jobs are handled until the shutdown deadline
+func (a *App) stopJobs(ctx context.Context) {
+	if err := jobs.Default.Stop(ctx); err != nil {
+		logger.Log.Warnf("Job queue stop timeout, %s", err.Error())
+	}
+}
+
+// stop consuming the messages and wait for the handling ones until the shutdown deadline
+func (a *App) stopListeners(ctx context.Context) {
+	if err := messaging.Default.Stop(ctx); err != nil {
+		logger.Log.Warnf("Message listeners stop timeout, %s", err.Error())
+	}
+}
+
+// fail the readiness and close the keep-alive connections after their current requests, then keep serving for the
+// pre-stop delay, so the load balancers move the traffic away before the listener is closed
+func (a *App) prepareStop(server *http.Server) {
+	server.SetKeepAlivesEnabled(false)
+	a.deregister()
+	a.setServing(false)
+	k8s.PrepareStop(preStopDelay())
+}
+
+// close the listener and wait for the in-flight requests until the shutdown deadline, the connections still serving
+// after it are closed and counted as aborted
+func (a *App) drain(ctx context.Context, server *http.Server) {
+	begin := time.Now()
+	active := a.inflight.active.Load()
+	err := server.Shutdown(ctx)
+	if err == nil {
+		logger.Log.Debugf("Drained %d in-flight requests in %s", active, time.Since(begin).Round(time.Millisecond))
+		return
+	}
+	aborted := a.inflight.active.Load()
+	_ = server.Close()
+	if errors.Is(err, context.DeadlineExceeded) {
+		logger.Log.Warnf("Server shutdown timeout after %s, %d in-flight requests aborted", time.Since(begin).Round(time.Millisecond), aborted)
+		return
+	}
+	logger.Log.Errorf("Server shutdown failure, %d in-flight requests aborted, %s", aborted, err.Error())
+}
+
+// deregister the instance from the service registry, so the clients stop discovering it before the server shuts down.
+// It's called by both the pre-stop and the shutdown, only the first one deregisters
+func (a *App) deregister() {
+	a.deregistered.Do(func() {
+		ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFunc()
+		if err := discovery.Default.Deregister(ctx); err != nil {
+			logger.Log.Warnf("Deregister instance error, %s", err.Error())
+		}
+	})
+}
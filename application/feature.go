@@ -0,0 +1,163 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+/*
+Feature the reusable bundle of controllers, beans, migrations and configuration defaults, such as a shared audit api
+imported by multiple services. The configuration of the feature is namespaced by features.<name>, and its apis are
+mounted under the route prefix, default /<name>, so the features don't collide with each other or the service.
+
+	features:
+	  audit:
+	    enabled: true       # default true
+	    prefix: /api/audit  # default /audit
+	    retention: 30d      # the configuration of the feature
+
+	func (a *AuditFeature) Name() string { return "audit" }
+
+	func (a *AuditFeature) Setup(f *application.FeatureContext) {
+	    f.Defaults(map[string]any{"retention": "7d"})
+	    f.Beans(&AuditStore{})
+	    f.Controllers(&AuditController{})
+	    f.Migrations(migrateAuditTable)
+	    f.DependsOn("db")
+	}
+*/
+type Feature interface {
+	// Name of the feature, unique in the application, it's the namespace of the configuration
+	Name() string
+
+	// Setup the feature, triggered when the feature is added to the application
+	Setup(f *FeatureContext)
+}
+
+// Migration the migration of the feature, such as creating the tables, it should be idempotent
+type Migration func(ctx context.Context) error
+
+// FeatureContext the namespaced registry of the feature
+type FeatureContext struct {
+	name        string
+	conf        *viper.Viper
+	controllers []interface{ PostConstruct() }
+	migrations  []Migration
+	dependsOn   []string
+}
+
+// Name of the feature
+func (f *FeatureContext) Name() string {
+	return f.name
+}
+
+// Key Get the namespaced key of the configuration, such as retention is features.audit.retention
+func (f *FeatureContext) Key(key string) string {
+	return "features." + f.name + "." + key
+}
+
+// Prefix Get the route prefix of the feature, default /<name>
+func (f *FeatureContext) Prefix() string {
+	return f.conf.GetString(f.Key("prefix"))
+}
+
+// Enabled Whether the feature is enabled, default true
+func (f *FeatureContext) Enabled() bool {
+	return f.conf.GetBool(f.Key("enabled"))
+}
+
+// Defaults Set the default configuration of the feature, the keys are relative to the namespace
+func (f *FeatureContext) Defaults(values map[string]any) {
+	for k, v := range values {
+		f.conf.SetDefault(f.Key(k), v)
+	}
+}
+
+// Config Read the configuration of the feature merged with the defaults
+// v: config struct pointer
+func (f *FeatureContext) Config(v any) error {
+	sub := viper.New()
+	ns := "features." + f.name + "."
+	for _, k := range f.conf.AllKeys() {
+		if rest, ok := strings.CutPrefix(k, ns); ok {
+			sub.Set(rest, f.conf.Get(k))
+		}
+	}
+	return sub.Unmarshal(v)
+}
+
+// Beans Add the beans of the feature, they can be injected into the controllers.
+// The beans whose conditions don't match are skipped, see mvc.ConditionalBean
+func (f *FeatureContext) Beans(beans ...any) {
+	mvc.SetBeans(beans...)
+}
+
+// Controllers Add the controllers of the feature, their apis are mounted under the prefix of the feature
+func (f *FeatureContext) Controllers(controllers ...interface{ PostConstruct() }) {
+	f.controllers = append(f.controllers, controllers...)
+}
+
+// Migrations Add the migrations of the feature, they run in order at startup with the modules
+func (f *FeatureContext) Migrations(migrations ...Migration) {
+	f.migrations = append(f.migrations, migrations...)
+}
+
+// DependsOn Names of the modules must be initialized before the migrations, such as the database module
+func (f *FeatureContext) DependsOn(modules ...string) {
+	f.dependsOn = append(f.dependsOn, modules...)
+}
+
+// Features Add the feature modules, the disabled ones and the ones whose conditions don't match are skipped
+func (a *App) Features(features ...Feature) *App {
+	conf := GetConfReader()
+	for _, feature := range features {
+		name := feature.Name()
+		if _, ok := a.features[name]; ok {
+			logger.Log.Fatalf("feature %s is duplicated", name)
+		}
+		if a.features == nil {
+			a.features = make(map[string]bool)
+		}
+		a.features[name] = true
+		f := &FeatureContext{name: name, conf: conf}
+		f.Defaults(map[string]any{"enabled": true, "prefix": "/" + name})
+		if !f.Enabled() || !mvc.Enabled(feature) {
+			logger.Log.Debugf("Feature %s is disabled", name)
+			continue
+		}
+		feature.Setup(f)
+		for _, c := range f.controllers {
+			mvc.RegisterGroup(f.Prefix(), c)
+		}
+		if len(f.migrations) > 0 {
+			a.modules = append(a.modules, &featureModule{f})
+		}
+	}
+	return a
+}
+
+// featureModule runs the migrations of the feature
+type featureModule struct {
+	*FeatureContext
+}
+
+func (m *featureModule) Name() string {
+	return "feature:" + m.name
+}
+
+func (m *featureModule) DependsOn() []string {
+	return m.dependsOn
+}
+
+func (m *featureModule) Init(ctx context.Context) error {
+	for i, migrate := range m.migrations {
+		if err := migrate(ctx); err != nil {
+			return fmt.Errorf("migration %d error, %w", i+1, err)
+		}
+	}
+	return nil
+}
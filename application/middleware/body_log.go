@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BodyLogConfig the request and response body logging, it's a debug aid and logged at debug level
+type BodyLogConfig struct {
+	Enabled       bool     `mapstructure:"enabled"`        // Whether to log the bodies, default false
+	MaxBodySize   int      `mapstructure:"max_body_size"`  // Maximum logged bytes of each body, default 4096
+	RedactFields  []string `mapstructure:"redact_fields"`  // Json and form fields whose values are redacted, appended to mvc.SensitiveFields
+	Paths         []string `mapstructure:"paths"`          // Ant-style patterns of the route templates logged, default all routes
+	ExcludedPaths []string `mapstructure:"excluded_paths"` // Ant-style patterns of the route templates not logged
+}
+
+/*
+BodyLog The gin middleware logs the request and response bodies up to the maximum size through the logger plugin,
+the values of the redacted fields in the json and form bodies are replaced, the bodies of other content types,
+such as the files, are logged by the size only.
+*/
+func BodyLog(conf BodyLogConfig) gin.HandlerFunc {
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = 4096
+	}
+	redact := redactor(append(append([]string(nil), mvc.SensitiveFields...), conf.RedactFields...))
+	var included, excluded func(ctx *gin.Context) bool
+	if len(conf.Paths) > 0 {
+		included = mvc.PathPredicate(conf.Paths...)
+	}
+	if len(conf.ExcludedPaths) > 0 {
+		excluded = mvc.PathPredicate(conf.ExcludedPaths...)
+	}
+	return func(ctx *gin.Context) {
+		if (included != nil && !included(ctx)) || (excluded != nil && excluded(ctx)) {
+			ctx.Next()
+			return
+		}
+		var reqBody []byte
+		var reqTruncated bool
+		if ctx.Request.Body != nil {
+			reqBody, reqTruncated = peekRequestBody(ctx, conf.MaxBodySize)
+		}
+		w := &bodyLogWriter{ResponseWriter: ctx.Writer, max: conf.MaxBodySize}
+		ctx.Writer = w
+		start := time.Now()
+		defer func() {
+			ctx.Writer = w.ResponseWriter
+			var buf strings.Builder
+			buf.WriteString("[body] trace_id=" + ctx.GetString("trace_id") + " " + ctx.Request.Method + " " + ctx.Request.URL.Path +
+				" status=" + strconv.Itoa(w.Status()) + " latency=" + time.Since(start).String())
+			if len(reqBody) > 0 {
+				buf.WriteString(" request_body=" + formatBody(ctx.ContentType(), reqBody, reqTruncated, redact))
+			}
+			if len(w.buf) > 0 {
+				buf.WriteString(" response_body=" + formatBody(w.Header().Get("Content-Type"), w.buf, w.Size() > len(w.buf), redact))
+			}
+			logger.Log.Debug(buf.String())
+		}()
+		ctx.Next()
+	}
+}
+
+// read the head of the request body, the body is still readable in full by the handlers
+func peekRequestBody(ctx *gin.Context, max int) ([]byte, bool) {
+	body := ctx.Request.Body
+	head, _ := io.ReadAll(io.LimitReader(body, int64(max)+1))
+	ctx.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(head), body), Closer: body}
+	if len(head) > max {
+		return head[:max], true
+	}
+	return head, false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter keeps the head of the response body
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	max int
+	buf []byte
+}
+
+func (w *bodyLogWriter) capture(b []byte) {
+	if rest := w.max - len(w.buf); rest > 0 {
+		w.buf = append(w.buf, b[:min(len(b), rest)]...)
+	}
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// the redacted and quoted body, the bodies of the binary content types are logged by the size
+func formatBody(contentType string, body []byte, truncated bool, redact func(string) string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !textual(mediaType) {
+		suffix := ""
+		if truncated {
+			suffix = "+"
+		}
+		return "<" + mediaType + " " + strconv.Itoa(len(body)) + suffix + " bytes>"
+	}
+	s := strconv.Quote(redact(string(body)))
+	if truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
+
+func textual(mediaType string) bool {
+	return mediaType == "" || strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") || mediaType == "application/x-www-form-urlencoded"
+}
+
+// the redactor replaces the values of the fields in the json and form bodies, it works on the truncated bodies too
+func redactor(fields []string) func(string) string {
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = regexp.QuoteMeta(f)
+	}
+	names := strings.Join(quoted, "|")
+	jsonField := regexp.MustCompile(`(?i)("(?:` + names + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	formField := regexp.MustCompile(`(?i)(^|&)((?:` + names + `)=)[^&]*`)
+	return func(body string) string {
+		body = jsonField.ReplaceAllString(body, `${1}"`+mvc.Redacted+`"`)
+		return formField.ReplaceAllString(body, "${1}${2}"+mvc.Redacted)
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPRule the client ips allowed or denied on the routes, the ip is resolved by gin with the trusted proxies
+type IPRule struct {
+	Paths []string `mapstructure:"paths"` // Ant-style patterns of the route templates, default all routes
+	Allow []string `mapstructure:"allow"` // Allowed ips or CIDR ranges, such as 10.0.0.0/8, empty means all ips except the denied ones
+	Deny  []string `mapstructure:"deny"`  // Denied ips or CIDR ranges, checked before the allowed ones
+}
+
+type ipRule struct {
+	match func(ctx *gin.Context) bool
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// IPFilter The gin middleware responds the requests of the denied client ips with http status 403,
+// every rule matching the route applies. The unmatched routes are not filtered
+func IPFilter(rules []IPRule) (gin.HandlerFunc, error) {
+	compiled := make([]ipRule, 0, len(rules))
+	for _, r := range rules {
+		c := ipRule{}
+		var err error
+		if c.allow, err = parsePrefixes(r.Allow); err != nil {
+			return nil, err
+		}
+		if c.deny, err = parsePrefixes(r.Deny); err != nil {
+			return nil, err
+		}
+		if len(r.Paths) > 0 {
+			c.match = mvc.PathPredicate(r.Paths...)
+		}
+		compiled = append(compiled, c)
+	}
+	return func(ctx *gin.Context) {
+		if ctx.FullPath() == "" {
+			ctx.Next()
+			return
+		}
+		var ip netip.Addr
+		parsed := false
+		for _, r := range compiled {
+			if r.match != nil && !r.match(ctx) {
+				continue
+			}
+			if !parsed {
+				ip, _ = netip.ParseAddr(ctx.ClientIP())
+				ip, parsed = ip.Unmap(), true
+			}
+			if containsIP(r.deny, ip) || (len(r.allow) > 0 && !containsIP(r.allow, ip)) {
+				logger.Log.Debugf("[ip_filter] %s %s is denied", ip, ctx.Request.URL.Path)
+				resp.StatusFailed(ctx, exception.NewStatusErr(http.StatusForbidden, resp.ForbiddenCode, "禁止访问"))
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Next()
+	}, nil
+}
+
+// the ips are the single address ranges, the IPv4-mapped ones are unmapped as the client ips
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if strings.Contains(v, "/") {
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %s, %s", v, err.Error())
+			}
+			// the IPv4-mapped range matches the unmapped client ips, such as ::ffff:10.0.0.0/104
+			if p.Addr().Is4In6() && p.Bits() >= 96 {
+				p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip %s, %s", v, err.Error())
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// the invalid ip is in no range
+func containsIP(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log = &logger.DefaultLog{}
+	filter, err := IPFilter([]IPRule{
+		{Paths: []string{"/admin/**"}, Allow: []string{"10.0.0.0/8", "::ffff:192.168.0.0/112", "2001:db8::/32"}, Deny: []string{"10.0.0.13"}},
+		{Deny: []string{"203.0.113.0/24"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := gin.New()
+	if err = e.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	e.Use(filter)
+	e.GET("/admin/users", func(ctx *gin.Context) {})
+	e.GET("/public", func(ctx *gin.Context) {})
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{name: "allowed range", path: "/admin/users", remoteAddr: "10.1.2.3:80", want: http.StatusOK},
+		{name: "denied in the allowed range", path: "/admin/users", remoteAddr: "10.0.0.13:80", want: http.StatusForbidden},
+		{name: "out of the allowed ranges", path: "/admin/users", remoteAddr: "172.16.0.1:80", want: http.StatusForbidden},
+		{name: "IPv4-mapped client in the IPv4 range", path: "/admin/users", remoteAddr: "[::ffff:10.1.2.3]:80", want: http.StatusOK},
+		{name: "IPv4 client in the IPv4-mapped range", path: "/admin/users", remoteAddr: "192.168.1.1:80", want: http.StatusOK},
+		{name: "IPv4-mapped client denied", path: "/admin/users", remoteAddr: "[::ffff:10.0.0.13]:80", want: http.StatusForbidden},
+		{name: "IPv6 range", path: "/admin/users", remoteAddr: "[2001:db8::1]:80", want: http.StatusOK},
+		{name: "forwarded ip of the untrusted proxy", path: "/admin/users", remoteAddr: "172.16.0.1:80", forwarded: "10.1.2.3", want: http.StatusForbidden},
+		{name: "denied on all routes", path: "/public", remoteAddr: "203.0.113.7:80", want: http.StatusForbidden},
+		{name: "route without the allowed ranges", path: "/public", remoteAddr: "172.16.0.1:80", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPFilterInvalidRange(t *testing.T) {
+	for _, v := range []string{"10.0.0.0/33", "10.0.0.256", "example.com"} {
+		if _, err := IPFilter([]IPRule{{Allow: []string{v}}}); err == nil {
+			t.Errorf("IPFilter of %s error = nil, want the invalid range", v)
+		}
+	}
+}
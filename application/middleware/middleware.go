@@ -1,11 +1,36 @@
 package middleware
 
 import (
+	"errors"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"time"
 )
 
-// Cors Cross-domain middleware
+// CorsConfig the cross-domain policy
+type CorsConfig struct {
+	AllowOrigins     []string      `mapstructure:"allow_origins"`     // Allowed origins, such as https://app.example.com and https://*.example.com, * means any origin
+	AllowMethods     []string      `mapstructure:"allow_methods"`     // Allowed methods, default PUT, PATCH, POST, GET, DELETE, OPTIONS and HEAD
+	AllowHeaders     []string      `mapstructure:"allow_headers"`     // Allowed request headers, default Origin, Authorization and Content-Type
+	ExposeHeaders    []string      `mapstructure:"expose_headers"`    // Response headers exposed to the scripts, default Content-Length
+	AllowCredentials bool          `mapstructure:"allow_credentials"` // Whether to allow the cookies, not allowed with the origin *, default false
+	MaxAge           time.Duration `mapstructure:"max_age"`           // How long the preflight results are cached, default 12h
+}
+
+// Validate Check the policy works in the browsers, which reject the credentials of any origin
+func (c CorsConfig) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			return errors.New("cors: allow_credentials is not allowed with the origin *, list the origins instead")
+		}
+	}
+	return nil
+}
+
+// Cors Cross-domain middleware allows every origin with credentials, only for the development
 func Cors() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowMethods:     []string{"PUT", "PATCH", "POST", "GET", "DELETE", "OPTIONS", "HEAD"},
@@ -17,3 +42,38 @@ func Cors() gin.HandlerFunc {
 		},
 	})
 }
+
+// CorsWith Cross-domain middleware of the policy, it panics when the origins are invalid or the policy is rejected by Validate
+func CorsWith(conf CorsConfig) gin.HandlerFunc {
+	if err := conf.Validate(); err != nil {
+		panic(err)
+	}
+	c := cors.Config{
+		AllowOrigins:     conf.AllowOrigins,
+		AllowMethods:     conf.AllowMethods,
+		AllowHeaders:     conf.AllowHeaders,
+		ExposeHeaders:    conf.ExposeHeaders,
+		AllowCredentials: conf.AllowCredentials,
+		AllowWildcard:    true,
+		MaxAge:           conf.MaxAge,
+	}
+	if len(c.AllowMethods) == 0 {
+		c.AllowMethods = []string{"PUT", "PATCH", "POST", "GET", "DELETE", "OPTIONS", "HEAD"}
+	}
+	if len(c.AllowHeaders) == 0 {
+		c.AllowHeaders = []string{"Origin", "Authorization", "Content-Type"}
+	}
+	if len(c.ExposeHeaders) == 0 {
+		c.ExposeHeaders = []string{"Content-Length"}
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = 12 * time.Hour
+	}
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			c.AllowOrigins, c.AllowAllOrigins = nil, true
+			break
+		}
+	}
+	return cors.New(c)
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecurityHeadersConfig the security headers of the responses, the empty header is not sent
+type SecurityHeadersConfig struct {
+	Enabled               bool          `mapstructure:"enabled"`                 // Whether to send the security headers, default false
+	HSTSMaxAge            time.Duration `mapstructure:"hsts_max_age"`            // Max age of Strict-Transport-Security, only sent over https, default 8760h, 0 means not sent
+	HSTSIncludeSubdomains bool          `mapstructure:"hsts_include_subdomains"` // Add includeSubDomains to Strict-Transport-Security, default false
+	HSTSPreload           bool          `mapstructure:"hsts_preload"`            // Add preload to Strict-Transport-Security, default false
+	ContentTypeOptions    string        `mapstructure:"content_type_options"`    // X-Content-Type-Options, default nosniff
+	FrameOptions          string        `mapstructure:"frame_options"`           // X-Frame-Options, default DENY
+	ReferrerPolicy        string        `mapstructure:"referrer_policy"`         // Referrer-Policy, default strict-origin-when-cross-origin
+	ContentSecurityPolicy string        `mapstructure:"content_security_policy"` // Content-Security-Policy, such as default-src 'self'
+	CSPReportOnly         bool          `mapstructure:"csp_report_only"`         // Send the policy as Content-Security-Policy-Report-Only, default false
+	PermissionsPolicy     string        `mapstructure:"permissions_policy"`      // Permissions-Policy, such as camera=(), microphone=()
+	ExcludedPaths         []string      `mapstructure:"excluded_paths"`          // Ant-style patterns of the route templates without the headers
+}
+
+// DefaultSecurityHeaders the recommended security headers
+func DefaultSecurityHeaders() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		Enabled:            true,
+		HSTSMaxAge:         365 * 24 * time.Hour,
+		ContentTypeOptions: "nosniff",
+		FrameOptions:       "DENY",
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+	}
+}
+
+// SecurityHeaders The gin middleware sets the security headers of the responses, the handlers can override them
+func SecurityHeaders(conf SecurityHeadersConfig) gin.HandlerFunc {
+	var headers [][2]string
+	add := func(name, value string) {
+		if value != "" {
+			headers = append(headers, [2]string{name, value})
+		}
+	}
+	add("X-Content-Type-Options", conf.ContentTypeOptions)
+	add("X-Frame-Options", conf.FrameOptions)
+	add("Referrer-Policy", conf.ReferrerPolicy)
+	add("Permissions-Policy", conf.PermissionsPolicy)
+	if conf.CSPReportOnly {
+		add("Content-Security-Policy-Report-Only", conf.ContentSecurityPolicy)
+	} else {
+		add("Content-Security-Policy", conf.ContentSecurityPolicy)
+	}
+	var hsts string
+	if conf.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(conf.HSTSMaxAge.Seconds()), 10)
+		if conf.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if conf.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	var excluded func(ctx *gin.Context) bool
+	if len(conf.ExcludedPaths) > 0 {
+		excluded = mvc.PathPredicate(conf.ExcludedPaths...)
+	}
+	return func(ctx *gin.Context) {
+		if excluded != nil && excluded(ctx) {
+			ctx.Next()
+			return
+		}
+		h := ctx.Writer.Header()
+		for _, kv := range headers {
+			h.Set(kv[0], kv[1])
+		}
+		// browsers ignore the header over http
+		if hsts != "" && (ctx.Request.TLS != nil || strings.EqualFold(ctx.GetHeader("X-Forwarded-Proto"), "https")) {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		ctx.Next()
+	}
+}
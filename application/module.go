@@ -0,0 +1,356 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/archine/gin-plus/v3/banner"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Module the independent component initialized at startup, such as redis, kafka, db and templates.
+// The modules are initialized in parallel after PreApply, so the beans they provide can be injected into the controllers
+type Module interface {
+	// Name of the module, unique in the application
+	Name() string
+
+	// Init the module, returning error stops the application.
+	// The context is canceled when the startup timeout is reached or another module fails
+	Init(ctx context.Context) error
+}
+
+// DependentModule the module initialized after its dependencies
+type DependentModule interface {
+	Module
+
+	// DependsOn Names of the modules must be initialized before this one
+	DependsOn() []string
+}
+
+// PrioritizedModule the module started earlier than the lower priority ones when they are ready at the same time
+type PrioritizedModule interface {
+	Module
+
+	// Priority of the module, the higher the earlier, default 0
+	Priority() int
+}
+
+// StatusModule the module reporting its status line printed after the banner, such as connected to 127.0.0.1:6379
+type StatusModule interface {
+	Module
+
+	// Status of the module, empty means the startup duration is printed
+	Status() string
+}
+
+// ConfigurableModule the module reading its configuration before it's initialized, such as db.Module reading the
+// datasource. It's configured even in the mock mode, the error stops the application
+type ConfigurableModule interface {
+	Module
+
+	// Configure the module by the application configuration
+	Configure(conf *viper.Viper) error
+}
+
+// BeanModule the module providing the beans injected like the controllers after the apis are mounted, such as the grpc
+// services. They're discovered as the listeners, and injected even in the mock mode
+type BeanModule interface {
+	Module
+
+	// Beans injected by mvc.Autowire
+	Beans() []any
+}
+
+// EndpointModule the module mounting its endpoints with the built-in endpoints after the apis are mounted, such as the
+// graphql endpoint. It's mounted even in the mock mode
+type EndpointModule interface {
+	Module
+
+	// Mount the endpoints
+	Mount(e *gin.Engine)
+}
+
+// ServerModule the module serving its own protocol alongside the http server, such as the grpc server. It serves even
+// in the mock mode
+type ServerModule interface {
+	Module
+
+	// Serve on the listener of the application before PostStart, the returned listener is served by the http server.
+	// The module can split the connections of the shared port, or listen on its own port and return the listener
+	Serve(ln net.Listener) (net.Listener, error)
+
+	// SetServing Set whether the module accepts the calls, true after the application started and false when it stops
+	SetServing(serving bool)
+
+	// Stop serving and wait for the running calls until the context is done, it's called after the http server drained
+	Stop(ctx context.Context) error
+}
+
+// ModuleTiming the startup timeline of a module
+type ModuleTiming struct {
+	Name     string
+	Start    time.Duration // Offset from the startup of the modules
+	Duration time.Duration
+}
+
+// Startup timeline of the modules, in order of completion
+var moduleTimeline []ModuleTiming
+
+// ModuleTimeline Get the startup timeline of the modules
+func ModuleTimeline() []ModuleTiming {
+	return moduleTimeline
+}
+
+// Modules Add the modules initialized at startup, the modules whose conditions don't match are skipped,
+// see mvc.ConditionalBean
+func (a *App) Modules(modules ...Module) *App {
+	for _, m := range modules {
+		if mvc.Enabled(m) {
+			a.modules = append(a.modules, m)
+		}
+	}
+	return a
+}
+
+// configure the modules implementing ConfigurableModule in order
+func configureModules(modules []Module, conf *viper.Viper) error {
+	for _, m := range modules {
+		if cm, ok := m.(ConfigurableModule); ok {
+			if err := cm.Configure(conf); err != nil {
+				return fmt.Errorf("module %s configure error, %w", m.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// the beans of the modules implementing BeanModule
+func moduleBeans(modules []Module) []any {
+	var beans []any
+	for _, m := range modules {
+		if bm, ok := m.(BeanModule); ok {
+			beans = append(beans, bm.Beans()...)
+		}
+	}
+	return beans
+}
+
+type moduleResult struct {
+	index  int
+	timing ModuleTiming
+	err    error
+}
+
+/*
+start the modules in parallel as the dependency graph permits, at most parallelism modules run at the same time,
+0 means unlimited. The first failure cancels the others and is returned after the running ones return.
+*/
+func startModules(modules []Module, parallelism int, timeout time.Duration) ([]ModuleTiming, error) {
+	if len(modules) == 0 {
+		return nil, nil
+	}
+	index := make(map[string]int, len(modules))
+	for i, m := range modules {
+		if _, ok := index[m.Name()]; ok {
+			return nil, fmt.Errorf("module %s is duplicated", m.Name())
+		}
+		index[m.Name()] = i
+	}
+	pending := make([]int, len(modules)) // count of uninitialized dependencies
+	dependents := make([][]int, len(modules))
+	for i, m := range modules {
+		dm, ok := m.(DependentModule)
+		if !ok {
+			continue
+		}
+		for _, dep := range dm.DependsOn() {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("module %s depends on the unknown module %s", m.Name(), dep)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+	if cycle := findCycle(modules, dependents); cycle != "" {
+		return nil, fmt.Errorf("modules have circular dependencies, %s", cycle)
+	}
+	if parallelism <= 0 {
+		parallelism = len(modules)
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+	var ready []int
+	for i := range modules {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	begin := time.Now()
+	results := make(chan moduleResult, len(modules))
+	var timeline []ModuleTiming
+	var firstErr error
+	running := make(map[int]bool)
+	for done := 0; done < len(modules); done++ {
+		if firstErr == nil {
+			sortByPriority(modules, ready)
+			for len(running) < parallelism && len(ready) > 0 {
+				i := ready[0]
+				ready = ready[1:]
+				running[i] = true
+				go func(i int) {
+					start := time.Now()
+					err := modules[i].Init(ctx)
+					if err == nil && ctx.Err() != nil {
+						err = ctx.Err()
+					}
+					results <- moduleResult{i, ModuleTiming{modules[i].Name(), start.Sub(begin), time.Since(start)}, err}
+				}(i)
+			}
+		}
+		if len(running) == 0 {
+			break
+		}
+		var r moduleResult
+		select {
+		case r = <-results:
+		case <-ctx.Done():
+			if firstErr != nil {
+				r = <-results
+				break
+			}
+			// the modules ignoring the context are not waited
+			var names []string
+			for i := range running {
+				names = append(names, modules[i].Name())
+			}
+			sort.Strings(names)
+			return timeline, fmt.Errorf("modules init timeout, %s not finished", strings.Join(names, ", "))
+		}
+		delete(running, r.index)
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("module %s init error, %w", r.timing.Name, r.err)
+				cancel()
+			}
+			continue
+		}
+		timeline = append(timeline, r.timing)
+		for _, j := range dependents[r.index] {
+			if pending[j]--; pending[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	return timeline, firstErr
+}
+
+func sortByPriority(modules []Module, ready []int) {
+	priority := func(i int) int {
+		if pm, ok := modules[i].(PrioritizedModule); ok {
+			return pm.Priority()
+		}
+		return 0
+	}
+	sort.SliceStable(ready, func(x, y int) bool {
+		return priority(ready[x]) > priority(ready[y])
+	})
+}
+
+// find a dependency cycle, such as a -> b -> a, empty means no cycle
+func findCycle(modules []Module, dependents [][]int) string {
+	state := make([]int, len(modules)) // 0 unvisited, 1 visiting, 2 visited
+	var path []string
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		state[i] = 1
+		path = append(path, modules[i].Name())
+		for _, j := range dependents[i] {
+			if state[j] == 1 {
+				path = append(path, modules[j].Name())
+				return true
+			}
+			if state[j] == 0 && visit(j) {
+				return true
+			}
+		}
+		state[i] = 2
+		path = path[:len(path)-1]
+		return false
+	}
+	for i := range modules {
+		if state[i] == 0 && visit(i) {
+			return strings.Join(path, " -> ")
+		}
+	}
+	return ""
+}
+
+// print the startup timeline of the modules in debug level
+func printModuleTimeline(timeline []ModuleTiming, total time.Duration) {
+	var b strings.Builder
+	for _, t := range timeline {
+		_, _ = fmt.Fprintf(&b, "  %-16s +%-10s %s\n", t.Name, t.Start.Round(time.Millisecond), t.Duration.Round(time.Millisecond))
+	}
+	logger.Log.Debugf("Started %d modules in %s:\n%s", len(timeline), total.Round(time.Millisecond), b.String())
+}
+
+// print the initialization durations of the beans in debug level, the slow ones are reported in the status lines
+func printBeanTimeline(timeline []mvc.BeanTiming, slow time.Duration) {
+	if len(timeline) == 0 {
+		return
+	}
+	var b strings.Builder
+	for _, t := range timeline {
+		_, _ = fmt.Fprintf(&b, "  %-40s %s\n", t.Name, t.Duration.Round(time.Microsecond))
+		if slow > 0 && t.Duration >= slow {
+			banner.AddStatus(t.Name, "slow initialization (%s)", t.Duration.Round(time.Millisecond))
+		}
+	}
+	logger.Log.Debugf("Initialized %d beans:\n%s", len(timeline), b.String())
+}
+
+// report the status lines of the started modules
+func addModuleStatus(modules []Module, timeline []ModuleTiming) {
+	byName := make(map[string]Module, len(modules))
+	for _, m := range modules {
+		byName[m.Name()] = m
+	}
+	for _, t := range timeline {
+		status := fmt.Sprintf("started (%s)", t.Duration.Round(time.Millisecond))
+		if sm, ok := byName[t.Name].(StatusModule); ok {
+			if s := sm.Status(); s != "" {
+				status = s
+			}
+		}
+		banner.AddStatus(t.Name, "%s", status)
+	}
+}
+
+// close the modules implementing io.Closer in the reverse order of the startup
+func closeModules(modules []Module, timeline []ModuleTiming) {
+	byName := make(map[string]Module, len(modules))
+	for _, m := range modules {
+		byName[m.Name()] = m
+	}
+	for i := len(timeline) - 1; i >= 0; i-- {
+		if c, ok := byName[timeline[i].Name].(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				logger.Log.Warnf("Module %s close error, %s", timeline[i].Name, err.Error())
+			}
+		}
+	}
+}
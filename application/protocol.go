@@ -0,0 +1,69 @@
+package application
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/plugin/metrics"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Extra header bytes accepted by the server, the requests exceeding the configured limit within it
+// are responded with the standard error envelope instead of the plain text of net/http
+const headerBytesSlack = 64 << 10
+
+var (
+	protocolErrorsOnce sync.Once
+	protocolErrors     *metrics.Counter
+)
+
+// protocolGuard reject the oversized headers and malformed requests with the standard error envelope
+func protocolGuard(maxHeaderBytes int) gin.HandlerFunc {
+	if Conf.Metrics.Enabled {
+		protocolErrorsOnce.Do(func() {
+			protocolErrors = metrics.NewCounter("http_protocol_errors_total", "Number of requests rejected by protocol errors.", "status", "reason")
+		})
+	}
+	return func(ctx *gin.Context) {
+		if size := headerSize(ctx.Request); size > maxHeaderBytes {
+			rejectRequest(ctx, http.StatusRequestHeaderFieldsTooLarge, "header_too_large", "请求头过大")
+			return
+		}
+		if _, err := url.ParseQuery(ctx.Request.URL.RawQuery); err != nil {
+			rejectRequest(ctx, http.StatusBadRequest, "malformed_query", "请求参数格式错误")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func rejectRequest(ctx *gin.Context, status int, reason, msg string) {
+	logger.Log.Warnf("Reject request [%s %s] from %s, %s", ctx.Request.Method, ctx.Request.URL.Path, ctx.ClientIP(), reason)
+	if protocolErrors != nil {
+		protocolErrors.Inc(http.StatusText(status), reason)
+	}
+	resp.InitResp(ctx).WithBasic(resp.BadRequestCode, msg, nil).To(status)
+	ctx.Abort()
+}
+
+// the approximate size of the request line and the headers
+func headerSize(r *http.Request) int {
+	size := len(r.Method) + len(r.RequestURI) + len(r.Proto) + 4
+	for k, vs := range r.Header {
+		for _, v := range vs {
+			size += len(k) + len(v) + 4
+		}
+	}
+	return size
+}
+
+// logWriter redirect the errors of net/http to the logger, such as TLS handshake errors
+type logWriter struct{}
+
+func (l *logWriter) Write(p []byte) (int, error) {
+	logger.Log.Warn(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
@@ -0,0 +1,80 @@
+package application
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SelfCheck Startup self check, triggered after the server is listening.
+// baseUrl: the local address of the server, such as http://127.0.0.1:4006
+// Return error means the check failed, and the application will stop.
+type SelfCheck func(baseUrl string) error
+
+// SelfTestEndpoint the endpoint requested during the startup self test
+type SelfTestEndpoint struct {
+	Method string            `mapstructure:"method"` // Request method, default GET
+	Path   string            `mapstructure:"path"`   // Request path, such as /health
+	Status int               `mapstructure:"status"` // Expected http status, default 200
+	Header map[string]string `mapstructure:"header"` // Request headers
+}
+
+// SelfCheck Add startup self checks, they run after the configured self test endpoints
+func (a *App) SelfCheck(checks ...SelfCheck) *App {
+	a.selfChecks = append(a.selfChecks, checks...)
+	return a
+}
+
+// run the startup self test, return the first failure
+func (a *App) selfTest() error {
+	if !Conf.SelfTest.Enabled && len(a.selfChecks) == 0 {
+		return nil
+	}
+	baseUrl := fmt.Sprintf("http://127.0.0.1:%d", Conf.Server.Port)
+	if Conf.SelfTest.Enabled {
+		client := &http.Client{Timeout: Conf.SelfTest.Timeout}
+		for _, endpoint := range Conf.SelfTest.Endpoints {
+			if err := requestEndpoint(client, baseUrl, endpoint); err != nil {
+				return err
+			}
+		}
+	}
+	for _, check := range a.selfChecks {
+		if err := check(baseUrl); err != nil {
+			return err
+		}
+	}
+	logger.Log.Debugf("Application self test passed")
+	return nil
+}
+
+func requestEndpoint(client *http.Client, baseUrl string, endpoint SelfTestEndpoint) error {
+	method := strings.ToUpper(endpoint.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	expected := endpoint.Status
+	if expected == 0 {
+		expected = http.StatusOK
+	}
+	req, err := http.NewRequest(method, baseUrl+endpoint.Path, nil)
+	if err != nil {
+		return fmt.Errorf("self test [%s %s] error, %s", method, endpoint.Path, err.Error())
+	}
+	for k, v := range endpoint.Header {
+		req.Header.Set(k, v)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("self test [%s %s] error, %s", method, endpoint.Path, err.Error())
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != expected {
+		return fmt.Errorf("self test [%s %s] failed, expected status %d but got %d", method, endpoint.Path, expected, resp.StatusCode)
+	}
+	logger.Log.Debugf("Self test [%s %s] passed in %s", method, endpoint.Path, time.Since(start))
+	return nil
+}
@@ -0,0 +1,50 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"net"
+	"net/http"
+)
+
+// listen the http server and the server modules, each server module serves on the listener and returns the one of the
+// http server, so it can split the connections of the shared port
+func (a *App) listen(server *http.Server) {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		logger.Log.Fatalf("Application start error, %s", err.Error())
+	}
+	for _, m := range a.modules {
+		if sm, ok := m.(ServerModule); ok {
+			if ln, err = sm.Serve(ln); err != nil {
+				logger.Log.Fatalf("Module %s serve error, %s", m.Name(), err.Error())
+			}
+		}
+	}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			logger.Log.Fatalf("Application start error, %s", err.Error())
+		}
+	}()
+}
+
+// set whether the server modules accept the calls
+func (a *App) setServing(serving bool) {
+	for _, m := range a.modules {
+		if sm, ok := m.(ServerModule); ok {
+			sm.SetServing(serving)
+		}
+	}
+}
+
+// stop the server modules and wait for the running calls until the shutdown deadline
+func (a *App) stopServers(ctx context.Context) {
+	for _, m := range a.modules {
+		if sm, ok := m.(ServerModule); ok {
+			if err := sm.Stop(ctx); err != nil {
+				logger.Log.Warnf("Module %s stop timeout, %s", m.Name(), err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package application
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/banner"
+	"github.com/archine/gin-plus/v3/listener"
+	"github.com/archine/gin-plus/v3/mvc"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StartupPhase the duration of a phase of the startup
+type StartupPhase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// StartupPhases Get the durations of the startup phases of the application, in order
+func (a *App) StartupPhases() []StartupPhase {
+	return a.clock.phases
+}
+
+// the clock measuring the startup phases, each phase lasts from the end of the previous one
+type phaseClock struct {
+	begin  time.Time
+	last   time.Time
+	phases []StartupPhase
+}
+
+func newPhaseClock() *phaseClock {
+	now := time.Now()
+	return &phaseClock{begin: now, last: now}
+}
+
+// mark the end of the phase
+func (c *phaseClock) mark(name string) {
+	now := time.Now()
+	c.phases = append(c.phases, StartupPhase{Name: name, Duration: now.Sub(c.last)})
+	c.last = now
+}
+
+// the status lines of the controllers, the routes, the interceptors, the listeners and the startup phases
+func (a *App) startupLines() []banner.StatusLine {
+	controllers := make(map[string]bool)
+	routes := mvc.Routes()
+	for _, r := range routes {
+		controllers[r.Controller] = true
+	}
+	interceptors := make([]string, 0, len(a.interceptors))
+	for _, i := range a.interceptors {
+		if si, ok := i.(*scopedInterceptor); ok {
+			interceptors = append(interceptors, fmt.Sprintf("%T (%s)", si.MethodInterceptor, si.pattern))
+			continue
+		}
+		interceptors = append(interceptors, fmt.Sprintf("%T", i))
+	}
+	var listeners []string
+	for _, l := range listener.Sorted(a.listeners) {
+		listeners = append(listeners, fmt.Sprintf("%T", l))
+	}
+	phases := make([]string, 0, len(a.clock.phases))
+	for _, p := range a.clock.phases {
+		phases = append(phases, fmt.Sprintf("%s %s", p.Name, p.Duration.Round(time.Millisecond)))
+	}
+	return []banner.StatusLine{
+		{Name: "Controllers", Status: strconv.Itoa(len(controllers))},
+		{Name: "Routes", Status: strconv.Itoa(len(routes))},
+		{Name: "Interceptors", Status: orNone(interceptors)},
+		{Name: "Listeners", Status: orNone(listeners)},
+		{Name: "Startup", Status: fmt.Sprintf("%s (%s)", time.Since(a.clock.begin).Round(time.Millisecond), strings.Join(phases, ", "))},
+	}
+}
+
+func orNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,70 @@
+package application
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"html/template"
+	"io/fs"
+)
+
+// templates the html templates of the application
+type templates struct {
+	pattern    string
+	fsys       fs.FS
+	fsPatterns []string
+	funcs      template.FuncMap
+}
+
+// Templates Load the html templates matched by the glob pattern, such as templates/*.html.
+// Except in prod environment, the templates are reloaded on every render, so the changes take effect without restarting
+func (a *App) Templates(pattern string) *App {
+	a.templates.pattern = pattern
+	return a
+}
+
+/*
+TemplatesFS Load the html templates from the fs, usually an embed.FS, the patterns are the same as fs.Glob.
+
+	//go:embed templates
+	var views embed.FS
+
+	app.TemplatesFS(views, "templates/*.html")
+*/
+func (a *App) TemplatesFS(fsys fs.FS, patterns ...string) *App {
+	a.templates.fsys = fsys
+	a.templates.fsPatterns = patterns
+	return a
+}
+
+// FuncMap Add the custom functions available in the html templates
+func (a *App) FuncMap(funcs template.FuncMap) *App {
+	if a.templates.funcs == nil {
+		a.templates.funcs = template.FuncMap{}
+	}
+	for name, fn := range funcs {
+		a.templates.funcs[name] = fn
+	}
+	return a
+}
+
+// load the html templates into the engine, the pattern of the configuration is used when no template is set
+func (a *App) loadTemplates() {
+	t := &a.templates
+	if t.funcs != nil {
+		a.e.SetFuncMap(t.funcs)
+	}
+	if t.fsys != nil {
+		tmpl, err := template.New("").Funcs(t.funcs).ParseFS(t.fsys, t.fsPatterns...)
+		if err != nil {
+			logger.Log.Fatalf("Load html templates error, %s", err.Error())
+		}
+		a.e.SetHTMLTemplate(tmpl)
+		return
+	}
+	pattern := t.pattern
+	if pattern == "" {
+		pattern = Conf.Template.Pattern
+	}
+	if pattern != "" {
+		a.e.LoadHTMLGlob(pattern)
+	}
+}
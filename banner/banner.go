@@ -1,5 +1,11 @@
 package banner
 
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
 var Banner = `
       /¯¯¯¯\
     o-|[][]|-o
@@ -12,3 +18,40 @@ var Banner = `
      |__||__|
      |__||__| (v3.1.2)
 `
+
+// Config the startup banner
+type Config struct {
+	Enabled  bool   `mapstructure:"enabled"`  // Whether to print the banner and the startup status, default true
+	Location string `mapstructure:"location"` // Path of the banner file, used instead of the default banner when it exists, default banner.txt
+}
+
+// the placeholders such as ${app.name}, the default value follows the colon, such as ${app.name:demo}
+var placeholder = regexp.MustCompile(`\$\{([^}:]+)(?::([^}]*))?}`)
+
+/*
+Render Substitute the placeholders of the banner by the resolver, the placeholder not resolved is replaced by its
+default value, or kept as it is when it has no default value.
+
+	Render("${app.name:demo} ${version}", resolve)
+*/
+func Render(text string, resolve func(name string) (string, bool)) string {
+	return placeholder.ReplaceAllStringFunc(text, func(s string) string {
+		m := placeholder.FindStringSubmatch(s)
+		if v, ok := resolve(strings.TrimSpace(m[1])); ok {
+			return v
+		}
+		if strings.Contains(s, ":") {
+			return m[2]
+		}
+		return s
+	})
+}
+
+// Load the banner file from the fs, such as an embed.FS
+func Load(fsys fs.FS, name string) (string, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
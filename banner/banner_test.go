@@ -0,0 +1,31 @@
+package banner
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	values := map[string]string{"app.name": "order", "version": "v1.2.0", "empty": ""}
+	resolve := func(name string) (string, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"${app.name}", "order"},
+		{"${ app.name }", "order"},
+		{"${app.name:demo} ${version}", "order v1.2.0"},
+		{"${empty:demo}", ""},
+		{"${app.port:8080}", "8080"},
+		{"${app.port:}", ""},
+		{"${app.port}", "${app.port}"},
+		{"$app.name {app.name}", "$app.name {app.name}"},
+		{"(${version}) ${unknown} ${app.name}", "(v1.2.0) ${unknown} order"},
+		{"no placeholder", "no placeholder"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.text, resolve); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package banner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// StatusLine the status line printed after the banner when the application starts, such as Redis: connected (3ms)
+type StatusLine struct {
+	Name   string
+	Status string
+}
+
+var (
+	statusMu    sync.Mutex
+	statusLines []StatusLine
+)
+
+// AddStatus Append the status line, the modules and the plugins report their status by it.
+//
+//	banner.AddStatus("Redis", "connected (%s)", cost)
+func AddStatus(name, format string, args ...any) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	statusLines = append(statusLines, StatusLine{Name: name, Status: fmt.Sprintf(format, args...)})
+}
+
+// StatusLines Get the appended status lines
+func StatusLines() []StatusLine {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return append([]StatusLine(nil), statusLines...)
+}
+
+// Format the status lines with the names aligned
+func Format(lines []StatusLine) string {
+	width := 0
+	for _, l := range lines {
+		width = max(width, len(l.Name))
+	}
+	var b strings.Builder
+	for _, l := range lines {
+		_, _ = fmt.Fprintf(&b, "  %-*s : %s\n", width, l.Name, l.Status)
+	}
+	return b.String()
+}
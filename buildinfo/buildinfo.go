@@ -0,0 +1,104 @@
+package buildinfo
+
+import (
+	"github.com/gin-gonic/gin"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+/*
+The build information set by the ldflags, the absent ones are read from the build information embedded by the go
+toolchain, such as the vcs revision and time of the main module.
+
+	go build -ldflags "-X github.com/archine/gin-plus/v3/buildinfo.Version=v1.2.0 \
+	    -X github.com/archine/gin-plus/v3/buildinfo.Commit=$(git rev-parse HEAD) \
+	    -X github.com/archine/gin-plus/v3/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+*/
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Info the build information of the running application
+type Info struct {
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Whether the working tree had local changes when it was built
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module,omitempty"` // Path of the main module
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get the build information, it's resolved once
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		info.Module = bi.Main.Path
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	})
+	return info
+}
+
+// ShortCommit Get the first 7 characters of the commit
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 7 {
+		return i.Commit[:7]
+	}
+	return i.Commit
+}
+
+// String the version, the short commit and the build time, such as v1.2.0 (3f2a1b9, 2026-01-02T15:04:05Z)
+func (i Info) String() string {
+	version := i.Version
+	if version == "" {
+		version = "unknown"
+	}
+	detail := i.ShortCommit()
+	if i.Modified && detail != "" {
+		detail += "-dirty"
+	}
+	if i.BuildTime != "" {
+		if detail != "" {
+			detail += ", "
+		}
+		detail += i.BuildTime
+	}
+	if detail == "" {
+		return version
+	}
+	return version + " (" + detail + ")"
+}
+
+// Handler Respond the build information as json
+func Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(200, Get())
+	}
+}
@@ -0,0 +1,31 @@
+package buildinfo
+
+import "testing"
+
+func TestInfoString(t *testing.T) {
+	tests := []struct {
+		name string
+		info Info
+		want string
+	}{
+		{"empty", Info{}, "unknown"},
+		{"version only", Info{Version: "v1.2.0"}, "v1.2.0"},
+		{"short commit", Info{Version: "v1.2.0", Commit: "3f2a1b9c0d"}, "v1.2.0 (3f2a1b9)"},
+		{"commit of 7 characters", Info{Version: "v1.2.0", Commit: "3f2a1b9"}, "v1.2.0 (3f2a1b9)"},
+		{"modified", Info{Version: "v1.2.0", Commit: "3f2a1b9c0d", Modified: true}, "v1.2.0 (3f2a1b9-dirty)"},
+		{"modified without commit", Info{Version: "v1.2.0", Modified: true}, "v1.2.0"},
+		{"build time only", Info{BuildTime: "2026-01-02T15:04:05Z"}, "unknown (2026-01-02T15:04:05Z)"},
+		{
+			name: "all",
+			info: Info{Version: "v1.2.0", Commit: "3f2a1b9c0d", Modified: true, BuildTime: "2026-01-02T15:04:05Z"},
+			want: "v1.2.0 (3f2a1b9-dirty, 2026-01-02T15:04:05Z)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Command clientgen generates the go client package from the OpenAPI document of a gin-plus application.
+//
+//	go run github.com/archine/gin-plus/v3/cmd/clientgen -spec http://user-service:4006/openapi.json -pkg userapi -o userapi/client.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/clientgen"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	spec := flag.String("spec", "", "Url or file of the OpenAPI document, such as http://localhost:4006/openapi.json")
+	pkg := flag.String("pkg", "client", "Package name of the client")
+	out := flag.String("o", "client/client.go", "Output file of the client")
+	tags := flag.String("tags", "", "Only generate the operations of the tags, separated by commas")
+	flag.Parse()
+	if *spec == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	doc, err := clientgen.Load(*spec)
+	if err != nil {
+		fail(err)
+	}
+	conf := clientgen.Config{Package: *pkg}
+	if *tags != "" {
+		conf.Tags = strings.Split(*tags, ",")
+	}
+	src, err := clientgen.Generate(doc, conf)
+	if err != nil {
+		fail(err)
+	}
+	if err = os.MkdirAll(filepath.Dir(*out), 0755); err != nil {
+		fail(err)
+	}
+	if err = os.WriteFile(*out, src, 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(1)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"github.com/archine/ioc"
+	"github.com/spf13/viper"
+	"reflect"
+	"sync"
+)
+
+// Typed value cache, the key is the configuration key and the type name
+var valueCache sync.Map
+
+/*
+Get the configuration value of the key and convert it to the specified type.
+The converted values are cached, they are reset when the application reloads the configuration, call Reset() after
+changing the configuration otherwise.
+
+	port, err := config.Get[int]("server.port")
+	timeout, err := config.Get[time.Duration]("redis.timeout")
+	redisConf, err := config.Get[RedisConf]("redis")
+*/
+func Get[T any](key string) (T, error) {
+	var val T
+	cacheKey := key + "#" + reflect.TypeOf(&val).Elem().String()
+	if cached, ok := valueCache.Load(cacheKey); ok {
+		return cached.(T), nil
+	}
+	v, ok := ioc.GetBeanByName("viper.Viper").(*viper.Viper)
+	if !ok {
+		return val, fmt.Errorf("config [%s] error, the configuration has not been loaded", key)
+	}
+	if !v.IsSet(key) {
+		return val, fmt.Errorf("config [%s] not found", key)
+	}
+	if err := v.UnmarshalKey(key, &val); err != nil {
+		return val, fmt.Errorf("config [%s] error, %s", key, err.Error())
+	}
+	valueCache.Store(cacheKey, val)
+	return val, nil
+}
+
+// MustGet the configuration value of the key, panic if the key does not exist or fails to convert
+func MustGet[T any](key string) T {
+	val, err := Get[T](key)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+// GetOrDefault the configuration value of the key, return def if the key does not exist or fails to convert
+func GetOrDefault[T any](key string, def T) T {
+	val, err := Get[T](key)
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// Reset clear the cached values
+func Reset() {
+	valueCache.Range(func(key, _ any) bool {
+		valueCache.Delete(key)
+		return true
+	})
+}
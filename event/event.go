@@ -0,0 +1,247 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Default the application event bus
+var Default = NewBus(Config{})
+
+// Config the event bus
+type Config struct {
+	Workers   int `mapstructure:"workers"`    // Workers delivering the async events, default 8
+	QueueSize int `mapstructure:"queue_size"` // Async events waiting for the workers, the publisher blocks when it's full, default 1024
+}
+
+/*
+Subscription the handler of the events of the type, the type can be an interface, such as subscribing all the events
+implementing it. It's created by On() and OnAsync().
+*/
+type Subscription struct {
+	typ     reflect.Type
+	async   bool
+	name    string
+	handler func(ctx context.Context, event any) error
+}
+
+/*
+Subscriber the bean subscribing the events, the subscriptions are registered when the application starts
+
+	func (m *MailService) Subscriptions() []event.Subscription {
+	    return []event.Subscription{
+	        event.OnAsync(m.SendOrderMail),
+	    }
+	}
+
+	func (m *MailService) SendOrderMail(ctx context.Context, e *OrderCreated) error {}
+*/
+type Subscriber interface {
+	Subscriptions() []Subscription
+}
+
+// On Create the subscription delivered synchronously, the error is returned to the publisher
+func On[T any](handler func(ctx context.Context, event T) error) Subscription {
+	return subscription(handler, false)
+}
+
+// OnAsync Create the subscription delivered by the workers, the error and the panic are logged
+func OnAsync[T any](handler func(ctx context.Context, event T) error) Subscription {
+	return subscription(handler, true)
+}
+
+func subscription[T any](handler func(ctx context.Context, event T) error, async bool) Subscription {
+	return Subscription{
+		typ:   reflect.TypeOf((*T)(nil)).Elem(),
+		async: async,
+		name:  runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name(),
+		handler: func(ctx context.Context, event any) error {
+			return handler(ctx, event.(T))
+		},
+	}
+}
+
+// Bus the in-process event bus
+type Bus struct {
+	mu      sync.RWMutex
+	conf    Config
+	subs    []Subscription
+	matched map[reflect.Type][]Subscription // subscriptions of the event types
+	start   sync.Once
+	queue   chan func()
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+// NewBus Create the event bus, the workers are started on the first async event
+func NewBus(conf Config) *Bus {
+	b := &Bus{matched: make(map[reflect.Type][]Subscription)}
+	b.SetConfig(conf)
+	return b
+}
+
+// SetConfig Set the configuration, it doesn't take effect after the workers are started
+func (b *Bus) SetConfig(conf Config) {
+	if conf.Workers <= 0 {
+		conf.Workers = 8
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = 1024
+	}
+	b.mu.Lock()
+	b.conf = conf
+	b.mu.Unlock()
+}
+
+// Subscribe Register the subscriptions
+func (b *Bus) Subscribe(subs ...Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, subs...)
+	b.matched = make(map[reflect.Type][]Subscription)
+}
+
+// Register Subscribe the subscriptions of the beans implementing Subscriber, the other beans are ignored
+func (b *Bus) Register(beans ...any) {
+	for _, bean := range beans {
+		if s, ok := bean.(Subscriber); ok {
+			b.Subscribe(s.Subscriptions()...)
+		}
+	}
+}
+
+// Publish Deliver the event with the background context, see PublishContext
+func (b *Bus) Publish(event any) error {
+	return b.PublishContext(context.Background(), event)
+}
+
+/*
+PublishContext Deliver the event to the subscriptions of its type in order of the registration. The sync subscriptions
+run on the caller goroutine, all of them are called and their errors are joined, the panic is returned as the error.
+The async subscriptions are queued for the workers with the context detached from the cancellation.
+*/
+func (b *Bus) PublishContext(ctx context.Context, event any) error {
+	if event == nil {
+		return nil
+	}
+	var errs []error
+	for _, s := range b.subscriptions(reflect.TypeOf(event)) {
+		if s.async {
+			b.dispatch(ctx, s, event)
+			continue
+		}
+		if err := deliver(ctx, s, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// the subscriptions of the event type, cached by the type
+func (b *Bus) subscriptions(t reflect.Type) []Subscription {
+	b.mu.RLock()
+	subs, ok := b.matched[t]
+	b.mu.RUnlock()
+	if ok {
+		return subs
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs = nil
+	for _, s := range b.subs {
+		if t == s.typ || (s.typ.Kind() == reflect.Interface && t.Implements(s.typ)) {
+			subs = append(subs, s)
+		}
+	}
+	b.matched[t] = subs
+	return subs
+}
+
+// queue the async delivery, the publisher blocks when the queue is full
+func (b *Bus) dispatch(ctx context.Context, s Subscription, event any) {
+	b.start.Do(func() {
+		b.mu.RLock()
+		conf := b.conf
+		b.mu.RUnlock()
+		b.queue = make(chan func(), conf.QueueSize)
+		for i := 0; i < conf.Workers; i++ {
+			go func() {
+				for task := range b.queue {
+					task()
+				}
+			}()
+		}
+	})
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		logger.Log.Warnf("Event %T is dropped, the bus is closed", event)
+		return
+	}
+	// the queue is closed after the added tasks are done, so it's sent without the lock
+	b.wg.Add(1)
+	b.mu.RUnlock()
+	ctx = context.WithoutCancel(ctx)
+	b.queue <- func() {
+		defer b.wg.Done()
+		if err := deliver(ctx, s, event); err != nil {
+			logger.Log.Errorf("Async event %T handler %s failed, %s", event, s.name, err.Error())
+		}
+	}
+}
+
+// call the handler, the panic is returned as the error
+func deliver(ctx context.Context, s Subscription, event any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Errorf("Event %T handler %s panic, %v\n%s", event, s.name, r, debug.Stack())
+			err = fmt.Errorf("event handler panic, %v", r)
+		}
+	}()
+	return s.handler(ctx, event)
+}
+
+// Close Wait for the queued async events, the events published after closing are dropped
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+	// wait for the workers started by the dispatching events, no worker starts after closing
+	b.start.Do(func() {})
+	b.wg.Wait()
+	if b.queue != nil {
+		close(b.queue)
+	}
+	return nil
+}
+
+// Subscribe Register the subscriptions to the default bus
+func Subscribe(subs ...Subscription) {
+	Default.Subscribe(subs...)
+}
+
+/*
+Publish Deliver the event by the default bus, the errors of the sync subscriptions are returned
+
+	if err := event.Publish(&OrderCreated{Id: order.Id}); err != nil {
+	    return err
+	}
+*/
+func Publish(event any) error {
+	return Default.Publish(event)
+}
+
+// PublishContext Deliver the event with the context by the default bus, such as the request context carrying the trace
+func PublishContext(ctx context.Context, event any) error {
+	return Default.PublishContext(ctx, event)
+}
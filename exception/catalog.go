@@ -0,0 +1,121 @@
+package exception
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/archine/gin-plus/v3/i18n"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage the language of the messages when the language of the client is not declared
+var DefaultLanguage = "zh-CN"
+
+// ErrorCode the business error declared in the catalog
+type ErrorCode struct {
+	Code     int               `json:"code"`             // Business code, such as 40401
+	Status   int               `json:"status,omitempty"` // Http status, default 200
+	Key      string            `json:"key"`              // Message key, such as user.not_found
+	Messages map[string]string `json:"messages"`         // Message of each language, such as {"zh-CN": "用户 %d 不存在", "en": "user %d not found"}
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = make(map[int]ErrorCode)
+)
+
+/*
+DeclareErrors Declare the business errors in the catalog, panic when the code is declared twice.
+
+	var UserNotFound = exception.ErrorCode{Code: 40401, Status: 404, Key: "user.not_found",
+	    Messages: map[string]string{"zh-CN": "用户 %d 不存在", "en": "user %d not found"}}
+
+	func init() {
+	    exception.DeclareErrors(UserNotFound)
+	}
+
+	panic(UserNotFound.New(id))
+*/
+func DeclareErrors(codes ...ErrorCode) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	for _, c := range codes {
+		if exist, ok := catalog[c.Code]; ok {
+			panic(fmt.Sprintf("error code %d is declared by both %s and %s", c.Code, exist.Key, c.Key))
+		}
+		catalog[c.Code] = c
+	}
+}
+
+// LookupError Get the declared business error of the code
+func LookupError(code int) (ErrorCode, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	c, ok := catalog[code]
+	return c, ok
+}
+
+// Catalog Get the declared business errors sorted by the code
+func Catalog() []ErrorCode {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	list := make([]ErrorCode, 0, len(catalog))
+	for _, c := range catalog {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// CatalogJSON Generate the catalog as json, such as writing it to a file for the client teams
+func CatalogJSON() ([]byte, error) {
+	return json.MarshalIndent(Catalog(), "", "  ")
+}
+
+// Declared Whether the exception is created from the error declared in the catalog
+func (b *BusinessException) Declared() bool {
+	return b.declared
+}
+
+// New Create the business exception of the error, the args format the message
+func (e ErrorCode) New(args ...any) *BusinessException {
+	return &BusinessException{Code: e.Code, Msg: e.Message(DefaultLanguage, args...), Args: args, declared: true}
+}
+
+// Message Get the message of the language formatted by the args, such as en-US falls back to en,
+// then the message of the key in the i18n bundle, then the messages of the default language, then the key
+func (e ErrorCode) Message(lang string, args ...any) string {
+	msg, ok := e.Messages[lang]
+	if !ok {
+		if base, _, found := strings.Cut(lang, "-"); found {
+			msg, ok = e.Messages[base]
+		}
+	}
+	if !ok {
+		for l, m := range e.Messages {
+			if strings.EqualFold(l, lang) {
+				msg, ok = m, true
+				break
+			}
+		}
+	}
+	if !ok && e.Key != "" {
+		msg, ok = i18n.Default.Lookup(lang, e.Key)
+	}
+	if !ok {
+		msg, ok = e.Messages[DefaultLanguage]
+	}
+	if !ok && e.Key != "" {
+		msg, ok = i18n.Default.Message(DefaultLanguage, e.Key)
+	}
+	if !ok {
+		return e.Key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
@@ -0,0 +1,14 @@
+package exception
+
+// ChecksumException the received body does not match the checksum declared by the client
+type ChecksumException struct {
+	Msg string
+}
+
+func (c *ChecksumException) Error() string {
+	return c.Msg
+}
+
+func NewChecksumErr(msg string) *ChecksumException {
+	return &ChecksumException{msg}
+}
@@ -8,8 +8,10 @@ import (
 
 // BusinessException the service level exception, equivalent to resp.BadRequest
 type BusinessException struct {
-	Code int
-	Msg  string
+	Code     int
+	Msg      string
+	Args     []any // Args of the message, the message of the error declared in the catalog is localized with them
+	declared bool
 }
 
 func (b *BusinessException) Error() string {
@@ -17,11 +19,11 @@ func (b *BusinessException) Error() string {
 }
 
 func NewBusinessErr(msg string) *BusinessException {
-	return &BusinessException{40000, msg}
+	return &BusinessException{Code: 40000, Msg: msg}
 }
 
 func NewBusinessErrWithCode(code int, msg string) *BusinessException {
-	return &BusinessException{code, msg}
+	return &BusinessException{Code: code, Msg: msg}
 }
 
 // OrThrow if err not nil, panic
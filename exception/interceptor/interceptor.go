@@ -1,30 +1,79 @@
 package interceptor
 
 import (
+	"errors"
 	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/mvc"
 	"github.com/archine/gin-plus/v3/plugin/logger"
 	"github.com/archine/gin-plus/v3/resp"
 	"github.com/gin-gonic/gin"
+	"runtime/debug"
+	"time"
 )
 
 // GlobalExceptionInterceptor gin global exception interceptor
 // add via gin middleware.
-// thrown when the exception type is string and the BusinessException
+// The typed exceptions, such as panic(exception.NotFound("msg")), and the errors registered by exception.RegisterError
+// are responded with their codes without the stack trace, other panic values are responded with http status 500 and logged with the full stack
 func GlobalExceptionInterceptor(context *gin.Context) {
 	defer func() {
 		if r := recover(); r != nil {
 			switch t := r.(type) {
 			case *exception.BusinessException:
 				exception.PrintSimpleStack(t)
-				resp.DirectRespWithCode(context, t.Code, t.Msg)
+				resp.BusinessFailed(context, t)
+			case *exception.ValidationException:
+				logger.Log.Debugf("Parameter validation failed, %s", t.Msg)
+				resp.ValidationFailed(context, t)
+			case *exception.ChecksumException:
+				logger.Log.Debugf("Checksum mismatch, %s", t.Msg)
+				resp.ChecksumMismatch(context, t.Msg)
+			case *exception.StatusException:
+				logger.Log.Debugf("Request [%s %s] failed, %d %s", context.Request.Method, context.Request.URL.Path, t.Code, t.Msg)
+				resp.StatusFailed(context, t)
 			case error:
-				exception.PrintStack(t)
+				if mvc.DegradeError(context, t) {
+					logger.Log.Warnf("Request [%s %s] degraded, %s", context.Request.Method, context.Request.URL.Path, t.Error())
+					return
+				}
+				var se *exception.StatusException
+				if errors.As(t, &se) {
+					logger.Log.Debugf("Request [%s %s] failed, %s", context.Request.Method, context.Request.URL.Path, t.Error())
+					resp.StatusFailed(context, se)
+					return
+				}
+				if resp.ErrorMapped(context, t) {
+					logger.Log.Debugf("Request [%s %s] failed, %s", context.Request.Method, context.Request.URL.Path, t.Error())
+					return
+				}
+				printPanic(context, t)
 				resp.SeverError(context, true)
 			default:
-				logger.Log.Error(r)
+				printPanic(context, r)
 				resp.SeverError(context, true)
 			}
 		}
 	}()
 	context.Next()
 }
+
+// log the unknown panic value with the request and the full stack, then report it
+func printPanic(context *gin.Context, r any) {
+	p := &PanicReport{
+		Value:    r,
+		Stack:    debug.Stack(),
+		Time:     time.Now(),
+		Method:   context.Request.Method,
+		Path:     context.Request.URL.Path,
+		Route:    context.FullPath(),
+		TraceId:  context.GetString("trace_id"),
+		ClientIP: context.ClientIP(),
+		Request:  context.Request,
+	}
+	if p.TraceId != "" {
+		logger.Log.Errorf("Request [%s %s] panic, trace_id: %s, client: %s, %v\n%s", p.Method, p.Path, p.TraceId, p.ClientIP, r, p.Stack)
+	} else {
+		logger.Log.Errorf("Request [%s %s] panic, client: %s, %v\n%s", p.Method, p.Path, p.ClientIP, r, p.Stack)
+	}
+	report(p)
+}
@@ -0,0 +1,68 @@
+package interceptor
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PanicReport the panic recovered by the global exception interceptor
+type PanicReport struct {
+	Value    any           // The panic value
+	Stack    []byte        // Stack trace of the panic
+	Time     time.Time     // When the panic is recovered
+	Method   string        // Request method
+	Path     string        // Request path
+	Route    string        // Route template, such as /user/:id
+	TraceId  string        // Trace id of the request, empty when the tracing is disabled
+	ClientIP string        // Client ip
+	Request  *http.Request // The request, read only. The body may be consumed by the handler
+}
+
+// PanicReporter reports the unexpected panics, such as to sentry or the alerting webhooks
+type PanicReporter interface {
+	Report(report *PanicReport)
+}
+
+// PanicReporterFunc the function as the PanicReporter
+type PanicReporterFunc func(report *PanicReport)
+
+func (f PanicReporterFunc) Report(report *PanicReport) {
+	f(report)
+}
+
+var (
+	reportersMu sync.RWMutex
+	reporters   []PanicReporter
+)
+
+/*
+RegisterPanicReporter Register the reporters of the panics, the typed exceptions and the registered errors are not reported.
+The reporters are called asynchronously, so the slow reporter doesn't delay the response.
+
+	interceptor.RegisterPanicReporter(interceptor.PanicReporterFunc(func(report *interceptor.PanicReport) {
+	    sentry.CurrentHub().Recover(report.Value)
+	}))
+*/
+func RegisterPanicReporter(r ...PanicReporter) {
+	reportersMu.Lock()
+	defer reportersMu.Unlock()
+	reporters = append(reporters, r...)
+}
+
+// report the panic to every reporter, the panic of the reporter is logged
+func report(r *PanicReport) {
+	reportersMu.RLock()
+	defer reportersMu.RUnlock()
+	for _, reporter := range reporters {
+		go func(reporter PanicReporter) {
+			defer func() {
+				if p := recover(); p != nil {
+					logger.Log.Errorf("Panic reporter %T panic, %v", reporter, p)
+				}
+			}()
+			reporter.Report(r)
+		}(reporter)
+	}
+}
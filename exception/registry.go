@@ -0,0 +1,121 @@
+package exception
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Mapping the response of the registered error
+type Mapping struct {
+	Status  int    // Http status, 0 means the status of the error declared in the catalog, then 200
+	Code    int    // Business code, the error declared in the catalog with the code provides the localized message
+	Message string // Message template, %v is formatted by the error. Empty means the declared message, then the message of the error
+}
+
+type registered struct {
+	match   func(err error) bool
+	key     any // Identity of the registered error or type, nil means the duplicate can't be detected
+	name    string
+	mapping Mapping
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []registered
+)
+
+/*
+RegisterError Register the sentinel error with its response, the wrapped errors are matched by errors.Is.
+Panic when the error is registered twice.
+
+	var ErrOrderClosed = errors.New("order closed")
+
+	func init() {
+	    exception.RegisterError(ErrOrderClosed, exception.Mapping{Status: 409, Code: 40901, Message: "订单已关闭"})
+	}
+
+	panic(fmt.Errorf("pay order %d: %w", id, ErrOrderClosed)) // responded with 409 and 40901
+*/
+func RegisterError(target error, mapping Mapping) {
+	register(registered{
+		match:   func(err error) bool { return errors.Is(err, target) },
+		key:     identity(target),
+		name:    fmt.Sprintf("%T(%s)", target, target.Error()),
+		mapping: mapping,
+	})
+}
+
+/*
+RegisterErrorType Register the error type with its response, the wrapped errors are matched by errors.As.
+Panic when the type is registered twice.
+
+	exception.RegisterErrorType[*pgconn.PgError](exception.Mapping{Status: 500, Code: 50001, Message: "数据库错误"})
+*/
+func RegisterErrorType[T error](mapping Mapping) {
+	register(registered{
+		match: func(err error) bool {
+			var t T
+			return errors.As(err, &t)
+		},
+		key:     reflect.TypeOf((*T)(nil)).Elem(),
+		name:    fmt.Sprintf("%T", *new(T)),
+		mapping: mapping,
+	})
+}
+
+func register(r registered) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, exist := range registry {
+		if r.key != nil && exist.key == r.key {
+			panic(fmt.Sprintf("error %s is registered twice", r.name))
+		}
+	}
+	registry = append(registry, r)
+}
+
+// the identity of the sentinel error, the distinct errors with the same text are different
+func identity(target error) any {
+	if target == nil || !reflect.TypeOf(target).Comparable() {
+		return nil
+	}
+	return target
+}
+
+// MapError Get the mapping of the error, the first registered one matching the error wins
+func MapError(err error) (Mapping, bool) {
+	if err == nil {
+		return Mapping{}, false
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, r := range registry {
+		if r.match(err) {
+			return r.mapping, true
+		}
+	}
+	return Mapping{}, false
+}
+
+// Exception Create the status exception of the error, the declared message is localized by the language
+func (m Mapping) Exception(err error, lang string) *StatusException {
+	status, msg := m.Status, m.Message
+	if code, ok := LookupError(m.Code); ok {
+		if status == 0 {
+			status = code.Status
+		}
+		if msg == "" {
+			msg = code.Message(lang)
+		}
+	}
+	switch {
+	case msg == "":
+		msg = err.Error()
+	case strings.Contains(msg, "%v"):
+		msg = fmt.Sprintf(msg, err)
+	}
+	return &StatusException{Status: status, Code: m.Code, Msg: msg}
+}
@@ -0,0 +1,81 @@
+package exception
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Business codes of the status exceptions, the resp package responds the same codes
+const (
+	BadRequestCode  = 40000
+	NonLoginCode    = 40001
+	ForbiddenCode   = 40003
+	NotFoundCode    = 40004
+	ConflictCode    = 40009
+	UnavailableCode = 50003
+)
+
+/*
+StatusException the exception responded with the business code and the http status, panic with it to respond the
+failure directly without the stack trace noise.
+
+	user, ok := repo.Find(id)
+	if !ok {
+	    panic(exception.NotFound("用户 %d 不存在", id))
+	}
+*/
+type StatusException struct {
+	Status int    // Http status, 0 means 200
+	Code   int    // Business code
+	Msg    string // Business message
+	Data   any    // Response data, optional
+}
+
+func (s *StatusException) Error() string {
+	return s.Msg
+}
+
+// WithData Set the response data of the exception
+func (s *StatusException) WithData(data any) *StatusException {
+	s.Data = data
+	return s
+}
+
+// NewStatusErr Create the exception responded with the http status and the business code
+func NewStatusErr(status, code int, format string, args ...any) *StatusException {
+	msg := format
+	if len(args) > 0 {
+		msg = fmt.Sprintf(format, args...)
+	}
+	return &StatusException{Status: status, Code: code, Msg: msg}
+}
+
+// BadRequest The business failure, equivalent to resp.BadRequest
+func BadRequest(format string, args ...any) *StatusException {
+	return NewStatusErr(http.StatusOK, BadRequestCode, format, args...)
+}
+
+// Unauthorized Not logged in, equivalent to resp.NoLogin
+func Unauthorized(format string, args ...any) *StatusException {
+	return NewStatusErr(http.StatusUnauthorized, NonLoginCode, format, args...)
+}
+
+// Forbidden Insufficient permission, responded with http status 403
+func Forbidden(format string, args ...any) *StatusException {
+	return NewStatusErr(http.StatusForbidden, ForbiddenCode, format, args...)
+}
+
+// NotFound The resource does not exist, responded with http status 404
+func NotFound(format string, args ...any) *StatusException {
+	return NewStatusErr(http.StatusNotFound, NotFoundCode, format, args...)
+}
+
+// Conflict The request conflicts with the state of the resource, responded with http status 409
+func Conflict(format string, args ...any) *StatusException {
+	return NewStatusErr(http.StatusConflict, ConflictCode, format, args...)
+}
+
+// Unavailable The service is temporarily unavailable, responded with http status 503
+func Unavailable(format string, args ...any) *StatusException {
+	return NewStatusErr(http.StatusServiceUnavailable, UnavailableCode, format, args...)
+}
@@ -0,0 +1,36 @@
+package exception
+
+import "github.com/archine/gin-plus/v3/validation"
+
+// ValidationException the parameter validation exception, responded with the errors of each field
+type ValidationException struct {
+	Msg    string
+	Errors []validation.FieldError
+	cause  error
+	obj    any
+}
+
+func (v *ValidationException) Error() string {
+	return v.Msg
+}
+
+// NewValidationErr Create the validation exception from the binding error
+// obj: the bound object, used to get the custom messages of the fields. can be nil
+func NewValidationErr(err error, obj any) *ValidationException {
+	errs := validation.Translate(err, obj)
+	return &ValidationException{Msg: errs[0].Message, Errors: errs, cause: err, obj: obj}
+}
+
+// Localize Get the exception with the messages of the language
+func (v *ValidationException) Localize(lang string) *ValidationException {
+	var errs []validation.FieldError
+	if v.cause != nil {
+		errs = validation.TranslateLang(v.cause, v.obj, lang)
+	} else {
+		errs = validation.Localize(v.Errors, lang)
+	}
+	if len(errs) == 0 {
+		return v
+	}
+	return &ValidationException{Msg: errs[0].Message, Errors: errs, cause: v.cause, obj: v.obj}
+}
@@ -0,0 +1,199 @@
+package i18n
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+Default the message bundle of the framework, the business errors and the validation messages are translated by it.
+The bundle files are named by the language, such as zh-CN.yaml, en.json, and the nested keys are joined by dot:
+
+	# en.yaml
+	user:
+	  not_found: user %d not found
+	validation:
+	  required: "{field} is required"
+*/
+var Default = NewBundle("zh-CN")
+
+// Config the message bundles
+type Config struct {
+	Dir      string `mapstructure:"dir"`      // Directory of the bundle files, empty means no file is loaded
+	Fallback string `mapstructure:"fallback"` // Language used when the message of the client language is missing, default zh-CN
+}
+
+// Bundle the messages of the languages
+type Bundle struct {
+	mu       sync.RWMutex
+	fallback string
+	messages map[string]map[string]string // language -> key -> message
+}
+
+// NewBundle Create the bundle with the fallback language
+func NewBundle(fallback string) *Bundle {
+	return &Bundle{fallback: fallback, messages: make(map[string]map[string]string)}
+}
+
+// Fallback Get the fallback language
+func (b *Bundle) Fallback() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.fallback
+}
+
+// SetFallback Set the fallback language
+func (b *Bundle) SetFallback(lang string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fallback = lang
+}
+
+// Add the messages of the language, the existing keys are overridden
+func (b *Bundle) Add(lang string, messages map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, ok := b.messages[lang]
+	if !ok {
+		m = make(map[string]string, len(messages))
+		b.messages[lang] = m
+	}
+	for k, v := range messages {
+		m[k] = v
+	}
+}
+
+/*
+Load the bundle files of the file system, such as the embed.FS. The json, yaml and yml files of the dir are loaded,
+the language is the file name without the extension.
+
+	//go:embed i18n
+	var bundles embed.FS
+
+	i18n.Default.Load(bundles, "i18n")
+*/
+func (b *Bundle) Load(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		// json is parsed as yaml too
+		var tree map[string]any
+		if err = yaml.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("parse message bundle %s error, %s", entry.Name(), err.Error())
+		}
+		messages := make(map[string]string)
+		flatten("", tree, messages)
+		b.Add(strings.TrimSuffix(entry.Name(), ext), messages)
+	}
+	return nil
+}
+
+// LoadDir Load the bundle files of the directory
+func (b *Bundle) LoadDir(dir string) error {
+	return b.Load(os.DirFS(dir), ".")
+}
+
+func flatten(prefix string, tree map[string]any, messages map[string]string) {
+	for k, v := range tree {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]any); ok {
+			flatten(k, sub, messages)
+			continue
+		}
+		messages[k] = fmt.Sprint(v)
+	}
+}
+
+// Lookup Get the message of the language without the fallback language, such as en-US falls back to en
+func (b *Bundle) Lookup(lang, key string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if msg, ok := b.messages[lang][key]; ok {
+		return msg, true
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		if msg, ok := b.messages[base][key]; ok {
+			return msg, true
+		}
+	}
+	for l, m := range b.messages {
+		if strings.EqualFold(l, lang) {
+			if msg, ok := m[key]; ok {
+				return msg, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Message Get the message of the language formatted by the args, then the message of the fallback language
+func (b *Bundle) Message(lang, key string, args ...any) (string, bool) {
+	msg, ok := b.Lookup(lang, key)
+	if !ok {
+		if msg, ok = b.Lookup(b.Fallback(), key); !ok {
+			return "", false
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...), true
+	}
+	return msg, true
+}
+
+// Translate Get the message of the language, the key is returned when it's missing
+func (b *Bundle) Translate(lang, key string, args ...any) string {
+	if msg, ok := b.Message(lang, key, args...); ok {
+		return msg
+	}
+	return key
+}
+
+// T Translate the key by the language of the request
+func T(ctx *gin.Context, key string, args ...any) string {
+	return Default.Translate(Lang(ctx), key, args...)
+}
+
+// Lang Get the language of the highest weight in the Accept-Language header, default the fallback language
+func Lang(ctx *gin.Context) string {
+	return ParseAcceptLanguage(ctx.GetHeader("Accept-Language"), Default.Fallback())
+}
+
+// ParseAcceptLanguage Get the language of the highest weight in the Accept-Language header, default the fallback
+func ParseAcceptLanguage(header, fallback string) string {
+	lang, weight := fallback, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > weight {
+			lang, weight = tag, q
+		}
+	}
+	return lang
+}
@@ -1,6 +1,10 @@
 package listener
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"sort"
+)
 
 // ApplicationListener Application listener
 type ApplicationListener interface{}
@@ -25,6 +29,38 @@ type ApplicationEventListener interface {
 	PostStop()
 }
 
+/*
+OrderedListener the listener triggered in the declared order, the lower order runs earlier and the listeners without
+the order are 0. The PreStop event is triggered in reverse order, so the listener started first stops last.
+
+	func (l *DatabaseListener) Order() int {
+	    return -100
+	}
+*/
+type OrderedListener interface {
+	ApplicationListener
+
+	// Order the order of the listener
+	Order() int
+}
+
+// Sorted Get the listeners sorted by the order, the listeners of the same order keep their registration order
+func Sorted(listeners []ApplicationListener) []ApplicationListener {
+	sorted := make([]ApplicationListener, len(listeners))
+	copy(sorted, listeners)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return order(sorted[i]) < order(sorted[j])
+	})
+	return sorted
+}
+
+func order(l ApplicationListener) int {
+	if ol, ok := l.(OrderedListener); ok {
+		return ol.Order()
+	}
+	return 0
+}
+
 // ConfigListener Configuration listener, used to load configuration
 type ConfigListener interface {
 	ApplicationListener
@@ -33,36 +69,56 @@ type ConfigListener interface {
 	Read(v *viper.Viper) error
 }
 
-// DoPreApply Trigger the PreApply event
+// DoPreApply Trigger the PreApply event in order
 func DoPreApply(listeners []ApplicationListener) {
-	for _, l := range listeners {
+	for _, l := range Sorted(listeners) {
 		if ael, ok := l.(ApplicationEventListener); ok {
 			ael.PreApply()
 		}
 	}
 }
 
-// DoPreStart Trigger the PreStart event
+// DoPreStart Trigger the PreStart event in order, except the async listeners, they are triggered by StartAsync.
+// It panics with the error of the failed FallibleListener, use DoPreStartE to handle the error
 func DoPreStart(listeners []ApplicationListener) {
-	for _, l := range listeners {
+	if err := DoPreStartE(listeners); err != nil {
+		panic(err)
+	}
+}
+
+// DoPreStartE Trigger the PreStart event like DoPreStart, returns the error of the first failed FallibleListener,
+// the following listeners are not triggered
+func DoPreStartE(listeners []ApplicationListener) error {
+	for _, l := range Sorted(listeners) {
+		if isAsync(l) {
+			continue
+		}
+		if fl, ok := l.(FallibleListener); ok {
+			if err := fl.PreStartE(); err != nil {
+				return fmt.Errorf("%T: %w", l, err)
+			}
+			continue
+		}
 		if ael, ok := l.(ApplicationEventListener); ok {
 			ael.PreStart()
 		}
 	}
+	return nil
 }
 
-// DoPreStop Trigger the PreStop event
+// DoPreStop Trigger the PreStop event in reverse order
 func DoPreStop(listeners []ApplicationListener) {
-	for _, l := range listeners {
-		if ael, ok := l.(ApplicationEventListener); ok {
+	sorted := Sorted(listeners)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if ael, ok := sorted[i].(ApplicationEventListener); ok {
 			ael.PreStop()
 		}
 	}
 }
 
-// DoPostStop Trigger the PostStop event
+// DoPostStop Trigger the PostStop event in order
 func DoPostStop(listeners []ApplicationListener) {
-	for _, l := range listeners {
+	for _, l := range Sorted(listeners) {
 		if ael, ok := l.(ApplicationEventListener); ok {
 			ael.PostStop()
 		}
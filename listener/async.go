@@ -0,0 +1,101 @@
+package listener
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Policies of the failed async listeners
+const (
+	FailStartup    = "fail" // Stop the application
+	LogAndContinue = "log"  // Log the errors and keep running
+)
+
+// Config the application listeners
+type Config struct {
+	AsyncFailure string `mapstructure:"async_failure"` // Policy of the failed async listeners, fail or log, default fail
+}
+
+/*
+AsyncListener the event listener whose PreStart runs in the background after the server is bound, so the slow work
+such as the cache warmup and the index building doesn't delay the startup. The readiness probe fails until all the
+async listeners finish, the panic of PreStart is collected as the error.
+
+	func (w *Warmup) Async() bool {
+	    return true
+	}
+*/
+type AsyncListener interface {
+	ApplicationEventListener
+
+	// Async Whether the PreStart runs asynchronously
+	Async() bool
+}
+
+// FallibleListener the event listener reports the failure of PreStart by the error instead of the panic,
+// PreStartE is called instead of PreStart
+type FallibleListener interface {
+	ApplicationEventListener
+
+	// PreStartE The PreStart event, the error fails the startup
+	PreStartE() error
+}
+
+// Group the running async listeners
+type Group struct {
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// Wait for all the async listeners, returns the errors of the failed ones
+func (g *Group) Wait() []error {
+	g.wg.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.errs
+}
+
+// StartAsync Trigger the PreStart event of the async listeners concurrently, they are started in order
+func StartAsync(listeners []ApplicationListener) *Group {
+	g := &Group{}
+	for _, l := range Sorted(listeners) {
+		if !isAsync(l) {
+			continue
+		}
+		g.wg.Add(1)
+		go func(l ApplicationEventListener) {
+			defer g.wg.Done()
+			if err := preStart(l); err != nil {
+				g.mu.Lock()
+				g.errs = append(g.errs, fmt.Errorf("%T: %w", l, err))
+				g.mu.Unlock()
+			}
+		}(l.(ApplicationEventListener))
+	}
+	return g
+}
+
+// Whether the PreStart of the listener runs asynchronously
+func isAsync(l ApplicationListener) bool {
+	al, ok := l.(AsyncListener)
+	return ok && al.Async()
+}
+
+// trigger the PreStart event, the panic is returned as the error
+func preStart(l ApplicationEventListener) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+				return
+			}
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	if fl, ok := l.(FallibleListener); ok {
+		return fl.PreStartE()
+	}
+	l.PreStart()
+	return nil
+}
@@ -0,0 +1,74 @@
+package listener
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+)
+
+/*
+RoutesListener the listener triggered right after the apis are mounted by the mvc layer, such as auditing the routes
+
+	func (a *AuditListener) OnRoutesMounted(routes []mvc.RouteInfo) {
+	    for _, r := range routes {
+	        if strings.HasPrefix(r.Path, "/admin") && r.Name == "" {
+	            logger.Log.Warnf("admin api %s %s has no name", r.Method, r.Path)
+	        }
+	    }
+	}
+*/
+type RoutesListener interface {
+	ApplicationListener
+
+	// OnRoutesMounted The event after the apis are mounted
+	OnRoutesMounted(routes []mvc.RouteInfo)
+}
+
+// PostStartListener the listener triggered after the server is accepting the connections and the self test passed,
+// such as registering the instance to the service discovery or sending the warmup requests
+type PostStartListener interface {
+	ApplicationListener
+
+	// PostStart The event after the application started
+	PostStart()
+}
+
+// DoRoutesMounted Trigger the OnRoutesMounted event in order, each listener gets a copy of the routes
+func DoRoutesMounted(listeners []ApplicationListener, routes []mvc.RouteInfo) {
+	for _, l := range Sorted(listeners) {
+		if rl, ok := l.(RoutesListener); ok {
+			rl.OnRoutesMounted(append([]mvc.RouteInfo(nil), routes...))
+		}
+	}
+}
+
+// DoPostStart Trigger the PostStart event in order, the panic of a listener is returned as the error
+// and the following listeners are not triggered
+func DoPostStart(listeners []ApplicationListener) (err error) {
+	for _, l := range Sorted(listeners) {
+		psl, ok := l.(PostStartListener)
+		if !ok {
+			continue
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%T: %v", l, r)
+				}
+			}()
+			psl.PostStart()
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsListener Whether the value listens any event of the application, such as the bean implementing PreStart
+func IsListener(v any) bool {
+	switch v.(type) {
+	case ApplicationEventListener, FallibleListener, RoutesListener, PostStartListener:
+		return true
+	}
+	return false
+}
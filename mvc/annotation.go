@@ -0,0 +1,185 @@
+package mvc
+
+import (
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// UseAnnotation Declares the named middlewares of the api method, they run before other annotation middlewares.
+// The middlewares are registered by RegisterMiddleware.
+//
+//	// DeleteUser
+//	// @DELETE(path="/user/:id") delete user
+//	// @Use(auth, audit)
+//	func (u *UserController) DeleteUser(ctx *gin.Context) {}
+const UseAnnotation = "Use"
+
+// Named middlewares used by the api methods
+var namedMiddlewares = make(map[string]gin.HandlerFunc)
+
+// AnnotationHandler Build the route middleware of an annotation
+// val: the value of the annotation on the api method
+type AnnotationHandler func(val string) gin.HandlerFunc
+
+// The orders of the annotation middlewares, the lower order runs earlier and the annotations without the order are 0,
+// so the caller is authenticated and authorized before the saved responses are replayed
+const (
+	AuthenticationOrder = -200 // Annotations authenticating the caller, such as @Auth, @APIKey and @Signed
+	AuthorizationOrder  = -100 // Annotations authorizing the caller, such as @RequireRole and @RequirePermission
+	CacheOrder          = 100  // Annotations replaying the saved responses, such as @Cacheable and @Idempotent
+)
+
+// Annotation handlers, sorted by the order and then the registration order
+var annotationHandlers []namedAnnotationHandler
+
+type namedAnnotationHandler struct {
+	name    string
+	order   int
+	handler AnnotationHandler
+}
+
+// RegisterAnnotationHandler Register the handler of the specified annotation in the order 0.
+// When the api method is annotated, the middleware built by the handler runs before the method,
+// middlewares of multiple annotations run in order, see RegisterOrderedAnnotationHandler.
+func RegisterAnnotationHandler(annotationName string, handler AnnotationHandler) {
+	RegisterOrderedAnnotationHandler(annotationName, 0, handler)
+}
+
+// RegisterOrderedAnnotationHandler Register the handler of the specified annotation in the order, the middleware of the
+// lower order runs earlier, the ones of the same order run in registration order.
+//
+//	mvc.RegisterOrderedAnnotationHandler("Audit", mvc.AuthorizationOrder+1, auditHandler)
+func RegisterOrderedAnnotationHandler(annotationName string, order int, handler AnnotationHandler) {
+	i := sort.Search(len(annotationHandlers), func(i int) bool {
+		return annotationHandlers[i].order > order
+	})
+	annotationHandlers = slices.Insert(annotationHandlers, i, namedAnnotationHandler{annotationName, order, handler})
+}
+
+// RegisterMiddleware Register the named middleware, which can be declared on api methods by @Use(name)
+func RegisterMiddleware(name string, middleware gin.HandlerFunc) {
+	namedMiddlewares[name] = middleware
+}
+
+// annotation middlewares of the api method
+func buildAnnotationHandlers(annotations Annotations) []gin.HandlerFunc {
+	if len(annotations) == 0 {
+		return nil
+	}
+	var handlers []gin.HandlerFunc
+	if val, ok := annotations[StrictAnnotation]; ok {
+		handlers = append(handlers, strictHandler(val))
+	}
+	if _, ok := annotations[ChecksumAnnotation]; ok {
+		handlers = append(handlers, VerifyChecksum(true))
+	}
+	if val, ok := annotations[UseAnnotation]; ok {
+		for _, name := range splitAnnotationArgs(val) {
+			name = strings.Trim(strings.TrimSpace(name), `"`)
+			if name == "" {
+				continue
+			}
+			middleware, exist := namedMiddlewares[name]
+			if !exist {
+				logger.Log.Fatalf("middleware [%s] declared by @%s is not registered", name, UseAnnotation)
+			}
+			handlers = append(handlers, middleware)
+		}
+	}
+	for _, h := range annotationHandlers {
+		val, ok := annotations[h.name]
+		if !ok {
+			continue
+		}
+		if hf := h.handler(val); hf != nil {
+			handlers = append(handlers, hf)
+		}
+	}
+	return handlers
+}
+
+// ParseAnnotationArgs Parse the annotation value into named arguments.
+// such as: key="user:{id}", ttl="60s" -> {"key": "user:{id}", "ttl": "60s"}
+// unnamed argument is stored with the name "value", such as: "user:{id}" -> {"value": "user:{id}"}
+func ParseAnnotationArgs(val string) map[string]string {
+	args := make(map[string]string)
+	for _, part := range splitAnnotationArgs(val) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, v, found := strings.Cut(part, "=")
+		if !found || strings.HasPrefix(strings.TrimSpace(name), `"`) {
+			args["value"] = strings.Trim(part, `"`)
+			continue
+		}
+		args[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return args
+}
+
+// split by commas outside the quotes
+func splitAnnotationArgs(val string) []string {
+	var parts []string
+	inQuote := false
+	start := 0
+	for i, c := range val {
+		switch c {
+		case '"':
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				parts = append(parts, val[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, val[start:])
+}
+
+// Annotations of the methods of the beans which are not the controllers, type name -> method name -> annotations
+var methodAnnotations = make(map[string]map[string]Annotations)
+
+// keep the annotations of the non-controller types before the api cache is released
+func keepMethodAnnotations() {
+	controllers := make(map[string]bool, len(appliedControllers))
+	for _, c := range appliedControllers {
+		controllers[reflect.TypeOf(c).Elem().Name()] = true
+	}
+	for typeName, methods := range core.Apis {
+		if controllers[typeName] {
+			continue
+		}
+		annotations := make(map[string]Annotations, len(methods))
+		for _, m := range methods {
+			annotations[m.Name] = m.Annotations
+		}
+		methodAnnotations[typeName] = annotations
+	}
+}
+
+// MethodAnnotations Get the annotations of the methods of the bean which is not the controller, such as the message
+// listeners. The key is the method name, the annotations are generated by gp-ast as the api methods
+func MethodAnnotations(bean any) map[string]Annotations {
+	t := reflect.TypeOf(bean)
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if annotations, ok := methodAnnotations[t.Name()]; ok {
+		return annotations
+	}
+	// the annotations are found in the api cache before it's released
+	annotations := make(map[string]Annotations)
+	for _, m := range core.Apis[t.Name()] {
+		annotations[m.Name] = m.Annotations
+	}
+	return annotations
+}
@@ -0,0 +1,201 @@
+package mvc
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/ioc"
+	"reflect"
+	"strings"
+)
+
+// PostConstructBean the bean initialized after the injection, such as opening the connections.
+// It's triggered before the PostConstruct of the controller it's injected into, the dependencies are initialized first
+type PostConstructBean interface {
+	PostConstruct()
+}
+
+// PreDestroyBean the bean released when the application stops, such as closing the connections.
+// It's triggered in reverse order of the initialization, so the dependencies are released last
+type PreDestroyBean interface {
+	PreDestroy()
+}
+
+var (
+	// the beans whose PostConstruct was triggered
+	constructed = make(map[any]bool)
+	// the constructed beans and controllers in order of the initialization
+	constructionOrder []any
+	// the beans injected by Autowire
+	autowired []any
+	// the beans set by SetBeans
+	registered []any
+)
+
+/*
+Beans Get the beans set by SetBeans, the controllers, the autowired beans and the beans of the ioc container injected into
+them, the beans injected into the beans are found recursively, the dependencies are before the beans depending on them.
+The controllers and the beans injected into them are found after Apply(). The beans set by ioc.SetBeans directly and not
+injected anywhere are not found, because the container doesn't list its beans.
+*/
+func Beans() []any {
+	visited := make(map[any]bool)
+	var beans []any
+	collect := func(bean any) {
+		beans = append(beans, bean)
+	}
+	for _, b := range registered {
+		if v := reflect.ValueOf(b); v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+			walkBeans(v, visited, collect)
+		} else if hashable(b) && !visited[b] {
+			visited[b] = true
+			beans = append(beans, b)
+		}
+	}
+	for _, c := range appliedControllers {
+		walkBeans(reflect.ValueOf(c), visited, collect)
+	}
+	for _, b := range autowired {
+		walkBeans(reflect.ValueOf(b), visited, collect)
+	}
+	return beans
+}
+
+// whether the value can be the key of the map
+func hashable(v any) bool {
+	return v != nil && reflect.TypeOf(v).Comparable()
+}
+
+// walk the bean and its dependencies, the dependencies are visited first
+func walkBeans(v reflect.Value, visited map[any]bool, fn func(bean any)) {
+	if visited[v.Interface()] {
+		return
+	}
+	visited[v.Interface()] = true
+	elem := v.Elem()
+	for i := 0; i < elem.NumField(); i++ {
+		if !elem.Type().Field(i).IsExported() {
+			continue
+		}
+		f := elem.Field(i)
+		if f.Kind() == reflect.Interface && !f.IsNil() {
+			f = f.Elem()
+		}
+		if isBean(f) {
+			walkBeans(f, visited, fn)
+		}
+	}
+	fn(v.Interface())
+}
+
+// whether the value is the bean of the container or the named bean
+func isBean(v reflect.Value) bool {
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	if namedPointers[v.Pointer()] {
+		return true
+	}
+	bean := ioc.GetBeanByName(v.Type().Elem().String())
+	return bean != nil && reflect.ValueOf(bean).Pointer() == v.Pointer()
+}
+
+/*
+trigger PostConstruct of the beans injected into the controller once, the dependencies are initialized first and the
+controller itself is excluded. The dependency cycle stops the application when a bean of the cycle implements
+PostConstructBean, because it can't be initialized after its dependencies, otherwise it's only warned, such as:
+
+	dependency cycle: *service.OrderService -> *service.UserService -> *service.OrderService
+*/
+func constructBeans(controller any) {
+	visiting := make(map[any]int)
+	var path []string
+	var stack []any
+	var visit func(v reflect.Value)
+	visit = func(v reflect.Value) {
+		bean := v.Interface()
+		path = append(path, v.Type().String())
+		if i, ok := visiting[bean]; ok {
+			cycle := strings.Join(path[i:], " -> ")
+			path = path[:len(path)-1]
+			for _, b := range stack[i:] {
+				if _, ok := b.(PostConstructBean); ok && b != controller {
+					logger.Log.Fatalf("dependency cycle: %s, %T can't be initialized after its dependencies, inject one of them by mvc.Lazy to break the cycle", cycle, b)
+				}
+			}
+			logger.Log.Warnf("dependency cycle: %s", cycle)
+			return
+		}
+		if constructed[bean] {
+			path = path[:len(path)-1]
+			return
+		}
+		visiting[bean] = len(stack)
+		stack = append(stack, bean)
+		elem := v.Elem()
+		for i := 0; i < elem.NumField(); i++ {
+			if !elem.Type().Field(i).IsExported() {
+				continue
+			}
+			f := elem.Field(i)
+			if f.Kind() == reflect.Interface && !f.IsNil() {
+				f = f.Elem()
+			}
+			if isBean(f) {
+				visit(f)
+			}
+		}
+		delete(visiting, bean)
+		stack = stack[:len(stack)-1]
+		path = path[:len(path)-1]
+		if bean == controller {
+			return
+		}
+		markConstructed(bean)
+		if pc, ok := bean.(PostConstructBean); ok {
+			timed(v.Type().String(), false, pc.PostConstruct)
+		}
+	}
+	visit(reflect.ValueOf(controller))
+}
+
+/*
+Autowire Inject the beans which are not the controllers, such as the grpc services, and initialize their dependencies.
+The bean itself is initialized after its dependencies when it implements PostConstructBean, it's found by Beans() and
+destroyed with the controllers
+*/
+func Autowire(beans ...any) {
+	for _, bean := range beans {
+		ioc.Inject(bean)
+		injectNamed(reflect.ValueOf(bean), make(map[any]bool))
+		constructBeans(bean)
+		if pc, ok := bean.(PostConstructBean); ok && !constructed[bean] {
+			markConstructed(bean)
+			timed(reflect.TypeOf(bean).String(), false, pc.PostConstruct)
+		}
+		autowired = append(autowired, bean)
+	}
+}
+
+// record the bean initialized, it's destroyed when the application stops
+func markConstructed(bean any) {
+	constructed[bean] = true
+	constructionOrder = append(constructionOrder, bean)
+}
+
+// DestroyBeans Trigger PreDestroy of the controllers and the beans in reverse order of the initialization, the beans
+// never initialized, such as the mocked controllers, are not destroyed. The panic of one bean doesn't skip the others
+func DestroyBeans() {
+	for i := len(constructionOrder) - 1; i >= 0; i-- {
+		pd, ok := constructionOrder[i].(PreDestroyBean)
+		if !ok {
+			continue
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Log.Errorf("Bean %T PreDestroy panic, %v", pd, r)
+				}
+			}()
+			pd.PreDestroy()
+		}()
+	}
+}
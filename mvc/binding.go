@@ -0,0 +1,73 @@
+package mvc
+
+import (
+	"errors"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"net/http"
+)
+
+// Bind the request into the struct pointer, then validate it by the binding tags.
+// The path parameters are bound by the uri tag, the query parameters by the form tag,
+// and the body is bound according to the Content-Type, such as json and form.
+// The json body is bound strictly when it is enabled by BindingConfig or declared by @Strict.
+// Returns *exception.ValidationException when the binding or validation fails,
+// *exception.ChecksumException when the body or the files do not match the checksums verified by VerifyChecksum.
+func Bind(ctx *gin.Context, obj any) error {
+	uri := make(map[string][]string, len(ctx.Params))
+	for _, p := range ctx.Params {
+		uri[p.Key] = []string{p.Value}
+	}
+	err := binding.MapFormWithTag(obj, uri, "uri")
+	if err == nil {
+		err = binding.MapFormWithTag(obj, ctx.Request.URL.Query(), "form")
+	}
+	if err == nil {
+		if hasBody(ctx.Request) && ctx.ContentType() == binding.MIMEJSON && strictJSON(ctx) {
+			err = bindStrictJSON(ctx, obj)
+		} else if hasBody(ctx.Request) {
+			// the body binding validates the struct
+			err = ctx.ShouldBindWith(obj, binding.Default(ctx.Request.Method, ctx.ContentType()))
+		} else {
+			err = binding.Validator.ValidateStruct(obj)
+		}
+	}
+	// the mismatch of the body takes precedence over the validation errors
+	if drainErr := drainChecksum(ctx); drainErr != nil {
+		err = drainErr
+	}
+	var checksumErr *exception.ChecksumException
+	if errors.As(err, &checksumErr) {
+		return checksumErr
+	}
+	var validationErr *exception.ValidationException
+	if errors.As(err, &validationErr) {
+		return validationErr
+	}
+	if err != nil {
+		return exception.NewValidationErr(err, obj)
+	}
+	if checksumVerified(ctx) {
+		return verifyFileChecksums(obj)
+	}
+	return nil
+}
+
+// MustBind Bind the request into the struct pointer, panic with *exception.ValidationException when it fails,
+// the global exception interceptor responds the errors of each field with http status 400.
+//
+//	var arg User
+//	mvc.MustBind(ctx, &arg)
+func MustBind(ctx *gin.Context, obj any) {
+	if err := Bind(ctx, obj); err != nil {
+		panic(err)
+	}
+}
+
+func hasBody(r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodDelete {
+		return false
+	}
+	return r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody
+}
@@ -0,0 +1,290 @@
+package mvc
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"reflect"
+	"strings"
+)
+
+/*
+ChecksumAnnotation Declares the api method to require the checksum of the request body,
+the requests without the checksum headers are rejected as the bad requests.
+
+	// UploadReport
+	// @POST(path="/report") upload the report
+	// @Checksum
+	func (r *ReportController) UploadReport(ctx *gin.Context) {}
+*/
+const ChecksumAnnotation = "Checksum"
+
+// ChecksumConfig the checksum verification of the request bodies
+type ChecksumConfig struct {
+	Enabled bool `mapstructure:"enabled"` // Whether to verify the bodies carrying the checksum headers, default false
+}
+
+// the context key marking the checksum of the multipart files is verified
+const checksumKey = "gin-plus/checksum"
+
+// algorithms of the checksum, the names are normalized without dashes, such as sha-256 -> sha256
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// the digest declared by the client
+type checksum struct {
+	algorithm string
+	sum       []byte
+}
+
+/*
+VerifyChecksum The gin middleware verifies the request body against the checksum headers:
+
+	Content-MD5: base64 of the md5 digest, RFC 1864
+	X-Checksum: algorithm=value, the value is hex or base64, such as sha256=9f86d08...
+	Content-Digest: algorithm=:base64:, RFC 9530, such as sha-256=:n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=:
+
+The multipart files are verified by the headers of their own parts when they are bound or uploaded.
+The body is verified when it is read to the end, the mismatch is returned as *exception.ChecksumException by the read.
+Bind and Upload read the rest of the body to verify it, the typed parameters respond the mismatch with http status 422.
+required: reject the requests without the checksum headers
+*/
+func VerifyChecksum(required bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(checksumKey, true)
+		sums, err := parseChecksums(textproto.MIMEHeader(ctx.Request.Header))
+		if err != nil {
+			resp.DirectBadRequest(ctx, err.Error())
+			ctx.Abort()
+			return
+		}
+		if len(sums) == 0 {
+			if required && !isMultipart(ctx) {
+				resp.DirectBadRequest(ctx, "缺少请求体的校验和")
+				ctx.Abort()
+			}
+			return
+		}
+		// verified by the global middleware already
+		if _, ok := ctx.Request.Body.(*checksumReader); ok {
+			return
+		}
+		reader := newChecksumReader(ctx.Request.Body, sums)
+		ctx.Request.Body = reader
+		ctx.Next()
+		// the handler ignoring the read error still gets the mismatch responded
+		if reader.err != nil && !ctx.Writer.Written() {
+			resp.ChecksumMismatch(ctx, reader.err.Msg)
+		}
+	}
+}
+
+func isMultipart(ctx *gin.Context) bool {
+	return strings.HasPrefix(ctx.ContentType(), "multipart/")
+}
+
+// checksumVerified Whether the checksum of the request is verified
+func checksumVerified(ctx *gin.Context) bool {
+	return ctx.GetBool(checksumKey)
+}
+
+// parse the checksum headers, the unknown algorithms of X-Checksum and Content-Digest are ignored
+func parseChecksums(h textproto.MIMEHeader) ([]checksum, error) {
+	var sums []checksum
+	if v := strings.TrimSpace(h.Get("Content-MD5")); v != "" {
+		sum, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(sum) != md5.Size {
+			return nil, errors.New("Content-MD5 格式错误")
+		}
+		sums = append(sums, checksum{"md5", sum})
+	}
+	for _, header := range []string{"X-Checksum", "Content-Digest"} {
+		for _, v := range h.Values(header) {
+			for _, item := range strings.Split(v, ",") {
+				name, value, found := strings.Cut(strings.TrimSpace(item), "=")
+				if !found {
+					return nil, fmt.Errorf("%s 格式错误", header)
+				}
+				algorithm := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "")
+				newHash, ok := checksumAlgorithms[algorithm]
+				if !ok {
+					continue
+				}
+				sum, err := decodeChecksum(strings.Trim(strings.TrimSpace(value), ":"), newHash().Size())
+				if err != nil {
+					return nil, fmt.Errorf("%s 格式错误", header)
+				}
+				sums = append(sums, checksum{algorithm, sum})
+			}
+		}
+	}
+	return sums, nil
+}
+
+// the value is hex or base64 with or without the padding
+func decodeChecksum(v string, size int) ([]byte, error) {
+	if len(v) == size*2 {
+		if sum, err := hex.DecodeString(v); err == nil {
+			return sum, nil
+		}
+	}
+	sum, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		sum, err = base64.RawStdEncoding.DecodeString(v)
+	}
+	if err != nil || len(sum) != size {
+		return nil, errors.New("invalid checksum")
+	}
+	return sum, nil
+}
+
+// hashes of the read data
+type checksumHashes struct {
+	sums   []checksum
+	hashes []hash.Hash
+	w      io.Writer
+}
+
+func newChecksumHashes(sums []checksum) *checksumHashes {
+	h := &checksumHashes{sums: sums}
+	writers := make([]io.Writer, len(sums))
+	for i, s := range sums {
+		h.hashes = append(h.hashes, checksumAlgorithms[s.algorithm]())
+		writers[i] = h.hashes[i]
+	}
+	h.w = io.MultiWriter(writers...)
+	return h
+}
+
+// verify the hashes, returns the mismatch
+func (h *checksumHashes) verify(name string) *exception.ChecksumException {
+	for i, s := range h.sums {
+		if !bytes.Equal(h.hashes[i].Sum(nil), s.sum) {
+			return exception.NewChecksumErr(fmt.Sprintf("%s的 %s 校验和不匹配", name, s.algorithm))
+		}
+	}
+	return nil
+}
+
+// checksumReader hashes the body while it is read, the mismatch is returned at EOF
+type checksumReader struct {
+	io.ReadCloser
+	hashes *checksumHashes
+	err    *exception.ChecksumException
+}
+
+func newChecksumReader(body io.ReadCloser, sums []checksum) *checksumReader {
+	return &checksumReader{ReadCloser: body, hashes: newChecksumHashes(sums)}
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.ReadCloser.Read(p)
+	_, _ = r.hashes.w.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		if r.err = r.hashes.verify("请求体"); r.err != nil {
+			return n, r.err
+		}
+	}
+	return n, err
+}
+
+// read the rest of the body verified by VerifyChecksum, returns the mismatch
+func drainChecksum(ctx *gin.Context) error {
+	reader, ok := ctx.Request.Body.(*checksumReader)
+	if !ok {
+		return nil
+	}
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return err
+	}
+	return nil
+}
+
+// hash the multipart part while it is read, verified by the headers of the part
+func partChecksum(part *multipart.Part) (io.Reader, *checksumHashes, error) {
+	sums, err := parseChecksums(part.Header)
+	if err != nil || len(sums) == 0 {
+		return part, nil, err
+	}
+	hashes := newChecksumHashes(sums)
+	return io.TeeReader(part, hashes.w), hashes, nil
+}
+
+// verify the checksums of the multipart files bound to the struct, declared by the headers of their parts
+func verifyFileChecksums(obj any) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		switch f.Type() {
+		case fileHeaderType:
+			if err := verifyFileChecksum(f.Interface().(*multipart.FileHeader)); err != nil {
+				return err
+			}
+		case reflect.SliceOf(fileHeaderType):
+			for _, fh := range f.Interface().([]*multipart.FileHeader) {
+				if err := verifyFileChecksum(fh); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func verifyFileChecksum(fh *multipart.FileHeader) error {
+	if fh == nil {
+		return nil
+	}
+	sums, err := parseChecksums(fh.Header)
+	if err != nil {
+		return exception.NewBusinessErr(fmt.Sprintf("文件[%s]的%s", fh.Filename, err.Error()))
+	}
+	if len(sums) == 0 {
+		return nil
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hashes := newChecksumHashes(sums)
+	if _, err = io.Copy(hashes.w, file); err != nil {
+		return err
+	}
+	if ce := hashes.verify("文件[" + fh.Filename + "]"); ce != nil {
+		return ce
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package mvc
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/ioc"
+	"github.com/spf13/viper"
+	"reflect"
+	"strings"
+)
+
+// Conditional annotations of the api method, the api is mounted only when the condition matches
+//
+//	// ListUsers
+//	// @GET(path="/admin/users") list users
+//	// @ConditionalOnProperty("features.admin-api")
+//	func (a *AdminController) ListUsers(ctx *gin.Context) {}
+//
+//	// Debug
+//	// @GET(path="/debug") debug info
+//	// @ConditionalOnProfile(dev, test)
+//	func (a *AdminController) Debug(ctx *gin.Context) {}
+const (
+	ConditionalOnPropertyAnnotation = "ConditionalOnProperty"
+	ConditionalOnProfileAnnotation  = "ConditionalOnProfile"
+)
+
+// Condition decides whether the controller or the api is mounted, it is evaluated once when the apis are applied
+type Condition struct {
+	Desc  string // Description of the condition, printed when it does not match
+	Match func() bool
+}
+
+// ConditionalController Declares the conditions of the controller, all apis of the controller are mounted only when all conditions match
+type ConditionalController interface {
+	// Conditions of the controller
+	Conditions() []Condition
+}
+
+/*
+OnProperty Create the condition matched when the property of the configuration exists and is not false.
+havingValue is optional, the property must equal it case-insensitively then.
+
+	func (a *AdminController) Conditions() []mvc.Condition {
+	    return []mvc.Condition{mvc.OnProperty("features.admin-api")}
+	}
+*/
+func OnProperty(key string, havingValue ...string) Condition {
+	return property(key, havingValue, false)
+}
+
+// OnProfile Create the condition matched when the active profile (server.env) is one of the profiles
+func OnProfile(profiles ...string) Condition {
+	return Condition{
+		Desc: fmt.Sprintf("profile in %v", profiles),
+		Match: func() bool {
+			active := propertyValue("server.env")
+			for _, p := range profiles {
+				if strings.EqualFold(p, active) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func property(key string, havingValue []string, matchIfMissing bool) Condition {
+	desc := "property " + key
+	if len(havingValue) > 0 {
+		desc += "=" + havingValue[0]
+	}
+	return Condition{
+		Desc: desc,
+		Match: func() bool {
+			v := propertyValue(key)
+			if v == "" {
+				return matchIfMissing
+			}
+			if len(havingValue) > 0 {
+				return strings.EqualFold(v, havingValue[0])
+			}
+			return !strings.EqualFold(v, "false")
+		},
+	}
+}
+
+// value of the configuration property, empty when the configuration is not loaded
+func propertyValue(key string) string {
+	v, ok := ioc.GetBeanByName("viper.Viper").(*viper.Viper)
+	if !ok || !v.IsSet(key) {
+		return ""
+	}
+	return v.GetString(key)
+}
+
+// conditions declared by the annotations of the api method
+func annotationConditions(annotations Annotations) []Condition {
+	var conditions []Condition
+	if val, ok := annotations[ConditionalOnPropertyAnnotation]; ok {
+		args := ParseAnnotationArgs(val)
+		key := args["name"]
+		if key == "" {
+			key = args["value"]
+		}
+		var havingValue []string
+		if args["havingValue"] != "" {
+			havingValue = append(havingValue, args["havingValue"])
+		}
+		conditions = append(conditions, property(key, havingValue, strings.EqualFold(args["matchIfMissing"], "true")))
+	}
+	if val, ok := annotations[ConditionalOnProfileAnnotation]; ok {
+		var profiles []string
+		for _, p := range splitAnnotationArgs(val) {
+			if p = strings.Trim(strings.TrimSpace(p), `"`); p != "" {
+				profiles = append(profiles, p)
+			}
+		}
+		conditions = append(conditions, OnProfile(profiles...))
+	}
+	return conditions
+}
+
+// the first condition not matched, nil means all conditions match
+func unmatchedCondition(conditions []Condition) *Condition {
+	for i := range conditions {
+		if !conditions[i].Match() {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// the controllers whose conditions match
+func enabledControllers(controllers []abstractController) []abstractController {
+	enabled := controllers[:0]
+	for _, c := range controllers {
+		if cc, ok := c.(ConditionalController); ok {
+			if cond := unmatchedCondition(cc.Conditions()); cond != nil {
+				logger.Log.Debugf("Controller %s is not mounted, condition [%s] does not match", reflect.TypeOf(c).Elem().Name(), cond.Desc)
+				continue
+			}
+		}
+		enabled = append(enabled, c)
+	}
+	return enabled
+}
+
+/*
+ConditionalBean Declares the conditions of the bean, the bean is created only when all conditions match.
+It's checked by SetBeans() and the application for the listeners, the interceptors and the modules
+
+	func (c *OrderConsumer) Conditions() []mvc.Condition {
+	    return []mvc.Condition{mvc.OnProperty("kafka.enabled", "true")}
+	}
+*/
+type ConditionalBean interface {
+	// Conditions of the bean
+	Conditions() []Condition
+}
+
+// Enabled Whether the conditions of the bean match, true when the bean doesn't implement ConditionalBean
+func Enabled(bean any) bool {
+	cb, ok := bean.(ConditionalBean)
+	if !ok {
+		return true
+	}
+	if cond := unmatchedCondition(cb.Conditions()); cond != nil {
+		logger.Log.Debugf("Bean %T is not created, condition [%s] does not match", bean, cond.Desc)
+		return false
+	}
+	return true
+}
+
+// SetBeans Set the beans whose conditions match into the ioc container, see ConditionalBean. The beans are recorded,
+// so the application discovers the listeners and the interceptors among them
+func SetBeans(beans ...any) {
+	for _, bean := range beans {
+		if Enabled(bean) {
+			ioc.SetBeans(bean)
+			registered = append(registered, bean)
+		}
+	}
+}
+
+/*
+SetBeansOn Set the beans into the ioc container only when all conditions match, usually the beans of the third party
+libraries that can't declare the conditions. The conditions are evaluated with the loaded configuration.
+
+	mvc.SetBeansOn([]mvc.Condition{mvc.OnProperty("kafka.enabled", "true")}, kafkaConsumer)
+*/
+func SetBeansOn(conditions []Condition, beans ...any) {
+	if cond := unmatchedCondition(conditions); cond != nil {
+		logger.Log.Debugf("%d beans are not created, condition [%s] does not match", len(beans), cond.Desc)
+		return
+	}
+	SetBeans(beans...)
+}
@@ -2,9 +2,12 @@ package mvc
 
 import (
 	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/plugin/logger"
 	"github.com/archine/ioc"
 	"github.com/gin-gonic/gin"
 	"reflect"
+	"strings"
+	"time"
 )
 
 // Annotations the annotation of Api method
@@ -13,9 +16,15 @@ type Annotations map[string]string
 // Global controller cache
 var controllerCache []abstractController
 
-// Annotations of each API
+// Annotations of each API, keyed by the method and the path, so the routes of the same path keep their own annotations
 var annotationCache map[string]Annotations
 
+// Controllers applied by Apply, the beans are found from them after the cache is released
+var appliedControllers []abstractController
+
+// Route prefixes of the controllers registered by RegisterGroup
+var controllerPrefixes = make(map[abstractController]string)
+
 type abstractController interface {
 	// PostConstruct Triggered after dependency injection is completed. You can continue to decorate the controller here
 	PostConstruct()
@@ -27,11 +36,30 @@ type Controller struct{}
 
 func (c *Controller) PostConstruct() {}
 
+// MiddlewareController Declares the gin middlewares of the controller,
+// they only apply to the apis of the controller and run after the global middlewares.
+type MiddlewareController interface {
+	// Middlewares of the controller, triggered after PostConstruct
+	Middlewares() []gin.HandlerFunc
+}
+
 // Register controllers
 func Register(controller ...abstractController) {
 	controllerCache = append(controllerCache, controller...)
 }
 
+// RegisterGroup Register the controllers whose apis are mounted under the prefix, such as the controllers of a feature module
+func RegisterGroup(prefix string, controller ...abstractController) {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	for _, c := range controller {
+		controllerPrefixes[c] = prefix
+	}
+	controllerCache = append(controllerCache, controller...)
+}
+
 // IsController Determine whether it is controller
 func IsController(v interface{}) bool {
 	ct := reflect.TypeOf(v)
@@ -46,47 +74,128 @@ func IsController(v interface{}) bool {
 // @param autowired: whether enable autowired properties
 func Apply(e *gin.Engine, autowired bool) {
 	if core.Apis == nil {
+		appliedControllers = append(appliedControllers, controllerCache...)
 		for _, controller := range controllerCache {
 			if autowired {
 				ioc.Inject(controller)
+				injectNamed(reflect.ValueOf(controller), make(map[any]bool))
+				constructBeans(controller)
 			}
 		}
 		return
 	}
 	ginProxy := reflect.ValueOf(e)
 	annotationCache = make(map[string]Annotations)
+	annotatedTimeouts = make(map[string]time.Duration)
+	streamingRoutes = make(map[string]bool)
+	controllerCache = enabledControllers(controllerCache)
+	appliedControllers = append(appliedControllers, controllerCache...)
+	if mockConf.Enabled {
+		logger.Log.Warn("Mock mode is enabled, the apis respond the examples")
+	}
 	for _, controller := range controllerCache {
-		if autowired {
+		// the mocked controllers are not called, so their dependencies are not required
+		if autowired && !mockConf.Enabled {
 			ioc.Inject(controller)
+			injectNamed(reflect.ValueOf(controller), make(map[any]bool))
+		}
+		if !mockConf.Enabled {
+			if autowired {
+				constructBeans(controller)
+			}
+			timed(reflect.TypeOf(controller).String(), false, controller.PostConstruct)
+			markConstructed(controller)
 		}
-		controller.PostConstruct()
 		controllerTypeOf := reflect.TypeOf(controller).Elem()
 		controllerProxy := reflect.ValueOf(controller)
 		methodInfosAst := core.Apis[controllerTypeOf.Name()]
+		routerProxy := ginProxy
+		if mc, ok := controller.(MiddlewareController); ok && !mockConf.Enabled {
+			routerProxy = reflect.ValueOf(e.Group("", mc.Middlewares()...))
+		}
+		if wc, ok := controller.(WebSocketController); ok && !mockConf.Enabled {
+			for path, h := range wc.WebSockets() {
+				route := RouteInfo{Method: "GET", Path: path, Controller: controllerTypeOf.Name(), Handler: "WebSockets"}
+				mountRoute(routerProxy, route, []reflect.Value{reflect.ValueOf(wsHandler(h))})
+				streamingRoutes[annotationKey(route.Method, path)] = true
+			}
+		}
+		var exceptionHandlers []ExceptionHandler
+		if ec, ok := controller.(ExceptionHandlerController); ok && !mockConf.Enabled {
+			exceptionHandlers = ec.ExceptionHandlers()
+		}
 		for _, m := range methodInfosAst {
 			mValueProxy := controllerProxy.MethodByName(m.Name)
 			if mValueProxy.Kind() == reflect.Invalid {
 				continue
 			}
-			ginMethod := ginProxy.MethodByName(m.Method)
-			args := []reflect.Value{reflect.ValueOf(m.ApiPath)}
-			args = append(args, mValueProxy)
-			ginMethod.Call(args)
-			annotationCache[m.ApiPath] = m.Annotations
+			if cond := unmatchedCondition(annotationConditions(m.Annotations)); cond != nil {
+				logger.Log.Debugf("Api %s.%s is not mounted, condition [%s] does not match", controllerTypeOf.Name(), m.Name, cond.Desc)
+				continue
+			}
+			methods, path, err := routeMethods(m)
+			if err != nil {
+				logger.Log.Fatalf("invalid api method %s.%s, %s", controllerTypeOf.Name(), m.Name, err.Error())
+			}
+			timeout, timed, err := annotatedTimeout(m.Annotations)
+			if err != nil {
+				logger.Log.Fatalf("invalid api method %s.%s, %s", controllerTypeOf.Name(), m.Name, err.Error())
+			}
+			routed := *m
+			routed.ApiPath = path
+			apiPath, versionHandler := versionRoute(controller, &routed)
+			if prefix := controllerPrefixes[controller]; prefix != "" {
+				apiPath = strings.TrimSuffix(prefix+"/"+strings.TrimPrefix(apiPath, "/"), "/")
+			}
+			var args []reflect.Value
+			if versionHandler != nil {
+				args = append(args, reflect.ValueOf(versionHandler))
+			}
+			for _, h := range buildAnnotationHandlers(m.Annotations) {
+				args = append(args, reflect.ValueOf(h))
+			}
+			var handler gin.HandlerFunc
+			if mockConf.Enabled {
+				handler, err = mockHandler(apiPath, controllerTypeOf.Name(), m.Name, mValueProxy.Type(), m.Annotations)
+			} else {
+				handler, err = adaptHandler(apiPath, mValueProxy, exceptionHandlers)
+			}
+			if err != nil {
+				logger.Log.Fatalf("invalid api method %s.%s, %s", controllerTypeOf.Name(), m.Name, err.Error())
+			}
+			if len(exceptionHandlers) > 0 {
+				handler = withExceptionHandlers(handler, exceptionHandlers)
+			}
+			args = append(args, reflect.ValueOf(handler))
+			name := routeName(controllerTypeOf.Name(), m)
+			for _, method := range methods {
+				route := RouteInfo{Method: method, Path: apiPath, Name: name, Controller: controllerTypeOf.Name(), Handler: m.Name}
+				mountRoute(routerProxy, route, args)
+				recordAPIDoc(route, mValueProxy.Type(), m.Annotations, controller)
+				annotationCache[annotationKey(method, apiPath)] = m.Annotations
+				if timed {
+					annotatedTimeouts[annotationKey(method, apiPath)] = timeout
+				}
+				if returnsEvents(mValueProxy.Type()) {
+					streamingRoutes[annotationKey(method, apiPath)] = true
+				}
+			}
 		}
 		if len(controllerCache) == 1 {
 			controllerCache = nil
-			return
+			break
 		}
 		controllerCache = controllerCache[1:]
 	}
+	keepMethodAnnotations()
 	core.Apis = nil // GC
+	printRoutes()
 }
 
 // GetAnnotation Gets the specified annotation
 // Returns the value of this annotation, when the has is false mine this val is empty
 func GetAnnotation(ctx *gin.Context, annotationName string) (val string, has bool) {
-	anno, has := annotationCache[ctx.FullPath()]
+	anno, has := annotationCache[annotationKey(ctx.Request.Method, ctx.FullPath())]
 	if !has || len(anno) == 0 {
 		return "", false
 	}
@@ -94,6 +203,15 @@ func GetAnnotation(ctx *gin.Context, annotationName string) (val string, has boo
 	return
 }
 
+// RouteAnnotations Gets the annotations of the route mounted by Apply
+func RouteAnnotations(method, path string) Annotations {
+	return annotationCache[annotationKey(method, path)]
+}
+
+func annotationKey(method, path string) string {
+	return method + " " + path
+}
+
 // MethodInterceptor API method interceptor
 // You can do logical processing before and after method calls
 type MethodInterceptor interface {
@@ -108,3 +226,12 @@ type MethodInterceptor interface {
 	// if you want to abort the current request, just call abort() and response inside the method
 	PostHandle(ctx *gin.Context)
 }
+
+// CompletionInterceptor Declares the interceptor to be notified when the request completes,
+// usually to release the resources acquired in PreHandle, record the metrics or audit
+type CompletionInterceptor interface {
+	// AfterCompletion triggered in reverse order after the request completes if PreHandle was triggered,
+	// even when the request is aborted or panics.
+	// err is the panic value, or the last error of the context, nil means the request completes normally
+	AfterCompletion(ctx *gin.Context, err any)
+}
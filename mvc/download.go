@@ -0,0 +1,52 @@
+package mvc
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Attachment Set the Content-Disposition header, the browser downloads the response as the file
+func Attachment(ctx *gin.Context, filename string) {
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
+
+// DownloadFile Download the local file with the filename, support range requests
+func DownloadFile(ctx *gin.Context, path, filename string) {
+	f, err := os.Open(path)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	Download(ctx, filename, stat.ModTime(), f)
+}
+
+// Download the content with the filename, support range and conditional requests
+func Download(ctx *gin.Context, filename string, modTime time.Time, content io.ReadSeeker) {
+	Attachment(ctx, filename)
+	http.ServeContent(ctx.Writer, ctx.Request, filename, modTime, content)
+}
+
+// DownloadStream Stream the content of io.Reader with the filename, the range is not supported.
+// The size is used as the Content-Length, negative means unknown and the response is chunked
+func DownloadStream(ctx *gin.Context, filename string, size int64, content io.Reader) {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	Attachment(ctx, filename)
+	ctx.DataFromReader(http.StatusOK, size, contentType, content, nil)
+}
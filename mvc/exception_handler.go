@@ -0,0 +1,68 @@
+package mvc
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"reflect"
+)
+
+// ExceptionHandler handles the errors of a specific type, created by HandleException
+type ExceptionHandler struct {
+	target reflect.Type
+	handle func(ctx *gin.Context, err error)
+}
+
+/*
+HandleException Create the exception handler of the error type E, the error is matched by errors.As.
+
+	func (u *UserController) ExceptionHandlers() []mvc.ExceptionHandler {
+	    return []mvc.ExceptionHandler{
+	        mvc.HandleException(func(ctx *gin.Context, err *NotFoundError) {
+	            resp.DirectRespWithCode(ctx, 40004, err.Error())
+	        }),
+	    }
+	}
+*/
+func HandleException[E error](fn func(ctx *gin.Context, err E)) ExceptionHandler {
+	return ExceptionHandler{
+		target: reflect.TypeOf((*E)(nil)).Elem(),
+		handle: func(ctx *gin.Context, err error) {
+			fn(ctx, err.(E))
+		},
+	}
+}
+
+// ExceptionHandlerController Declares the exception handlers of the controller,
+// they handle the errors returned or panicked by the apis of the controller, in order of declaration.
+// The errors not handled fall back to the global exception interceptor.
+type ExceptionHandlerController interface {
+	// ExceptionHandlers of the controller, triggered after PostConstruct
+	ExceptionHandlers() []ExceptionHandler
+}
+
+// handle the error by the first matched handler, return false when no handler matches
+func handleException(ctx *gin.Context, handlers []ExceptionHandler, err error) bool {
+	for _, h := range handlers {
+		target := reflect.New(h.target)
+		if errors.As(err, target.Interface()) {
+			h.handle(ctx, target.Elem().Interface().(error))
+			return true
+		}
+	}
+	return false
+}
+
+// recover the panicked errors of the handler, the unhandled ones are panicked again
+func withExceptionHandlers(handler gin.HandlerFunc, handlers []ExceptionHandler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				if err, ok := r.(error); ok && handleException(ctx, handlers, err) {
+					return
+				}
+				panic(r)
+			}
+		}()
+		handler(ctx)
+	}
+}
@@ -0,0 +1,124 @@
+package mvc
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"sync"
+)
+
+/*
+FallbackAnnotation Declares the fallback of the api method, it responds the degraded data, such as the cached or stub data,
+when the circuit of the route is open, the handler timed out or the handler failed by the downstream timeout or open circuit.
+
+	// Recommend
+	// @GET(path="/recommend") recommended products
+	// @Fallback("hotProducts")
+	func (p *ProductController) Recommend(ctx *gin.Context) {}
+*/
+const FallbackAnnotation = "Fallback"
+
+// DegradedHeader the response header declares the response is degraded, the value is the reason
+const DegradedHeader = "X-Degraded"
+
+// Reasons of the degradation
+const (
+	DegradedTimeout     = "timeout"
+	DegradedCircuitOpen = "circuit-open"
+)
+
+// Fallback produces the degraded data of the route, returning error means the failure is responded as usual.
+// When the handler timed out, ctx is a copy of the request context taken before the handler ran, the fallback must not write the response
+type Fallback func(ctx *gin.Context, cause error) (any, error)
+
+var (
+	fallbacks      = make(map[string]Fallback)
+	routeFallbacks sync.Map // method and route -> Fallback, nil when it is not declared
+)
+
+// RegisterFallback Register the named fallback, which can be declared by @Fallback("name")
+func RegisterFallback(name string, fallback Fallback) {
+	fallbacks[name] = fallback
+}
+
+/*
+Degrade Respond the degraded data produced by the fallback of the route with the X-Degraded header,
+returns false when the route has no fallback or the fallback failed.
+
+	data, err := client.Recommend(ctx)
+	if errors.Is(err, breaker.ErrOpen) && mvc.Degrade(ctx, mvc.DegradedCircuitOpen, err) {
+	    return
+	}
+*/
+func Degrade(ctx *gin.Context, reason string, cause error) bool {
+	data, ok := fallbackData(ctx, cause)
+	if !ok {
+		return false
+	}
+	ctx.Header(DegradedHeader, reason)
+	resp.Json(ctx, data)
+	ctx.Abort()
+	return true
+}
+
+// DegradeError Degrade the route when the error is the downstream failure, such as context.DeadlineExceeded
+// or the error implementing DegradeReason() string like breaker.ErrOpen
+func DegradeError(ctx *gin.Context, err error) bool {
+	reason := degradeReason(err)
+	if reason == "" {
+		return false
+	}
+	return Degrade(ctx, reason, err)
+}
+
+// the reason of the downstream failure, empty means it is not degraded
+func degradeReason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return DegradedTimeout
+	}
+	var d interface{ DegradeReason() string }
+	if errors.As(err, &d) {
+		return d.DegradeReason()
+	}
+	return ""
+}
+
+// the data produced by the fallback of the route
+func fallbackData(ctx *gin.Context, cause error) (any, bool) {
+	return callFallback(routeFallback(ctx), ctx, cause)
+}
+
+func callFallback(fallback Fallback, ctx *gin.Context, cause error) (any, bool) {
+	if fallback == nil {
+		return nil, false
+	}
+	data, err := fallback(ctx, cause)
+	if err != nil {
+		logger.Log.Warnf("Fallback of [%s %s] error, %s", ctx.Request.Method, ctx.Request.URL.Path, err.Error())
+		return nil, false
+	}
+	return data, true
+}
+
+func routeFallback(ctx *gin.Context) Fallback {
+	route := ctx.FullPath()
+	if route == "" {
+		return nil
+	}
+	key := annotationKey(ctx.Request.Method, route)
+	if f, ok := routeFallbacks.Load(key); ok {
+		return f.(Fallback)
+	}
+	var fallback Fallback
+	if val, ok := GetAnnotation(ctx, FallbackAnnotation); ok {
+		name := strings.Trim(ParseAnnotationArgs(val)["value"], `"`)
+		if fallback, ok = fallbacks[name]; !ok {
+			logger.Log.Errorf("fallback [%s] declared by @%s on %s is not registered", name, FallbackAnnotation, route)
+		}
+	}
+	routeFallbacks.Store(key, fallback)
+	return fallback
+}
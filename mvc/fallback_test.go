@@ -0,0 +1,42 @@
+package mvc
+
+import (
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteFallbackOfMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log = &logger.DefaultLog{}
+	RegisterFallback("cachedOrders", func(ctx *gin.Context, cause error) (any, error) {
+		return "cached", nil
+	})
+	annotationCache = map[string]Annotations{
+		annotationKey(http.MethodGet, "/order"):  {FallbackAnnotation: `"cachedOrders"`},
+		annotationKey(http.MethodPost, "/order"): {},
+	}
+	defer func() {
+		annotationCache = nil
+	}()
+	e := gin.New()
+	degrade := func(ctx *gin.Context) {
+		if !Degrade(ctx, DegradedCircuitOpen, errors.New("open")) {
+			ctx.Status(http.StatusServiceUnavailable)
+		}
+	}
+	e.GET("/order", degrade)
+	e.POST("/order", degrade)
+	// each method is served twice, so the cached fallback of the first one is hit
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodGet, http.MethodPost} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(method, "/order", nil))
+		degraded := w.Header().Get(DegradedHeader) != ""
+		if want := method == http.MethodGet; degraded != want {
+			t.Errorf("%s /order degraded = %v, want %v", method, degraded, want)
+		}
+	}
+}
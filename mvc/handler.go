@@ -0,0 +1,247 @@
+package mvc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ginContextType = reflect.TypeOf((*gin.Context)(nil))
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// parameter binder of the api method, returns the bound value or the error responded to the client
+type paramBinder func(ctx *gin.Context) (reflect.Value, error)
+
+/*
+adapt the api method to the gin handler. Besides the *gin.Context, the method can declare typed parameters:
+
+	scalar parameters (string, bool, int, uint, float) are bound to the path parameters in order of declaration
+	struct parameters are bound by Bind(), from the path parameters, the query parameters and the body
+	the Pageable parameter is bound by BindPageable(), from the page, size and sort query parameters
+	the request scoped bean is got from its provider, see RequestScoped()
+
+The method can also return values, they are responded unless the method has already responded:
+
+	(T) or (T, error): the value is responded by resp.Json(), the error is responded by resp.DirectRespErr()
+	(error): nil is responded by resp.Ok(), otherwise by resp.DirectRespErr()
+	(<-chan Event) or (<-chan Event, error): the events are streamed by StreamEvents()
+
+Example:
+
+	// @GET(path="/user/:id")
+	func (u *UserController) GetUser(ctx *gin.Context, id int64) {}
+
+	type Query struct {
+	    Name string `form:"name" binding:"required"`
+	}
+
+	// @GET(path="/dept/:deptId/user")
+	func (u *UserController) ListUser(ctx *gin.Context, deptId int64, query *Query) {}
+
+Binding failures are responded with http status 400.
+The returned errors are handled by the exception handlers of the controller first.
+
+	// @GET(path="/user/:id")
+	func (u *UserController) GetUser(ctx *gin.Context, id int64) (*UserDTO, error) {}
+*/
+func adaptHandler(apiPath string, method reflect.Value, exceptionHandlers []ExceptionHandler) (gin.HandlerFunc, error) {
+	if h, ok := method.Interface().(func(*gin.Context)); ok {
+		return h, nil
+	}
+	mt := method.Type()
+	if mt.NumIn() == 0 || mt.In(0) != ginContextType {
+		return nil, fmt.Errorf("the first parameter must be *gin.Context")
+	}
+	valueIndex, errIndex := -1, -1
+	switch mt.NumOut() {
+	case 0:
+	case 1:
+		if mt.Out(0) == errorType {
+			errIndex = 0
+		} else {
+			valueIndex = 0
+		}
+	case 2:
+		if mt.Out(1) != errorType {
+			return nil, fmt.Errorf("the second return value must be error")
+		}
+		valueIndex, errIndex = 0, 1
+	default:
+		return nil, fmt.Errorf("at most two return values are supported")
+	}
+	binders, err := paramBinders(apiPath, mt)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *gin.Context) {
+		args, ok := bindParams(ctx, binders)
+		if !ok {
+			return
+		}
+		out := method.Call(args)
+		if len(out) == 0 || ctx.Writer.Written() || ctx.IsAborted() {
+			return
+		}
+		if errIndex >= 0 && !out[errIndex].IsNil() {
+			err := out[errIndex].Interface().(error)
+			failScope(ctx, err)
+			if !handleException(ctx, exceptionHandlers, err) && !DegradeError(ctx, err) {
+				resp.DirectRespErr(ctx, err)
+			}
+			return
+		}
+		if valueIndex >= 0 {
+			if mt.Out(valueIndex) == eventChanType && !out[valueIndex].IsNil() {
+				StreamEvents(ctx, out[valueIndex].Interface().(<-chan Event))
+				return
+			}
+			resp.Json(ctx, out[valueIndex].Interface())
+			return
+		}
+		resp.Ok(ctx)
+	}, nil
+}
+
+// the binders of the parameters following the *gin.Context
+func paramBinders(apiPath string, mt reflect.Type) ([]paramBinder, error) {
+	pathParams := pathParamNames(apiPath)
+	var binders []paramBinder
+	for i := 1; i < mt.NumIn(); i++ {
+		pt := mt.In(i)
+		switch {
+		case isScoped(pt):
+			binders = append(binders, scopedBinder(pt))
+		case pt == pageableType || (pt.Kind() == reflect.Pointer && pt.Elem() == pageableType):
+			binders = append(binders, pageableBinder(pt.Kind() == reflect.Pointer))
+		case pt.Kind() == reflect.Struct || (pt.Kind() == reflect.Pointer && pt.Elem().Kind() == reflect.Struct):
+			binders = append(binders, structBinder(pt))
+		case isScalar(pt.Kind()):
+			if len(pathParams) == 0 {
+				return nil, fmt.Errorf("the scalar parameter %d has no corresponding path parameter", i)
+			}
+			binders = append(binders, scalarBinder(pathParams[0], pt))
+			pathParams = pathParams[1:]
+		default:
+			return nil, fmt.Errorf("unsupported parameter type %s", pt)
+		}
+	}
+	return binders, nil
+}
+
+// bind the arguments of the api method, the failure is responded with http status 400,
+// the checksum mismatch with http status 422
+func bindParams(ctx *gin.Context, binders []paramBinder) ([]reflect.Value, bool) {
+	args := make([]reflect.Value, 0, len(binders)+1)
+	args = append(args, reflect.ValueOf(ctx))
+	for _, b := range binders {
+		v, err := b(ctx)
+		if err != nil {
+			var ve *exception.ValidationException
+			var ce *exception.ChecksumException
+			var be *exception.BusinessException
+			var se *scopeError
+			switch {
+			case errors.As(err, &se):
+				resp.DirectRespErr(ctx, se.err)
+			case errors.As(err, &ce), errors.As(err, &be):
+				resp.DirectRespErr(ctx, err)
+			case errors.As(err, &ve):
+				resp.ValidationFailed(ctx, ve)
+			default:
+				resp.ValidationFailed(ctx, exception.NewValidationErr(err, nil))
+			}
+			return nil, false
+		}
+		args = append(args, v)
+	}
+	return args, true
+}
+
+// the names of the path parameters, such as /user/:id/*path -> [id, path]
+func pathParamNames(apiPath string) []string {
+	var names []string
+	for _, seg := range strings.Split(apiPath, "/") {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			names = append(names, seg[1:])
+		}
+	}
+	return names
+}
+
+func structBinder(pt reflect.Type) paramBinder {
+	isPtr := pt.Kind() == reflect.Pointer
+	if isPtr {
+		pt = pt.Elem()
+	}
+	return func(ctx *gin.Context) (reflect.Value, error) {
+		ptr := reflect.New(pt)
+		if err := Bind(ctx, ptr.Interface()); err != nil {
+			return ptr, err
+		}
+		if isPtr {
+			return ptr, nil
+		}
+		return ptr.Elem(), nil
+	}
+}
+
+func scalarBinder(name string, pt reflect.Type) paramBinder {
+	return func(ctx *gin.Context) (reflect.Value, error) {
+		v := reflect.New(pt).Elem()
+		if err := setScalar(v, ctx.Param(name)); err != nil {
+			return reflect.Value{}, fmt.Errorf("参数[%s]格式错误", name)
+		}
+		return v, nil
+	}
+}
+
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// convert the string to the kind of the value
+func setScalar(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package mvc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BeanTiming the initialization duration of a bean
+type BeanTiming struct {
+	Name     string
+	Duration time.Duration
+	Lazy     bool // Whether it's initialized on the first use
+}
+
+var (
+	timelineMu   sync.Mutex
+	beanTimeline []BeanTiming
+)
+
+// BeanTimeline Get the initialization durations of the beans, the slowest first
+func BeanTimeline() []BeanTiming {
+	timelineMu.Lock()
+	defer timelineMu.Unlock()
+	timeline := append([]BeanTiming(nil), beanTimeline...)
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Duration > timeline[j].Duration
+	})
+	return timeline
+}
+
+// run the initialization and record its duration
+func timed(name string, lazy bool, fn func()) {
+	begin := time.Now()
+	defer func() {
+		timelineMu.Lock()
+		beanTimeline = append(beanTimeline, BeanTiming{Name: name, Duration: time.Since(begin), Lazy: lazy})
+		timelineMu.Unlock()
+	}()
+	fn()
+}
+
+/*
+Lazy the provider of the expensive bean, the bean is created on the first Get() instead of the startup, such as the
+client connecting to the search engine. The bean implementing PostConstructBean is initialized after it's created,
+and PreDestroy is triggered when the application stops if it's created. The provider is set into the ioc container,
+so it's injected into the controllers like the other beans, it also breaks the dependency cycle:
+
+	mvc.SetBeans(mvc.LazyBean(func() (*SearchClient, error) {
+	    return NewSearchClient(conf.Search)
+	}))
+
+	type SearchService struct {
+	    Client *mvc.Lazy[*SearchClient]
+	}
+
+	func (s *SearchService) Search(ctx context.Context, q string) ([]Hit, error) {
+	    client, err := s.Client.Get()
+	}
+*/
+type Lazy[T any] struct {
+	once    sync.Once
+	factory func() (T, error)
+	bean    T
+	err     error
+	created bool
+}
+
+// LazyBean Create the provider of the lazy bean
+func LazyBean[T any](factory func() (T, error)) *Lazy[T] {
+	return &Lazy[T]{factory: factory}
+}
+
+// Get the bean, it's created by the factory on the first call. The failure is cached, so the factory runs once
+func (l *Lazy[T]) Get() (T, error) {
+	l.once.Do(func() {
+		name := reflect.TypeOf((*T)(nil)).Elem().String()
+		timed(name, true, func() {
+			if l.bean, l.err = l.factory(); l.err != nil {
+				l.err = fmt.Errorf("create lazy %s error, %w", name, l.err)
+				return
+			}
+			if pc, ok := any(l.bean).(PostConstructBean); ok {
+				pc.PostConstruct()
+			}
+			l.created = true
+		})
+	})
+	return l.bean, l.err
+}
+
+// MustGet Get the bean, panic when it can't be created
+func (l *Lazy[T]) MustGet() T {
+	bean, err := l.Get()
+	if err != nil {
+		panic(err)
+	}
+	return bean
+}
+
+// PreDestroy Trigger PreDestroy of the bean if it's created
+func (l *Lazy[T]) PreDestroy() {
+	// the bean is not created after stopping
+	l.once.Do(func() {})
+	if pd, ok := any(l.bean).(PreDestroyBean); ok && l.created {
+		pd.PreDestroy()
+	}
+}
@@ -0,0 +1,167 @@
+package mvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ExampleAnnotation Declares the example response of the api method in mock mode,
+the value is the json data, or the golden file and the http status.
+
+	// GetUser
+	// @GET(path="/user/:id") get user
+	// @Example({"id": 1, "name": "Tom"})
+	func (u *UserController) GetUser(ctx *gin.Context, id int64) (*UserDTO, error) {}
+
+	// CreateUser
+	// @POST(path="/user") create user
+	// @Example(file="user/created.json", status="201")
+	func (u *UserController) CreateUser(ctx *gin.Context, user *UserDTO) error {}
+*/
+const ExampleAnnotation = "Example"
+
+// MockConfig the mock mode, the apis only bind and validate the parameters then respond the examples,
+// the controllers are neither injected nor called, so the frontend can develop against the unfinished backend
+type MockConfig struct {
+	Enabled bool          `mapstructure:"enabled"` // Whether to respond the examples instead of calling the apis, default false
+	Dir     string        `mapstructure:"dir"`     // Directory of the golden files, default mocks
+	Delay   time.Duration `mapstructure:"delay"`   // Simulated latency of each response, default 0
+}
+
+var mockConf MockConfig
+
+// SetMockConfig Sets the mock mode, it must be called before Apply
+func SetMockConfig(conf MockConfig) {
+	if conf.Dir == "" {
+		conf.Dir = "mocks"
+	}
+	mockConf = conf
+}
+
+// MockEnabled Whether the apis are mocked
+func MockEnabled() bool {
+	return mockConf.Enabled
+}
+
+// example response of the api method
+type mockExample struct {
+	status int
+	data   any // json.RawMessage or the zero value of the return type, nil means resp.Ok()
+}
+
+/*
+create the mock handler of the api method, the example is resolved in order:
+
+	@Example annotation
+	golden file {dir}/{Controller}/{Method}.json
+	zero value of the return type, so the shape of the response is still visible
+*/
+func mockHandler(apiPath, controller, name string, mt reflect.Type, annotations Annotations) (gin.HandlerFunc, error) {
+	if mt.NumIn() == 0 || mt.In(0) != ginContextType {
+		return nil, fmt.Errorf("the first parameter must be *gin.Context")
+	}
+	binders, err := paramBinders(apiPath, mt)
+	if err != nil {
+		return nil, err
+	}
+	example, err := resolveExample(controller, name, mt, annotations)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *gin.Context) {
+		if _, ok := bindParams(ctx, binders); !ok {
+			return
+		}
+		if mockConf.Delay > 0 {
+			time.Sleep(mockConf.Delay)
+		}
+		ctx.Header("X-Mock", "true")
+		if example.data == nil {
+			resp.Ok(ctx)
+		} else {
+			resp.InitResp(ctx).WithBasic(0, "ok", example.data).To(example.status)
+		}
+	}, nil
+}
+
+func resolveExample(controller, name string, mt reflect.Type, annotations Annotations) (*mockExample, error) {
+	example := &mockExample{status: 200}
+	if val, ok := annotations[ExampleAnnotation]; ok {
+		val = strings.TrimSpace(val)
+		if strings.HasPrefix(val, "{") || strings.HasPrefix(val, "[") {
+			if !json.Valid([]byte(val)) {
+				return nil, fmt.Errorf("invalid json of @%s", ExampleAnnotation)
+			}
+			example.data = json.RawMessage(val)
+			return example, nil
+		}
+		args := ParseAnnotationArgs(val)
+		if args["status"] != "" {
+			status, err := strconv.Atoi(args["status"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid status of @%s, %s", ExampleAnnotation, args["status"])
+			}
+			example.status = status
+		}
+		file := args["file"]
+		if file == "" {
+			file = args["value"]
+		}
+		if file != "" {
+			b, err := readGolden(filepath.Join(mockConf.Dir, file))
+			if err != nil {
+				return nil, err
+			}
+			example.data = b
+			return example, nil
+		}
+	}
+	golden := filepath.Join(mockConf.Dir, controller, name+".json")
+	if _, err := os.Stat(golden); err == nil {
+		b, err := readGolden(golden)
+		if err != nil {
+			return nil, err
+		}
+		example.data = b
+		return example, nil
+	}
+	if mt.NumOut() > 0 && mt.Out(0) != errorType {
+		example.data = zeroValue(mt.Out(0))
+	}
+	return example, nil
+}
+
+func readGolden(file string) (json.RawMessage, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read the example %s error, %s", file, err.Error())
+	}
+	if !json.Valid(b) {
+		return nil, fmt.Errorf("invalid json of the example %s", file)
+	}
+	return b, nil
+}
+
+// zero value of the type, the pointers of the structs are allocated and the slices are empty
+func zeroValue(t reflect.Type) any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		if t.Elem().Kind() == reflect.Struct {
+			return reflect.New(t.Elem()).Interface()
+		}
+	case reflect.Slice:
+		return reflect.MakeSlice(t, 0, 0).Interface()
+	case reflect.Map:
+		return reflect.MakeMap(t).Interface()
+	}
+	return reflect.Zero(t).Interface()
+}
@@ -0,0 +1,77 @@
+package mvc
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"reflect"
+)
+
+// QualifierTag the tag of the field injected with the named bean
+const QualifierTag = "qualifier"
+
+var (
+	namedBeans    = make(map[string]any)
+	namedPointers = make(map[uintptr]bool)
+)
+
+/*
+SetNamedBean Set the bean by the name, so multiple beans of the same type can be injected into the fields by the
+qualifier tag. The bean must be a pointer, the existing bean of the name is overwritten.
+
+	mvc.SetNamedBean("cache", redis.NewClient(&redis.Options{Addr: "cache:6379"}))
+	mvc.SetNamedBean("queue", redis.NewClient(&redis.Options{Addr: "queue:6379"}))
+
+	type OrderService struct {
+	    Cache *redis.Client `qualifier:"cache"`
+	    Queue *redis.Client `qualifier:"queue"`
+	}
+*/
+func SetNamedBean(name string, bean any) {
+	v := reflect.ValueOf(bean)
+	if v.Kind() != reflect.Pointer {
+		logger.Log.Fatalf("named bean %s must be a pointer, but it's %T", name, bean)
+	}
+	namedBeans[name] = bean
+	namedPointers[v.Pointer()] = true
+}
+
+// GetNamedBean Get the bean by the name, nil when it's absent
+func GetNamedBean(name string) any {
+	return namedBeans[name]
+}
+
+/*
+inject the named beans into the fields with the qualifier tag of the bean and the beans injected into it, the ioc
+container may have injected the fields by the type, they are overwritten. Panic when the named bean is absent or its
+type doesn't match the field
+*/
+func injectNamed(v reflect.Value, visited map[any]bool) {
+	if visited[v.Interface()] {
+		return
+	}
+	visited[v.Interface()] = true
+	elem := v.Elem()
+	for i := 0; i < elem.NumField(); i++ {
+		sf := elem.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := elem.Field(i)
+		if name := sf.Tag.Get(QualifierTag); name != "" {
+			bean, ok := namedBeans[name]
+			if !ok {
+				logger.Log.Fatalf("%s.%s requires the named bean %s, but it's absent", elem.Type(), sf.Name, name)
+			}
+			bv := reflect.ValueOf(bean)
+			if !bv.Type().AssignableTo(f.Type()) {
+				logger.Log.Fatalf("%s.%s requires %s, but the named bean %s is %s", elem.Type(), sf.Name, f.Type(), name, bv.Type())
+			}
+			f.Set(bv)
+		}
+		if f.Kind() == reflect.Interface && !f.IsNil() {
+			f = f.Elem()
+		}
+		if isBean(f) {
+			injectNamed(f, visited)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package mvc
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"html/template"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+Documentation annotations of the api method, they are optional.
+The operations are tagged by the controller name without @Tags.
+
+	// GetUser
+	// @GET(path="/user/:id") get user
+	// @Summary("Get the user")
+	// @Description("Returns 404 when the user is not found")
+	// @Tags(user, admin)
+	func (u *UserController) GetUser(ctx *gin.Context, id int64) (*UserDTO, error) {}
+*/
+const (
+	SummaryAnnotation     = "Summary"
+	DescriptionAnnotation = "Description"
+	TagsAnnotation        = "Tags"
+)
+
+// OpenAPIConfig the OpenAPI 3 document generated from the mounted apis
+type OpenAPIConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`     // Whether to serve the document, default false
+	Path        string        `mapstructure:"path"`        // Endpoint of the document, default /openapi.json
+	Title       string        `mapstructure:"title"`       // Title of the api, default API
+	Version     string        `mapstructure:"version"`     // Version of the api, default 1.0.0
+	Description string        `mapstructure:"description"` // Description of the api
+	SwaggerUI   string        `mapstructure:"swagger_ui"`  // Endpoint of the Swagger UI page, default empty means not served
+	SwaggerCDN  string        `mapstructure:"swagger_cdn"` // Where the page loads the swagger-ui-dist assets, default https://unpkg.com/swagger-ui-dist@5
+	ServerURLs  []string      `mapstructure:"server_urls"` // Base urls of the api, default empty means the same origin
+	Examples    ExampleConfig `mapstructure:"examples"`    // Record the real requests and responses as the examples
+}
+
+// OpenAPIDoc the OpenAPI 3 document
+type OpenAPIDoc struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Servers    []OpenAPIServer                  `json:"servers,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas,omitempty"`
+	} `json:"components"`
+}
+
+// OpenAPIInfo the info object of the document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIServer the server object of the document
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// Operation the api operation
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter the path or query parameter of the operation
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody the body of the operation
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response the response of the operation
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType the content of the body or the response
+type MediaType struct {
+	Schema  *Schema         `json:"schema"`
+	Example json.RawMessage `json:"example,omitempty"`
+}
+
+// the api method mounted by Apply, documented when the document is requested
+type apiDoc struct {
+	route       RouteInfo
+	mt          reflect.Type
+	annotations Annotations
+	deprecated  bool
+}
+
+var (
+	openAPIConf OpenAPIConfig
+	apiDocs     []apiDoc
+	openAPIOnce sync.Once
+	openAPIJson []byte
+)
+
+// SetOpenAPIConfig Set the OpenAPI document, it must be called before Apply
+func SetOpenAPIConfig(conf OpenAPIConfig) {
+	if conf.Path == "" {
+		conf.Path = "/openapi.json"
+	}
+	if conf.Title == "" {
+		conf.Title = "API"
+	}
+	if conf.Version == "" {
+		conf.Version = "1.0.0"
+	}
+	if conf.SwaggerCDN == "" {
+		conf.SwaggerCDN = "https://unpkg.com/swagger-ui-dist@5"
+	}
+	openAPIConf = conf
+}
+
+// record the api method for the document
+func recordAPIDoc(route RouteInfo, mt reflect.Type, annotations Annotations, controller abstractController) {
+	if !openAPIConf.Enabled {
+		return
+	}
+	_, deprecated := controller.(DeprecatedController)
+	apiDocs = append(apiDocs, apiDoc{route: route, mt: mt, annotations: annotations, deprecated: deprecated})
+}
+
+// OpenAPI Generate the OpenAPI 3 document of the apis mounted by Apply
+func OpenAPI() *OpenAPIDoc {
+	doc := &OpenAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: openAPIConf.Title, Version: openAPIConf.Version, Description: openAPIConf.Description},
+		Paths:   make(map[string]map[string]*Operation),
+	}
+	for _, u := range openAPIConf.ServerURLs {
+		doc.Servers = append(doc.Servers, OpenAPIServer{URL: u})
+	}
+	registry := newSchemaRegistry()
+	operationIDs := make(map[string]bool)
+	registry.schemas["Result"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"err_code": {Type: "integer", Description: "business code, 0 means success"},
+			"err_msg":  {Type: "string"},
+			"trace_id": {Type: "string"},
+			"ret":      {Description: "response data"},
+		},
+		Required: []string{"err_code", "err_msg"},
+	}
+	for _, d := range apiDocs {
+		method := strings.ToLower(d.route.Method)
+		// CONNECT has no operation in OpenAPI
+		if method == "connect" {
+			continue
+		}
+		path := openAPIPath(d.route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		op := buildOperation(d, registry)
+		// the api mounted for multiple methods shares the route name
+		if operationIDs[op.OperationID] {
+			op.OperationID += "." + method
+		}
+		operationIDs[op.OperationID] = true
+		doc.Paths[path][method] = op
+	}
+	doc.Components.Schemas = registry.schemas
+	return doc
+}
+
+// OpenAPIHandler Respond the OpenAPI document, it is generated on the first request,
+// or on each request when the examples are recorded
+func OpenAPIHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if openAPIConf.Examples.Enabled {
+			b, _ := json.Marshal(OpenAPI())
+			ctx.Data(http.StatusOK, "application/json; charset=utf-8", b)
+			return
+		}
+		openAPIOnce.Do(func() {
+			openAPIJson, _ = json.Marshal(OpenAPI())
+		})
+		ctx.Data(http.StatusOK, "application/json; charset=utf-8", openAPIJson)
+	}
+}
+
+var swaggerUITemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.CDN}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.CDN}}/swagger-ui-bundle.js"></script>
+<script>
+window.onload = function () {
+  SwaggerUIBundle({url: "{{.Spec}}", dom_id: "#swagger-ui", deepLinking: true});
+};
+</script>
+</body>
+</html>
+`))
+
+// SwaggerUIHandler Respond the Swagger UI page of the document, the assets are loaded from the swagger_cdn
+func SwaggerUIHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Content-Type", "text/html; charset=utf-8")
+		ctx.Status(http.StatusOK)
+		_ = swaggerUITemplate.Execute(ctx.Writer, map[string]string{
+			"Title": openAPIConf.Title,
+			"CDN":   strings.TrimSuffix(openAPIConf.SwaggerCDN, "/"),
+			"Spec":  openAPIConf.Path,
+		})
+	}
+}
+
+// the gin path to the OpenAPI path, such as /user/:id/*path -> /user/{id}/{path}
+func openAPIPath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+func buildOperation(d apiDoc, registry *schemaRegistry) *Operation {
+	op := &Operation{
+		OperationID: d.route.Name,
+		Summary:     ParseAnnotationArgs(d.annotations[SummaryAnnotation])["value"],
+		Description: ParseAnnotationArgs(d.annotations[DescriptionAnnotation])["value"],
+		Deprecated:  d.deprecated,
+		Responses:   make(map[string]*Response),
+	}
+	for _, tag := range splitAnnotationArgs(d.annotations[TagsAnnotation]) {
+		if tag = strings.Trim(strings.TrimSpace(tag), `"`); tag != "" {
+			op.Tags = append(op.Tags, tag)
+		}
+	}
+	if len(op.Tags) == 0 {
+		op.Tags = []string{d.route.Controller}
+	}
+	if d.mt != nil {
+		buildParameters(op, d, registry)
+	}
+	status := exampleStatus(d.annotations)
+	op.Responses[strconv.Itoa(status)] = successResponse(d, http.StatusText(status), registry)
+	if len(op.Parameters) > 0 || op.RequestBody != nil {
+		op.Responses["400"] = &Response{Description: "Validation failed", Content: resultContent(nil)}
+	}
+	if openAPIConf.Examples.Enabled {
+		mergeExamples(op, d.route)
+	}
+	return op
+}
+
+// the status of the success response, declared by @Example(status="201")
+func exampleStatus(annotations Annotations) int {
+	if status, err := strconv.Atoi(ParseAnnotationArgs(annotations[ExampleAnnotation])["status"]); err == nil {
+		return status
+	}
+	return http.StatusOK
+}
+
+/*
+the parameters of the api method, they are bound in the same way as adaptHandler:
+
+	scalar parameters -> path parameters in order of declaration
+	struct fields with the uri tag -> path parameters
+	struct fields with the form tag -> query parameters, or the form body of the methods with body
+	other struct fields -> json body of the methods with body
+	Pageable -> page, size and sort query parameters
+*/
+func buildParameters(op *Operation, d apiDoc, registry *schemaRegistry) {
+	pathParams := pathParamNames(d.route.Path)
+	withBody := d.route.Method != http.MethodGet && d.route.Method != http.MethodHead && d.route.Method != http.MethodDelete
+	for i := 1; i < d.mt.NumIn(); i++ {
+		pt := d.mt.In(i)
+		if isScoped(pt) {
+			continue
+		}
+		if isScalar(pt.Kind()) {
+			if len(pathParams) > 0 {
+				addParameter(op, &Parameter{Name: pathParams[0], In: "path", Required: true, Schema: registry.schemaOf(pt)})
+				pathParams = pathParams[1:]
+			}
+			continue
+		}
+		for pt.Kind() == reflect.Pointer {
+			pt = pt.Elem()
+		}
+		if pt == pageableType {
+			pageParameters(op)
+			continue
+		}
+		if pt.Kind() != reflect.Struct {
+			continue
+		}
+		multipartBody := false
+		structParameters(pt, func(f reflect.StructField) {
+			name := tagName(f, "uri")
+			in := "path"
+			if name == "" && !withBody {
+				name, in = tagName(f, "form"), "query"
+			}
+			if name == "" {
+				if f.Type == fileHeaderType || f.Type == reflect.SliceOf(fileHeaderType) {
+					multipartBody = true
+				}
+				return
+			}
+			s := registry.schemaOf(f.Type)
+			required := applyBindingTag(s, f) || in == "path"
+			addParameter(op, &Parameter{Name: name, In: in, Required: required, Schema: s})
+		})
+		if !withBody {
+			continue
+		}
+		skipPath := func(f reflect.StructField) bool { return tagName(f, "uri") != "" }
+		if multipartBody {
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{
+				"multipart/form-data": {Schema: formSchema(pt, registry, skipPath)},
+			}}
+		} else if schema := registry.structSchema(pt, skipPath); len(schema.Properties) > 0 {
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{
+				"application/json": {Schema: schema},
+			}}
+		}
+	}
+}
+
+// the query parameters of the Pageable
+func pageParameters(op *Operation) {
+	one, maxSize := 1.0, float64(pageConf.MaxSize)
+	addParameter(op, &Parameter{Name: pageConf.PageParam, In: "query", Schema: &Schema{Type: "integer", Minimum: &one,
+		Description: "page number, starts from 1"}})
+	addParameter(op, &Parameter{Name: pageConf.SizeParam, In: "query", Schema: &Schema{Type: "integer", Minimum: &one, Maximum: &maxSize,
+		Description: "page size, default " + strconv.Itoa(pageConf.DefaultSize)}})
+	addParameter(op, &Parameter{Name: pageConf.SortParam, In: "query", Schema: &Schema{Type: "string",
+		Description: "sort fields separated by comma, prefixed with - for descending, such as -createdAt,name"}})
+}
+
+// add the parameter, the path parameter bound by both the scalar and the struct is documented once
+func addParameter(op *Operation, p *Parameter) {
+	for _, exist := range op.Parameters {
+		if exist.Name == p.Name && exist.In == p.In {
+			return
+		}
+	}
+	op.Parameters = append(op.Parameters, p)
+}
+
+// visit the fields of the struct, the embedded structs are promoted
+func structParameters(t reflect.Type, visit func(f reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct && tagName(f, "form") == "" && tagName(f, "uri") == "" {
+			structParameters(ft, visit)
+			continue
+		}
+		if f.IsExported() {
+			visit(f)
+		}
+	}
+}
+
+// the multipart form schema, the fields are named by the form tag
+func formSchema(t reflect.Type, registry *schemaRegistry, skip func(f reflect.StructField) bool) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	structParameters(t, func(f reflect.StructField) {
+		name := tagName(f, "form")
+		if name == "" || skip(f) {
+			return
+		}
+		fs := registry.schemaOf(f.Type)
+		if applyBindingTag(fs, f) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	})
+	return s
+}
+
+// the success response decided by the return values of the api method
+func successResponse(d apiDoc, description string, registry *schemaRegistry) *Response {
+	r := &Response{Description: description}
+	if d.mt == nil || d.mt.NumOut() == 0 || d.mt.Out(0) == errorType {
+		r.Content = resultContent(nil)
+		return r
+	}
+	if d.mt.Out(0) == eventChanType {
+		r.Description = "Server-Sent Events"
+		r.Content = map[string]*MediaType{"text/event-stream": {Schema: &Schema{Type: "string"}}}
+		return r
+	}
+	r.Content = resultContent(registry.schemaOf(d.mt.Out(0)))
+	if val := strings.TrimSpace(d.annotations[ExampleAnnotation]); strings.HasPrefix(val, "{") || strings.HasPrefix(val, "[") {
+		if json.Valid([]byte(val)) {
+			r.Content["application/json"].Schema.AllOf[1].Properties["ret"].Example = json.RawMessage(val)
+		}
+	}
+	return r
+}
+
+// the json content of the resp.Result, the ret is the data schema
+func resultContent(data *Schema) map[string]*MediaType {
+	schema := &Schema{Ref: "#/components/schemas/Result"}
+	if data != nil {
+		if data.Ref != "" {
+			// the siblings of $ref are ignored in OpenAPI 3.0
+			data = &Schema{AllOf: []*Schema{data}}
+		}
+		schema = &Schema{AllOf: []*Schema{schema, {Type: "object", Properties: map[string]*Schema{"ret": data}}}}
+	}
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
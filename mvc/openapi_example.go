@@ -0,0 +1,204 @@
+package mvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SensitiveFields the fields whose values are redacted from the logged bodies and the recorded examples, matched case-insensitively
+var SensitiveFields = []string{"password", "passwd", "pwd", "secret", "token", "access_token", "refresh_token",
+	"id_token", "client_secret", "authorization", "api_key", "apikey", "credit_card", "card_number", "cvv"}
+
+// Redacted the replacement of the sensitive values
+const Redacted = "******"
+
+// ExampleConfig records the real requests and responses of the apis as the examples of the OpenAPI document
+type ExampleConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`       // Whether to record the examples, default false, ignored in prod environment
+	MaxBodySize  int      `mapstructure:"max_body_size"` // Bodies larger than it are not recorded, default 16384
+	RedactFields []string `mapstructure:"redact_fields"` // Fields whose values are redacted, appended to SensitiveFields
+}
+
+// the recorded examples of a route
+type routeExample struct {
+	request   json.RawMessage
+	responses map[int]json.RawMessage
+}
+
+var (
+	examplesMu sync.RWMutex
+	examples   = make(map[string]*routeExample) // method route -> examples
+)
+
+/*
+RecordExamples The gin middleware records the json request and the json responses of each route,
+the latest ones are merged into the OpenAPI document as the examples. The values of the sensitive fields are redacted.
+*/
+func RecordExamples(conf ExampleConfig) gin.HandlerFunc {
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = 16 << 10
+	}
+	fields := make(map[string]bool)
+	for _, f := range append(append([]string(nil), SensitiveFields...), conf.RedactFields...) {
+		fields[strings.ToLower(f)] = true
+	}
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		if route == "" {
+			ctx.Next()
+			return
+		}
+		var reqBody []byte
+		if ctx.Request.Body != nil && ctx.Request.Body != http.NoBody && isJSON(ctx.ContentType()) {
+			head, _ := io.ReadAll(io.LimitReader(ctx.Request.Body, int64(conf.MaxBodySize)+1))
+			ctx.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(head), ctx.Request.Body), Closer: ctx.Request.Body}
+			if len(head) <= conf.MaxBodySize {
+				reqBody = head
+			}
+		}
+		w := &exampleWriter{ResponseWriter: ctx.Writer, max: conf.MaxBodySize}
+		ctx.Writer = w
+		defer func() {
+			ctx.Writer = w.ResponseWriter
+		}()
+		ctx.Next()
+		status := w.Status()
+		if w.overflow || len(w.buf) == 0 || status >= http.StatusInternalServerError || !isJSON(w.Header().Get("Content-Type")) {
+			return
+		}
+		res := sanitizeExample(w.buf, fields)
+		if res == nil {
+			return
+		}
+		req := sanitizeExample(reqBody, fields)
+		examplesMu.Lock()
+		defer examplesMu.Unlock()
+		key := ctx.Request.Method + " " + route
+		e, ok := examples[key]
+		if !ok {
+			e = &routeExample{responses: make(map[int]json.RawMessage)}
+			examples[key] = e
+		}
+		e.responses[status] = res
+		// the request of the success response is the most representative one
+		if req != nil && (e.request == nil || status < http.StatusBadRequest) {
+			e.request = req
+		}
+	}
+}
+
+// the json with the values of the sensitive fields redacted, nil when it is not valid json
+func sanitizeExample(body []byte, fields map[string]bool) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil
+	}
+	b, err := json.Marshal(redactValue(v, fields))
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func redactValue(v any, fields map[string]bool) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if fields[strings.ToLower(k)] {
+				t[k] = Redacted
+			} else {
+				t[k] = redactValue(val, fields)
+			}
+		}
+	case []any:
+		for i, val := range t {
+			t[i] = redactValue(val, fields)
+		}
+	}
+	return v
+}
+
+// the recorded examples of the api
+func recordedExample(method, route string) (json.RawMessage, map[int]json.RawMessage) {
+	examplesMu.RLock()
+	defer examplesMu.RUnlock()
+	e, ok := examples[method+" "+route]
+	if !ok {
+		return nil, nil
+	}
+	responses := make(map[int]json.RawMessage, len(e.responses))
+	for status, res := range e.responses {
+		responses[status] = res
+	}
+	return e.request, responses
+}
+
+// merge the recorded examples into the operation
+func mergeExamples(op *Operation, route RouteInfo) {
+	request, responses := recordedExample(route.Method, route.Path)
+	if request != nil && op.RequestBody != nil {
+		if mt, ok := op.RequestBody.Content["application/json"]; ok {
+			mt.Example = request
+		}
+	}
+	for status, res := range responses {
+		code := strconv.Itoa(status)
+		r, ok := op.Responses[code]
+		if !ok {
+			r = &Response{Description: http.StatusText(status), Content: resultContent(nil)}
+			op.Responses[code] = r
+		}
+		if mt, ok := r.Content["application/json"]; ok {
+			mt.Example = res
+		}
+	}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasSuffix(mediaType, "json")
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// exampleWriter keeps the response body up to the maximum size
+type exampleWriter struct {
+	gin.ResponseWriter
+	max      int
+	buf      []byte
+	overflow bool
+}
+
+func (w *exampleWriter) capture(b []byte) {
+	if w.overflow {
+		return
+	}
+	if len(w.buf)+len(b) > w.max {
+		w.overflow, w.buf = true, nil
+		return
+	}
+	w.buf = append(w.buf, b...)
+}
+
+func (w *exampleWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *exampleWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
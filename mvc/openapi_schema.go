@@ -0,0 +1,283 @@
+package mvc
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema the OpenAPI schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Example              json.RawMessage    `json:"example,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas of the struct types, they are referenced by #/components/schemas/{name}
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// schema of the type, the named structs are registered as components and referenced
+func (r *schemaRegistry) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer && t != fileHeaderType {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case fileHeaderType:
+		return &Schema{Type: "string", Format: "binary"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t, nil)
+		}
+		return &Schema{Ref: "#/components/schemas/" + r.register(t)}
+	}
+	return &Schema{}
+}
+
+// register the struct as the component, the name is qualified by the package when it is taken by another type
+func (r *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+	name := schemaName(t.Name())
+	if _, taken := r.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = schemaName(pkg[strings.LastIndex(pkg, "/")+1:] + "." + t.Name())
+	}
+	r.names[t] = name
+	r.schemas[name] = &Schema{} // placeholder of the recursive types
+	r.schemas[name] = r.structSchema(t, nil)
+	return name
+}
+
+// the generic type names such as Page[main.User] are not valid component names
+func schemaName(name string) string {
+	return strings.Map(func(c rune) rune {
+		if c == '[' || c == ']' || c == '*' || c == ',' || c == '/' || c == ' ' {
+			return '_'
+		}
+		return c
+	}, name)
+}
+
+// object schema of the struct, the skip decides the fields left out, such as the path parameters
+func (r *schemaRegistry) structSchema(t reflect.Type, skip func(f reflect.StructField) bool) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	r.addFields(s, t, skip)
+	return s
+}
+
+func (r *schemaRegistry) addFields(s *Schema, t reflect.Type, skip func(f reflect.StructField) bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if skip != nil && skip(f) {
+			continue
+		}
+		name, inline := jsonFieldName(f)
+		if name == "" && !inline {
+			continue
+		}
+		if inline {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			r.addFields(s, ft, skip)
+			continue
+		}
+		fs := r.schemaOf(f.Type)
+		if applyBindingTag(fs, f) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// the json name of the field, inline means the embedded struct whose fields are promoted
+func jsonFieldName(f reflect.StructField) (name string, inline bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if name == "" && f.Anonymous {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			return "", true
+		}
+	}
+	if !f.IsExported() {
+		return "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, false
+}
+
+// tagName the name of the field declared by the form, uri or header tag, empty when it is not declared
+func tagName(f reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+/*
+apply the validation rules of the binding tag to the schema, returns whether the field is required.
+
+	required -> required
+	min, max, gt, gte, lt, lte, len -> minimum and maximum of numbers, the lengths of strings and arrays
+	oneof -> enum
+	email, url, uri, uuid, ip, ipv4, ipv6, datetime -> format
+*/
+func applyBindingTag(s *Schema, f reflect.StructField) bool {
+	required := false
+	ft := f.Type
+	for ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+		// the rules after dive apply to the elements
+		if rule == "dive" {
+			break
+		}
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "gte", "gt":
+			setBound(s, ft, param, true, name == "gt")
+		case "max", "lte", "lt":
+			setBound(s, ft, param, false, name == "lt")
+		case "len":
+			setBound(s, ft, param, true, false)
+			setBound(s, ft, param, false, false)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(s.Type, v))
+			}
+		case "email":
+			s.Format = "email"
+		case "url", "uri":
+			s.Format = "uri"
+		case "uuid", "uuid4":
+			s.Format = "uuid"
+		case "ip", "ipv4", "ipv6":
+			s.Format = name
+		case "datetime":
+			s.Format = "date-time"
+		}
+	}
+	return required
+}
+
+func setBound(s *Schema, t reflect.Type, param string, lower, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		l := int(n)
+		if exclusive && lower {
+			l++
+		} else if exclusive {
+			l--
+		}
+		switch {
+		case t.Kind() == reflect.String && lower:
+			s.MinLength = &l
+		case t.Kind() == reflect.String:
+			s.MaxLength = &l
+		case lower:
+			s.MinItems = &l
+		default:
+			s.MaxItems = &l
+		}
+	default:
+		if lower {
+			s.Minimum, s.ExclusiveMinimum = &n, exclusive
+		} else {
+			s.Maximum, s.ExclusiveMaximum = &n, exclusive
+		}
+	}
+}
+
+func enumValue(typ, v string) any {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
@@ -0,0 +1,206 @@
+package mvc
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/validation"
+	"github.com/gin-gonic/gin"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+SortableAnnotation Declares the fields the api method can be sorted by, the other sort fields are rejected.
+The field can be mapped to the column, such as createdAt:created_at. The sort is rejected when it's not declared
+
+	// ListUser
+	// @GET(path="/user") list users
+	// @Sortable("name, createdAt:created_at")
+	func (u *UserController) ListUser(ctx *gin.Context, page mvc.Pageable) (*resp.Page[User], error) {}
+*/
+const SortableAnnotation = "Sortable"
+
+// PageConfig the pagination query parameters
+type PageConfig struct {
+	PageParam   string `mapstructure:"page_param"`   // Query parameter of the page number, default page
+	SizeParam   string `mapstructure:"size_param"`   // Query parameter of the page size, default size
+	SortParam   string `mapstructure:"sort_param"`   // Query parameter of the sort, default sort
+	DefaultSize int    `mapstructure:"default_size"` // Page size when it's absent, default 20
+	MaxSize     int    `mapstructure:"max_size"`     // Larger page sizes are limited to it, default 100
+}
+
+var pageConf = withPageDefaults(PageConfig{})
+
+// SetPageConfig Set the pagination configuration
+func SetPageConfig(conf PageConfig) {
+	pageConf = withPageDefaults(conf)
+}
+
+func withPageDefaults(conf PageConfig) PageConfig {
+	if conf.PageParam == "" {
+		conf.PageParam = "page"
+	}
+	if conf.SizeParam == "" {
+		conf.SizeParam = "size"
+	}
+	if conf.SortParam == "" {
+		conf.SortParam = "sort"
+	}
+	if conf.DefaultSize <= 0 {
+		conf.DefaultSize = 20
+	}
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = 100
+	}
+	return conf
+}
+
+// Order the sort of a field
+type Order struct {
+	Field  string // Field of the query parameter
+	Column string // Column of the field declared by @Sortable, default the field
+	Desc   bool
+}
+
+/*
+Pageable the page request bound from the query parameters, such as ?page=2&size=20&sort=-createdAt,name.
+The page number starts from 1, the field prefixed with - or suffixed with :desc is sorted descending.
+It's bound when it's declared as the parameter of the api method, or by BindPageable()
+*/
+type Pageable struct {
+	Page int
+	Size int
+	Sort []Order
+}
+
+var pageableType = reflect.TypeOf(Pageable{})
+
+// Offset the number of the rows skipped
+func (p Pageable) Offset() int {
+	return (p.Page - 1) * p.Size
+}
+
+// Limit the number of the rows of the page
+func (p Pageable) Limit() int {
+	return p.Size
+}
+
+// OrderBy the sql order by clause of the sort without the ORDER BY keyword, such as created_at DESC, name ASC.
+// Empty when it's not sorted
+func (p Pageable) OrderBy() string {
+	items := make([]string, 0, len(p.Sort))
+	for _, o := range p.Sort {
+		dir := "ASC"
+		if o.Desc {
+			dir = "DESC"
+		}
+		items = append(items, o.Column+" "+dir)
+	}
+	return strings.Join(items, ", ")
+}
+
+// the sortable fields of the routes keyed by the method and the path, the field maps to the column
+var sortableFields sync.Map
+
+// BindPageable Bind the page request from the query parameters, the page size is limited by the max size and the page
+// is limited so the offset can't overflow.
+// Returns *exception.ValidationException when the parameters are invalid or the sort field is not declared by @Sortable
+func BindPageable(ctx *gin.Context) (Pageable, error) {
+	p := Pageable{Page: 1, Size: pageConf.DefaultSize}
+	if v := ctx.Query(pageConf.PageParam); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return p, pageErr(pageConf.PageParam, validation.TypeRule, "int", fmt.Sprintf("%s 的类型必须为 int", pageConf.PageParam))
+		}
+		if n > 1 {
+			p.Page = min(n, math.MaxInt/pageConf.MaxSize)
+		}
+	}
+	if v := ctx.Query(pageConf.SizeParam); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return p, pageErr(pageConf.SizeParam, validation.TypeRule, "int", fmt.Sprintf("%s 的类型必须为 int", pageConf.SizeParam))
+		}
+		if n > 0 {
+			p.Size = min(n, pageConf.MaxSize)
+		}
+	}
+	fields := sortable(ctx)
+	for _, values := range ctx.QueryArray(pageConf.SortParam) {
+		for _, v := range strings.Split(values, ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			o := Order{}
+			if f, ok := strings.CutPrefix(v, "-"); ok {
+				v, o.Desc = f, true
+			} else if f, dir, ok := strings.Cut(v, ":"); ok {
+				v, o.Desc = f, strings.EqualFold(dir, "desc")
+			}
+			if len(fields) == 0 {
+				return p, pageErr(pageConf.SortParam, "oneof", "", fmt.Sprintf("%s 不支持排序", pageConf.SortParam))
+			}
+			column, ok := fields[v]
+			if !ok {
+				allowed := make([]string, 0, len(fields))
+				for f := range fields {
+					allowed = append(allowed, f)
+				}
+				sort.Strings(allowed)
+				param := strings.Join(allowed, ", ")
+				return p, pageErr(pageConf.SortParam, "oneof", param, fmt.Sprintf("%s 的取值必须为 [%s]", pageConf.SortParam, param))
+			}
+			o.Field, o.Column = v, column
+			p.Sort = append(p.Sort, o)
+		}
+	}
+	return p, nil
+}
+
+// the fields declared by @Sortable of the route, nil means the route can't be sorted
+func sortable(ctx *gin.Context) map[string]string {
+	route := ctx.FullPath()
+	key := annotationKey(ctx.Request.Method, route)
+	if v, ok := sortableFields.Load(key); ok {
+		return v.(map[string]string)
+	}
+	var fields map[string]string
+	if val, ok := GetAnnotation(ctx, SortableAnnotation); ok {
+		fields = make(map[string]string)
+		for _, item := range strings.Split(strings.Trim(ParseAnnotationArgs(val)["value"], `"`), ",") {
+			field, column, found := strings.Cut(strings.TrimSpace(item), ":")
+			if field == "" {
+				continue
+			}
+			if !found {
+				column = field
+			}
+			fields[field] = strings.TrimSpace(column)
+		}
+	}
+	if route != "" {
+		sortableFields.Store(key, fields)
+	}
+	return fields
+}
+
+func pageErr(field, rule, param, msg string) *exception.ValidationException {
+	return &exception.ValidationException{Msg: msg, Errors: []validation.FieldError{{Field: field, Rule: rule, Param: param, Message: msg}}}
+}
+
+func pageableBinder(isPtr bool) paramBinder {
+	return func(ctx *gin.Context) (reflect.Value, error) {
+		p, err := BindPageable(ctx)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if isPtr {
+			return reflect.ValueOf(&p), nil
+		}
+		return reflect.ValueOf(p), nil
+	}
+}
@@ -0,0 +1,109 @@
+package mvc
+
+import (
+	"github.com/gin-gonic/gin"
+	"path"
+	"strings"
+)
+
+/*
+PathPredicate Create the predicate matching the route template of the request by the ant-style patterns,
+the patterns prefixed with ! exclude the routes. Without the include patterns, all routes not excluded match.
+
+	?   matches one character in a segment
+	*   matches zero or more characters in a segment
+	**  matches zero or more segments
+
+The route template is matched rather than the raw url, such as /user/:id for /user/1,
+so the wildcard of the template is matched literally. The request not matching any route never matches.
+
+	func (a *AuthInterceptor) Predicate(ctx *gin.Context) bool {
+	    return a.predicate(ctx)
+	}
+
+	a.predicate = mvc.PathPredicate("/api/**", "!/api/public/**", "!/api/login")
+*/
+func PathPredicate(patterns ...string) func(ctx *gin.Context) bool {
+	match := PathMatcher(patterns...)
+	return func(ctx *gin.Context) bool {
+		return match(ctx.FullPath())
+	}
+}
+
+// PathMatcher Create the matcher of the route templates by the patterns of PathPredicate, the empty route never matches
+func PathMatcher(patterns ...string) func(route string) bool {
+	var includes, excludes []string
+	for _, p := range patterns {
+		if exclude, ok := strings.CutPrefix(p, "!"); ok {
+			excludes = append(excludes, exclude)
+		} else {
+			includes = append(includes, p)
+		}
+	}
+	return func(route string) bool {
+		if route == "" {
+			return false
+		}
+		for _, p := range excludes {
+			if MatchPath(p, route) {
+				return false
+			}
+		}
+		if len(includes) == 0 {
+			return true
+		}
+		for _, p := range includes {
+			if MatchPath(p, route) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchPath Whether the path matches the ant-style pattern, such as /api/**/user/*
+func MatchPath(pattern, p string) bool {
+	return matchSegments(splitPath(pattern), splitPath(p))
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func matchSegments(patterns, segs []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			// collapse the consecutive **, then try each remaining suffix of the segments
+			for len(patterns) > 0 && patterns[0] == "**" {
+				patterns = patterns[1:]
+			}
+			if len(patterns) == 0 {
+				return true
+			}
+			for i := range segs {
+				if matchSegments(patterns, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		// path.Match supports * and ?, the characters of the route template such as : are literal
+		if ok, err := path.Match(escapePattern(patterns[0]), segs[0]); err != nil || !ok {
+			return false
+		}
+		patterns, segs = patterns[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// escape the characters of path.Match other than * and ?, such as [ and \
+func escapePattern(p string) string {
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`).Replace(p)
+}
@@ -0,0 +1,91 @@
+package mvc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/archine/ast-base/core"
+	"net/http"
+	"strings"
+)
+
+/*
+RouteAnnotation Declares the methods and the path of the api method, multiple methods are separated by |,
+ANY means all the standard methods, the custom methods such as PROPFIND are also supported.
+
+	// Health
+	// @Route(GET|HEAD, "/health") health probe
+	func (h *HealthController) Health(ctx *gin.Context) {}
+
+	// Preflight
+	// @Route(method="OPTIONS", path="/user", name="user.preflight") customized preflight
+	func (u *UserController) Preflight(ctx *gin.Context) {}
+
+The method declared by @ANY, @HEAD, @OPTIONS or a method list such as GET|POST is also accepted.
+*/
+const RouteAnnotation = "Route"
+
+// AnyMethod mounts the api for all the standard methods
+const AnyMethod = "ANY"
+
+// standard methods mounted by ANY, the same as gin.Any
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect, http.MethodTrace,
+}
+
+// resolve the methods and the path of the api method, @Route takes precedence over the parsed method and path
+func routeMethods(m *core.MethodInfo) ([]string, string, error) {
+	method, path := m.Method, m.ApiPath
+	if val, ok := m.Annotations[RouteAnnotation]; ok {
+		var positional []string
+		for _, part := range splitAnnotationArgs(val) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, v, found := strings.Cut(part, "=")
+			if !found || strings.HasPrefix(strings.TrimSpace(name), `"`) {
+				positional = append(positional, strings.Trim(part, `"`))
+				continue
+			}
+			switch v = strings.Trim(strings.TrimSpace(v), `"`); strings.TrimSpace(name) {
+			case "method":
+				method = v
+			case "path":
+				path = v
+			case "name":
+				// resolved by routeName
+			default:
+				return nil, "", fmt.Errorf("unknown argument %s of @%s", name, RouteAnnotation)
+			}
+		}
+		if len(positional) > 0 {
+			method = positional[0]
+		}
+		if len(positional) > 1 {
+			path = positional[1]
+		}
+	}
+	if path == "" {
+		return nil, "", errors.New("the api path is empty")
+	}
+	var methods []string
+	seen := make(map[string]bool)
+	for _, name := range strings.FieldsFunc(method, func(r rune) bool { return r == '|' || r == ',' || r == ' ' }) {
+		name = strings.ToUpper(name)
+		expanded := []string{name}
+		if name == AnyMethod {
+			expanded = anyMethods
+		}
+		for _, e := range expanded {
+			if !seen[e] {
+				seen[e] = true
+				methods = append(methods, e)
+			}
+		}
+	}
+	if len(methods) == 0 {
+		return nil, "", errors.New("the api method is empty")
+	}
+	return methods, path, nil
+}
@@ -0,0 +1,92 @@
+package mvc
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo the route mounted by the mvc layer
+type RouteInfo struct {
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Name       string `json:"name,omitempty"` // Route name used by URLFor
+	Controller string `json:"controller"`
+	Handler    string `json:"handler"`
+}
+
+// Route table of the mounted apis, the key of the owners is the method and the normalized path
+var (
+	routeTable  []RouteInfo
+	routeOwners = make(map[string]RouteInfo)
+)
+
+// Routes Get the route table mounted by the mvc layer
+func Routes() []RouteInfo {
+	return routeTable
+}
+
+// RoutesHandler Respond the route table as json, usually exposed as a management endpoint
+func RoutesHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(200, routeTable)
+	}
+}
+
+/*
+mount the route by the gin router, fail fast when the method and path have been registered by another api.
+The path parameters are normalized, so /user/:id and /user/:uid conflict as they do in gin.
+*/
+func mountRoute(router reflect.Value, route RouteInfo, handlers []reflect.Value) {
+	key := route.Method + " " + normalizePath(route.Path)
+	if owner, ok := routeOwners[key]; ok {
+		logger.Log.Fatalf("route conflict, [%s %s] of %s.%s is already registered by %s.%s", route.Method, route.Path,
+			route.Controller, route.Handler, owner.Controller, owner.Handler)
+	}
+	defer func() {
+		// gin panics on the wildcard conflicts, such as /user/:id and /user/list
+		if r := recover(); r != nil {
+			logger.Log.Fatalf("route conflict, [%s %s] of %s.%s can't be registered, %v", route.Method, route.Path,
+				route.Controller, route.Handler, r)
+		}
+	}()
+	if route.Name != "" {
+		if err := nameRoute(route); err != nil {
+			logger.Log.Fatalf("route conflict, %s", err.Error())
+		}
+	}
+	args := append([]reflect.Value{reflect.ValueOf(route.Method), reflect.ValueOf(route.Path)}, handlers...)
+	router.MethodByName("Handle").Call(args)
+	routeOwners[key] = route
+	routeTable = append(routeTable, route)
+}
+
+func normalizePath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") {
+			segs[i] = ":"
+		} else if strings.HasPrefix(seg, "*") {
+			segs[i] = "*"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// print the route table in debug level
+func printRoutes() {
+	if len(routeTable) == 0 {
+		return
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "METHOD\tPATH\tNAME\tHANDLER")
+	for _, r := range routeTable {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s.%s\n", r.Method, r.Path, r.Name, r.Controller, r.Handler)
+	}
+	_ = w.Flush()
+	logger.Log.Debugf("Mounted %d routes:\n%s", len(routeTable), b.String())
+}
@@ -0,0 +1,206 @@
+package mvc
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"sync"
+)
+
+// the key of the request scoped beans created in the request
+const scopeKey = "mvc.scope"
+
+/*
+RequestDisposer the request scoped bean released when the request completes, such as committing or rolling back the
+transaction. err is the panic value, the error returned by the api method or the last error of the context,
+nil means the request completes normally
+*/
+type RequestDisposer interface {
+	Dispose(ctx *gin.Context, err any)
+}
+
+/*
+Scoped the provider of the request scoped bean, the bean is created on the first Get() of the request and disposed in
+reverse order of the creation when the request completes. The provider is set into the ioc container, so it's
+injected into the controllers like the other beans:
+
+	mvc.SetBeans(mvc.RequestScoped(func(ctx *gin.Context) (*Tx, error) {
+	    return db.BeginTx(ctx, nil)
+	}))
+
+	type OrderController struct {
+	    mvc.Controller
+	    Tx *mvc.Scoped[*Tx]
+	}
+
+	func (o *OrderController) Create(ctx *gin.Context, order *Order) error {
+	    tx, err := o.Tx.Get(ctx)
+	}
+
+The bean can also be declared as the parameter of the api method directly:
+
+	func (o *OrderController) Create(ctx *gin.Context, tx *Tx, order *Order) error {}
+*/
+type Scoped[T any] struct {
+	factory func(ctx *gin.Context) (T, error)
+	dispose func(ctx *gin.Context, bean T, err any)
+}
+
+// the scoped beans of the request
+type requestScope struct {
+	mu    sync.Mutex
+	beans map[any]any
+	order []func(ctx *gin.Context, err any)
+	err   error // Error returned by the api method
+}
+
+// the providers of the types declared as the api method parameters
+var scopedTypes = make(map[reflect.Type]func(ctx *gin.Context) (reflect.Value, error))
+
+// RequestScoped Create the provider of the request scoped bean, the bean implementing RequestDisposer is disposed
+// when the request completes. The provider should be created before the apis are applied, one provider for each type
+func RequestScoped[T any](factory func(ctx *gin.Context) (T, error)) *Scoped[T] {
+	s := &Scoped[T]{factory: factory}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if _, ok := scopedTypes[t]; ok {
+		logger.Log.Fatalf("request scoped %s is provided more than once", t)
+	}
+	scopedTypes[t] = func(ctx *gin.Context) (reflect.Value, error) {
+		bean, err := s.Get(ctx)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&bean).Elem(), nil
+	}
+	return s
+}
+
+// OnDispose Set the function releasing the bean when the request completes, it's used instead of RequestDisposer
+func (s *Scoped[T]) OnDispose(fn func(ctx *gin.Context, bean T, err any)) *Scoped[T] {
+	s.dispose = fn
+	return s
+}
+
+// Get the bean of the request, it's created by the factory on the first call
+func (s *Scoped[T]) Get(ctx *gin.Context) (T, error) {
+	scope := scopeOf(ctx)
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	if bean, ok := scope.beans[s]; ok {
+		return bean.(T), nil
+	}
+	bean, err := s.factory(ctx)
+	if err != nil {
+		var zero T
+		return zero, &scopeError{fmt.Errorf("create request scoped %T error, %w", bean, err)}
+	}
+	scope.beans[s] = bean
+	scope.order = append(scope.order, func(ctx *gin.Context, err any) {
+		if s.dispose != nil {
+			s.dispose(ctx, bean, err)
+		} else if d, ok := any(bean).(RequestDisposer); ok {
+			d.Dispose(ctx, err)
+		}
+	})
+	return bean, nil
+}
+
+// MustGet Get the bean of the request, panic when it can't be created
+func (s *Scoped[T]) MustGet(ctx *gin.Context) T {
+	bean, err := s.Get(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return bean
+}
+
+func scopeOf(ctx *gin.Context) *requestScope {
+	if v, ok := ctx.Get(scopeKey); ok {
+		return v.(*requestScope)
+	}
+	scope := &requestScope{beans: make(map[any]any)}
+	ctx.Set(scopeKey, scope)
+	return scope
+}
+
+// RequestScope Dispose the request scoped beans created in the request when it completes, even when it panics.
+// It's used by the application, the beans created outside it are not disposed
+func RequestScope() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			r := recover()
+			disposeScope(ctx, r)
+			if r != nil {
+				panic(r)
+			}
+		}()
+		ctx.Next()
+	}
+}
+
+// dispose the beans of the request in reverse order
+func disposeScope(ctx *gin.Context, r any) {
+	v, ok := ctx.Get(scopeKey)
+	if !ok {
+		return
+	}
+	scope := v.(*requestScope)
+	err := r
+	if err == nil && scope.err != nil {
+		err = scope.err
+	}
+	if err == nil {
+		if last := ctx.Errors.Last(); last != nil {
+			err = last.Err
+		}
+	}
+	for i := len(scope.order) - 1; i >= 0; i-- {
+		func() {
+			// the panic of one bean doesn't skip the others
+			defer func() {
+				if p := recover(); p != nil {
+					logger.Log.Errorf("Request scoped bean dispose panic, %v", p)
+				}
+			}()
+			scope.order[i](ctx, err)
+		}()
+	}
+}
+
+// record the error returned by the api method, so the beans are disposed as failed
+func failScope(ctx *gin.Context, err error) {
+	if v, ok := ctx.Get(scopeKey); ok {
+		v.(*requestScope).err = err
+	}
+}
+
+// the error creating the request scoped bean, it's responded as the server error instead of the binding failure
+type scopeError struct {
+	err error
+}
+
+func (e *scopeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *scopeError) Unwrap() error {
+	return e.err
+}
+
+// the binder of the request scoped bean, the bean is not created in mock mode
+func scopedBinder(pt reflect.Type) paramBinder {
+	get := scopedTypes[pt]
+	return func(ctx *gin.Context) (reflect.Value, error) {
+		if mockConf.Enabled {
+			return reflect.Zero(pt), nil
+		}
+		return get(ctx)
+	}
+}
+
+// whether the parameter of the api method is the request scoped bean
+func isScoped(pt reflect.Type) bool {
+	_, ok := scopedTypes[pt]
+	return ok
+}
@@ -0,0 +1,138 @@
+package mvc
+
+import (
+	"bytes"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"os"
+)
+
+// SpoolConfig the request body spooling, the bodies are read before the handlers,
+// the small ones are kept in memory and the large ones are written to the temp files
+type SpoolConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`   // Whether to spool the request bodies, default false
+	Threshold int64  `mapstructure:"threshold"` // Bodies larger than it are spooled to the temp files, default 1MB
+	MaxSize   int64  `mapstructure:"max_size"`  // Maximum body size, the larger ones are rejected with http status 413. Default 0 means no limit
+	Dir       string `mapstructure:"dir"`       // Directory of the temp files, default os.TempDir()
+}
+
+// SpooledBody the spooled request body, it replaces the body of the request and can be read again after Seek.
+// The temp file is removed when the request ends, so the body can't be read by the goroutines outliving the request
+type SpooledBody struct {
+	io.ReadSeeker
+	size int64
+	file *os.File
+}
+
+// Size of the body in bytes
+func (s *SpooledBody) Size() int64 {
+	return s.size
+}
+
+// OnDisk Whether the body is spooled to the temp file
+func (s *SpooledBody) OnDisk() bool {
+	return s.file != nil
+}
+
+// Close does nothing, the temp file is removed when the request ends
+func (s *SpooledBody) Close() error {
+	return nil
+}
+
+func (s *SpooledBody) remove() {
+	if s.file != nil {
+		_ = s.file.Close()
+		_ = os.Remove(s.file.Name())
+	}
+}
+
+var errBodyTooLarge = errors.New("request body too large")
+
+/*
+Spool The gin middleware reads the request body before the handlers, the bodies exceeding the threshold are
+written to the temp files instead of the memory, so the large uploads of the slow clients don't occupy the memory.
+The handlers still read ctx.Request.Body, which is *SpooledBody and can be rewound:
+
+	if body, ok := mvc.GetSpooledBody(ctx); ok {
+	    _, _ = body.Seek(0, io.SeekStart)
+	}
+*/
+func Spool(conf SpoolConfig) gin.HandlerFunc {
+	if conf.Threshold <= 0 {
+		conf.Threshold = 1 << 20
+	}
+	return func(ctx *gin.Context) {
+		r := ctx.Request
+		if r.Body == nil || r.Body == http.NoBody {
+			return
+		}
+		if conf.MaxSize > 0 && r.ContentLength > conf.MaxSize {
+			resp.InitResp(ctx).WithBasic(resp.BadRequestCode, "请求体过大", nil).To(http.StatusRequestEntityTooLarge)
+			ctx.Abort()
+			return
+		}
+		body, err := spoolBody(r.Body, conf)
+		if err != nil {
+			if errors.Is(err, errBodyTooLarge) {
+				resp.InitResp(ctx).WithBasic(resp.BadRequestCode, "请求体过大", nil).To(http.StatusRequestEntityTooLarge)
+			} else {
+				logger.Log.Warnf("Spool request body [%s %s] error, %s", r.Method, r.URL.Path, err.Error())
+				resp.DirectBadRequest(ctx, "读取请求体失败")
+			}
+			ctx.Abort()
+			return
+		}
+		defer body.remove()
+		r.Body = body
+		r.ContentLength = body.size
+		ctx.Next()
+	}
+}
+
+// GetSpooledBody Get the body spooled by Spool
+func GetSpooledBody(ctx *gin.Context) (*SpooledBody, bool) {
+	body, ok := ctx.Request.Body.(*SpooledBody)
+	return body, ok
+}
+
+// read the body into the memory, or the temp file when it exceeds the threshold
+func spoolBody(src io.Reader, conf SpoolConfig) (*SpooledBody, error) {
+	buf := make([]byte, 0, min(conf.Threshold, 64<<10)+1)
+	b := bytes.NewBuffer(buf)
+	n, err := io.Copy(b, io.LimitReader(src, conf.Threshold+1))
+	if err != nil {
+		return nil, err
+	}
+	if conf.MaxSize > 0 && n > conf.MaxSize {
+		return nil, errBodyTooLarge
+	}
+	if n <= conf.Threshold {
+		return &SpooledBody{ReadSeeker: bytes.NewReader(b.Bytes()), size: n}, nil
+	}
+	file, err := os.CreateTemp(conf.Dir, "spool-*")
+	if err != nil {
+		return nil, err
+	}
+	body := &SpooledBody{file: file}
+	rest := src
+	if conf.MaxSize > 0 {
+		rest = io.LimitReader(src, conf.MaxSize-n+1)
+	}
+	size, err := io.Copy(file, io.MultiReader(b, rest))
+	if err == nil && conf.MaxSize > 0 && size > conf.MaxSize {
+		err = errBodyTooLarge
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		body.remove()
+		return nil, err
+	}
+	body.ReadSeeker, body.size = file, size
+	return body, nil
+}
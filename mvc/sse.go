@@ -0,0 +1,156 @@
+package mvc
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	SSEHeartbeat = 15 * time.Second // Interval of the keep-alive comment frames of the event streams, 0 disables it
+
+	eventChanType = reflect.TypeOf((<-chan Event)(nil))
+)
+
+// Event the server-sent event
+type Event struct {
+	ID    string        // Event id, the client sends it back by the Last-Event-ID header when reconnecting
+	Event string        // Event type, empty means message
+	Data  any           // Event data, string and []byte are written as they are, others are encoded as json
+	Retry time.Duration // Reconnection delay of the client, 0 means unchanged
+}
+
+// EventStream the server-sent event stream, it is safe to send events concurrently
+type EventStream struct {
+	ctx *gin.Context
+	mu  sync.Mutex
+}
+
+/*
+SSE Start the event stream, the headers are written and flushed at once.
+The stream is finished when the handler returns, use ctx.Request.Context() to detect the client disconnection.
+
+	// @GET(path="/progress/:id")
+	func (t *TaskController) Progress(ctx *gin.Context, id int64) {
+	    stream := mvc.SSE(ctx)
+	    for p := range t.TaskService.Watch(ctx.Request.Context(), id) {
+	        if stream.Send(mvc.Event{Event: "progress", Data: p}) != nil {
+	            return
+	        }
+	    }
+	}
+*/
+func SSE(ctx *gin.Context) *EventStream {
+	h := ctx.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// disable the response buffering of nginx
+	h.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.WriteHeaderNow()
+	ctx.Writer.Flush()
+	return &EventStream{ctx: ctx}
+}
+
+// Send the event and flush it, an error is returned when the client has disconnected
+func (s *EventStream) Send(e Event) error {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	var data string
+	switch v := e.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		j, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(j)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Comment Send a comment frame, it is ignored by the client
+func (s *EventStream) Comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+func (s *EventStream) write(frame string) error {
+	if err := s.ctx.Request.Context().Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.ctx.Writer.WriteString(frame); err != nil {
+		return err
+	}
+	s.ctx.Writer.Flush()
+	return nil
+}
+
+// whether the api method returns the event channel streamed by StreamEvents
+func returnsEvents(mt reflect.Type) bool {
+	for i := 0; i < mt.NumOut(); i++ {
+		if mt.Out(i) == eventChanType {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+StreamEvents Stream the events of the channel until it is closed or the client disconnects,
+the keep-alive comment frames are sent when idle. The api method can also return the channel directly:
+
+	// @GET(path="/notifications")
+	func (n *NotifyController) Subscribe(ctx *gin.Context) (<-chan mvc.Event, error) {
+	    return n.NotifyService.Subscribe(ctx.Request.Context())
+	}
+
+The producer should stop sending when the request context is done.
+*/
+func StreamEvents(ctx *gin.Context, events <-chan Event) {
+	stream := SSE(ctx)
+	var heartbeat <-chan time.Time
+	if SSEHeartbeat > 0 {
+		ticker := time.NewTicker(SSEHeartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	done := ctx.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-events:
+			if !ok || stream.Send(e) != nil {
+				return
+			}
+		case <-heartbeat:
+			if stream.Comment("ping") != nil {
+				return
+			}
+		}
+	}
+}
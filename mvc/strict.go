@@ -0,0 +1,239 @@
+package mvc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+StrictAnnotation Declares the json body of the api method to be bound strictly, the unknown fields and the
+values of the wrong types are rejected with http status 400 listing each offending field.
+@Strict(false) opts the api method out of the global strict mode.
+
+	// CreateUser
+	// @POST(path="/user") create user
+	// @Strict
+	func (u *UserController) CreateUser(ctx *gin.Context) {}
+*/
+const StrictAnnotation = "Strict"
+
+// BindingConfig the request binding
+type BindingConfig struct {
+	StrictJSON bool `mapstructure:"strict_json"` // Whether to bind the json bodies of all apis strictly, default false
+}
+
+var bindingConf BindingConfig
+
+// the context key of the strict mode declared by the api method
+const strictKey = "gin-plus/strict"
+
+// SetBindingConfig Set the request binding configuration
+func SetBindingConfig(conf BindingConfig) {
+	bindingConf = conf
+}
+
+func strictHandler(val string) gin.HandlerFunc {
+	strict := strings.TrimSpace(strings.Trim(ParseAnnotationArgs(val)["value"], `"`)) != "false"
+	return func(ctx *gin.Context) {
+		ctx.Set(strictKey, strict)
+	}
+}
+
+// whether the json body of the request is bound strictly, the annotation takes precedence over the configuration
+func strictJSON(ctx *gin.Context) bool {
+	if strict, ok := ctx.Get(strictKey); ok {
+		return strict.(bool)
+	}
+	return bindingConf.StrictJSON
+}
+
+// bind the json body after checking the unknown fields and the types of all fields
+func bindStrictJSON(ctx *gin.Context, obj any) error {
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err = decoder.Decode(&v); err != nil {
+		return err
+	}
+	var errs []validation.FieldError
+	checkJSON("", v, reflect.TypeOf(obj), &errs)
+	if len(errs) > 0 {
+		return &exception.ValidationException{Msg: errs[0].Message, Errors: errs}
+	}
+	return binding.JSON.BindBody(data, obj)
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// check the decoded json value against the type, the errors of each field are appended to errs
+func checkJSON(path string, v any, t reflect.Type, errs *[]validation.FieldError) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if v == nil || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		if _, ok := v.(string); !ok {
+			typeMismatch(path, "string", errs)
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			typeMismatch(path, "object", errs)
+			return
+		}
+		fields := make(map[string]reflect.StructField)
+		collectJSONFields(t, fields)
+		for _, key := range sortedKeys(obj) {
+			val := obj[key]
+			f, ok := lookupJSONField(fields, key)
+			if !ok {
+				name := joinJSONPath(path, key)
+				*errs = append(*errs, validation.FieldError{Field: name, Rule: validation.UnknownRule, Message: fmt.Sprintf("不支持的字段 %s", name)})
+				continue
+			}
+			// the values of the ,string option are quoted
+			if _, opts, _ := strings.Cut(f.Tag.Get("json"), ","); strings.Contains(opts, "string") {
+				if _, ok := val.(string); !ok && val != nil {
+					typeMismatch(joinJSONPath(path, key), "string", errs)
+				}
+				continue
+			}
+			checkJSON(joinJSONPath(path, key), val, f.Type, errs)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			typeMismatch(path, "object", errs)
+			return
+		}
+		for _, key := range sortedKeys(obj) {
+			checkJSON(joinJSONPath(path, key), obj[key], t.Elem(), errs)
+		}
+	case reflect.Slice, reflect.Array:
+		if _, ok := v.(string); ok && t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		list, ok := v.([]any)
+		if !ok {
+			typeMismatch(path, "array", errs)
+			return
+		}
+		for i, val := range list {
+			checkJSON(path+"["+strconv.Itoa(i)+"]", val, t.Elem(), errs)
+		}
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			typeMismatch(path, "string", errs)
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			typeMismatch(path, "boolean", errs)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.(json.Number)
+		if !ok {
+			typeMismatch(path, "integer", errs)
+			return
+		}
+		if i, err := n.Int64(); err != nil || reflect.Zero(t).OverflowInt(i) {
+			typeMismatch(path, "integer", errs)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := v.(json.Number)
+		if !ok {
+			typeMismatch(path, "integer", errs)
+			return
+		}
+		if u, err := strconv.ParseUint(n.String(), 10, 64); err != nil || reflect.Zero(t).OverflowUint(u) {
+			typeMismatch(path, "integer", errs)
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(json.Number); !ok {
+			typeMismatch(path, "number", errs)
+		}
+	}
+}
+
+// collect the json fields of the struct, the fields of the embedded structs are promoted
+// unless the outer struct declares the same name
+func collectJSONFields(t reflect.Type, fields map[string]reflect.StructField) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, inline := jsonFieldName(f)
+		if inline {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			embedded = append(embedded, ft)
+			continue
+		}
+		if _, exist := fields[name]; name != "" && !exist {
+			fields[name] = f
+		}
+	}
+	for _, et := range embedded {
+		collectJSONFields(et, fields)
+	}
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// the keys are matched case-insensitively like encoding/json, the exact match is preferred
+func lookupJSONField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if f, ok := fields[key]; ok {
+		return f, true
+	}
+	for name, f := range fields {
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func typeMismatch(path, expected string, errs *[]validation.FieldError) {
+	name := path
+	if name == "" {
+		name = "请求体"
+	}
+	*errs = append(*errs, validation.FieldError{Field: path, Rule: validation.TypeRule, Param: expected, Message: fmt.Sprintf("%s 的类型必须为 %s", name, expected)})
+}
@@ -0,0 +1,262 @@
+package mvc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+TimeoutAnnotation Declares the timeout of the api method, it takes precedence over the configuration.
+
+	// Export
+	// @GET(path="/report/export") export the report
+	// @Timeout("60s")
+	func (r *ReportController) Export(ctx *gin.Context) {}
+*/
+const TimeoutAnnotation = "Timeout"
+
+// TimeoutConfig the request timeouts, the request context is canceled when the timeout elapses and
+// the timeout response is sent unless the handler has written the response. The event streams are only
+// bounded by @Timeout, and the websocket upgrades are never bounded
+type TimeoutConfig struct {
+	Default time.Duration  `mapstructure:"default"` // Timeout of all routes except the streaming ones, default 0 means no timeout
+	Status  int            `mapstructure:"status"`  // Http status of the timeout response, 503 or 504, default 503
+	Routes  []RouteTimeout `mapstructure:"routes"`  // Timeouts of the routes, the first matching one is used
+}
+
+// RouteTimeout the timeout of the routes
+type RouteTimeout struct {
+	Path    string        `mapstructure:"path"`    // Ant-style pattern of the route templates, such as /report/**
+	Timeout time.Duration `mapstructure:"timeout"` // Timeout, 0 means no timeout
+}
+
+var (
+	// timeouts declared by the annotations of the routes, keyed by the method and the path as the annotations
+	annotatedTimeouts = make(map[string]time.Duration)
+	// the websocket endpoints and the api methods returning the event channel, keyed as the annotated timeouts
+	streamingRoutes = make(map[string]bool)
+)
+
+/*
+Timeout The gin middleware bounds the duration of the handlers, the timeout is resolved from @Timeout,
+then the routes of the configuration, then the default timeout.
+
+The handlers run in another goroutine and the request context is canceled when the timeout elapses,
+the handlers should return soon by checking ctx.Request.Context(). The writes after the timeout response
+are discarded, so the response is never written twice. The middleware still waits for the handlers to return,
+so the gin context is not reused while the handlers are running.
+*/
+func Timeout(conf TimeoutConfig) gin.HandlerFunc {
+	if conf.Status != http.StatusGatewayTimeout {
+		conf.Status = http.StatusServiceUnavailable
+	}
+	return func(ctx *gin.Context) {
+		d := routeTimeout(ctx, conf)
+		if d <= 0 {
+			ctx.Next()
+			return
+		}
+		// the fallback reads the copy with the request not timed out, the handler is still running with the context
+		var fallbackCtx *gin.Context
+		fallback := routeFallback(ctx)
+		if fallback != nil {
+			fallbackCtx = ctx.Copy()
+		}
+		c, cancel := context.WithTimeout(ctx.Request.Context(), d)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(c)
+		traceId := ctx.GetString("trace_id")
+		w := &timeoutWriter{ResponseWriter: ctx.Writer, header: ctx.Writer.Header().Clone()}
+		ctx.Writer = w
+		done := make(chan any, 1)
+		go func() {
+			defer func() {
+				done <- recover()
+			}()
+			ctx.Next()
+		}()
+		var r any
+		select {
+		case r = <-done:
+		case <-c.Done():
+			data, degraded := callFallback(fallback, fallbackCtx, c.Err())
+			if w.timeout(conf.Status, traceId, data, degraded) {
+				logger.Log.Warnf("Request [%s %s] timeout after %s", ctx.Request.Method, ctx.Request.URL.Path, d)
+			}
+			r = <-done
+		}
+		w.finish()
+		ctx.Writer = w.ResponseWriter
+		if r != nil {
+			if w.timedOut {
+				logger.Log.Errorf("Request [%s %s] panic after timeout, %v", ctx.Request.Method, ctx.Request.URL.Path, r)
+				return
+			}
+			// the panic is handled by the global exception interceptor
+			panic(r)
+		}
+	}
+}
+
+func routeTimeout(ctx *gin.Context, conf TimeoutConfig) time.Duration {
+	route := ctx.FullPath()
+	// the hijacked connection is written bypassing the timeout writer
+	if route == "" || ctx.GetHeader("Upgrade") != "" {
+		return 0
+	}
+	if strings.Contains(ctx.GetHeader("Accept"), "text/event-stream") {
+		return annotatedTimeouts[annotationKey(ctx.Request.Method, route)]
+	}
+	return TimeoutOf(conf, ctx.Request.Method, route)
+}
+
+// TimeoutOf Get the timeout of the route mounted by Apply, resolved as the Timeout middleware does, 0 means no timeout
+func TimeoutOf(conf TimeoutConfig, method, path string) time.Duration {
+	key := annotationKey(method, path)
+	if d, ok := annotatedTimeouts[key]; ok {
+		return d
+	}
+	if streamingRoutes[key] {
+		return 0
+	}
+	for _, r := range conf.Routes {
+		if MatchPath(r.Path, path) {
+			return r.Timeout
+		}
+	}
+	return conf.Default
+}
+
+// parse the timeout of @Timeout, ok is false without the annotation
+func annotatedTimeout(annotations Annotations) (d time.Duration, ok bool, err error) {
+	val, ok := annotations[TimeoutAnnotation]
+	if !ok {
+		return 0, false, nil
+	}
+	v := strings.Trim(ParseAnnotationArgs(val)["value"], `"`)
+	if d, err = time.ParseDuration(v); err != nil {
+		return 0, true, fmt.Errorf("invalid timeout of @%s(%s), %s", TimeoutAnnotation, val, err.Error())
+	}
+	return d, true, nil
+}
+
+// timeoutWriter the writer of the handlers, the headers are buffered until the response is written,
+// so they can't be changed by the handlers while the timeout response is written
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// copy the buffered headers before the response is written
+func (w *timeoutWriter) flushHeader() {
+	if w.ResponseWriter.Written() {
+		return
+	}
+	dst := w.ResponseWriter.Header()
+	for k := range dst {
+		if _, ok := w.header[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range w.header {
+		dst[k] = v
+	}
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.flushHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.flushHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.flushHeader()
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.flushHeader()
+		w.ResponseWriter.Flush()
+	}
+}
+
+// write the timeout response unless the handler has written the response, returns whether it is written.
+// The degraded data of the fallback is responded instead when it's degraded
+func (w *timeoutWriter) timeout(status int, traceId string, data any, degraded bool) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	if w.ResponseWriter.Written() {
+		return false
+	}
+	result := &resp.Result{Code: resp.UnavailableCode, Message: "服务繁忙,请稍后再试", TraceId: traceId}
+	if status == http.StatusGatewayTimeout {
+		result.Code, result.Message = resp.TimeoutCode, "请求超时,请稍后再试"
+	}
+	h := w.ResponseWriter.Header()
+	if degraded {
+		status = http.StatusOK
+		result.Code, result.Message, result.Data = 0, "ok", data
+		h.Set(DegradedHeader, DegradedTimeout)
+	}
+	body, _ := json.Marshal(result)
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(status)
+	_, _ = w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+	return true
+}
+
+// copy the buffered headers of the handler completed in time without writing the body, such as ctx.Status(204)
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.flushHeader()
+	}
+}
@@ -0,0 +1,88 @@
+package mvc
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutOfStreams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log = &logger.DefaultLog{}
+	annotatedTimeouts = map[string]time.Duration{annotationKey(http.MethodGet, "/bounded"): 50 * time.Millisecond}
+	streamingRoutes = map[string]bool{annotationKey(http.MethodGet, "/events"): true}
+	defer func() {
+		annotatedTimeouts = make(map[string]time.Duration)
+		streamingRoutes = make(map[string]bool)
+	}()
+	e := gin.New()
+	e.Use(Timeout(TimeoutConfig{Default: 50 * time.Millisecond}))
+	stream := func(ctx *gin.Context) {
+		events := make(chan Event, 1)
+		go func() {
+			time.Sleep(150 * time.Millisecond)
+			events <- Event{Data: "done"}
+			close(events)
+		}()
+		StreamEvents(ctx, events)
+	}
+	e.GET("/events", stream)
+	e.GET("/stream", stream)
+	e.GET("/bounded", stream)
+	e.GET("/slow", func(ctx *gin.Context) {
+		<-ctx.Request.Context().Done()
+	})
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		want   string // the body contains
+	}{
+		{name: "route returning the events", path: "/events", want: "data: done"},
+		{name: "event stream accepted", path: "/stream", accept: "text/event-stream", want: "data: done"},
+		{name: "event stream with @Timeout", path: "/bounded", accept: "text/event-stream", want: ""},
+		{name: "ordinary route", path: "/slow", want: "服务繁忙"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+			if tt.want == "" && strings.Contains(w.Body.String(), "data: done") {
+				t.Errorf("body = %q, want the stream ended by the timeout", w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %q, want containing %q", w.Body.String(), tt.want)
+			}
+		})
+	}
+	if d := TimeoutOf(TimeoutConfig{Default: time.Second}, http.MethodGet, "/events"); d != 0 {
+		t.Errorf("TimeoutOf the streaming route = %s, want 0", d)
+	}
+}
+
+func TestTimeoutSkipsUpgrades(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(Timeout(TimeoutConfig{Default: time.Millisecond}))
+	e.GET("/ws", func(ctx *gin.Context) {
+		if _, ok := ctx.Writer.(*timeoutWriter); ok {
+			t.Error("the upgrade request is written by the timeout writer")
+		}
+		time.Sleep(20 * time.Millisecond)
+		if ctx.Request.Context().Err() != nil {
+			t.Error("the context of the upgrade request is canceled")
+		}
+	})
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+}
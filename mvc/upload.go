@@ -0,0 +1,144 @@
+package mvc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UploadOptions the restrictions of the uploaded file
+type UploadOptions struct {
+	MaxSize    int64    // Maximum file size in bytes, 0 means no limit
+	Extensions []string // Allowed extensions, such as .png, case-insensitive. Empty means all extensions
+	TempDir    string   // Directory of the temp file, default os.TempDir()
+}
+
+// UploadedFile the file streamed to the temp file, call Remove() after use
+type UploadedFile struct {
+	Filename    string // Original filename
+	ContentType string // Content type declared by the client
+	Size        int64  // File size in bytes
+	Path        string // Path of the temp file
+}
+
+// Open the temp file
+func (u *UploadedFile) Open() (*os.File, error) {
+	return os.Open(u.Path)
+}
+
+// MoveTo Move the temp file to the destination
+func (u *UploadedFile) MoveTo(dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Rename(u.Path, dst)
+}
+
+// Remove the temp file
+func (u *UploadedFile) Remove() error {
+	return os.Remove(u.Path)
+}
+
+/*
+Upload Stream the file of the multipart field to a temp file, without buffering the whole file in memory.
+The restrictions are checked while streaming, *exception.BusinessException is returned when they are violated.
+When the checksum is verified by VerifyChecksum, the file is verified by the checksum headers of its part,
+*exception.ChecksumException is returned on the mismatch.
+
+	file, err := mvc.Upload(ctx, "file", mvc.UploadOptions{MaxSize: 10 << 20, Extensions: []string{".png", ".jpg"}})
+	if err != nil {
+	    resp.DirectRespErr(ctx, err)
+	    return
+	}
+	defer file.Remove()
+*/
+func Upload(ctx *gin.Context, field string, opts UploadOptions) (*UploadedFile, error) {
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		return nil, exception.NewBusinessErr("请使用 multipart/form-data 上传文件")
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, exception.NewBusinessErr(fmt.Sprintf("未找到上传的文件[%s]", field))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() != field || part.FileName() == "" {
+			_ = part.Close()
+			continue
+		}
+		file, err := saveTemp(part, opts, checksumVerified(ctx))
+		_ = part.Close()
+		if err == nil {
+			if err = drainChecksum(ctx); err != nil {
+				_ = file.Remove()
+				return nil, err
+			}
+		}
+		return file, err
+	}
+}
+
+// verify: verify the file by the checksum headers of the part
+func saveTemp(part *multipart.Part, opts UploadOptions, verify bool) (*UploadedFile, error) {
+	filename := filepath.Base(part.FileName())
+	if !allowedExtension(filename, opts.Extensions) {
+		return nil, exception.NewBusinessErr(fmt.Sprintf("不支持的文件类型, 仅支持 %s", strings.Join(opts.Extensions, ", ")))
+	}
+	tmp, err := os.CreateTemp(opts.TempDir, "upload-*"+filepath.Ext(filename))
+	if err != nil {
+		return nil, err
+	}
+	var src io.Reader = part
+	var hashes *checksumHashes
+	if verify {
+		if src, hashes, err = partChecksum(part); err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+			return nil, exception.NewBusinessErr(fmt.Sprintf("文件[%s]的%s", filename, err.Error()))
+		}
+	}
+	if opts.MaxSize > 0 {
+		src = io.LimitReader(src, opts.MaxSize+1)
+	}
+	size, err := io.Copy(tmp, src)
+	_ = tmp.Close()
+	if err == nil && opts.MaxSize > 0 && size > opts.MaxSize {
+		err = exception.NewBusinessErr(fmt.Sprintf("文件大小不能超过 %d 字节", opts.MaxSize))
+	}
+	if err == nil && hashes != nil {
+		if ce := hashes.verify("文件[" + filename + "]"); ce != nil {
+			err = ce
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+	contentType := part.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &UploadedFile{Filename: filename, ContentType: contentType, Size: size, Path: tmp.Name()}, nil
+}
+
+func allowedExtension(filename string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := filepath.Ext(filename)
+	for _, e := range extensions {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
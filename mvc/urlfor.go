@@ -0,0 +1,103 @@
+package mvc
+
+import (
+	"fmt"
+	"github.com/archine/ast-base/core"
+	"net/url"
+	"strings"
+)
+
+/*
+NameAnnotation Declares the name of the api method, so its url can be built by URLFor.
+The name can also be declared by the name argument of @Route or the method annotation.
+Without the name, the api is named by the controller and the method, such as UserController.GetUser
+
+	// GetUser
+	// @GET(path="/user/:id") get user
+	// @Name("user.get")
+	func (u *UserController) GetUser(ctx *gin.Context) {}
+*/
+const NameAnnotation = "Name"
+
+// Named routes, the key is the route name
+var namedRoutes = make(map[string]RouteInfo)
+
+// name of the api method
+func routeName(controller string, m *core.MethodInfo) string {
+	if name := ParseAnnotationArgs(m.Annotations[NameAnnotation])["value"]; name != "" {
+		return name
+	}
+	for _, annotation := range []string{RouteAnnotation, m.Method} {
+		if name := ParseAnnotationArgs(m.Annotations[annotation])["name"]; name != "" {
+			return name
+		}
+	}
+	return controller + "." + m.Name
+}
+
+// register the route name, the name of another api fails fast
+func nameRoute(route RouteInfo) error {
+	if owner, ok := namedRoutes[route.Name]; ok && (owner.Controller != route.Controller || owner.Handler != route.Handler) {
+		return fmt.Errorf("route name %s is already used by %s.%s", route.Name, owner.Controller, owner.Handler)
+	}
+	namedRoutes[route.Name] = route
+	return nil
+}
+
+/*
+URLFor Build the url of the named route, the params are the name and value pairs,
+the path parameters are filled in and the rest are appended as the query.
+
+	// /v1/user/1?tab=profile
+	link, err := mvc.URLFor("user.get", "id", 1, "tab", "profile")
+*/
+func URLFor(name string, params ...any) (string, error) {
+	route, ok := namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("route %s is not found", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("params of route %s must be name and value pairs", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	var names []string
+	for i := 0; i < len(params); i += 2 {
+		k := fmt.Sprint(params[i])
+		if _, exist := values[k]; !exist {
+			names = append(names, k)
+		}
+		values[k] = fmt.Sprint(params[i+1])
+	}
+	segs := strings.Split(route.Path, "/")
+	for i, seg := range segs {
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+		v, exist := values[seg[1:]]
+		if !exist {
+			return "", fmt.Errorf("param %s of route %s is missing", seg[1:], name)
+		}
+		delete(values, seg[1:])
+		if seg[0] == ':' {
+			segs[i] = url.PathEscape(v)
+			continue
+		}
+		// the catch-all parameter keeps its slashes
+		parts := strings.Split(strings.TrimPrefix(v, "/"), "/")
+		for j, p := range parts {
+			parts[j] = url.PathEscape(p)
+		}
+		segs[i] = strings.Join(parts, "/")
+	}
+	path := strings.Join(segs, "/")
+	query := url.Values{}
+	for _, k := range names {
+		if v, exist := values[k]; exist {
+			query.Set(k, v)
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
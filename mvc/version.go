@@ -0,0 +1,60 @@
+package mvc
+
+import (
+	"github.com/archine/ast-base/core"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VersionAnnotation Declares the api version of the method, it takes precedence over the controller version.
+//
+//	// GetUser
+//	// @GET(path="/user") get user
+//	// @Version("v2")
+//	func (u *UserController) GetUser(ctx *gin.Context) {}
+const VersionAnnotation = "Version"
+
+// VersionedController Declares the api version of the controller, the routes are prefixed with the version,
+// such as: v1 -> /v1/user. Multiple versions of the same api can coexist in different controllers.
+type VersionedController interface {
+	// Version the api version, such as v1
+	Version() string
+}
+
+// DeprecatedController Declares the api version of the controller to be deprecated,
+// the responses carry the Deprecation and Sunset headers.
+type DeprecatedController interface {
+	VersionedController
+
+	// Sunset the date when the version will be removed, format 2006-01-02. Empty means unknown.
+	Sunset() string
+}
+
+// the versioned api path and the deprecation middleware
+func versionRoute(controller abstractController, m *core.MethodInfo) (string, gin.HandlerFunc) {
+	version := ParseAnnotationArgs(m.Annotations[VersionAnnotation])["value"]
+	if vc, ok := controller.(VersionedController); ok && version == "" {
+		version = vc.Version()
+	}
+	version = strings.Trim(version, "/")
+	if version == "" {
+		return m.ApiPath, nil
+	}
+	apiPath := "/" + version + "/" + strings.TrimPrefix(m.ApiPath, "/")
+	dc, ok := controller.(DeprecatedController)
+	if !ok {
+		return apiPath, nil
+	}
+	sunset := dc.Sunset()
+	if t, err := time.Parse(time.DateOnly, sunset); err == nil {
+		sunset = t.Format(http.TimeFormat)
+	}
+	return apiPath, func(ctx *gin.Context) {
+		ctx.Header("Deprecation", "true")
+		if sunset != "" {
+			ctx.Header("Sunset", sunset)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package mvc
+
+import (
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocketConfig the websocket upgrader configuration
+type WebSocketConfig struct {
+	ReadBufferSize    int           `mapstructure:"read_buffer_size"`   // Read buffer size, default 4096
+	WriteBufferSize   int           `mapstructure:"write_buffer_size"`  // Write buffer size, default 4096
+	HandshakeTimeout  time.Duration `mapstructure:"handshake_timeout"`  // Handshake timeout, default 10s
+	MaxMessageSize    int64         `mapstructure:"max_message_size"`   // Maximum size of the received message, default 1M
+	PingInterval      time.Duration `mapstructure:"ping_interval"`      // Interval of the ping, the connection is closed when no pong is received within twice of it. Default 0 means no ping
+	EnableCompression bool          `mapstructure:"enable_compression"` // Negotiate the per message compression, default false
+	AllowedOrigins    []string      `mapstructure:"allowed_origins"`    // Allowed origins, * means any. Default empty means the same origin only
+}
+
+var (
+	wsConf     WebSocketConfig
+	wsUpgrader *websocket.Upgrader
+)
+
+// SetWebSocketConfig Set the websocket configuration, must be called before Apply
+func SetWebSocketConfig(conf WebSocketConfig) {
+	if conf.ReadBufferSize == 0 {
+		conf.ReadBufferSize = 4096
+	}
+	if conf.WriteBufferSize == 0 {
+		conf.WriteBufferSize = 4096
+	}
+	if conf.HandshakeTimeout == 0 {
+		conf.HandshakeTimeout = 10 * time.Second
+	}
+	if conf.MaxMessageSize == 0 {
+		conf.MaxMessageSize = 1 << 20
+	}
+	wsConf = conf
+	wsUpgrader = &websocket.Upgrader{
+		ReadBufferSize:    conf.ReadBufferSize,
+		WriteBufferSize:   conf.WriteBufferSize,
+		HandshakeTimeout:  conf.HandshakeTimeout,
+		EnableCompression: conf.EnableCompression,
+	}
+	if len(conf.AllowedOrigins) > 0 {
+		wsUpgrader.CheckOrigin = checkOrigin(conf.AllowedOrigins)
+	}
+}
+
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		for _, o := range allowed {
+			if o == "*" || strings.EqualFold(o, origin) || strings.EqualFold(o, u.Host) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WebSocketHandler the lifecycle hooks of the websocket connection
+type WebSocketHandler interface {
+	// OnConnect triggered after the connection is upgraded, return an error to close the connection
+	OnConnect(conn *WsConn) error
+
+	// OnMessage triggered when a text or binary message is received, the messages are handled in order
+	OnMessage(conn *WsConn, messageType int, data []byte)
+
+	// OnClose triggered after the connection is closed, the err is nil when it is closed normally
+	OnClose(conn *WsConn, err error)
+}
+
+/*
+WebSocketController Declares the websocket endpoints of the controller, the key is the path.
+The middlewares of the controller also apply to the upgrade requests.
+
+	func (c *ChatController) WebSockets() map[string]mvc.WebSocketHandler {
+	    return map[string]mvc.WebSocketHandler{"/ws/chat": c.ChatHandler}
+	}
+*/
+type WebSocketController interface {
+	WebSockets() map[string]WebSocketHandler
+}
+
+// WsConn the websocket connection, it is safe to send messages concurrently
+type WsConn struct {
+	*websocket.Conn
+	ctx *gin.Context
+	mu  sync.Mutex
+}
+
+// Context of the upgrade request, the path params and the keys set by the middlewares are available.
+// Don't use it to respond, the connection has been hijacked
+func (w *WsConn) Context() *gin.Context {
+	return w.ctx
+}
+
+// Send a message, messageType is websocket.TextMessage or websocket.BinaryMessage
+func (w *WsConn) Send(messageType int, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.WriteMessage(messageType, data)
+}
+
+// SendText Send a text message
+func (w *WsConn) SendText(text string) error {
+	return w.Send(websocket.TextMessage, []byte(text))
+}
+
+// SendJSON Send the value as a json text message
+func (w *WsConn) SendJSON(v any) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.WriteJSON(v)
+}
+
+// CloseWith Send the close message with the code and reason, then close the connection
+func (w *WsConn) CloseWith(code int, reason string) error {
+	w.mu.Lock()
+	_ = w.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	w.mu.Unlock()
+	return w.Close()
+}
+
+// wsHandler the upgrade handler of the websocket endpoint
+func wsHandler(h WebSocketHandler) gin.HandlerFunc {
+	if wsUpgrader == nil {
+		SetWebSocketConfig(WebSocketConfig{})
+	}
+	return func(ctx *gin.Context) {
+		c, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			// the upgrader has responded the error
+			ctx.Abort()
+			return
+		}
+		conn := &WsConn{Conn: c, ctx: ctx}
+		defer conn.Close()
+		if err = h.OnConnect(conn); err != nil {
+			_ = conn.CloseWith(websocket.ClosePolicyViolation, err.Error())
+			h.OnClose(conn, err)
+			return
+		}
+		c.SetReadLimit(wsConf.MaxMessageSize)
+		if wsConf.PingInterval > 0 {
+			wait := 2 * wsConf.PingInterval
+			_ = c.SetReadDeadline(time.Now().Add(wait))
+			c.SetPongHandler(func(string) error {
+				return c.SetReadDeadline(time.Now().Add(wait))
+			})
+			stop := make(chan struct{})
+			defer close(stop)
+			go conn.keepAlive(stop)
+		}
+		h.OnClose(conn, conn.readLoop(h))
+	}
+}
+
+// read the messages until the connection is closed
+func (w *WsConn) readLoop(h WebSocketHandler) error {
+	for {
+		messageType, data, err := w.ReadMessage()
+		if err != nil {
+			var ce *websocket.CloseError
+			if errors.As(err, &ce) && (ce.Code == websocket.CloseNormalClosure || ce.Code == websocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+		h.OnMessage(w, messageType, data)
+	}
+}
+
+// ping the peer periodically until stopped
+func (w *WsConn) keepAlive(stop <-chan struct{}) {
+	ticker := time.NewTicker(wsConf.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			err := w.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsConf.PingInterval))
+			w.mu.Unlock()
+			if err != nil {
+				logger.Log.Debugf("websocket ping error, %s", err.Error())
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/plugin/ratelimit"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+/*
+APIKeyAnnotation Declares the api method requires the valid api key, usually the machine-to-machine endpoints
+
+	// SyncOrders
+	// @POST(path="/orders/sync") sync orders
+	// @APIKey
+	func (o *OrderController) SyncOrders(ctx *gin.Context) {
+	    key, _ := auth.CurrentKey(ctx)
+	}
+*/
+const (
+	APIKeyAnnotation = "APIKey"
+	APIKeyKey        = "gin-plus/api_key" // Key of the api key identity in the gin context
+)
+
+// APIKey the identity of the api key, it's also set to the context as the principal whose subject is apikey:{id}
+type APIKey struct {
+	ID          string          `mapstructure:"id"`          // Key id, logged and used by the rate limit instead of the key
+	Name        string          `mapstructure:"name"`        // Display name, such as the partner name
+	Owner       string          `mapstructure:"owner"`       // Owner of the key, such as the tenant
+	Roles       []string        `mapstructure:"roles"`       // Roles granted
+	Permissions []string        `mapstructure:"permissions"` // Permissions granted
+	Limit       ratelimit.Limit `mapstructure:"limit"`       // Rate limit of the key, default the limit of the configuration
+}
+
+// StaticKey the api key of the configuration
+type StaticKey struct {
+	Key    string `mapstructure:"key"` // The key, or the sha256 hex of it prefixed with sha256:, so the configuration has no plain key
+	APIKey `mapstructure:",squash"`
+}
+
+// APIKeyConfig the api key authentication
+type APIKeyConfig struct {
+	Enabled bool            `mapstructure:"enabled"` // Whether to authenticate the requests of the paths by the api key, default false. @APIKey works regardless
+	Paths   []string        `mapstructure:"paths"`   // Ant-style patterns of the route templates authenticated, default all routes
+	Header  string          `mapstructure:"header"`  // Header of the key, default X-Api-Key
+	Query   string          `mapstructure:"query"`   // Query parameter of the key, empty means not accepted
+	Limit   ratelimit.Limit `mapstructure:"limit"`   // Default rate limit of each key, unlimited by default
+	Keys    []StaticKey     `mapstructure:"keys"`    // Static keys, used when no resolver is set
+}
+
+// KeyResolver finds the identity of the api key, such as from the database. Return nil without error when the key is unknown
+type KeyResolver interface {
+	Resolve(ctx context.Context, key string) (*APIKey, error)
+}
+
+// KeyResolverFunc the function as the KeyResolver
+type KeyResolverFunc func(ctx context.Context, key string) (*APIKey, error)
+
+func (f KeyResolverFunc) Resolve(ctx context.Context, key string) (*APIKey, error) {
+	return f(ctx, key)
+}
+
+// Keys the resolver of the api keys, default the static keys of the configuration
+var Keys KeyResolver
+
+// configuration of @APIKey
+var apiKeyConf = withAPIKeyDefaults(APIKeyConfig{})
+
+func init() {
+	mvc.RegisterOrderedAnnotationHandler(APIKeyAnnotation, mvc.AuthenticationOrder, func(string) gin.HandlerFunc {
+		conf := apiKeyConf
+		conf.Paths = nil
+		return APIKeyMiddleware(conf)
+	})
+}
+
+// SetAPIKeyConfig Set the configuration of @APIKey, the static keys are the resolver when none is set
+func SetAPIKeyConfig(conf APIKeyConfig) {
+	apiKeyConf = withAPIKeyDefaults(conf)
+	if Keys == nil && len(conf.Keys) > 0 {
+		Keys = NewStaticKeys(conf.Keys...)
+	}
+}
+
+func withAPIKeyDefaults(conf APIKeyConfig) APIKeyConfig {
+	if conf.Header == "" {
+		conf.Header = "X-Api-Key"
+	}
+	return conf
+}
+
+// StaticKeys resolves the keys by the sha256 of them
+type StaticKeys map[string]*APIKey
+
+// NewStaticKeys Create the resolver of the keys
+func NewStaticKeys(keys ...StaticKey) StaticKeys {
+	s := make(StaticKeys, len(keys))
+	for i := range keys {
+		k := keys[i]
+		sum, hashed := strings.CutPrefix(k.Key, "sha256:")
+		if !hashed {
+			sum = hashKey(k.Key)
+		}
+		s[strings.ToLower(sum)] = &k.APIKey
+	}
+	return s
+}
+
+func (s StaticKeys) Resolve(_ context.Context, key string) (*APIKey, error) {
+	return s[hashKey(key)], nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CurrentKey Get the api key identity of the current request
+func CurrentKey(ctx *gin.Context) (*APIKey, bool) {
+	v, ok := ctx.Get(APIKeyKey)
+	if !ok {
+		return nil, false
+	}
+	k, ok := v.(*APIKey)
+	return k, ok
+}
+
+/*
+APIKeyMiddleware The gin middleware authenticates the requests of the paths by the api key of the header or the query parameter,
+the requests of each key are limited by its limit. The identity is set to the context and can be retrieved by CurrentKey(),
+it's also the principal, so @RequireRole and @RequirePermission work with the api keys too.
+*/
+func APIKeyMiddleware(conf APIKeyConfig) gin.HandlerFunc {
+	conf = withAPIKeyDefaults(conf)
+	var paths func(ctx *gin.Context) bool
+	if len(conf.Paths) > 0 {
+		paths = mvc.PathPredicate(conf.Paths...)
+	}
+	return func(ctx *gin.Context) {
+		if _, ok := CurrentKey(ctx); ok || ctx.FullPath() == "" || (paths != nil && !paths(ctx)) {
+			ctx.Next()
+			return
+		}
+		if _, anonymous := mvc.GetAnnotation(ctx, AnonymousAnnotation); anonymous {
+			ctx.Next()
+			return
+		}
+		key := ctx.GetHeader(conf.Header)
+		if key == "" && conf.Query != "" {
+			key = ctx.Query(conf.Query)
+		}
+		if key == "" {
+			resp.NoLogin(ctx, true, "缺少 API Key")
+			ctx.Abort()
+			return
+		}
+		if Keys == nil {
+			logger.Log.Error("auth: no api key resolver is set")
+			resp.NoLogin(ctx, true, "API Key 无效")
+			ctx.Abort()
+			return
+		}
+		identity, err := Keys.Resolve(ctx.Request.Context(), key)
+		if err != nil {
+			logger.Log.Errorf("auth: resolve api key error, %s", err.Error())
+			resp.ServiceUnavailable(ctx, 0)
+			ctx.Abort()
+			return
+		}
+		if identity == nil {
+			resp.NoLogin(ctx, true, "API Key 无效")
+			ctx.Abort()
+			return
+		}
+		limit := identity.Limit
+		if limit.Unlimited() {
+			limit = conf.Limit
+		}
+		if !limit.Unlimited() {
+			if allowed, retryAfter := ratelimit.Store.Take("apikey:"+identity.ID, limit); !allowed {
+				resp.TooManyRequests(ctx, retryAfter)
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Set(APIKeyKey, identity)
+		ctx.Set(PrincipalKey, &Principal{Subject: "apikey:" + identity.ID, Name: identity.Name, Tenant: identity.Owner,
+			Roles: identity.Roles, Permissions: identity.Permissions})
+		ctx.Next()
+	}
+}
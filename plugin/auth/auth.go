@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"errors"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/jwt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+/*
+AuthAnnotation Declares the api method requires the valid token, AnonymousAnnotation declares the api method
+skips the authentication when all routes are authenticated by the configuration.
+
+	// GetProfile
+	// @GET(path="/profile") get profile
+	// @Auth
+	func (u *UserController) GetProfile(ctx *gin.Context) {
+	    principal := auth.MustPrincipal(ctx)
+	}
+
+	// Login
+	// @POST(path="/login") login
+	// @Anonymous
+	func (u *UserController) Login(ctx *gin.Context) {}
+*/
+const (
+	AuthAnnotation      = "Auth"
+	AnonymousAnnotation = "Anonymous"
+	PrincipalKey        = "gin-plus/principal" // Key of the principal in the gin context
+)
+
+// Algorithms the supported algorithms
+var Algorithms = []string{jwt.HS256, jwt.HS384, jwt.HS512, jwt.RS256, jwt.RS384, jwt.RS512, jwt.ES256, jwt.ES384, jwt.ES512}
+
+// ClaimsMapping the claims mapped into the principal, nested claims are separated by dot, such as realm_access.roles
+type ClaimsMapping struct {
+	Subject     string `mapstructure:"subject"`     // Default sub
+	Name        string `mapstructure:"name"`        // Default name
+	Tenant      string `mapstructure:"tenant"`      // Default tenant
+	Roles       string `mapstructure:"roles"`       // Array or space-separated string, default roles
+	Permissions string `mapstructure:"permissions"` // Array or space-separated string, default permissions, such as scope
+}
+
+// Config the jwt authentication
+type Config struct {
+	Enabled       bool          `mapstructure:"enabled"`         // Whether to authenticate the requests of the paths except the @Anonymous ones, default false. @Auth works regardless
+	Paths         []string      `mapstructure:"paths"`           // Ant-style patterns of the route templates authenticated, default all routes
+	Header        string        `mapstructure:"header"`          // Header of the token, default Authorization
+	Scheme        string        `mapstructure:"scheme"`          // Scheme before the token in the header, default Bearer, empty means none
+	Query         string        `mapstructure:"query"`           // Query parameter of the token, such as access_token for the websockets, empty means not accepted
+	Secret        string        `mapstructure:"secret"`          // Secret of HS256, HS384 and HS512
+	PublicKeyFile string        `mapstructure:"public_key_file"` // Pem file of the RSA or EC public key or the certificate
+	JWKSURL       string        `mapstructure:"jwks_url"`        // Json web key set url of the authorization server
+	JWKSRefresh   time.Duration `mapstructure:"jwks_refresh"`    // Refetch interval of the key set, default 10m
+	Algorithms    []string      `mapstructure:"algorithms"`      // Accepted algorithms, default all supported ones
+	Issuer        string        `mapstructure:"issuer"`          // Required iss claim, empty means not checked
+	Audience      string        `mapstructure:"audience"`        // Required aud claim, empty means not checked
+	Claims        ClaimsMapping `mapstructure:"claims"`          // Claims mapped into the principal
+	APIKey        APIKeyConfig  `mapstructure:"api_key"`         // Api key authentication of the machine-to-machine endpoints
+}
+
+var (
+	// Default the authenticator created from the auth configuration when the application starts
+	Default *Authenticator
+
+	ErrNoToken  = errors.New("auth: no token")
+	ErrIssuer   = errors.New("auth: invalid issuer")
+	ErrAudience = errors.New("auth: invalid audience")
+)
+
+func init() {
+	mvc.RegisterOrderedAnnotationHandler(AuthAnnotation, mvc.AuthenticationOrder, authHandler)
+}
+
+// HasKeys Whether any verification key is configured
+func (c Config) HasKeys() bool {
+	return c.Secret != "" || c.PublicKeyFile != "" || c.JWKSURL != ""
+}
+
+// Authenticator verifies the tokens and maps the claims into the principals
+type Authenticator struct {
+	conf Config
+	keys *keySet
+}
+
+// New Create the authenticator
+func New(conf Config) (*Authenticator, error) {
+	if conf.Header == "" {
+		conf.Header = "Authorization"
+	}
+	if conf.JWKSRefresh <= 0 {
+		conf.JWKSRefresh = 10 * time.Minute
+	}
+	if len(conf.Algorithms) == 0 {
+		conf.Algorithms = Algorithms
+	}
+	c := &conf.Claims
+	for _, m := range []struct {
+		field *string
+		claim string
+	}{{&c.Subject, "sub"}, {&c.Name, "name"}, {&c.Tenant, "tenant"}, {&c.Roles, "roles"}, {&c.Permissions, "permissions"}} {
+		if *m.field == "" {
+			*m.field = m.claim
+		}
+	}
+	keys, err := newKeySet(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Authenticator{conf: conf, keys: keys}, nil
+}
+
+// Token Extract the token of the request from the header or the query parameter
+func (a *Authenticator) Token(ctx *gin.Context) string {
+	if v := ctx.GetHeader(a.conf.Header); v != "" {
+		if a.conf.Scheme == "" {
+			return v
+		}
+		if scheme, token, ok := strings.Cut(v, " "); ok && strings.EqualFold(scheme, a.conf.Scheme) {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if a.conf.Query != "" {
+		return ctx.Query(a.conf.Query)
+	}
+	return ""
+}
+
+// Authenticate Verify the token and map its claims into the principal
+func (a *Authenticator) Authenticate(token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	claims, err := jwt.Parse(token, func(kid, alg string) (*jwt.Key, error) {
+		for _, accepted := range a.conf.Algorithms {
+			if accepted == alg {
+				return a.keys.find(kid, alg)
+			}
+		}
+		return nil, jwt.ErrUnsupportedAlg
+	})
+	if err != nil {
+		return nil, err
+	}
+	if a.conf.Issuer != "" && claims.String("iss") != a.conf.Issuer {
+		return nil, ErrIssuer
+	}
+	if a.conf.Audience != "" && !contains(stringsClaim(claims, "aud"), a.conf.Audience) {
+		return nil, ErrAudience
+	}
+	m := a.conf.Claims
+	subject, _ := claim(claims, m.Subject).(string)
+	name, _ := claim(claims, m.Name).(string)
+	tenant, _ := claim(claims, m.Tenant).(string)
+	return &Principal{
+		Subject:     subject,
+		Name:        name,
+		Tenant:      tenant,
+		Roles:       stringsClaim(claims, m.Roles),
+		Permissions: stringsClaim(claims, m.Permissions),
+		Claims:      claims,
+		Token:       token,
+	}, nil
+}
+
+// authenticate the request, the failure is responded
+func (a *Authenticator) authenticate(ctx *gin.Context) bool {
+	if _, ok := Current(ctx); ok {
+		return true
+	}
+	principal, err := a.Authenticate(a.Token(ctx))
+	switch {
+	case errors.Is(err, ErrNoToken):
+		resp.NoLogin(ctx, true)
+	case errors.Is(err, jwt.ErrExpired):
+		resp.LoginExpired(ctx, true)
+	case err != nil:
+		logger.Log.Debugf("auth: invalid token, %s", err.Error())
+		resp.LoginExpired(ctx, true, "Token无效")
+	default:
+		ctx.Set(PrincipalKey, principal)
+		return true
+	}
+	ctx.Abort()
+	return false
+}
+
+/*
+Middleware The gin middleware authenticates the requests of the configured paths, the @Anonymous api methods and
+the unmatched routes are skipped. The principal is set to the context and can be retrieved by Current()
+*/
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	var paths func(ctx *gin.Context) bool
+	if len(a.conf.Paths) > 0 {
+		paths = mvc.PathPredicate(a.conf.Paths...)
+	}
+	return func(ctx *gin.Context) {
+		if ctx.FullPath() == "" || (paths != nil && !paths(ctx)) {
+			ctx.Next()
+			return
+		}
+		if _, anonymous := mvc.GetAnnotation(ctx, AnonymousAnnotation); anonymous {
+			ctx.Next()
+			return
+		}
+		if a.authenticate(ctx) {
+			ctx.Next()
+		}
+	}
+}
+
+func authHandler(string) gin.HandlerFunc {
+	if Default == nil {
+		logger.Log.Fatalf("@%s requires the auth configuration", AuthAnnotation)
+	}
+	return func(ctx *gin.Context) {
+		if Default.authenticate(ctx) {
+			ctx.Next()
+		}
+	}
+}
+
+// the claim of the path, nested claims are separated by dot
+func claim(claims jwt.Claims, path string) any {
+	var v any = map[string]any(claims)
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+// the array or space-separated string claim
+func stringsClaim(claims jwt.Claims, path string) []string {
+	switch v := claim(claims, path).(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
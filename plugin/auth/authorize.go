@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+/*
+RequireRoleAnnotation Declares the roles required by the api method, RequirePermissionAnnotation declares the permissions.
+Any one of the values is enough, match="all" requires all of them. The request is authenticated first when not yet.
+
+	// DeleteOrder
+	// @DELETE(path="/order/:id") delete order
+	// @RequireRole("admin", "ops")
+	// @RequirePermission("orders:write", "orders:delete", match="all")
+	func (o *OrderController) DeleteOrder(ctx *gin.Context) {}
+*/
+const (
+	RequireRoleAnnotation       = "RequireRole"
+	RequirePermissionAnnotation = "RequirePermission"
+)
+
+// PermissionEvaluator decides whether the principal is granted the permission, such as looking up the policies in the database
+type PermissionEvaluator interface {
+	HasPermission(ctx *gin.Context, principal *Principal, permission string) (bool, error)
+}
+
+// PermissionEvaluatorFunc the function as the PermissionEvaluator
+type PermissionEvaluatorFunc func(ctx *gin.Context, principal *Principal, permission string) (bool, error)
+
+func (f PermissionEvaluatorFunc) HasPermission(ctx *gin.Context, principal *Principal, permission string) (bool, error) {
+	return f(ctx, principal, permission)
+}
+
+// Evaluator the evaluator of @RequirePermission, default the permissions of the principal
+var Evaluator PermissionEvaluator = PermissionEvaluatorFunc(func(_ *gin.Context, principal *Principal, permission string) (bool, error) {
+	return principal.HasPermission(permission), nil
+})
+
+func init() {
+	mvc.RegisterOrderedAnnotationHandler(RequireRoleAnnotation, mvc.AuthorizationOrder, func(val string) gin.HandlerFunc {
+		values, all := parseRequirement(RequireRoleAnnotation, val)
+		return authorize(values, all, func(ctx *gin.Context, p *Principal, role string) (bool, error) {
+			return p.HasRole(role), nil
+		})
+	})
+	mvc.RegisterOrderedAnnotationHandler(RequirePermissionAnnotation, mvc.AuthorizationOrder, func(val string) gin.HandlerFunc {
+		values, all := parseRequirement(RequirePermissionAnnotation, val)
+		return authorize(values, all, func(ctx *gin.Context, p *Principal, permission string) (bool, error) {
+			return Evaluator.HasPermission(ctx, p, permission)
+		})
+	})
+}
+
+// RequireRole The gin middleware requires any one of the roles, usable by the route groups and @Use
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return authorize(roles, false, func(ctx *gin.Context, p *Principal, role string) (bool, error) {
+		return p.HasRole(role), nil
+	})
+}
+
+// RequirePermission The gin middleware requires any one of the permissions, evaluated by the Evaluator
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return authorize(permissions, false, func(ctx *gin.Context, p *Principal, permission string) (bool, error) {
+		return Evaluator.HasPermission(ctx, p, permission)
+	})
+}
+
+// the values and whether all of them are required
+func parseRequirement(annotation, val string) ([]string, bool) {
+	var values []string
+	all := false
+	for _, part := range strings.Split(val, ",") {
+		part = strings.TrimSpace(part)
+		if name, v, found := strings.Cut(part, "="); found && !strings.HasPrefix(name, `"`) {
+			all = strings.TrimSpace(name) == "match" && strings.Trim(strings.TrimSpace(v), `"`) == "all"
+			continue
+		}
+		if part = strings.Trim(part, `"`); part != "" {
+			values = append(values, part)
+		}
+	}
+	if len(values) == 0 {
+		logger.Log.Fatalf("@%s(%s) declares nothing", annotation, val)
+	}
+	return values, all
+}
+
+func authorize(values []string, all bool, granted func(ctx *gin.Context, p *Principal, value string) (bool, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := Current(ctx); !ok {
+			if Default == nil {
+				resp.NoLogin(ctx, true)
+				ctx.Abort()
+				return
+			}
+			if !Default.authenticate(ctx) {
+				return
+			}
+		}
+		p, _ := Current(ctx)
+		passed := all
+		for _, v := range values {
+			ok, err := granted(ctx, p, v)
+			if err != nil {
+				resp.DirectRespErr(ctx, err)
+				ctx.Abort()
+				return
+			}
+			if ok != all {
+				passed = ok
+				break
+			}
+		}
+		if !passed {
+			Deny(ctx)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// Deny Respond the forbidden exception with http status 403 and abort the request
+func Deny(ctx *gin.Context, msg ...string) {
+	message := "权限不足"
+	if len(msg) > 0 {
+		message = msg[0]
+	}
+	resp.StatusFailed(ctx, exception.NewStatusErr(http.StatusForbidden, resp.ForbiddenCode, message))
+	ctx.Abort()
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/httpclient"
+	"github.com/archine/gin-plus/v3/plugin/jwt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the verification keys of the configuration
+type keySet struct {
+	secret []byte
+	rsaKey *rsa.PublicKey
+	ecKey  *ecdsa.PublicKey
+	jwks   *jwksCache
+}
+
+func newKeySet(conf Config) (*keySet, error) {
+	ks := &keySet{secret: []byte(conf.Secret)}
+	if conf.PublicKeyFile != "" {
+		b, err := os.ReadFile(conf.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if ks.rsaKey, ks.ecKey, err = parsePublicKey(b); err != nil {
+			return nil, fmt.Errorf("auth: parse the public key file %s error, %s", conf.PublicKeyFile, err.Error())
+		}
+	}
+	if conf.JWKSURL != "" {
+		ks.jwks = &jwksCache{url: conf.JWKSURL, refresh: conf.JWKSRefresh}
+	}
+	if len(ks.secret) == 0 && ks.rsaKey == nil && ks.ecKey == nil && ks.jwks == nil {
+		return nil, fmt.Errorf("auth: one of the secret, public_key_file and jwks_url is required")
+	}
+	return ks, nil
+}
+
+// the RSA or EC public key of the pem block, the certificate is accepted too
+func parsePublicKey(b []byte) (*rsa.PublicKey, *ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, nil, fmt.Errorf("no pem block")
+	}
+	var pub any
+	var err error
+	switch block.Type {
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			pub = cert.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return k, nil, nil
+	case *ecdsa.PublicKey:
+		return nil, k, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported public key %T", pub)
+}
+
+// find the verification key by the kid and alg of the token header
+func (ks *keySet) find(kid, alg string) (*jwt.Key, error) {
+	switch {
+	case strings.HasPrefix(alg, "HS") && len(ks.secret) > 0:
+		return &jwt.Key{Alg: alg, Secret: ks.secret}, nil
+	case strings.HasPrefix(alg, "RS") && ks.rsaKey != nil:
+		return &jwt.Key{Alg: alg, PublicKey: ks.rsaKey}, nil
+	case strings.HasPrefix(alg, "ES") && ks.ecKey != nil:
+		return &jwt.Key{Alg: alg, ECPublicKey: ks.ecKey}, nil
+	}
+	if ks.jwks != nil && !strings.HasPrefix(alg, "HS") {
+		return ks.jwks.find(kid, alg)
+	}
+	return nil, jwt.ErrUnknownKey
+}
+
+// jwksCache the keys fetched from the jwks url, refetched when expired or the kid is unknown.
+// The keys are fetched without holding the lock, the cached keys are used while they're refreshed
+type jwksCache struct {
+	url       string
+	refresh   time.Duration
+	mu        sync.RWMutex
+	keys      []jwksKey
+	fetchedAt time.Time
+	fetching  chan struct{} // closed when the fetch in flight completes, nil without it
+}
+
+type jwksKey struct {
+	key *jwt.Key
+	alg string // the alg of the jwk, empty means all algorithms of the key type
+}
+
+func (j *jwksCache) find(kid, alg string) (*jwt.Key, error) {
+	j.mu.RLock()
+	key := j.match(kid, alg)
+	elapsed, inflight := time.Since(j.fetchedAt), j.fetching
+	j.mu.RUnlock()
+	if key != nil {
+		if elapsed > j.refresh {
+			j.refetch()
+		}
+		return key, nil
+	}
+	// the key may be rotated, refetch at most once every 10 seconds, or wait for the fetch in flight
+	switch {
+	case inflight != nil:
+		<-inflight
+	case elapsed > 10*time.Second || elapsed > j.refresh:
+		<-j.refetch()
+	default:
+		return nil, jwt.ErrUnknownKey
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if key = j.match(kid, alg); key != nil {
+		return key, nil
+	}
+	return nil, jwt.ErrUnknownKey
+}
+
+// refetch the keys in the background unless they're being fetched, the returned channel is closed when it completes
+func (j *jwksCache) refetch() <-chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.fetching != nil {
+		return j.fetching
+	}
+	done := make(chan struct{})
+	j.fetching, j.fetchedAt = done, time.Now()
+	go func() {
+		keys, ok := j.fetch()
+		j.mu.Lock()
+		if ok {
+			j.keys = keys
+		}
+		j.fetching = nil
+		j.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+func (j *jwksCache) match(kid, alg string) *jwt.Key {
+	for _, k := range j.keys {
+		if kid != "" && k.key.ID != kid {
+			continue
+		}
+		if k.alg == alg || (k.alg == "" && k.key.Alg[:2] == alg[:2]) {
+			key := *k.key
+			key.Alg = alg
+			return &key
+		}
+	}
+	return nil
+}
+
+// fetch the keys, ok is false when failed, so the previous keys are kept
+func (j *jwksCache) fetch() (keys []jwksKey, ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		logger.Log.Errorf("auth: fetch jwks error, %s", err.Error())
+		return nil, false
+	}
+	res, err := httpclient.Default.Do(req)
+	if err != nil {
+		logger.Log.Errorf("auth: fetch jwks error, %s", err.Error())
+		return nil, false
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		logger.Log.Errorf("auth: fetch jwks error, responded %d", res.StatusCode)
+		return nil, false
+	}
+	var set jwt.JWKS
+	if err = json.NewDecoder(res.Body).Decode(&set); err != nil {
+		logger.Log.Errorf("auth: fetch jwks error, %s", err.Error())
+		return nil, false
+	}
+	keys = make([]jwksKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.Key(); err == nil {
+			keys = append(keys, jwksKey{key: key, alg: jwk.Alg})
+		}
+	}
+	return keys, true
+}
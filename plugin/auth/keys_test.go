@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/jwt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWKSCacheFetchesOutsideTheLock(t *testing.T) {
+	logger.Log = &logger.DefaultLog{}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, _ := (&jwt.Key{ID: "k1", Alg: jwt.RS256, PrivateKey: rsaKey}).PublicJWK()
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			// the refetch stalls until the test releases it
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(jwt.JWKS{Keys: []jwt.JWK{jwk}})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	j := &jwksCache{url: server.URL, refresh: time.Hour}
+	if _, err = j.find("k1", jwt.RS256); err != nil {
+		t.Fatalf("find the key of the first fetch error, %v", err)
+	}
+	// the unknown kid refetches the keys once, the known one is found meanwhile
+	j.fetchedAt = time.Now().Add(-time.Minute)
+	unknown := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := j.find("rotated", jwt.RS256)
+			unknown <- err
+		}()
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := j.find("k1", jwt.RS256)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("find the cached key error, %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("find the cached key is blocked by the refetch")
+	}
+	release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if err = <-unknown; !errors.Is(err, jwt.ErrUnknownKey) {
+			t.Errorf("find the unknown kid error = %v, want %v", err, jwt.ErrUnknownKey)
+		}
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2, the concurrent refetches share one", n)
+	}
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/plugin/jwt"
+	"github.com/archine/gin-plus/v3/plugin/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	// the tenant limits use the tenant of the principal by default
+	ratelimit.PrincipalTenant = func(ctx *gin.Context) string {
+		if p, ok := Current(ctx); ok {
+			return p.Tenant
+		}
+		return ""
+	}
+	// the api key rules limit the authenticated keys by the id
+	ratelimit.AuthenticatedKey = func(ctx *gin.Context) string {
+		if key, ok := CurrentKey(ctx); ok {
+			return key.ID
+		}
+		return ""
+	}
+}
+
+// Principal the authenticated identity of the request
+type Principal struct {
+	Subject     string     // The subject, usually the user id
+	Name        string     // Display name
+	Tenant      string     // Tenant of the subject
+	Roles       []string   // Roles granted
+	Permissions []string   // Permissions granted
+	Claims      jwt.Claims // All claims of the token
+	Token       string     // The raw token, for calling the downstream services on behalf of the subject
+}
+
+// HasRole Whether the role is granted
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasPermission Whether the permission is granted
+func (p *Principal) HasPermission(permission string) bool {
+	return contains(p.Permissions, permission)
+}
+
+// Current Get the principal of the current request
+func Current(ctx *gin.Context) (*Principal, bool) {
+	v, ok := ctx.Get(PrincipalKey)
+	if !ok {
+		return nil, false
+	}
+	p, ok := v.(*Principal)
+	return p, ok
+}
+
+// MustPrincipal Get the principal of the current request, panic with the unauthorized exception when not authenticated
+func MustPrincipal(ctx *gin.Context) *Principal {
+	p, ok := Current(ctx)
+	if !ok {
+		panic(exception.Unauthorized("当前未登录"))
+	}
+	return p
+}
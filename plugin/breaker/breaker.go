@@ -0,0 +1,287 @@
+package breaker
+
+import (
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/plugin/metrics"
+	"sync"
+	"time"
+)
+
+// ErrOpen Returned when the circuit is open and the call is rejected,
+// the routes declaring @Fallback are degraded when their handlers fail by it
+var ErrOpen error = openError{}
+
+type openError struct{}
+
+func (openError) Error() string {
+	return "breaker: the circuit is open"
+}
+
+func (openError) DegradeReason() string {
+	return mvc.DegradedCircuitOpen
+}
+
+// State of the circuit
+type State int
+
+const (
+	Closed   State = iota // The calls are allowed and recorded
+	Open                  // The calls are rejected until the open timeout elapses
+	HalfOpen              // The probe calls are allowed, the circuit closes when all of them succeed
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Config the circuit breaker
+type Config struct {
+	FailureRate      float64       `mapstructure:"failure_rate"`       // Failure rate in the window to open the circuit, default 0.5
+	MinRequests      int           `mapstructure:"min_requests"`       // Minimum calls in the window before the failure rate is evaluated, default 20
+	Window           time.Duration `mapstructure:"window"`             // Rolling window of the calls, default 10s
+	OpenTimeout      time.Duration `mapstructure:"open_timeout"`       // Duration of the open circuit before the probes are allowed, default 30s
+	HalfOpenRequests int           `mapstructure:"half_open_requests"` // Probe calls allowed in the half-open state, default 1
+}
+
+func (c Config) withDefaults() Config {
+	if c.FailureRate <= 0 || c.FailureRate > 1 {
+		c.FailureRate = 0.5
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 1
+	}
+	return c
+}
+
+// buckets of the rolling window
+const windowBuckets = 10
+
+type bucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// Breaker the circuit breaker, the circuit opens when the failure rate of the rolling window reaches the threshold
+type Breaker struct {
+	name     string
+	conf     Config
+	mu       sync.Mutex
+	state    State
+	buckets  [windowBuckets]bucket
+	openedAt time.Time
+	probes   int  // probe calls in flight of the half-open state
+	passed   int  // succeeded probe calls of the half-open state
+	tripped  bool // the circuit is kept open until Reset()
+}
+
+// New Create the circuit breaker, the name is the label of the metrics
+func New(name string, conf Config) *Breaker {
+	initMetrics()
+	b := &Breaker{name: name, conf: conf.withDefaults()}
+	stateGauge.Set(float64(Closed), name)
+	return b
+}
+
+// Name of the breaker
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State of the circuit
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(time.Now())
+	return b.state
+}
+
+// RetryAfter the duration before the open circuit allows the probes, 0 when the circuit is not open
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != Open {
+		return 0
+	}
+	if b.tripped {
+		return b.conf.OpenTimeout
+	}
+	return max(b.conf.OpenTimeout-time.Since(b.openedAt), 0)
+}
+
+// Trip Open the circuit until Reset(), the probes are not allowed, such as the dependency is down by the health check
+func (b *Breaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tripped = true
+	if b.state != Open {
+		b.open(time.Now())
+	}
+}
+
+// Reset Close the circuit and clear the recorded calls
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tripped = false
+	b.buckets = [windowBuckets]bucket{}
+	if b.state != Closed {
+		b.setState(Closed)
+		logger.Log.Infof("Circuit [%s] is closed", b.name)
+	}
+}
+
+/*
+Allow the call, ErrOpen is returned when the circuit is open. Otherwise done must be called with the result of the call.
+
+	done, err := b.Allow()
+	if err != nil {
+	    return fallback()
+	}
+	err = call()
+	done(err == nil)
+*/
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(time.Now())
+	switch b.state {
+	case Open:
+		callCounter.Inc(b.name, "rejected")
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes >= b.conf.HalfOpenRequests {
+			callCounter.Inc(b.name, "rejected")
+			return nil, ErrOpen
+		}
+		b.probes++
+		return b.probeDone(), nil
+	}
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() { b.record(success) })
+	}, nil
+}
+
+// Do Call the function when the circuit allows, the error of the function is recorded as the failure
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	success := false
+	defer func() {
+		done(success)
+	}()
+	err = fn()
+	success = err == nil
+	return err
+}
+
+// the open circuit turns half-open after the open timeout
+func (b *Breaker) refresh(now time.Time) {
+	if b.state == Open && !b.tripped && now.Sub(b.openedAt) >= b.conf.OpenTimeout {
+		b.setState(HalfOpen)
+		b.probes, b.passed = 0, 0
+	}
+}
+
+func (b *Breaker) probeDone() func(success bool) {
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.state != HalfOpen {
+				return
+			}
+			if !success {
+				callCounter.Inc(b.name, "failure")
+				b.open(time.Now())
+				return
+			}
+			callCounter.Inc(b.name, "success")
+			if b.passed++; b.passed >= b.conf.HalfOpenRequests {
+				b.buckets = [windowBuckets]bucket{}
+				b.setState(Closed)
+				logger.Log.Infof("Circuit [%s] is closed", b.name)
+			}
+		})
+	}
+}
+
+func (b *Breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if success {
+		callCounter.Inc(b.name, "success")
+	} else {
+		callCounter.Inc(b.name, "failure")
+	}
+	// the calls finishing after the circuit opened are not counted
+	if b.state != Closed {
+		return
+	}
+	width := b.conf.Window / windowBuckets
+	start := now.Truncate(width)
+	cur := &b.buckets[int(start.UnixNano()/int64(width))%windowBuckets]
+	if !cur.start.Equal(start) {
+		*cur = bucket{start: start}
+	}
+	if success {
+		cur.successes++
+		return
+	}
+	cur.failures++
+	var total, failures int
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.conf.Window {
+			total += bk.successes + bk.failures
+			failures += bk.failures
+		}
+	}
+	if total >= b.conf.MinRequests && float64(failures)/float64(total) >= b.conf.FailureRate {
+		b.open(now)
+	}
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.openedAt = now
+	b.setState(Open)
+	logger.Log.Warnf("Circuit [%s] is open", b.name)
+}
+
+func (b *Breaker) setState(s State) {
+	b.state = s
+	stateGauge.Set(float64(s), b.name)
+}
+
+var (
+	metricsOnce sync.Once
+	stateGauge  *metrics.Gauge
+	callCounter *metrics.Counter
+)
+
+func initMetrics() {
+	metricsOnce.Do(func() {
+		stateGauge = metrics.NewGauge("circuit_breaker_state", "State of the circuit, 0 closed, 1 open, 2 half-open.", "name")
+		callCounter = metrics.NewCounter("circuit_breaker_calls_total", "Number of the calls through the circuit by result.", "name", "result")
+	})
+}
@@ -0,0 +1,79 @@
+package breaker
+
+import (
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+/*
+CircuitBreakerAnnotation Declares the api method to be broken by the circuit breaker of the name,
+default the method and the route of the request, such as "POST /order". The fallback is registered by RegisterFallback,
+default respond with http status 503.
+
+	// CreateOrder
+	// @POST(path="/order") create order
+	// @CircuitBreaker(name="order", fallback="orderBusy")
+	func (o *OrderController) CreateOrder(ctx *gin.Context) {}
+*/
+const CircuitBreakerAnnotation = "CircuitBreaker"
+
+// Fallbacks of the open circuits
+var fallbacks = make(map[string]gin.HandlerFunc)
+
+func init() {
+	mvc.RegisterAnnotationHandler(CircuitBreakerAnnotation, circuitBreakerHandler)
+}
+
+// RegisterFallback Register the named fallback, which can be declared by @CircuitBreaker(fallback="name")
+func RegisterFallback(name string, fallback gin.HandlerFunc) {
+	fallbacks[name] = fallback
+}
+
+func circuitBreakerHandler(val string) gin.HandlerFunc {
+	args := mvc.ParseAnnotationArgs(val)
+	name := args["name"]
+	if name == "" {
+		name = args["value"]
+	}
+	var fallback gin.HandlerFunc
+	if fn := strings.TrimSpace(args["fallback"]); fn != "" {
+		var exist bool
+		if fallback, exist = fallbacks[fn]; !exist {
+			logger.Log.Fatalf("fallback [%s] declared by @%s is not registered", fn, CircuitBreakerAnnotation)
+		}
+	}
+	if name != "" {
+		return Middleware(Get(name), fallback)
+	}
+	return func(ctx *gin.Context) {
+		Middleware(Get(ctx.Request.Method+" "+ctx.FullPath()), fallback)(ctx)
+	}
+}
+
+// Middleware The gin middleware breaks the requests by the breaker, the panics and the responses with
+// http status 5xx are recorded as the failures. When the circuit is open, the fallback responds the request,
+// nil means the fallback declared by @Fallback, otherwise respond with http status 503 and the Retry-After header
+func Middleware(b *Breaker, fallback gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		done, err := b.Allow()
+		if err != nil {
+			if fallback != nil {
+				fallback(ctx)
+			} else if !mvc.Degrade(ctx, mvc.DegradedCircuitOpen, err) {
+				resp.ServiceUnavailable(ctx, b.RetryAfter())
+			}
+			ctx.Abort()
+			return
+		}
+		success := false
+		defer func() {
+			done(success)
+		}()
+		ctx.Next()
+		success = ctx.Writer.Status() < http.StatusInternalServerError
+	}
+}
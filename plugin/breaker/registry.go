@@ -0,0 +1,64 @@
+package breaker
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Settings the circuit breakers of the application
+type Settings struct {
+	Default    Config            `mapstructure:"default"`     // Config of the breakers without specific configuration
+	Breakers   map[string]Config `mapstructure:"breakers"`    // Config of each breaker, the key is the breaker name
+	HttpClient bool              `mapstructure:"http_client"` // Whether to break the calls of httpclient.Default by the host, default false
+}
+
+var (
+	registryMu sync.Mutex
+	settings   Settings
+	registry   = make(map[string]*Breaker)
+)
+
+// Configure Set the configuration of the breakers, the created breakers are not changed
+func Configure(s Settings) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	settings = s
+}
+
+// Get the breaker of the name, it is created from the configuration of the name or the default configuration
+func Get(name string) *Breaker {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if b, ok := registry[name]; ok {
+		return b
+	}
+	conf, ok := settings.Breakers[name]
+	if !ok {
+		conf = settings.Default
+	}
+	b := New(name, conf)
+	registry[name] = b
+	return b
+}
+
+// Transport Wrap the round tripper, the calls are broken by the host of the request, such as user-service:4006.
+// The errors and the responses with http status 5xx are recorded as the failures
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := Get(req.URL.Host)
+	done, err := b.Allow()
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", err, req.URL.Host)
+	}
+	res, err := t.next.RoundTrip(req)
+	done(err == nil && res.StatusCode < http.StatusInternalServerError)
+	return res, err
+}
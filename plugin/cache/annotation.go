@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cache annotations of the api method, the key supports placeholders of path and query parameters
+//
+//	// GetUser
+//	// @GET(path="/user/:id") get user
+//	// @Cacheable(key="user:{id}", ttl="60s")
+//	func (u *UserController) GetUser(ctx *gin.Context) {}
+//
+//	// UpdateUser
+//	// @PUT(path="/user/:id") update user
+//	// @CacheEvict(key="user:{id};users")
+//	func (u *UserController) UpdateUser(ctx *gin.Context) {}
+//
+// @CacheEvict deletes the keys separated by semicolons. The annotations of the api methods cache the http responses,
+// the annotations of the bean methods cache their results when they're called through the fields, see Bind()
+const (
+	CacheableAnnotation  = "Cacheable"
+	CacheEvictAnnotation = "CacheEvict"
+)
+
+func init() {
+	mvc.RegisterOrderedAnnotationHandler(CacheableAnnotation, mvc.CacheOrder, cacheableHandler)
+	mvc.RegisterOrderedAnnotationHandler(CacheEvictAnnotation, mvc.CacheOrder, cacheEvictHandler)
+}
+
+// cached api response
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// responseRecorder records the response body while writing it to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+func cacheableHandler(val string) gin.HandlerFunc {
+	args := mvc.ParseAnnotationArgs(val)
+	key := args["key"]
+	if key == "" {
+		key = args["value"]
+	}
+	var ttl time.Duration
+	if args["ttl"] != "" {
+		var err error
+		if ttl, err = time.ParseDuration(args["ttl"]); err != nil {
+			logger.Log.Fatalf("invalid ttl of @%s(%s), %s", CacheableAnnotation, val, err.Error())
+		}
+	}
+	return func(ctx *gin.Context) {
+		k := resolveKey(ctx, key)
+		if b, ok := Store.Get(k); ok {
+			var cached cachedResponse
+			if json.Unmarshal(b, &cached) == nil {
+				ctx.Data(http.StatusOK, cached.ContentType, cached.Body)
+				ctx.Abort()
+				return
+			}
+		}
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+		ctx.Writer = recorder.ResponseWriter
+		if !succeeded(ctx) {
+			return
+		}
+		b, err := json.Marshal(&cachedResponse{ContentType: recorder.Header().Get("Content-Type"), Body: recorder.body.Bytes()})
+		if err == nil {
+			Store.Set(k, b, ttl)
+		}
+	}
+}
+
+func cacheEvictHandler(val string) gin.HandlerFunc {
+	args := mvc.ParseAnnotationArgs(val)
+	key := args["key"]
+	if key == "" {
+		key = args["value"]
+	}
+	keys := strings.Split(key, ";")
+	return func(ctx *gin.Context) {
+		ctx.Next()
+		if succeeded(ctx) {
+			resolved := make([]string, len(keys))
+			for i, k := range keys {
+				resolved[i] = resolveKey(ctx, strings.TrimSpace(k))
+			}
+			Store.Delete(resolved...)
+		}
+	}
+}
+
+// the request is handled successfully, both the http status and the business code are normal
+func succeeded(ctx *gin.Context) bool {
+	return ctx.Writer.Status() == http.StatusOK && ctx.GetInt("bcode") == 0 && len(ctx.Errors) == 0
+}
+
+// replace the placeholders of the key with path or query parameters, such as: user:{id}
+// empty key means the request uri is used as the key
+func resolveKey(ctx *gin.Context, key string) string {
+	if key == "" {
+		return ctx.Request.URL.RequestURI()
+	}
+	if !strings.Contains(key, "{") {
+		return key
+	}
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(key, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(key[start:], '}')
+		if end < 0 {
+			break
+		}
+		name := key[start+1 : start+end]
+		v, ok := ctx.Params.Get(name)
+		if !ok {
+			v = ctx.Query(name)
+		}
+		sb.WriteString(key[:start])
+		sb.WriteString(v)
+		key = key[start+end+1:]
+	}
+	sb.WriteString(key)
+	return sb.String()
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"encoding/json"
+	"time"
+)
+
+var (
+	Store Cache = NewMemoryCache() // Store cache instance, memory cache as default
+)
+
+// Cache the cache abstraction, the values are stored as bytes, so that it can be backed by remote storage
+type Cache interface {
+	// Get the value of the key, ok is false when the key does not exist or has expired
+	Get(key string) (val []byte, ok bool)
+
+	// Set the value of the key, ttl less than or equal to 0 means never expire
+	Set(key string, val []byte, ttl time.Duration)
+
+	// Delete the keys
+	Delete(keys ...string)
+}
+
+// Cacheable Get the cached value of the key, when missing, call the loader and cache its result.
+// The value is encoded as json, errors returned by the loader are not cached.
+//
+//	user, err := cache.Cacheable("user:"+id, time.Minute, func() (*User, error) {
+//	    return s.UserMapper.GetById(id)
+//	})
+func Cacheable[T any](key string, ttl time.Duration, loader func() (T, error)) (T, error) {
+	var val T
+	if b, ok := Store.Get(key); ok && json.Unmarshal(b, &val) == nil {
+		return val, nil
+	}
+	val, err := loader()
+	if err != nil {
+		return val, err
+	}
+	if b, err := json.Marshal(val); err == nil {
+		Store.Set(key, b, ttl)
+	}
+	return val, nil
+}
+
+// CacheEvict Delete the cached value of the keys after the action succeeds
+//
+//	err := cache.CacheEvict(func() error {
+//	    return s.UserMapper.Update(user)
+//	}, "user:"+id)
+func CacheEvict(action func() error, keys ...string) error {
+	if err := action(); err != nil {
+		return err
+	}
+	Store.Delete(keys...)
+	return nil
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"io"
+	"time"
+)
+
+// InvalidationConfig the cross-instance invalidation configuration, the evictions are broadcast by a redis channel
+type InvalidationConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`  // Whether to broadcast the evictions to all instances, default false
+	Addr     string `mapstructure:"addr"`     // Redis address, default the client of the redis configuration, or 127.0.0.1:6379 without it
+	Username string `mapstructure:"username"` // Redis username
+	Password string `mapstructure:"password"` // Redis password
+	DB       int    `mapstructure:"db"`       // Redis database
+	Channel  string `mapstructure:"channel"`  // Channel of the invalidation messages, default gin-plus:cache:invalidation
+}
+
+// InvalidationBus the pub/sub transport of the invalidation messages
+type InvalidationBus interface {
+	// Publish the message to all instances, including the publisher itself
+	Publish(ctx context.Context, msg []byte) error
+
+	// Subscribe the messages, it blocks until the context is done or the subscription fails
+	Subscribe(ctx context.Context, handle func(msg []byte)) error
+
+	// Close the bus
+	Close() error
+}
+
+// invalidation message
+type invalidation struct {
+	Source string   `json:"source"` // Instance id of the publisher
+	Keys   []string `json:"keys"`
+}
+
+/*
+InvalidatingCache wraps the local cache, the deleted keys are deleted by all instances subscribing the bus,
+so the replicas don't serve the stale values, both the cached values and the cached responses are covered.
+
+	c := cache.NewInvalidatingCache(cache.NewMemoryCache(), cache.NewRedisBus(client, "app:cache"))
+	c.Listen()
+	app.Cache(c)
+*/
+type InvalidatingCache struct {
+	Cache
+	bus    InvalidationBus
+	source string
+	cancel context.CancelFunc
+}
+
+// NewInvalidatingCache Create the cache broadcasting the evictions by the bus, call Listen to receive the evictions of other instances
+func NewInvalidatingCache(local Cache, bus InvalidationBus) *InvalidatingCache {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return &InvalidatingCache{Cache: local, bus: bus, source: hex.EncodeToString(b)}
+}
+
+// Delete the keys locally and broadcast the eviction, the failure of the broadcast is logged
+func (c *InvalidatingCache) Delete(keys ...string) {
+	c.Cache.Delete(keys...)
+	if len(keys) == 0 {
+		return
+	}
+	msg, _ := json.Marshal(&invalidation{Source: c.source, Keys: keys})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := c.bus.Publish(ctx, msg); err != nil {
+		logger.Log.Warnf("cache: broadcast the eviction of %v error, %s", keys, err.Error())
+	}
+}
+
+// Listen the evictions of other instances in background, the subscription is retried until Close
+func (c *InvalidatingCache) Listen() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go func() {
+		for {
+			err := c.bus.Subscribe(ctx, c.evict)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				logger.Log.Warnf("cache: subscribe the invalidation bus error, %s", err.Error())
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+}
+
+// Close Stop listening, close the bus and the local cache implementing io.Closer
+func (c *InvalidatingCache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	err := c.bus.Close()
+	if closer, ok := c.Cache.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+func (c *InvalidatingCache) evict(msg []byte) {
+	var inv invalidation
+	if err := json.Unmarshal(msg, &inv); err != nil {
+		logger.Log.Warnf("cache: invalid invalidation message, %s", err.Error())
+		return
+	}
+	if inv.Source != c.source {
+		c.Cache.Delete(inv.Keys...)
+	}
+}
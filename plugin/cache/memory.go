@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MemoryCache in-process cache holding the max entries, the least recently used ones are evicted when it's full.
+// Expired keys are removed when they are read and swept periodically when the keys are set
+type MemoryCache struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	lru        *list.List // the front is the most recently used
+	maxEntries int
+	lastSweep  time.Time
+}
+
+type memoryItem struct {
+	key      string
+	val      []byte
+	expireAt time.Time // zero means never expire
+}
+
+// NewMemoryCache Create a memory cache holding 10000 entries at most
+func NewMemoryCache() *MemoryCache {
+	return NewLimitedMemoryCache(0)
+}
+
+// NewLimitedMemoryCache Create a memory cache holding the max entries, default 10000
+func NewLimitedMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &MemoryCache{items: make(map[string]*list.Element), lru: list.New(), maxEntries: maxEntries, lastSweep: time.Now()}
+}
+
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := el.Value.(*memoryItem)
+	if !item.expireAt.IsZero() && time.Now().After(item.expireAt) {
+		m.remove(el)
+		return nil, false
+	}
+	m.lru.MoveToFront(el)
+	return item.val, true
+}
+
+func (m *MemoryCache) Set(key string, val []byte, ttl time.Duration) {
+	now := time.Now()
+	item := &memoryItem{key: key, val: val}
+	if ttl > 0 {
+		item.expireAt = now.Add(ttl)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	if el, ok := m.items[key]; ok {
+		el.Value = item
+		m.lru.MoveToFront(el)
+		return
+	}
+	m.items[key] = m.lru.PushFront(item)
+	for m.lru.Len() > m.maxEntries {
+		m.remove(m.lru.Back())
+	}
+}
+
+func (m *MemoryCache) Delete(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range keys {
+		if el, ok := m.items[k]; ok {
+			m.remove(el)
+		}
+	}
+}
+
+// Len the number of the entries, including the expired ones not swept yet
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+func (m *MemoryCache) remove(el *list.Element) {
+	m.lru.Remove(el)
+	delete(m.items, el.Value.(*memoryItem).key)
+}
+
+// remove the expired entries once a minute at most
+func (m *MemoryCache) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for el := m.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if item := el.Value.(*memoryItem); !item.expireAt.IsZero() && now.After(item.expireAt) {
+			m.remove(el)
+		}
+		el = prev
+	}
+}
+
+// Local Whether the values of the cache are kept in the process only, they're not shared by the replicas
+func Local(c Cache) bool {
+	switch c := c.(type) {
+	case *MemoryCache:
+		return true
+	case *InvalidatingCache:
+		return Local(c.Cache)
+	}
+	return false
+}
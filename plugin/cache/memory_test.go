@@ -0,0 +1,27 @@
+package cache
+
+import "testing"
+
+func TestMemoryCacheLimit(t *testing.T) {
+	c := NewLimitedMemoryCache(2)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+	c.Get("a")
+	c.Set("c", []byte("3"), 0)
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, ok := c.Get(tt.key); ok != tt.want {
+			t.Errorf("Get(%q) ok = %t, want %t", tt.key, ok, tt.want)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachedTag the tag of the function field calling the bean method through its cache annotations
+const CachedTag = "cached"
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+/*
+Bind Set the function fields tagged by cached to call the bean methods through their cache annotations. Go can't
+intercept the calls of the methods, so the callers call the field instead of the method, the field must be the same
+type as the method. The key supports the placeholders of the arguments, {0} is the first argument, the empty key
+means the type, the method and all the arguments.
+
+	type UserService struct {
+	    GetUser    func(ctx context.Context, id string) (*User, error) `cached:"FindUser"`
+	    UpdateUser func(ctx context.Context, user *User) error          `cached:"SaveUser"`
+	}
+
+	// FindUser
+	// @Cacheable(key="user:{1}", ttl="60s")
+	func (s *UserService) FindUser(ctx context.Context, id string) (*User, error) {}
+
+	// SaveUser
+	// @CacheEvict(key="user:{1.Id};users")
+	func (s *UserService) SaveUser(ctx context.Context, user *User) error {}
+
+The controllers call s.UserService.GetUser(ctx, id). Returns the error when the annotated method is not bound by a field,
+it would be cached silently never.
+*/
+func Bind(beans ...any) error {
+	for _, bean := range beans {
+		if mvc.IsController(bean) {
+			continue
+		}
+		annotations := mvc.MethodAnnotations(bean)
+		bound := make(map[string]bool)
+		v := reflect.ValueOf(bean)
+		if v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct {
+			elem := v.Elem()
+			for i := 0; i < elem.NumField(); i++ {
+				sf := elem.Type().Field(i)
+				name, ok := sf.Tag.Lookup(CachedTag)
+				if !ok {
+					continue
+				}
+				if !sf.IsExported() || sf.Type.Kind() != reflect.Func {
+					return fmt.Errorf("%T.%s tagged by %s must be the exported function", bean, sf.Name, CachedTag)
+				}
+				method := v.MethodByName(name)
+				if !method.IsValid() {
+					return fmt.Errorf("%T.%s calls the method %s, but it's absent", bean, sf.Name, name)
+				}
+				if method.Type() != sf.Type {
+					return fmt.Errorf("%T.%s is %s, but the method %s is %s", bean, sf.Name, sf.Type, name, method.Type())
+				}
+				fn, err := cachedMethod(fmt.Sprintf("%s.%s", elem.Type().Name(), name), method, annotations[name])
+				if err != nil {
+					return fmt.Errorf("%T.%s, %w", bean, name, err)
+				}
+				elem.Field(i).Set(fn)
+				bound[name] = true
+			}
+		}
+		for method, a := range annotations {
+			for _, name := range []string{CacheableAnnotation, CacheEvictAnnotation} {
+				if _, ok := a[name]; ok && !bound[method] {
+					return fmt.Errorf("%T.%s declares @%s, but no field is tagged by %s:\"%s\" to call it", bean, method, name, CachedTag, method)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// the function calling the method through the cache annotations
+func cachedMethod(name string, method reflect.Value, annotations mvc.Annotations) (reflect.Value, error) {
+	cacheable, hasCacheable := annotations[CacheableAnnotation]
+	evict, hasEvict := annotations[CacheEvictAnnotation]
+	t := method.Type()
+	returnsErr := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	switch {
+	case hasCacheable && hasEvict:
+		return reflect.Value{}, fmt.Errorf("@%s and @%s can't be declared on the same method", CacheableAnnotation, CacheEvictAnnotation)
+	case hasCacheable:
+		if t.NumOut() == 0 || t.NumOut() > 2 || (t.NumOut() == 2 && !returnsErr) || (t.NumOut() == 1 && returnsErr) {
+			return reflect.Value{}, fmt.Errorf("@%s requires the method returning the value or the value and the error", CacheableAnnotation)
+		}
+		args := mvc.ParseAnnotationArgs(cacheable)
+		key := args["key"]
+		if key == "" {
+			key = args["value"]
+		}
+		var ttl time.Duration
+		if args["ttl"] != "" {
+			var err error
+			if ttl, err = time.ParseDuration(args["ttl"]); err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid ttl of @%s(%s), %w", CacheableAnnotation, cacheable, err)
+			}
+		}
+		return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+			k := methodKey(name, key, in)
+			val := reflect.New(t.Out(0))
+			if b, ok := Store.Get(k); ok && json.Unmarshal(b, val.Interface()) == nil {
+				out := []reflect.Value{val.Elem()}
+				if returnsErr {
+					out = append(out, reflect.Zero(errorType))
+				}
+				return out
+			}
+			out := method.Call(in)
+			if returnsErr && !out[1].IsNil() {
+				return out
+			}
+			if b, err := json.Marshal(out[0].Interface()); err == nil {
+				Store.Set(k, b, ttl)
+			} else {
+				logger.Log.Warnf("Cache the result of %s error, %s", name, err.Error())
+			}
+			return out
+		}), nil
+	case hasEvict:
+		args := mvc.ParseAnnotationArgs(evict)
+		key := args["key"]
+		if key == "" {
+			key = args["value"]
+		}
+		keys := strings.Split(key, ";")
+		return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+			out := method.Call(in)
+			if returnsErr && !out[len(out)-1].IsNil() {
+				return out
+			}
+			resolved := make([]string, len(keys))
+			for i, k := range keys {
+				resolved[i] = methodKey(name, strings.TrimSpace(k), in)
+			}
+			Store.Delete(resolved...)
+			return out
+		}), nil
+	}
+	// the field calls the method directly when it has no cache annotation
+	return method, nil
+}
+
+// replace the placeholders of the key with the arguments, such as: user:{1} or user:{1.Id}. The empty key means the
+// name of the method and all the arguments
+func methodKey(name, key string, in []reflect.Value) string {
+	if key == "" {
+		var sb strings.Builder
+		sb.WriteString(name)
+		for _, arg := range in {
+			if arg.Type().Implements(contextType) {
+				continue
+			}
+			sb.WriteByte(':')
+			sb.WriteString(keyPart(arg))
+		}
+		return sb.String()
+	}
+	if !strings.Contains(key, "{") {
+		return key
+	}
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(key, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(key[start:], '}')
+		if end < 0 {
+			break
+		}
+		sb.WriteString(key[:start])
+		sb.WriteString(argValue(key[start+1:start+end], in))
+		key = key[start+end+1:]
+	}
+	sb.WriteString(key)
+	return sb.String()
+}
+
+// the argument of the placeholder, such as 1 or 1.Id, empty when it's absent
+func argValue(placeholder string, in []reflect.Value) string {
+	index, path, _ := strings.Cut(placeholder, ".")
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(in) {
+		return ""
+	}
+	v := in[i]
+	for _, field := range strings.Split(path, ".") {
+		if field == "" {
+			continue
+		}
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return ""
+		}
+		if v = v.FieldByName(field); !v.IsValid() {
+			return ""
+		}
+	}
+	return keyPart(v)
+}
+
+// the argument in the key, the structs, maps and slices are encoded as json
+func keyPart(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		b, _ := json.Marshal(v.Interface())
+		return string(b)
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/mvc"
+	"reflect"
+	"testing"
+)
+
+type user struct {
+	Id   string
+	Name string
+}
+
+type userService struct {
+	loads int
+}
+
+func (s *userService) FindUser(ctx context.Context, id string) (*user, error) {
+	s.loads++
+	if id == "" {
+		return nil, errors.New("id is empty")
+	}
+	return &user{Id: id, Name: "tom"}, nil
+}
+
+func (s *userService) SaveUser(ctx context.Context, u *user) error {
+	return nil
+}
+
+func TestCachedMethod(t *testing.T) {
+	Store = NewMemoryCache()
+	s := &userService{}
+	find, err := cachedMethod("userService.FindUser", reflect.ValueOf(s.FindUser), mvc.Annotations{CacheableAnnotation: `key="user:{1}", ttl="60s"`})
+	if err != nil {
+		t.Fatalf("cachedMethod error, %v", err)
+	}
+	save, err := cachedMethod("userService.SaveUser", reflect.ValueOf(s.SaveUser), mvc.Annotations{CacheEvictAnnotation: `key="user:{1.Id}"`})
+	if err != nil {
+		t.Fatalf("cachedMethod error, %v", err)
+	}
+	findUser := find.Interface().(func(context.Context, string) (*user, error))
+	saveUser := save.Interface().(func(context.Context, *user) error)
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		call      func() error
+		wantLoads int
+	}{
+		{"load on miss", func() error { _, err := findUser(ctx, "1"); return err }, 1},
+		{"hit", func() error { _, err := findUser(ctx, "1"); return err }, 1},
+		{"other key", func() error { _, err := findUser(ctx, "2"); return err }, 2},
+		{"error is not cached", func() error { _, _ = findUser(ctx, ""); _, _ = findUser(ctx, ""); return nil }, 4},
+		{"evict", func() error { return saveUser(ctx, &user{Id: "1"}) }, 4},
+		{"load after evicted", func() error { _, err := findUser(ctx, "1"); return err }, 5},
+		{"other key is kept", func() error { _, err := findUser(ctx, "2"); return err }, 5},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); err != nil {
+			t.Fatalf("%s, error %v", tt.name, err)
+		}
+		if s.loads != tt.wantLoads {
+			t.Errorf("%s, loads = %d, want %d", tt.name, s.loads, tt.wantLoads)
+		}
+	}
+	if u, err := findUser(ctx, "1"); err != nil || u.Id != "1" || u.Name != "tom" {
+		t.Errorf("cached user = %+v, %v", u, err)
+	}
+}
+
+func TestMethodKey(t *testing.T) {
+	in := []reflect.Value{reflect.ValueOf(context.Background()), reflect.ValueOf("7"), reflect.ValueOf(&user{Id: "1", Name: "tom"})}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"users", "users"},
+		{"user:{1}", "user:7"},
+		{"user:{2.Id}", "user:1"},
+		{"user:{2.Age}", "user:"},
+		{"user:{5}", "user:"},
+		{"", `userService.FindUser:7:{"Id":"1","Name":"tom"}`},
+	}
+	for _, tt := range tests {
+		if got := methodKey("userService.FindUser", tt.key, in); got != tt.want {
+			t.Errorf("methodKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
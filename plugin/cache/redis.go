@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// StoreConfig the storage of the cached values
+type StoreConfig struct {
+	Type       string `mapstructure:"type"`        // memory or redis, default memory. Redis shares the cached values between the instances
+	Addr       string `mapstructure:"addr"`        // Redis address, default the client of the redis configuration, or 127.0.0.1:6379 without it
+	Username   string `mapstructure:"username"`    // Redis username
+	Password   string `mapstructure:"password"`    // Redis password
+	DB         int    `mapstructure:"db"`          // Redis database
+	Prefix     string `mapstructure:"prefix"`      // Prefix of the keys, default gin-plus:cache:
+	MaxEntries int    `mapstructure:"max_entries"` // Max entries of the memory cache, the least recently used ones are evicted, default 10000
+}
+
+// NewStore Create the cache from the configuration
+func NewStore(conf StoreConfig) Cache {
+	if conf.Type != "redis" {
+		return NewLimitedMemoryCache(conf.MaxEntries)
+	}
+	if conf.Addr == "" {
+		conf.Addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: conf.Addr, Username: conf.Username, Password: conf.Password, DB: conf.DB})
+	return NewRedisCache(client, conf.Prefix)
+}
+
+// RedisCache the cache shared by the instances, the failure of redis is logged and treated as the cache miss,
+// so the outage of redis doesn't take down the service
+type RedisCache struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisCache Create the redis cache, the client is closed with the cache
+func NewRedisCache(client redis.UniversalClient, prefix string) *RedisCache {
+	if prefix == "" {
+		prefix = "gin-plus:cache:"
+	}
+	return &RedisCache{client: client, prefix: prefix}
+}
+
+func (r *RedisCache) Get(key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	b, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.Log.Warnf("cache: get %s error, %s", key, err.Error())
+		}
+		return nil, false
+	}
+	return b, true
+}
+
+func (r *RedisCache) Set(key string, val []byte, ttl time.Duration) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.client.Set(ctx, r.prefix+key, val, ttl).Err(); err != nil {
+		logger.Log.Warnf("cache: set %s error, %s", key, err.Error())
+	}
+}
+
+func (r *RedisCache) Delete(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = r.prefix + k
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// the keys are deleted one by one, because they may be in the different slots of the cluster
+	_, err := r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, k := range prefixed {
+			p.Del(ctx, k)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Log.Warnf("cache: delete %v error, %s", keys, err.Error())
+	}
+}
+
+// Close the redis client
+func (r *RedisCache) Close() error {
+	return r.client.Close()
+}
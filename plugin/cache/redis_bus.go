@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBus the invalidation bus backed by a redis channel
+type RedisBus struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// NewRedisBus Create the bus publishing to the channel, the client is closed with the bus
+func NewRedisBus(client redis.UniversalClient, channel string) *RedisBus {
+	if channel == "" {
+		channel = "gin-plus:cache:invalidation"
+	}
+	return &RedisBus{client: client, channel: channel}
+}
+
+// NewRedisInvalidation Create the invalidating cache of the local cache from the configuration, it starts listening
+func NewRedisInvalidation(local Cache, conf InvalidationConfig) *InvalidatingCache {
+	if conf.Addr == "" {
+		conf.Addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: conf.Addr, Username: conf.Username, Password: conf.Password, DB: conf.DB})
+	c := NewInvalidatingCache(local, NewRedisBus(client, conf.Channel))
+	c.Listen()
+	return c
+}
+
+func (b *RedisBus) Publish(ctx context.Context, msg []byte) error {
+	return b.client.Publish(ctx, b.channel, msg).Err()
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, handle func(msg []byte)) error {
+	ps := b.client.Subscribe(ctx, b.channel)
+	defer ps.Close()
+	// wait for the confirmation, so the failure of the connection is reported
+	if _, err := ps.Receive(ctx); err != nil {
+		return err
+	}
+	ch := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			handle([]byte(msg.Payload))
+		}
+	}
+}
+
+func (b *RedisBus) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,429 @@
+package clientgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+	"go/format"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Config the generated client package
+type Config struct {
+	Package string   // Package name of the client, default client
+	Tags    []string // Only generate the operations of the tags, default empty means all
+}
+
+// the operation of the document
+type operation struct {
+	name   string
+	method string
+	path   string
+	op     *mvc.Operation
+}
+
+// generator state of a client package
+type generator struct {
+	doc     *mvc.OpenAPIDoc
+	buf     bytes.Buffer
+	imports map[string]bool
+	types   []string // generated request types, such as the query and body structs
+}
+
+/*
+Load Load the OpenAPI document generated by the mvc layer, from the url of a running application or the file.
+
+	doc, err := clientgen.Load("http://user-service:4006/openapi.json")
+*/
+func Load(source string) (*mvc.OpenAPIDoc, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		var r *http.Response
+		if r, err = http.Get(source); err != nil {
+			return nil, err
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("get the document error, status %d", r.StatusCode)
+		}
+		data, err = io.ReadAll(r.Body)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc mvc.OpenAPIDoc
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse the document error, %s", err.Error())
+	}
+	return &doc, nil
+}
+
+/*
+Generate Generate the go client package of the document, every operation is a method of the Client,
+the schemas are generated as the structs, the path parameters are the arguments in order,
+the query parameters and the body are the generated structs, such as:
+
+	func (c *Client) GetUser(ctx context.Context, id int64) (*UserDTO, error)
+	func (c *Client) ListUser(ctx context.Context, query *ListUserQuery) ([]UserDTO, error)
+	func (c *Client) CreateUser(ctx context.Context, body *CreateUserBody) error
+*/
+func Generate(doc *mvc.OpenAPIDoc, conf Config) ([]byte, error) {
+	if conf.Package == "" {
+		conf.Package = "client"
+	}
+	g := &generator{doc: doc, imports: map[string]bool{"context": true, "net/http": true}}
+	ops := g.operations(conf.Tags)
+	if len(ops) == 0 {
+		return nil, errors.New("no operation to generate")
+	}
+	var methods bytes.Buffer
+	for _, o := range ops {
+		if err := g.method(&methods, o); err != nil {
+			return nil, fmt.Errorf("generate %s %s error, %s", o.method, o.path, err.Error())
+		}
+	}
+	var schemas bytes.Buffer
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		if name == "Result" {
+			continue
+		}
+		fmt.Fprintf(&schemas, "// %s the %s schema\ntype %s %s\n\n", goName(name), name, goName(name), g.goType(doc.Components.Schemas[name], false))
+	}
+	out := &g.buf
+	fmt.Fprintf(out, "// Code generated by gin-plus clientgen from %s %s. DO NOT EDIT.\n\n", doc.Info.Title, doc.Info.Version)
+	fmt.Fprintf(out, "package %s\n\nimport (\n", conf.Package)
+	for _, imp := range append(sortedKeys(g.imports), runtimeImports...) {
+		fmt.Fprintf(out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n\n")
+	out.WriteString(runtimeSource)
+	out.Write(methods.Bytes())
+	for _, t := range g.types {
+		out.WriteString(t)
+	}
+	out.Write(schemas.Bytes())
+	src, err := format.Source(dedupImports(out.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("format the client error, %s", err.Error())
+	}
+	return src, nil
+}
+
+// the operations sorted by the path and the method, named by the operation id
+func (g *generator) operations(tags []string) []operation {
+	var ops []operation
+	names := make(map[string]int)
+	for _, path := range sortedKeys(g.doc.Paths) {
+		for _, method := range sortedKeys(g.doc.Paths[path]) {
+			op := g.doc.Paths[path][method]
+			if len(tags) > 0 && !hasTag(op.Tags, tags) {
+				continue
+			}
+			o := operation{name: operationName(op), method: strings.ToUpper(method), path: path, op: op}
+			names[o.name]++
+			ops = append(ops, o)
+		}
+	}
+	// the operations of different controllers with the same method name are qualified by the id
+	for i := range ops {
+		if names[ops[i].name] > 1 {
+			ops[i].name = goName(ops[i].op.OperationID)
+		}
+	}
+	return ops
+}
+
+func hasTag(opTags, tags []string) bool {
+	for _, t := range opTags {
+		for _, want := range tags {
+			if t == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the default route name is Controller.Method, the method name is used then
+func operationName(op *mvc.Operation) string {
+	id := op.OperationID
+	if len(op.Tags) > 0 {
+		id = strings.TrimPrefix(id, op.Tags[0]+".")
+	}
+	return goName(id)
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)}`)
+
+func (g *generator) method(w *bytes.Buffer, o operation) error {
+	var args, pathArgs []string
+	// the path parameters not bound by the api method are strings
+	for _, m := range pathParamPattern.FindAllStringSubmatch(o.path, -1) {
+		schema := &mvc.Schema{Type: "string"}
+		for _, p := range o.op.Parameters {
+			if p.In == "path" && p.Name == m[1] {
+				schema = p.Schema
+			}
+		}
+		arg := argName(m[1])
+		args = append(args, arg+" "+g.goType(schema, false))
+		pathArgs = append(pathArgs, "pathParam("+arg+")")
+	}
+	var queryType string
+	for _, p := range o.op.Parameters {
+		if p.In == "query" {
+			queryType = o.name + "Query"
+		}
+	}
+	if queryType != "" {
+		g.queryType(queryType, o.op.Parameters)
+		args = append(args, "query *"+queryType)
+	}
+	bodyExpr, contentType := "nil", ""
+	if rb := o.op.RequestBody; rb != nil {
+		if mt, ok := rb.Content["application/json"]; ok {
+			bodyType := g.goType(mt.Schema, true)
+			if mt.Schema.Ref == "" && mt.Schema.Type == "object" && len(mt.Schema.Properties) > 0 {
+				bodyType = o.name + "Body"
+				g.types = append(g.types, fmt.Sprintf("// %s the body of %s\ntype %s %s\n\n", bodyType, o.name, bodyType, g.goType(mt.Schema, false)))
+				bodyType = "*" + bodyType
+			}
+			args = append(args, "body "+bodyType)
+			bodyExpr, contentType = "body", "application/json"
+		} else {
+			// the multipart form is built by the caller, such as multipart.Writer
+			args = append(args, "body io.Reader", "contentType string")
+			bodyExpr, contentType = "body", "contentType"
+			g.imports["io"] = true
+		}
+	}
+	ret, decode := g.result(o.op)
+	comment := o.op.Summary
+	if comment == "" {
+		comment = o.method + " " + o.path
+	}
+	fmt.Fprintf(w, "// %s %s\n", o.name, comment)
+	if o.op.Deprecated {
+		w.WriteString("//\n// Deprecated: the api is deprecated by the server.\n")
+	}
+	fmt.Fprintf(w, "func (c *Client) %s(%s) %s {\n", o.name, strings.Join(append([]string{"ctx context.Context"}, args...), ", "), ret)
+	path := pathParamPattern.ReplaceAllString(strings.ReplaceAll(o.path, "%", "%%"), "%s")
+	if len(pathArgs) > 0 {
+		fmt.Fprintf(w, "\tpath := fmt.Sprintf(%q, %s)\n", path, strings.Join(pathArgs, ", "))
+	} else {
+		fmt.Fprintf(w, "\tpath := %q\n", path)
+	}
+	query := "nil"
+	if queryType != "" {
+		query = "query.values()"
+	}
+	ctExpr := `""`
+	if contentType == "application/json" {
+		ctExpr = `"application/json"`
+	} else if contentType != "" {
+		ctExpr = contentType
+	}
+	w.WriteString(decode(fmt.Sprintf("c.do(ctx, %q, path, %s, %s, %s", o.method, query, bodyExpr, ctExpr)))
+	w.WriteString("}\n\n")
+	return nil
+}
+
+// the return values and the call of the operation
+func (g *generator) result(op *mvc.Operation) (string, func(call string) string) {
+	var content map[string]*mvc.MediaType
+	for _, status := range sortedKeys(op.Responses) {
+		if strings.HasPrefix(status, "2") {
+			content = op.Responses[status].Content
+			break
+		}
+	}
+	if _, ok := content["text/event-stream"]; ok {
+		return "(io.ReadCloser, error)", func(call string) string {
+			g.imports["io"] = true
+			return "\treturn c.stream(" + strings.TrimPrefix(call, "c.do(") + ")\n"
+		}
+	}
+	var data *mvc.Schema
+	if mt, ok := content["application/json"]; ok && len(mt.Schema.AllOf) == 2 {
+		data = mt.Schema.AllOf[1].Properties["ret"]
+	}
+	if data == nil {
+		return "error", func(call string) string {
+			return "\treturn " + call + ", nil)\n"
+		}
+	}
+	t := g.goType(data, true)
+	return "(" + t + ", error)", func(call string) string {
+		return fmt.Sprintf("\tvar ret %s\n\terr := %s, &ret)\n\treturn ret, err\n", t, call)
+	}
+}
+
+// the query struct of the operation, the zero values of the optional parameters are not sent
+func (g *generator) queryType(name string, params []*mvc.Parameter) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s the query parameters\ntype %s struct {\n", name, name)
+	var values strings.Builder
+	for _, p := range params {
+		if p.In != "query" {
+			continue
+		}
+		field := goName(p.Name)
+		fmt.Fprintf(&b, "\t%s %s `json:\"%s\"`\n", field, g.goType(p.Schema, false), p.Name)
+		fmt.Fprintf(&values, "\taddQuery(v, %q, q.%s, %t)\n", p.Name, field, p.Required)
+	}
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "func (q *%s) values() url.Values {\n\tv := url.Values{}\n\tif q == nil {\n\t\treturn v\n\t}\n%s\treturn v\n}\n\n", name, values.String())
+	g.types = append(g.types, b.String())
+}
+
+/*
+the go type of the schema, ptr means the objects are referenced by pointers, such as the returned structs
+
+	$ref -> the generated struct
+	string(date-time) -> time.Time, string(byte|binary) -> []byte
+	integer(int32) -> int32, integer -> int64
+	array -> slice, object with additionalProperties -> map, object -> struct
+*/
+func (g *generator) goType(s *mvc.Schema, ptr bool) string {
+	if s == nil {
+		return "any"
+	}
+	if len(s.AllOf) == 1 {
+		return g.goType(s.AllOf[0], ptr)
+	}
+	if s.Ref != "" {
+		name := goName(strings.TrimPrefix(s.Ref, "#/components/schemas/"))
+		if ptr {
+			return "*" + name
+		}
+		return name
+	}
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time"
+		case "byte", "binary":
+			return "[]byte"
+		}
+		return "string"
+	case "boolean":
+		return "bool"
+	case "integer":
+		if s.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "array":
+		return "[]" + g.goType(s.Items, false)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties, false)
+		}
+		if len(s.Properties) == 0 {
+			return "map[string]any"
+		}
+		return g.structType(s)
+	}
+	return "any"
+}
+
+func (g *generator) structType(s *mvc.Schema) string {
+	required := make(map[string]bool, len(s.Required))
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for _, name := range sortedKeys(s.Properties) {
+		tag := name
+		if !required[name] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:\"%s\"`\n", goName(name), g.goType(s.Properties[name], true), tag)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+var initialisms = map[string]string{"id": "ID", "url": "URL", "uri": "URI", "http": "HTTP", "api": "API", "ip": "IP", "json": "JSON", "uuid": "UUID"}
+
+// the exported go identifier of the name, such as user_id -> UserID, user.get -> UserGet
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		if up, ok := initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(up)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	if b.Len() == 0 || b.String()[0] >= '0' && b.String()[0] <= '9' {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// the argument of the path parameter, such as user_id -> userID
+func argName(param string) string {
+	name := goName(param)
+	for lower, up := range initialisms {
+		if strings.HasPrefix(name, up) {
+			name = lower + name[len(up):]
+			break
+		}
+	}
+	name = strings.ToLower(name[:1]) + name[1:]
+	switch name {
+	case "ctx", "query", "body", "contentType", "path", "ret", "err":
+		return name + "Param"
+	}
+	if token.IsKeyword(name) {
+		return name + "Param"
+	}
+	return name
+}
+
+// the imports of the runtime source and the operations may overlap
+func dedupImports(src []byte) []byte {
+	head, rest, _ := bytes.Cut(src, []byte(")\n"))
+	lines := bytes.Split(head, []byte("\n"))
+	seen := make(map[string]bool)
+	var out [][]byte
+	for _, l := range lines {
+		if seen[string(l)] && bytes.HasPrefix(l, []byte("\t\"")) {
+			continue
+		}
+		seen[string(l)] = true
+		out = append(out, l)
+	}
+	return append(append(bytes.Join(out, []byte("\n")), ")\n"...), rest...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,137 @@
+package clientgen
+
+// imports of the runtime source
+var runtimeImports = []string{"bytes", "encoding/json", "fmt", "io", "net/url", "reflect", "strings", "time"}
+
+// the client and the helpers shared by the generated operations
+const runtimeSource = `// Client the api client, the responses are unwrapped from the err_code, err_msg and ret envelope
+type Client struct {
+	BaseURL    string       // Base url of the service, such as http://user-service:4006
+	HTTPClient *http.Client // Default http.DefaultClient
+	Header     http.Header  // Headers of every request, such as Authorization
+}
+
+// New Create the api client of the base url
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient, Header: http.Header{}}
+}
+
+// Error the error responded by the api, the err_code is not 0 or the http status is not 2xx
+type Error struct {
+	Status  int    // Http status
+	Code    int    // Business code
+	Message string // Business message
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api error, status %d, code %d, %s", e.Status, e.Code, e.Message)
+}
+
+type result struct {
+	Code    int             ` + "`json:\"err_code\"`" + `
+	Message string          ` + "`json:\"err_msg\"`" + `
+	Data    json.RawMessage ` + "`json:\"ret\"`" + `
+}
+
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, body any, contentType string) (*http.Response, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return hc.Do(req)
+}
+
+// send the request and decode the ret into the out, out is nil means the ret is ignored
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, contentType string, out any) error {
+	r, err := c.request(ctx, method, path, query, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	var res result
+	if err = json.Unmarshal(data, &res); err != nil {
+		if r.StatusCode/100 != 2 {
+			return &Error{Status: r.StatusCode, Message: strings.TrimSpace(string(data))}
+		}
+		return fmt.Errorf("decode the response error, %s", err.Error())
+	}
+	if r.StatusCode/100 != 2 || res.Code != 0 {
+		return &Error{Status: r.StatusCode, Code: res.Code, Message: res.Message}
+	}
+	if out == nil || len(res.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(res.Data, out)
+}
+
+// send the request and return the event stream, the caller closes it
+func (c *Client) stream(ctx context.Context, method, path string, query url.Values, body any, contentType string) (io.ReadCloser, error) {
+	r, err := c.request(ctx, method, path, query, body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode/100 != 2 {
+		defer r.Body.Close()
+		data, _ := io.ReadAll(r.Body)
+		return nil, &Error{Status: r.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	return r.Body, nil
+}
+
+func pathParam(v any) string {
+	return url.PathEscape(fmt.Sprint(v))
+}
+
+// add the query parameter, the zero value of the optional parameter is not sent
+func addQuery(v url.Values, name string, val any, required bool) {
+	rv := reflect.ValueOf(val)
+	if !required && rv.IsZero() {
+		return
+	}
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			v.Add(name, queryValue(rv.Index(i).Interface()))
+		}
+		return
+	}
+	v.Set(name, queryValue(val))
+}
+
+func queryValue(val any) string {
+	if t, ok := val.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(val)
+}
+
+`
@@ -0,0 +1,295 @@
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+NoCompressAnnotation Declares the responses of the api method are not compressed,
+such as the api serving the images, archives or streams.
+
+	// Download
+	// @GET(path="/file/:id") download the file
+	// @NoCompress
+	func (f *FileController) Download(ctx *gin.Context) {}
+*/
+const NoCompressAnnotation = "NoCompress"
+
+// Supported encodings
+const (
+	Brotli = "br"
+	Gzip   = "gzip"
+)
+
+// Config the response compression
+type Config struct {
+	Enabled              bool     `mapstructure:"enabled"`                // Whether to compress the responses, default false
+	MinSize              int      `mapstructure:"min_size"`               // Responses smaller than it are not compressed, default 1024
+	Level                int      `mapstructure:"level"`                  // Compression level, gzip 1-9 and brotli 0-11, default 0 means the default level of each encoding
+	Encodings            []string `mapstructure:"encodings"`              // Encodings in order of preference, default br and gzip
+	ExcludedContentTypes []string `mapstructure:"excluded_content_types"` // Content types not compressed, such as image/* and application/zip, appended to the defaults
+	ExcludedPaths        []string `mapstructure:"excluded_paths"`         // Ant-style patterns of the route templates not compressed, such as /file/**
+}
+
+// the content types already compressed or streamed
+var defaultExcludedTypes = []string{"image/*", "video/*", "audio/*", "font/woff", "font/woff2", "application/zip",
+	"application/gzip", "application/x-gzip", "application/x-7z-compressed", "application/x-rar-compressed",
+	"application/x-bzip2", "application/x-xz", "application/zstd", "application/octet-stream", "application/pdf",
+	"text/event-stream"}
+
+// the context key of the request opting out of the compression
+const skipKey = "gin-plus/compress-skip"
+
+// Skip the compression of the response, it must be called before the response is written
+func Skip(ctx *gin.Context) {
+	ctx.Set(skipKey, true)
+}
+
+var (
+	gzipPools   sync.Map // level -> *sync.Pool
+	brotliPools sync.Map
+)
+
+/*
+Middleware The gin middleware compresses the responses by the encoding accepted by the client, the response is
+buffered until it reaches the minimum size, so the small ones are sent uncompressed. The responses already encoded,
+such as the pre-compressed static assets, the partial responses and the ones accepting the ranges, and the responses
+of the excluded content types, paths and the api methods declared by @NoCompress are not compressed. The strong etag
+of the compressed response is weakened.
+It should be added before the interceptors, so the responses written by the interceptors are compressed too.
+*/
+func Middleware(conf Config) gin.HandlerFunc {
+	if conf.MinSize <= 0 {
+		conf.MinSize = 1024
+	}
+	if len(conf.Encodings) == 0 {
+		conf.Encodings = []string{Brotli, Gzip}
+	}
+	excludedTypes := append(append([]string(nil), defaultExcludedTypes...), conf.ExcludedContentTypes...)
+	var excludedPaths func(ctx *gin.Context) bool
+	if len(conf.ExcludedPaths) > 0 {
+		excludedPaths = mvc.PathPredicate(conf.ExcludedPaths...)
+	}
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodHead || ctx.GetHeader("Upgrade") != "" {
+			ctx.Next()
+			return
+		}
+		encoding := negotiate(ctx.GetHeader("Accept-Encoding"), conf.Encodings)
+		if encoding == "" {
+			ctx.Next()
+			return
+		}
+		if excludedPaths != nil && excludedPaths(ctx) {
+			ctx.Next()
+			return
+		}
+		if _, ok := mvc.GetAnnotation(ctx, NoCompressAnnotation); ok {
+			ctx.Next()
+			return
+		}
+		w := &writer{ResponseWriter: ctx.Writer, ctx: ctx, conf: &conf, excludedTypes: excludedTypes, encoding: encoding}
+		ctx.Writer = w
+		defer func() {
+			w.close()
+			ctx.Writer = w.ResponseWriter
+		}()
+		ctx.Next()
+	}
+}
+
+// the preferred encoding accepted by the client, empty when none is accepted
+func negotiate(accept string, encodings []string) string {
+	if accept == "" {
+		return ""
+	}
+	accepted := make(map[string]bool)
+	for _, item := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+	for _, e := range encodings {
+		if ok, declared := accepted[e]; ok || (!declared && accepted["*"]) {
+			return e
+		}
+	}
+	return ""
+}
+
+// writer buffers the response until the minimum size, then decides whether to compress it
+type writer struct {
+	gin.ResponseWriter
+	ctx           *gin.Context
+	conf          *Config
+	excludedTypes []string
+	encoding      string
+	buf           []byte
+	decided       bool
+	enc           io.WriteCloser
+	release       func()
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.conf.MinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written the buffered response is considered written
+func (w *writer) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *writer) WriteHeaderNow() {
+	if !w.decided && len(w.buf) == 0 {
+		_ = w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush the streamed response is compressed when it is eligible, regardless of the size
+func (w *writer) Flush() {
+	if !w.decided {
+		_ = w.decide(true)
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// decide whether to compress by the headers, then write the buffered response
+func (w *writer) decide(large bool) error {
+	w.decided = true
+	if large && w.eligible() {
+		h := w.ResponseWriter.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		// the compressed bytes differ from the ones of the strong etag, they're only weakly equivalent
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		if !strings.Contains(strings.ToLower(strings.Join(h.Values("Vary"), ",")), "accept-encoding") {
+			h.Add("Vary", "Accept-Encoding")
+		}
+		w.enc, w.release = newEncoder(w.encoding, w.conf.Level, w.ResponseWriter)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *writer) eligible() bool {
+	status := w.ResponseWriter.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent ||
+		status == http.StatusNotModified || w.ctx.GetBool(skipKey) {
+		return false
+	}
+	h := w.ResponseWriter.Header()
+	// the ranges are the offsets of the uncompressed bytes, such as the static assets served by http.ServeContent
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" || h.Get("Accept-Ranges") != "" {
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range w.excludedTypes {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return false
+			}
+		} else if strings.EqualFold(mediaType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// write the rest of the response and close the encoder
+func (w *writer) close() {
+	if !w.decided {
+		_ = w.decide(len(w.buf) >= w.conf.MinSize)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.release()
+		w.enc = nil
+	}
+}
+
+// the pooled encoder writing to w, release puts it back to the pool after it is closed
+func newEncoder(encoding string, level int, w io.Writer) (io.WriteCloser, func()) {
+	if encoding == Brotli {
+		if level <= 0 || level > brotli.BestCompression {
+			level = 4
+		}
+		p, _ := brotliPools.LoadOrStore(level, &sync.Pool{New: func() any {
+			return brotli.NewWriterLevel(nil, level)
+		}})
+		pool := p.(*sync.Pool)
+		bw := pool.Get().(*brotli.Writer)
+		bw.Reset(w)
+		return bw, func() { pool.Put(bw) }
+	}
+	if level <= 0 || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	p, _ := gzipPools.LoadOrStore(level, &sync.Pool{New: func() any {
+		gw, _ := gzip.NewWriterLevel(nil, level)
+		return gw
+	}})
+	pool := p.(*sync.Pool)
+	gw := pool.Get().(*gzip.Writer)
+	gw.Reset(w)
+	return gw, func() { pool.Put(gw) }
+}
@@ -0,0 +1,90 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/archine/gin-plus/v3/resp"
+	"strings"
+)
+
+// ErrInvalidCursor The cursor is malformed or tampered
+var ErrInvalidCursor = errors.New("cursor: invalid cursor")
+
+var encoding = base64.RawURLEncoding
+
+// Codec encodes the key fields of the last item into the opaque cursor signed by HMAC-SHA256,
+// so the clients can't forge the cursors to page through the rows they shouldn't see
+type Codec struct {
+	secret []byte
+}
+
+// NewCodec Create the codec signing the cursors with the secret, panic when the secret is empty,
+// because the cursors signed by the empty secret can be forged
+func NewCodec(secret []byte) *Codec {
+	if len(secret) == 0 {
+		panic("cursor: the secret of the codec is required")
+	}
+	return &Codec{secret: secret}
+}
+
+// Encode the key into the cursor, key is usually the struct of the sort columns
+//
+//	type UserKey struct {
+//	    CreatedAt time.Time `json:"c" cursor:"created_at"`
+//	    ID        int64     `json:"i" cursor:"id"`
+//	}
+func (c *Codec) Encode(key any) (string, error) {
+	payload, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode the cursor into the key pointer, ErrInvalidCursor is returned when the cursor is malformed or tampered
+func (c *Codec) Decode(cursor string, key any) error {
+	p, s, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	payload, err := encoding.DecodeString(p)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	sig, err := encoding.DecodeString(s)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return ErrInvalidCursor
+	}
+	if err = json.Unmarshal(payload, key); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func (c *Codec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// NewPage Create the page from the items queried with limit+1, the extra item only means there are more items
+// and is dropped, the next cursor is encoded from the key of the last item in the page
+func NewPage[T, K any](c *Codec, items []T, limit int, key func(T) K) (resp.CursorPage[T], error) {
+	page := resp.CursorPage[T]{Data: items}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if limit <= 0 || len(items) <= limit {
+		return page, nil
+	}
+	page.Data, page.HasMore = items[:limit], true
+	next, err := c.Encode(key(page.Data[limit-1]))
+	if err != nil {
+		return page, err
+	}
+	page.NextCursor = next
+	return page, nil
+}
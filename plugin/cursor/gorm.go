@@ -0,0 +1,78 @@
+package cursor
+
+import (
+	"errors"
+	"github.com/archine/gin-plus/v3/resp"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+)
+
+// Query the keyset pagination query
+type Query struct {
+	Cursor string // Cursor of the previous page, empty means the first page
+	Limit  int    // Page size
+	Desc   bool   // Whether to sort the items descending
+}
+
+/*
+Paginate Query the page of the keyset pagination by gorm, the rows are sorted by the columns of the key struct K
+in the declaration order, so the last column must be unique such as the primary key.
+The column is the cursor tag of the field, default the column name of the naming strategy.
+
+	type UserKey struct {
+	    CreatedAt time.Time `json:"c" cursor:"created_at"`
+	    ID        int64     `json:"i"`
+	}
+
+	page, err := cursor.Paginate(db.Where("tenant_id = ?", tenant), codec, cursor.Query{Cursor: c, Limit: 20, Desc: true},
+	    func(u User) UserKey { return UserKey{CreatedAt: u.CreatedAt, ID: u.ID} })
+	if errors.Is(err, cursor.ErrInvalidCursor) {
+	    resp.DirectBadRequest(ctx, "无效的游标")
+	}
+*/
+func Paginate[T, K any](db *gorm.DB, c *Codec, q Query, key func(T) K) (resp.CursorPage[T], error) {
+	kt := reflect.TypeOf((*K)(nil)).Elem()
+	if kt.Kind() != reflect.Struct || kt.NumField() == 0 {
+		return resp.CursorPage[T]{}, errors.New("cursor: the key must be the struct of the sort columns")
+	}
+	var columns []string
+	for i := 0; i < kt.NumField(); i++ {
+		f := kt.Field(i)
+		column := f.Tag.Get("cursor")
+		if column == "" {
+			column = db.NamingStrategy.ColumnName("", f.Name)
+		}
+		columns = append(columns, db.Statement.Quote(column))
+	}
+	order, op := " ASC", ">"
+	if q.Desc {
+		order, op = " DESC", "<"
+	}
+	tx := db.Session(&gorm.Session{})
+	if q.Cursor != "" {
+		var k K
+		if err := c.Decode(q.Cursor, &k); err != nil {
+			return resp.CursorPage[T]{}, err
+		}
+		kv := reflect.ValueOf(k)
+		args := make([]any, kt.NumField())
+		placeholders := make([]string, kt.NumField())
+		for i := range args {
+			args[i], placeholders[i] = kv.Field(i).Interface(), "?"
+		}
+		// the row value comparison keeps the sort stable across the pages with the same leading column
+		tx = tx.Where("("+strings.Join(columns, ", ")+") "+op+" ("+strings.Join(placeholders, ", ")+")", args...)
+	}
+	for _, column := range columns {
+		tx = tx.Order(column + order)
+	}
+	if q.Limit > 0 {
+		tx = tx.Limit(q.Limit + 1)
+	}
+	var items []T
+	if err := tx.Find(&items).Error; err != nil {
+		return resp.CursorPage[T]{}, err
+	}
+	return NewPage(c, items, q.Limit, key)
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// Config the datasource
+type Config struct {
+	Driver          string        `mapstructure:"driver"`             // Driver of the database, mysql or the driver registered by RegisterDriver(), default mysql
+	DSN             string        `mapstructure:"dsn"`                // Data source name, such as user:pass@tcp(127.0.0.1:3306)/db?charset=utf8mb4&parseTime=True&loc=Local, empty means no datasource
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`     // Max open connections, default 100
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`     // Max idle connections, default 10
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`  // Max lifetime of the connection, default 1h
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"` // Max idle time of the connection, default 10m
+	SlowThreshold   time.Duration `mapstructure:"slow_threshold"`     // The sql slower than it is logged as the warning, default 200ms, negative means disabled
+	LogLevel        string        `mapstructure:"log_level"`          // silent, error, warn or info logging all the sql, default warn
+}
+
+// Dialector the function creating the gorm dialector from the dsn
+type Dialector func(dsn string) gorm.Dialector
+
+var (
+	mu      sync.RWMutex
+	drivers = map[string]Dialector{"mysql": mysql.Open}
+	// Default the datasource created from the datasource configuration when the application starts, nil when no dsn
+	Default *gorm.DB
+)
+
+// RegisterDriver Register the dialector of the driver, such as postgres.Open of gorm.io/driver/postgres
+func RegisterDriver(name string, dialector Dialector) {
+	mu.Lock()
+	defer mu.Unlock()
+	drivers[name] = dialector
+}
+
+// Open the datasource and configure its connection pool, the sql is logged by the application logger
+func Open(conf Config, opts ...gorm.Option) (*gorm.DB, error) {
+	if conf.Driver == "" {
+		conf.Driver = "mysql"
+	}
+	if conf.MaxOpenConns <= 0 {
+		conf.MaxOpenConns = 100
+	}
+	if conf.MaxIdleConns <= 0 {
+		conf.MaxIdleConns = 10
+	}
+	if conf.ConnMaxLifetime <= 0 {
+		conf.ConnMaxLifetime = time.Hour
+	}
+	if conf.ConnMaxIdleTime <= 0 {
+		conf.ConnMaxIdleTime = 10 * time.Minute
+	}
+	if conf.SlowThreshold == 0 {
+		conf.SlowThreshold = 200 * time.Millisecond
+	}
+	mu.RLock()
+	dialector, ok := drivers[conf.Driver]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown datasource driver %s, register it by db.RegisterDriver()", conf.Driver)
+	}
+	opts = append([]gorm.Option{&gorm.Config{Logger: newLogger(conf)}}, opts...)
+	db, err := gorm.Open(dialector(conf.DSN), opts...)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(conf.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(conf.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
+	return db, nil
+}
+
+// Ping the default datasource, it's the health indicator of the datasource
+func Ping(ctx context.Context) error {
+	if Default == nil {
+		return errors.New("no datasource")
+	}
+	sqlDB, err := Default.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close the connection pool of the default datasource
+func Close() error {
+	if Default == nil {
+		return nil
+	}
+	sqlDB, err := Default.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
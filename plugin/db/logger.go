@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"strings"
+	"time"
+)
+
+// sqlLogger logs the sql of gorm by the application logger, the record not found error is not logged
+type sqlLogger struct {
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+func newLogger(conf Config) gormlogger.Interface {
+	level := gormlogger.Warn
+	switch strings.ToLower(conf.LogLevel) {
+	case "silent":
+		level = gormlogger.Silent
+	case "error":
+		level = gormlogger.Error
+	case "info":
+		level = gormlogger.Info
+	}
+	return &sqlLogger{level: level, slowThreshold: conf.SlowThreshold}
+}
+
+func (l *sqlLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	c := *l
+	c.level = level
+	return &c
+}
+
+func (l *sqlLogger) Info(_ context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Info {
+		logger.Log.Infof(msg, args...)
+	}
+}
+
+func (l *sqlLogger) Warn(_ context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Warn {
+		logger.Log.Warnf(msg, args...)
+	}
+}
+
+func (l *sqlLogger) Error(_ context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Error {
+		logger.Log.Errorf(msg, args...)
+	}
+}
+
+func (l *sqlLogger) Trace(_ context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin).Round(time.Microsecond)
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		logger.Log.Errorf("SQL error [%s] [rows:%d] %s, %s", elapsed, rows, sql, err.Error())
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		logger.Log.Warnf("Slow SQL >= %s [%s] [rows:%d] %s", l.slowThreshold, elapsed, rows, sql)
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		logger.Log.Infof("SQL [%s] [rows:%d] %s", elapsed, rows, sql)
+	}
+}
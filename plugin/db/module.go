@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/dependency"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+/*
+Module the application module opening Default from the datasource configuration, the *gorm.DB is set as the bean and
+added to the dependency checks, the connection pool is closed when the application stops.
+
+	application.Default().Modules(db.NewModule()).Run()
+*/
+type Module struct {
+	conf Config
+	opts []gorm.Option
+}
+
+// NewModule Create the datasource module, the options are applied after the logger of the application
+func NewModule(opts ...gorm.Option) *Module {
+	return &Module{opts: opts}
+}
+
+func (m *Module) Name() string {
+	return "datasource"
+}
+
+// Configure Read the datasource configuration
+func (m *Module) Configure(conf *viper.Viper) error {
+	if err := conf.UnmarshalKey("datasource", &m.conf); err != nil {
+		return err
+	}
+	if m.conf.DSN == "" {
+		return errors.New("datasource.dsn is required")
+	}
+	dependency.AddIndicator("datasource", true, Ping)
+	return nil
+}
+
+// Init Open the datasource
+func (m *Module) Init(context.Context) error {
+	db, err := Open(m.conf, m.opts...)
+	if err != nil {
+		return err
+	}
+	Default = db
+	mvc.SetBeans(db)
+	return nil
+}
+
+func (m *Module) Status() string {
+	if Default == nil {
+		return ""
+	}
+	return Default.Dialector.Name()
+}
+
+// Close the connection pool
+func (m *Module) Close() error {
+	return Close()
+}
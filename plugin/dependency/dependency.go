@@ -0,0 +1,248 @@
+package dependency
+
+import (
+	"context"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/breaker"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config the scheduled pings of the dependencies
+type Config struct {
+	Interval         time.Duration `mapstructure:"interval"`          // Interval of the pings, default 30s
+	Timeout          time.Duration `mapstructure:"timeout"`           // Timeout of each ping, default 5s
+	FailureThreshold int           `mapstructure:"failure_threshold"` // Consecutive failures to mark the dependency down, default 3
+	FailFast         bool          `mapstructure:"fail_fast"`         // Trip the circuit breaker of the host of the down dependency until it's up, default false
+	Path             string        `mapstructure:"path"`              // Endpoint exposing the status of the dependencies as json, default empty means not exposed
+	Endpoints        []Endpoint    `mapstructure:"endpoints"`         // Dependencies to ping
+}
+
+// Endpoint the dependency endpoint
+type Endpoint struct {
+	Name     string                          `mapstructure:"name"`     // Name of the dependency, such as user-service
+	URL      string                          `mapstructure:"url"`      // Url of the ping, such as http://user-service:4006/health
+	Method   string                          `mapstructure:"method"`   // Request method, default GET
+	Status   int                             `mapstructure:"status"`   // Expected http status, default any 2xx
+	Header   map[string]string               `mapstructure:"header"`   // Request headers
+	Interval time.Duration                   `mapstructure:"interval"` // Interval of the pings, default the global interval
+	Critical bool                            `mapstructure:"critical"` // Whether the application is unhealthy when the dependency is down
+	Ping     func(ctx context.Context) error `mapstructure:"-"`        // Ping instead of the http request, such as the database, it's set by AddIndicator()
+}
+
+// the health indicators of the plugins, they're pinged by the monitor with the endpoints
+var indicators []Endpoint
+
+// AddIndicator Add the health indicator pinged with the dependency endpoints, such as the database connection pool.
+// It should be added before the monitor is created
+func AddIndicator(name string, critical bool, ping func(ctx context.Context) error) {
+	indicators = append(indicators, Endpoint{Name: name, Critical: critical, Ping: ping})
+}
+
+// HasIndicators Whether any health indicator is added
+func HasIndicators() bool {
+	return len(indicators) > 0
+}
+
+// Status the status of the dependency
+type Status struct {
+	Name      string        `json:"name"`
+	URL       string        `json:"url"`
+	Up        bool          `json:"up"`
+	Critical  bool          `json:"critical"`
+	Failures  int           `json:"failures"` // Consecutive failures
+	Latency   time.Duration `json:"latency"`
+	LastCheck time.Time     `json:"last_check"`
+	LastError string        `json:"last_error,omitempty"`
+	Since     time.Time     `json:"since"` // When the dependency became up or down
+}
+
+// Monitor pings the dependencies on the schedule
+type Monitor struct {
+	conf   Config
+	client *http.Client
+	mu     sync.RWMutex
+	status map[string]*Status
+	// name of the dependency -> circuit breaker of the host of its url, tripped while the dependency is down
+	breakers map[string]*breaker.Breaker
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// Default the monitor created from the dependencies configuration when the application starts
+var Default *Monitor
+
+// NewMonitor Create the monitor, the dependencies are considered up until the pings fail
+func NewMonitor(conf Config) *Monitor {
+	if conf.Interval <= 0 {
+		conf.Interval = 30 * time.Second
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 5 * time.Second
+	}
+	if conf.FailureThreshold <= 0 {
+		conf.FailureThreshold = 3
+	}
+	conf.Endpoints = append(append([]Endpoint(nil), conf.Endpoints...), indicators...)
+	m := &Monitor{
+		conf:     conf,
+		client:   &http.Client{Timeout: conf.Timeout},
+		status:   make(map[string]*Status),
+		breakers: make(map[string]*breaker.Breaker),
+	}
+	now := time.Now()
+	for _, e := range conf.Endpoints {
+		m.status[e.Name] = &Status{Name: e.Name, URL: e.URL, Up: true, Critical: e.Critical, Since: now}
+		if u, err := url.Parse(e.URL); conf.FailFast && err == nil && u.Host != "" {
+			m.breakers[e.Name] = breaker.Get(u.Host)
+		}
+	}
+	return m
+}
+
+// Start pinging the dependencies, each dependency is pinged immediately and then on its interval
+func (m *Monitor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	for _, e := range m.conf.Endpoints {
+		interval := e.Interval
+		if interval <= 0 {
+			interval = m.conf.Interval
+		}
+		m.wg.Add(1)
+		go func(e Endpoint) {
+			defer m.wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				m.check(ctx, e)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(e)
+	}
+}
+
+// Close Stop pinging the dependencies
+func (m *Monitor) Close() error {
+	if m.cancel != nil {
+		m.cancel()
+		m.wg.Wait()
+	}
+	return nil
+}
+
+func (m *Monitor) check(ctx context.Context, e Endpoint) {
+	start := time.Now()
+	err := m.ping(ctx, e)
+	if ctx.Err() != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.status[e.Name]
+	s.LastCheck, s.Latency = start, time.Since(start)
+	if err == nil {
+		if !s.Up {
+			logger.Log.Infof("Dependency [%s] is up", e.Name)
+			s.Up, s.Since = true, start
+			if b, ok := m.breakers[e.Name]; ok {
+				b.Reset()
+			}
+		}
+		s.Failures, s.LastError = 0, ""
+		return
+	}
+	s.Failures++
+	s.LastError = err.Error()
+	if s.Up && s.Failures >= m.conf.FailureThreshold {
+		logger.Log.Warnf("Dependency [%s] is down, %s", e.Name, err.Error())
+		s.Up, s.Since = false, start
+		if b, ok := m.breakers[e.Name]; ok {
+			b.Trip()
+		}
+	}
+}
+
+func (m *Monitor) ping(ctx context.Context, e Endpoint) error {
+	if e.Ping != nil {
+		ctx, cancelFunc := context.WithTimeout(ctx, m.conf.Timeout)
+		defer cancelFunc()
+		return e.Ping(ctx)
+	}
+	method := e.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, e.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range e.Header {
+		req.Header.Set(k, v)
+	}
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	_ = res.Body.Close()
+	if (e.Status > 0 && res.StatusCode != e.Status) || (e.Status == 0 && res.StatusCode/100 != 2) {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Available Whether the dependency is up, the unknown dependency is considered available
+func (m *Monitor) Available(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.status[name]
+	return !ok || s.Up
+}
+
+// Healthy Whether all the critical dependencies are up
+func (m *Monitor) Healthy() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, s := range m.status {
+		if s.Critical && !s.Up {
+			return false
+		}
+	}
+	return true
+}
+
+// Status Get the status of the dependencies sorted by the name
+func (m *Monitor) Status() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]Status, 0, len(m.status))
+	for _, s := range m.status {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Handler Respond the status of the dependencies as json, the http status is 503 when any critical dependency is down
+func (m *Monitor) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		status := http.StatusOK
+		if !m.Healthy() {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.JSON(status, gin.H{"healthy": status == http.StatusOK, "dependencies": m.Status()})
+	}
+}
@@ -0,0 +1,109 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/discovery"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config the consul agent
+type Config struct {
+	Addr            string        `mapstructure:"addr"`             // Address of the agent, default http://127.0.0.1:8500
+	Token           string        `mapstructure:"token"`            // Acl token
+	DeregisterAfter time.Duration `mapstructure:"deregister_after"` // The critical instance is deregistered after it by the agent, default 1m
+}
+
+// Registry registers the instances by the agent http api, the ttl check is passed by the heartbeat and the health url
+// is checked by the agent
+type Registry struct {
+	conf   Config
+	client *http.Client
+}
+
+// New Create the consul registry
+func New(conf Config) *Registry {
+	if conf.Addr == "" {
+		conf.Addr = "http://127.0.0.1:8500"
+	}
+	if !strings.Contains(conf.Addr, "://") {
+		conf.Addr = "http://" + conf.Addr
+	}
+	if conf.DeregisterAfter <= 0 {
+		conf.DeregisterAfter = time.Minute
+	}
+	return &Registry{conf: conf, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type check struct {
+	CheckID                        string `json:"CheckID,omitempty"`
+	TTL                            string `json:"TTL,omitempty"`
+	HTTP                           string `json:"HTTP,omitempty"`
+	Interval                       string `json:"Interval,omitempty"`
+	Timeout                        string `json:"Timeout,omitempty"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter"`
+}
+
+// the id of the ttl check of the instance
+func checkId(inst *discovery.Instance) string {
+	return "service:" + inst.Id
+}
+
+func (r *Registry) Register(ctx context.Context, inst *discovery.Instance, ttl time.Duration) error {
+	deregisterAfter := r.conf.DeregisterAfter.String()
+	checks := []check{{CheckID: checkId(inst), TTL: ttl.String(), DeregisterCriticalServiceAfter: deregisterAfter}}
+	if inst.HealthURL != "" {
+		checks = append(checks, check{HTTP: inst.HealthURL, Interval: "10s", Timeout: "5s", DeregisterCriticalServiceAfter: deregisterAfter})
+	}
+	body, _ := json.Marshal(map[string]any{
+		"ID":      inst.Id,
+		"Name":    inst.Name,
+		"Address": inst.Host,
+		"Port":    inst.Port,
+		"Meta":    inst.Metadata,
+		"Checks":  checks,
+	})
+	if err := r.do(ctx, "/v1/agent/service/register", body); err != nil {
+		return err
+	}
+	// the ttl check is critical until it's passed
+	return r.Heartbeat(ctx, inst)
+}
+
+func (r *Registry) Heartbeat(ctx context.Context, inst *discovery.Instance) error {
+	return r.do(ctx, "/v1/agent/check/pass/"+url.PathEscape(checkId(inst)), nil)
+}
+
+func (r *Registry) Deregister(ctx context.Context, inst *discovery.Instance) error {
+	return r.do(ctx, "/v1/agent/service/deregister/"+url.PathEscape(inst.Id), nil)
+}
+
+// send the put request, the unknown check means the instance was deregistered by the agent
+func (r *Registry) do(ctx context.Context, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, r.conf.Addr+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if r.conf.Token != "" {
+		req.Header.Set("X-Consul-Token", r.conf.Token)
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if res.StatusCode == http.StatusNotFound || strings.Contains(string(msg), "Unknown check") {
+		return discovery.ErrNotRegistered
+	}
+	return fmt.Errorf("consul %s responded %d, %s", path, res.StatusCode, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,220 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/buildinfo"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"net"
+	"path"
+	"sync"
+	"time"
+)
+
+// ErrNotRegistered Returned by the heartbeat when the registry lost the instance, such as the ttl expired, the instance
+// is registered again
+var ErrNotRegistered = errors.New("discovery: the instance is not registered")
+
+// Config the registration of the running instance
+type Config struct {
+	Type       string            `mapstructure:"type"`        // Registry type, consul, nacos or etcd, default empty means not registered
+	Name       string            `mapstructure:"name"`        // Service name, default the last element of the main module path
+	Host       string            `mapstructure:"host"`        // Advertised host, default the pod ip, then the first non-loopback ipv4 address
+	Port       int               `mapstructure:"port"`        // Advertised port, default the server port
+	Metadata   map[string]string `mapstructure:"metadata"`    // Metadata of the instance, the version of the build is added
+	HealthPath string            `mapstructure:"health_path"` // Path of the health check url checked by the registry, default empty means the heartbeat only
+	TTL        time.Duration     `mapstructure:"ttl"`         // The instance expires without the heartbeat, default 30s
+	Heartbeat  time.Duration     `mapstructure:"heartbeat"`   // Interval of the heartbeat, default 1/3 of the ttl
+}
+
+// Instance the registered instance
+type Instance struct {
+	Id        string            `json:"id"`
+	Name      string            `json:"name"`
+	Host      string            `json:"host"`
+	Port      int               `json:"port"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	HealthURL string            `json:"health_url,omitempty"`
+}
+
+// Addr the host and the port of the instance
+func (i *Instance) Addr() string {
+	return net.JoinHostPort(i.Host, fmt.Sprint(i.Port))
+}
+
+// Registry the service registry, such as consul
+type Registry interface {
+	// Register the instance expiring after the ttl without the heartbeat
+	Register(ctx context.Context, inst *Instance, ttl time.Duration) error
+	// Heartbeat Renew the ttl of the instance, ErrNotRegistered means the instance should be registered again
+	Heartbeat(ctx context.Context, inst *Instance) error
+	// Deregister the instance
+	Deregister(ctx context.Context, inst *Instance) error
+}
+
+// Registrar registers the running instance and keeps it alive by the heartbeat
+type Registrar struct {
+	conf       Config
+	registry   Registry
+	inst       *Instance
+	mu         sync.Mutex
+	registered bool
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// Default the registrar of the application, the instance is registered once the application is serving and deregistered on PreStop
+var Default = &Registrar{}
+
+// SetConfig Set the configuration, it should be set before the instance is registered
+func (r *Registrar) SetConfig(conf Config) {
+	if conf.TTL <= 0 {
+		conf.TTL = 30 * time.Second
+	}
+	if conf.Heartbeat <= 0 {
+		conf.Heartbeat = conf.TTL / 3
+	}
+	r.conf = conf
+}
+
+// SetRegistry Set the registry, the instance isn't registered without it
+func (r *Registrar) SetRegistry(registry Registry) {
+	r.registry = registry
+}
+
+// Enabled Whether the registry is set
+func (r *Registrar) Enabled() bool {
+	return r.registry != nil
+}
+
+// Instance Get the registered instance, nil before it's registered
+func (r *Registrar) Instance() *Instance {
+	return r.inst
+}
+
+// the instance of the configuration
+func (r *Registrar) instance() (*Instance, error) {
+	inst := &Instance{Name: r.conf.Name, Host: r.conf.Host, Port: r.conf.Port, Metadata: make(map[string]string)}
+	if inst.Name == "" {
+		if module := buildinfo.Get().Module; module != "" {
+			inst.Name = path.Base(module)
+		}
+	}
+	if inst.Name == "" {
+		return nil, errors.New("the service name is required")
+	}
+	if inst.Host == "" {
+		inst.Host = localIP()
+	}
+	if inst.Host == "" || inst.Port <= 0 {
+		return nil, errors.New("the host and the port are required")
+	}
+	inst.Id = fmt.Sprintf("%s-%s-%d", inst.Name, inst.Host, inst.Port)
+	for k, v := range r.conf.Metadata {
+		inst.Metadata[k] = v
+	}
+	if version := buildinfo.Get().Version; version != "" {
+		inst.Metadata["version"] = version
+	}
+	if r.conf.HealthPath != "" {
+		inst.HealthURL = "http://" + inst.Addr() + r.conf.HealthPath
+	}
+	return inst, nil
+}
+
+/*
+Register the instance and start the heartbeat. The failed registration is logged and retried by the heartbeat, so the
+application starts when the registry is temporarily unavailable
+*/
+func (r *Registrar) Register(ctx context.Context) error {
+	if r.registry == nil {
+		return nil
+	}
+	inst, err := r.instance()
+	if err != nil {
+		return err
+	}
+	r.inst = inst
+	if err = r.register(ctx); err != nil {
+		logger.Log.Errorf("Register instance %s error, retry after %s, %s", inst.Id, r.conf.Heartbeat, err.Error())
+	}
+	loopCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go r.keepalive(loopCtx)
+	return nil
+}
+
+func (r *Registrar) register(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.registry.Register(ctx, r.inst, r.conf.TTL); err != nil {
+		return err
+	}
+	r.registered = true
+	logger.Log.Debugf("Instance %s is registered at %s", r.inst.Id, r.inst.Addr())
+	return nil
+}
+
+// renew the ttl on the schedule, the instance lost by the registry is registered again
+func (r *Registrar) keepalive(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.conf.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		beatCtx, cancel := context.WithTimeout(ctx, r.conf.Heartbeat)
+		err := ErrNotRegistered
+		r.mu.Lock()
+		if r.registered {
+			err = r.registry.Heartbeat(beatCtx, r.inst)
+		}
+		r.mu.Unlock()
+		if errors.Is(err, ErrNotRegistered) {
+			err = r.register(beatCtx)
+		}
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			logger.Log.Warnf("Heartbeat of instance %s error, %s", r.inst.Id, err.Error())
+		}
+	}
+}
+
+// Deregister Stop the heartbeat and deregister the instance, it's deregistered once
+func (r *Registrar) Deregister(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	r.wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.registered {
+		return nil
+	}
+	r.registered = false
+	if err := r.registry.Deregister(ctx, r.inst); err != nil {
+		return err
+	}
+	logger.Log.Debugf("Instance %s is deregistered", r.inst.Id)
+	return nil
+}
+
+// the first non-loopback ipv4 address
+func localIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/discovery"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config the etcd cluster
+type Config struct {
+	Endpoints []string `mapstructure:"endpoints"` // Endpoints of the grpc gateway, default http://127.0.0.1:2379
+	Prefix    string   `mapstructure:"prefix"`    // Key prefix of the instances, the key is <prefix><name>/<id>, default /services/
+	Username  string   `mapstructure:"username"`  // Username when the auth is enabled
+	Password  string   `mapstructure:"password"`  // Password when the auth is enabled
+}
+
+/*
+Registry registers the instances by the json api of the grpc gateway, the instance is put as json with the lease of the
+ttl and the lease is kept alive by the heartbeat:
+
+	/services/order-service/order-service-10.0.0.5-4006 -> {"id":"order-service-10.0.0.5-4006","name":"order-service","host":"10.0.0.5","port":4006}
+*/
+type Registry struct {
+	conf   Config
+	client *http.Client
+	mu     sync.Mutex
+	lease  string
+	token  string
+}
+
+// New Create the etcd registry
+func New(conf Config) *Registry {
+	if len(conf.Endpoints) == 0 {
+		conf.Endpoints = []string{"http://127.0.0.1:2379"}
+	}
+	for i, e := range conf.Endpoints {
+		if !strings.Contains(e, "://") {
+			conf.Endpoints[i] = "http://" + e
+		}
+	}
+	if conf.Prefix == "" {
+		conf.Prefix = "/services/"
+	}
+	return &Registry{conf: conf, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Key the key of the instance
+func (r *Registry) Key(inst *discovery.Instance) string {
+	return r.conf.Prefix + inst.Name + "/" + inst.Id
+}
+
+func (r *Registry) Register(ctx context.Context, inst *discovery.Instance, ttl time.Duration) error {
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	if err := r.call(ctx, "/v3/lease/grant", map[string]any{"TTL": int64(max(ttl, time.Second) / time.Second)}, &grant); err != nil {
+		return err
+	}
+	value, _ := json.Marshal(inst)
+	put := map[string]any{
+		"key":   base64.StdEncoding.EncodeToString([]byte(r.Key(inst))),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": grant.ID,
+	}
+	if err := r.call(ctx, "/v3/kv/put", put, nil); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.lease = grant.ID
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Registry) Heartbeat(ctx context.Context, _ *discovery.Instance) error {
+	r.mu.Lock()
+	lease := r.lease
+	r.mu.Unlock()
+	var res struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	if err := r.call(ctx, "/v3/lease/keepalive", map[string]any{"ID": lease}, &res); err != nil {
+		return err
+	}
+	// the expired lease is kept alive with no ttl
+	if res.Result.TTL == "" || res.Result.TTL == "0" {
+		return discovery.ErrNotRegistered
+	}
+	return nil
+}
+
+func (r *Registry) Deregister(ctx context.Context, _ *discovery.Instance) error {
+	r.mu.Lock()
+	lease := r.lease
+	r.lease = ""
+	r.mu.Unlock()
+	if lease == "" {
+		return nil
+	}
+	// the key is deleted with the lease
+	return r.call(ctx, "/v3/lease/revoke", map[string]any{"ID": lease}, nil)
+}
+
+// call the api on the endpoints in order until one responds, the token is renewed when it's invalid
+func (r *Registry) call(ctx context.Context, path string, body any, out any) error {
+	payload, _ := json.Marshal(body)
+	var errs []error
+	for _, endpoint := range r.conf.Endpoints {
+		err := r.post(ctx, endpoint, path, payload, out, true)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) post(ctx context.Context, endpoint, path string, payload []byte, out any, retry bool) error {
+	token, err := r.authenticate(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		if retry && token != "" && strings.Contains(string(body), "invalid auth token") {
+			r.mu.Lock()
+			r.token = ""
+			r.mu.Unlock()
+			return r.post(ctx, endpoint, path, payload, out, false)
+		}
+		return fmt.Errorf("etcd %s responded %d, %s", path, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// the auth token when the auth is enabled
+func (r *Registry) authenticate(ctx context.Context, endpoint string) (string, error) {
+	if r.conf.Username == "" {
+		return "", nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token != "" {
+		return r.token, nil
+	}
+	payload, _ := json.Marshal(map[string]string{"name": r.conf.Username, "password": r.conf.Password})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v3/auth/authenticate", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("etcd authenticate responded %d", res.StatusCode)
+	}
+	if err = json.NewDecoder(res.Body).Decode(&auth); err != nil {
+		return "", err
+	}
+	r.token = auth.Token
+	return r.token, nil
+}
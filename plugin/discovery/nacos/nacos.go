@@ -0,0 +1,178 @@
+package nacos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/discovery"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config the nacos server
+type Config struct {
+	Addr      string `mapstructure:"addr"`      // Address of the server, default http://127.0.0.1:8848
+	Namespace string `mapstructure:"namespace"` // Namespace id, default public
+	Group     string `mapstructure:"group"`     // Group of the service, default DEFAULT_GROUP
+	Cluster   string `mapstructure:"cluster"`   // Cluster of the instance, default DEFAULT
+	Username  string `mapstructure:"username"`  // Username when the auth is enabled
+	Password  string `mapstructure:"password"`  // Password when the auth is enabled
+}
+
+// Registry registers the ephemeral instances by the open api, the instance is removed by the server when the beats stop
+type Registry struct {
+	conf    Config
+	client  *http.Client
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// New Create the nacos registry
+func New(conf Config) *Registry {
+	if conf.Addr == "" {
+		conf.Addr = "http://127.0.0.1:8848"
+	}
+	if !strings.Contains(conf.Addr, "://") {
+		conf.Addr = "http://" + conf.Addr
+	}
+	if conf.Group == "" {
+		conf.Group = "DEFAULT_GROUP"
+	}
+	if conf.Cluster == "" {
+		conf.Cluster = "DEFAULT"
+	}
+	return &Registry{conf: conf, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// the parameters identifying the instance
+func (r *Registry) params(inst *discovery.Instance) url.Values {
+	params := url.Values{}
+	params.Set("serviceName", inst.Name)
+	params.Set("groupName", r.conf.Group)
+	params.Set("clusterName", r.conf.Cluster)
+	params.Set("ip", inst.Host)
+	params.Set("port", strconv.Itoa(inst.Port))
+	params.Set("ephemeral", "true")
+	if r.conf.Namespace != "" {
+		params.Set("namespaceId", r.conf.Namespace)
+	}
+	return params
+}
+
+func (r *Registry) Register(ctx context.Context, inst *discovery.Instance, _ time.Duration) error {
+	params := r.params(inst)
+	metadata := inst.Metadata
+	if inst.HealthURL != "" {
+		metadata = make(map[string]string, len(inst.Metadata)+1)
+		for k, v := range inst.Metadata {
+			metadata[k] = v
+		}
+		metadata["health-check-url"] = inst.HealthURL
+	}
+	b, _ := json.Marshal(metadata)
+	params.Set("metadata", string(b))
+	params.Set("weight", "1")
+	params.Set("healthy", "true")
+	params.Set("enabled", "true")
+	_, err := r.do(ctx, http.MethodPost, "/nacos/v1/ns/instance", params)
+	return err
+}
+
+func (r *Registry) Heartbeat(ctx context.Context, inst *discovery.Instance) error {
+	params := r.params(inst)
+	beat, _ := json.Marshal(map[string]any{
+		"serviceName": r.conf.Group + "@@" + inst.Name,
+		"ip":          inst.Host,
+		"port":        inst.Port,
+		"cluster":     r.conf.Cluster,
+		"metadata":    inst.Metadata,
+		"scheduled":   true,
+	})
+	params.Set("beat", string(beat))
+	body, err := r.do(ctx, http.MethodPut, "/nacos/v1/ns/instance/beat", params)
+	if err != nil {
+		return err
+	}
+	var res struct {
+		Code int `json:"code"`
+	}
+	// 20404 means the instance was removed by the server
+	if json.Unmarshal(body, &res) == nil && res.Code == 20404 {
+		return discovery.ErrNotRegistered
+	}
+	return nil
+}
+
+func (r *Registry) Deregister(ctx context.Context, inst *discovery.Instance) error {
+	_, err := r.do(ctx, http.MethodDelete, "/nacos/v1/ns/instance", r.params(inst))
+	return err
+}
+
+// the access token when the auth is enabled, it's renewed before it expires
+func (r *Registry) accessToken(ctx context.Context) (string, error) {
+	if r.conf.Username == "" {
+		return "", nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token != "" && time.Now().Before(r.expires) {
+		return r.token, nil
+	}
+	form := url.Values{"username": {r.conf.Username}, "password": {r.conf.Password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.conf.Addr+"/nacos/v1/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := r.send(req)
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		AccessToken string `json:"accessToken"`
+		TokenTtl    int64  `json:"tokenTtl"`
+	}
+	if err = json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	r.token = res.AccessToken
+	r.expires = time.Now().Add(time.Duration(res.TokenTtl)*time.Second - time.Minute)
+	return r.token, nil
+}
+
+func (r *Registry) do(ctx context.Context, method, path string, params url.Values) ([]byte, error) {
+	token, err := r.accessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("nacos login error, %w", err)
+	}
+	if token != "" {
+		params.Set("accessToken", token)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.conf.Addr+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.send(req)
+}
+
+func (r *Registry) send(req *http.Request) ([]byte, error) {
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nacos %s responded %d, %s", req.URL.Path, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
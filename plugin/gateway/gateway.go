@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Route the proxy route, forward the matched requests to the upstream
+type Route struct {
+	Path          string            `mapstructure:"path"`           // Route path, gin pattern is supported, such as /order/*path
+	Methods       []string          `mapstructure:"methods"`        // Request methods, empty means all methods
+	Upstream      string            `mapstructure:"upstream"`       // Upstream url, such as http://order-svc:8080
+	StripPrefix   string            `mapstructure:"strip_prefix"`   // Prefix removed from the request path before forwarding
+	Timeout       time.Duration     `mapstructure:"timeout"`        // Response header timeout of the upstream, default 0 means no timeout
+	SetHeaders    map[string]string `mapstructure:"set_headers"`    // Headers set to the forwarded request
+	RemoveHeaders []string          `mapstructure:"remove_headers"` // Headers removed from the forwarded request
+	Auth          struct {
+		Type     string `mapstructure:"type"`     // Injected authorization type, supports bearer and basic
+		Token    string `mapstructure:"token"`    // Token of the bearer authorization
+		Username string `mapstructure:"username"` // Username of the basic authorization
+		Password string `mapstructure:"password"` // Password of the basic authorization
+	} `mapstructure:"auth"`
+}
+
+// Mount the proxy routes to the gin engine
+func Mount(e *gin.Engine, routes []Route) {
+	for _, r := range routes {
+		handler := newHandler(r)
+		if len(r.Methods) == 0 {
+			e.Any(r.Path, handler)
+		} else {
+			for _, m := range r.Methods {
+				e.Handle(strings.ToUpper(m), r.Path, handler)
+			}
+		}
+		logger.Log.Debugf("Gateway route [%s] -> [%s]", r.Path, r.Upstream)
+	}
+}
+
+func newHandler(r Route) gin.HandlerFunc {
+	target, err := url.Parse(r.Upstream)
+	if err != nil {
+		logger.Log.Fatalf("invalid upstream of the gateway route [%s], %s", r.Path, err.Error())
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = r.Timeout
+	proxy.Transport = transport
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		if r.StripPrefix != "" {
+			req.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, r.StripPrefix), "/")
+			req.URL.RawPath = ""
+		}
+		director(req)
+		req.Host = target.Host
+		for _, h := range r.RemoveHeaders {
+			req.Header.Del(h)
+		}
+		for k, v := range r.SetHeaders {
+			req.Header.Set(k, v)
+		}
+		switch strings.ToLower(r.Auth.Type) {
+		case "bearer":
+			req.Header.Set("Authorization", "Bearer "+r.Auth.Token)
+		case "basic":
+			req.SetBasicAuth(r.Auth.Username, r.Auth.Password)
+		}
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		logger.Log.Errorf("Gateway route [%s] forward error, %s", r.Path, err.Error())
+		ctx := req.Context().Value(ginContextKey{}).(*gin.Context)
+		resp.InitResp(ctx).WithBasic(resp.SystemErrorCode, "服务暂不可用", nil).To(http.StatusBadGateway)
+	}
+	return func(ctx *gin.Context) {
+		proxy.ServeHTTP(ctx.Writer, ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), ginContextKey{}, ctx)))
+	}
+}
+
+// the key of the gin context in the forwarded request context
+type ginContextKey struct{}
@@ -0,0 +1,111 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	gqlgen "github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"time"
+)
+
+// Config the graphql endpoint
+type Config struct {
+	Path            string `mapstructure:"path"`             // Path of the endpoint, default /graphql
+	Playground      bool   `mapstructure:"playground"`       // Serve the playground, the introspection is enabled with it, default false
+	PlaygroundPath  string `mapstructure:"playground_path"`  // Path of the playground, default /playground
+	Introspection   bool   `mapstructure:"introspection"`    // Allow the introspection queries, default false
+	ComplexityLimit int    `mapstructure:"complexity_limit"` // Max complexity of the queries, default 0 means unlimited
+}
+
+// Schema the executable schema generated by gqlgen, such as generated.NewExecutableSchema(generated.Config{Resolvers: r})
+type Schema = gqlgen.ExecutableSchema
+
+type ginContextKey struct{}
+
+// GinContext Get the gin context of the request in the resolvers, nil when the request isn't served by the endpoint
+func GinContext(ctx context.Context) *gin.Context {
+	c, _ := ctx.Value(ginContextKey{}).(*gin.Context)
+	return c
+}
+
+/*
+Mount the endpoint of the schema and the playground. The errors returned or panicked by the resolvers are mapped the same
+as the api methods, the message is the localized message and the extensions contain the business code and the http
+status, the unknown errors are logged and described as the server error:
+
+	{"errors": [{"message": "订单已关闭", "path": ["payOrder"], "extensions": {"code": 40901, "status": 409}}]}
+*/
+func Mount(r gin.IRouter, conf Config, schema Schema) {
+	if conf.Path == "" {
+		conf.Path = "/graphql"
+	}
+	if conf.PlaygroundPath == "" {
+		conf.PlaygroundPath = "/playground"
+	}
+	srv := handler.New(schema)
+	srv.AddTransport(transport.Websocket{KeepAlivePingInterval: 10 * time.Second})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+	srv.SetQueryCache(lru.New(1000))
+	if conf.Introspection || conf.Playground {
+		srv.Use(extension.Introspection{})
+	}
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New(100)})
+	if conf.ComplexityLimit > 0 {
+		srv.Use(extension.FixedComplexityLimit(conf.ComplexityLimit))
+	}
+	srv.SetRecoverFunc(recoverResolver)
+	srv.SetErrorPresenter(presentError)
+	serve := func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), ginContextKey{}, ctx))
+		srv.ServeHTTP(ctx.Writer, ctx.Request)
+	}
+	r.GET(conf.Path, serve)
+	r.POST(conf.Path, serve)
+	r.OPTIONS(conf.Path, serve)
+	if conf.Playground {
+		r.GET(conf.PlaygroundPath, gin.WrapF(playground.Handler("GraphQL", conf.Path)))
+	}
+}
+
+// the panic is mapped by the error presenter like the returned error, it's presented in the deferred recover, so the
+// unknown one is logged with the stack of the panic
+func recoverResolver(_ context.Context, r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("resolver panic, %v", r)
+}
+
+// map the errors of the resolvers, the errors of the parsing and the validation are kept
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := gqlgen.DefaultErrorPresenter(ctx, err)
+	ginCtx := GinContext(ctx)
+	if gqlErr.Err == nil || ginCtx == nil {
+		return gqlErr
+	}
+	ex, known := resp.ErrorOf(ginCtx, gqlErr.Err)
+	if !known {
+		exception.PrintStack(gqlErr.Err)
+	}
+	gqlErr.Message = ex.Msg
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = make(map[string]any)
+	}
+	gqlErr.Extensions["code"] = ex.Code
+	gqlErr.Extensions["status"] = ex.Status
+	if ex.Data != nil {
+		gqlErr.Extensions["data"] = ex.Data
+	}
+	return gqlErr
+}
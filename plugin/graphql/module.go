@@ -0,0 +1,55 @@
+package graphql
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+/*
+Module the application module mounting the schema generated by gqlgen, the resolvers are injected like the controllers
+before the schema serves the requests:
+
+	resolver := &graph.Resolver{}
+	application.Default().Modules(graphql.NewModule(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}), resolver)).Run()
+*/
+type Module struct {
+	conf      Config
+	schema    Schema
+	resolvers []any
+}
+
+// NewModule Create the graphql module of the schema and its resolvers
+func NewModule(schema Schema, resolvers ...any) *Module {
+	return &Module{schema: schema, resolvers: resolvers}
+}
+
+func (m *Module) Name() string {
+	return "graphql"
+}
+
+// Configure Read the graphql configuration
+func (m *Module) Configure(conf *viper.Viper) error {
+	return conf.UnmarshalKey("graphql", &m.conf)
+}
+
+func (m *Module) Init(context.Context) error {
+	return nil
+}
+
+// Beans the resolvers injected like the controllers
+func (m *Module) Beans() []any {
+	return m.resolvers
+}
+
+// Mount the endpoint of the schema and the playground
+func (m *Module) Mount(e *gin.Engine) {
+	Mount(e, m.conf, m.schema)
+}
+
+func (m *Module) Status() string {
+	if m.conf.Path == "" {
+		return "/graphql"
+	}
+	return m.conf.Path
+}
@@ -0,0 +1,120 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"net"
+	"time"
+)
+
+// Config the grpc server
+type Config struct {
+	Port           int           `mapstructure:"port"`              // Port of the grpc server, default 0 means sharing the http port, the grpc requests are recognized by the content type
+	Reflection     bool          `mapstructure:"reflection"`        // Register the reflection service, such as for grpcurl, default false
+	MaxRecvMsgSize int           `mapstructure:"max_recv_msg_size"` // Max size of the received message in bytes, default 4MB
+	MaxSendMsgSize int           `mapstructure:"max_send_msg_size"` // Max size of the sent message in bytes, default unlimited
+	MatchTimeout   time.Duration `mapstructure:"match_timeout"`     // Timeout of reading the first bytes on the shared port to recognize the protocol, default 10s
+}
+
+/*
+Service the grpc service implementation, it's injected like the controllers and registered on the server:
+
+	type GreeterService struct {
+	    pb.UnimplementedGreeterServer
+	    UserService *service.UserService
+	}
+
+	func (g *GreeterService) RegisterService(s grpc.ServiceRegistrar) {
+	    pb.RegisterGreeterServer(s, g)
+	}
+*/
+type Service interface {
+	RegisterService(s grpc.ServiceRegistrar)
+}
+
+// Interceptor the bean intercepting the unary calls, it's discovered like the method interceptors and runs after the
+// logging and the recovery. The mvc.MethodInterceptor and mvc.CompletionInterceptor depend on the gin context, they
+// don't intercept the grpc calls, implement Interceptor for the logic shared by both protocols
+type Interceptor interface {
+	Intercept(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error)
+}
+
+// Server the grpc server hosted by the application with the health service
+type Server struct {
+	*grpc.Server
+	health *health.Server
+}
+
+// New Create the grpc server with the interceptors, the recovery and the logging run first
+func New(conf Config, interceptors []Interceptor, opts ...grpc.ServerOption) *Server {
+	unary := []grpc.UnaryServerInterceptor{unaryLogging, unaryRecovery}
+	for _, i := range interceptors {
+		unary = append(unary, i.Intercept)
+	}
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(streamLogging, streamRecovery),
+	}, opts...)
+	if conf.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(conf.MaxRecvMsgSize))
+	}
+	if conf.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(conf.MaxSendMsgSize))
+	}
+	s := &Server{Server: grpc.NewServer(opts...), health: health.NewServer()}
+	healthpb.RegisterHealthServer(s.Server, s.health)
+	if conf.Reflection {
+		reflection.Register(s.Server)
+	}
+	// not serving until the application started
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return s
+}
+
+// Register the services
+func (s *Server) Register(services ...Service) {
+	for _, svc := range services {
+		svc.RegisterService(s.Server)
+	}
+}
+
+// SetServing Set the status of the health service, it's not serving while the application is starting or stopping
+func (s *Server) SetServing(serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	for name := range s.GetServiceInfo() {
+		s.health.SetServingStatus(name, status)
+	}
+	s.health.SetServingStatus("", status)
+}
+
+// Serve the listener until the server is stopped
+func (s *Server) Serve(ln net.Listener) error {
+	if err := s.Server.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Stop Wait for the running calls until the context is done, then the calls still running are canceled
+func (s *Server) Stop(ctx context.Context) error {
+	s.health.Shutdown()
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Server.Stop()
+		return errors.New("grpc calls are aborted")
+	}
+}
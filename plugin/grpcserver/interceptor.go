@@ -0,0 +1,60 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+	"time"
+)
+
+// log the failed calls at the error level and the others at the debug level
+func logCall(method string, begin time.Time, err error) {
+	code := status.Code(err)
+	latency := time.Since(begin).Round(time.Microsecond)
+	switch code {
+	case codes.OK:
+		logger.Log.Debugf("[gRPC] %s %s %s", method, code, latency)
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		logger.Log.Errorf("[gRPC] %s %s %s, %s", method, code, latency, err.Error())
+	default:
+		logger.Log.Warnf("[gRPC] %s %s %s, %s", method, code, latency, err.Error())
+	}
+}
+
+func unaryLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	begin := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(info.FullMethod, begin, err)
+	return resp, err
+}
+
+func streamLogging(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	begin := time.Now()
+	err := handler(srv, ss)
+	logCall(info.FullMethod, begin, err)
+	return err
+}
+
+// the panic is logged with the stack and responded as the internal error
+func unaryRecovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Errorf("[gRPC] %s panic, %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func streamRecovery(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Errorf("[gRPC] %s panic, %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(srv, ss)
+}
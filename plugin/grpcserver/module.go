@@ -0,0 +1,169 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/soheilhy/cmux"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"net"
+	"time"
+)
+
+/*
+Module the application module hosting the grpc services alongside the http server, the grpc server starts and stops
+with the http server. The services are injected like the controllers, the beans implementing Interceptor intercept the
+unary calls. The grpc server shares the http port by default, set grpc.port to listen on a separate port.
+
+	application.Default().Modules(grpcserver.NewModule(&GreeterService{})).Run()
+*/
+type Module struct {
+	conf         Config
+	httpPort     int
+	services     []Service
+	options      []grpc.ServerOption
+	interceptors []Interceptor
+	server       *Server
+	mux          cmux.CMux
+}
+
+// NewModule Create the grpc module of the services
+func NewModule(services ...Service) *Module {
+	return &Module{services: services}
+}
+
+// Options Add the options of the grpc server, such as the credentials and the keepalive
+func (m *Module) Options(opts ...grpc.ServerOption) *Module {
+	m.options = append(m.options, opts...)
+	return m
+}
+
+// Interceptors Add the interceptors of the unary calls, the beans implementing Interceptor are added when it serves
+func (m *Module) Interceptors(interceptors ...Interceptor) *Module {
+	m.interceptors = append(m.interceptors, interceptors...)
+	return m
+}
+
+func (m *Module) Name() string {
+	return "grpc"
+}
+
+// Configure Read the grpc configuration
+func (m *Module) Configure(conf *viper.Viper) error {
+	m.httpPort = conf.GetInt("server.port")
+	if err := conf.UnmarshalKey("grpc", &m.conf); err != nil {
+		return err
+	}
+	if m.conf.MatchTimeout <= 0 {
+		m.conf.MatchTimeout = 10 * time.Second
+	}
+	return nil
+}
+
+func (m *Module) Init(context.Context) error {
+	return nil
+}
+
+// Beans the services injected like the controllers
+func (m *Module) Beans() []any {
+	beans := make([]any, len(m.services))
+	for i, s := range m.services {
+		beans[i] = s
+	}
+	return beans
+}
+
+func (m *Module) Status() string {
+	if m.conf.Port > 0 {
+		return fmt.Sprintf("port %d, %d services", m.conf.Port, len(m.services))
+	}
+	return fmt.Sprintf("shared port %d, %d services", m.httpPort, len(m.services))
+}
+
+// Serve the grpc server, the grpc requests are recognized by the content type on the shared port
+func (m *Module) Serve(ln net.Listener) (net.Listener, error) {
+	m.server = New(m.conf, m.discoverInterceptors(), m.options...)
+	m.server.Register(m.services...)
+	grpcLn, httpLn := ln, ln
+	if m.conf.Port > 0 {
+		var err error
+		if grpcLn, err = net.Listen("tcp", fmt.Sprintf(":%d", m.conf.Port)); err != nil {
+			return nil, err
+		}
+	} else {
+		m.mux = cmux.New(ln)
+		// the connection sending nothing is closed instead of holding the matcher
+		m.mux.SetReadTimeout(m.conf.MatchTimeout)
+		// the grpc clients wait for the settings frame before sending the headers
+		grpcLn = m.mux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+		httpLn = &muxListener{m.mux.Match(cmux.Any())}
+		go func() {
+			if err := m.mux.Serve(); err != nil && !isClosed(err) {
+				logger.Log.Errorf("Connection multiplexer error, %s", err.Error())
+			}
+		}()
+	}
+	go func() {
+		if err := m.server.Serve(grpcLn); err != nil && !isClosed(err) {
+			logger.Log.Fatalf("gRPC server start error, %s", err.Error())
+		}
+	}()
+	return httpLn, nil
+}
+
+// the added interceptors followed by the beans implementing Interceptor
+func (m *Module) discoverInterceptors() []Interceptor {
+	interceptors := append([]Interceptor(nil), m.interceptors...)
+	added := make(map[Interceptor]bool)
+	for _, i := range interceptors {
+		added[i] = true
+	}
+	for _, bean := range mvc.Beans() {
+		if i, ok := bean.(Interceptor); ok && !added[i] {
+			added[i] = true
+			interceptors = append(interceptors, i)
+			logger.Log.Debugf("gRPC interceptor %T is discovered", bean)
+		}
+	}
+	return interceptors
+}
+
+// SetServing Set the status of the health service
+func (m *Module) SetServing(serving bool) {
+	if m.server != nil {
+		m.server.SetServing(serving)
+	}
+}
+
+// Stop the grpc server and wait for the running calls until the context is done
+func (m *Module) Stop(ctx context.Context) error {
+	if m.server == nil {
+		return nil
+	}
+	err := m.server.Stop(ctx)
+	if m.mux != nil {
+		m.mux.Close()
+	}
+	return err
+}
+
+// muxListener reports the closed multiplexer as the closed listener, so the http server treats it as closed
+type muxListener struct {
+	net.Listener
+}
+
+func (l *muxListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil && isClosed(err) {
+		return nil, net.ErrClosed
+	}
+	return conn, err
+}
+
+// whether the error is caused by closing the listener
+func isClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, cmux.ErrListenerClosed) || errors.Is(err, cmux.ErrServerClosed)
+}
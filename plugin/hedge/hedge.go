@@ -0,0 +1,82 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoReplica returned when no replica is given
+var ErrNoReplica = errors.New("hedge: no replica")
+
+// Read reads from a replica, it should return as soon as the context is canceled
+type Read[T any] func(ctx context.Context) (T, error)
+
+/*
+Do the hedged read, only for idempotent reads.
+The first replica is read at once, when it doesn't return within the delay, the next replica is raced, and so on.
+A failed read triggers the next replica immediately. Returns the first success and cancels the others,
+or the last error when all replicas fail.
+
+	user, err := hedge.Do(ctx.Request.Context(), 50*time.Millisecond,
+	    func(c context.Context) (*User, error) { return primary.GetUser(c, id) },
+	    func(c context.Context) (*User, error) { return replica.GetUser(c, id) },
+	)
+*/
+func Do[T any](ctx context.Context, delay time.Duration, replicas ...Read[T]) (T, error) {
+	var zero T
+	if len(replicas) == 0 {
+		return zero, ErrNoReplica
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		val T
+		err error
+	}
+	results := make(chan result, len(replicas))
+	launch := func(r Read[T]) {
+		go func() {
+			v, err := r(ctx)
+			results <- result{v, err}
+		}()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	launch(replicas[0])
+	next, pending := 1, 1
+	var lastErr error
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.val, nil
+			}
+			lastErr = r.err
+			if next < len(replicas) {
+				launch(replicas[next])
+				next++
+				pending++
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(delay)
+			} else if pending == 0 {
+				return zero, lastErr
+			}
+		case <-timer.C:
+			if next < len(replicas) {
+				launch(replicas[next])
+				next++
+				pending++
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	Default = New(Config{}) // Default outbound http client, created from the http_client configuration when the application starts
+)
+
+// Config the outbound http client configuration
+type Config struct {
+	Timeout               time.Duration         `mapstructure:"timeout"`                 // Request timeout, default 0 means no timeout
+	DialTimeout           time.Duration         `mapstructure:"dial_timeout"`            // Dial timeout, default 5s
+	KeepAlive             time.Duration         `mapstructure:"keep_alive"`              // TCP keep alive period, default 30s
+	FallbackDelay         time.Duration         `mapstructure:"fallback_delay"`          // Happy Eyeballs delay before racing the next address, default 300ms, negative disables it
+	DNSCacheTTL           time.Duration         `mapstructure:"dns_cache_ttl"`           // TTL of the resolved addresses, default 0 means no cache
+	MaxIdleConns          int                   `mapstructure:"max_idle_conns"`          // Maximum idle connections of all hosts, default 100
+	MaxIdleConnsPerHost   int                   `mapstructure:"max_idle_conns_per_host"` // Maximum idle connections of each host, default 10
+	MaxConnsPerHost       int                   `mapstructure:"max_conns_per_host"`      // Maximum connections of each host, default 0 means no limit
+	IdleConnTimeout       time.Duration         `mapstructure:"idle_conn_timeout"`       // Idle connection timeout, default 90s
+	TLSHandshakeTimeout   time.Duration         `mapstructure:"tls_handshake_timeout"`   // TLS handshake timeout, default 10s
+	TLSSessionCacheSize   int                   `mapstructure:"tls_session_cache_size"`  // Cached TLS sessions for resumption, default 0 means no resumption
+	InsecureSkipVerify    bool                  `mapstructure:"insecure_skip_verify"`    // Skip the verification of the server certificate, default false
+	ResponseHeaderTimeout time.Duration         `mapstructure:"response_header_timeout"` // Response header timeout, default 0 means no timeout
+	Hosts                 map[string]HostConfig `mapstructure:"hosts"`                   // Connection pool of specific hosts, the key is host or host:port
+	Log                   LogConfig             `mapstructure:"log"`                     // Outbound request logging
+	Retry                 RetryConfig           `mapstructure:"retry"`                   // Retry of the idempotent requests
+}
+
+// HostConfig the connection pool of a specific host
+type HostConfig struct {
+	MaxIdleConnsPerHost int `mapstructure:"max_idle_conns_per_host"` // Maximum idle connections of the host
+	MaxConnsPerHost     int `mapstructure:"max_conns_per_host"`      // Maximum connections of the host
+}
+
+// New Create an outbound http client
+func New(conf Config) *http.Client {
+	setDefaults(&conf)
+	rt := http.RoundTripper(newTransport(conf, conf.MaxIdleConnsPerHost, conf.MaxConnsPerHost))
+	if len(conf.Hosts) > 0 {
+		hosts := make(map[string]http.RoundTripper, len(conf.Hosts))
+		for host, hc := range conf.Hosts {
+			hosts[host] = newTransport(conf, hc.MaxIdleConnsPerHost, hc.MaxConnsPerHost)
+		}
+		rt = &hostTransport{hosts: hosts, fallback: rt}
+	}
+	rt = &callTransport{next: rt, log: conf.Log, retry: conf.Retry}
+	return &http.Client{Transport: rt, Timeout: conf.Timeout}
+}
+
+func setDefaults(conf *Config) {
+	if conf.DialTimeout == 0 {
+		conf.DialTimeout = 5 * time.Second
+	}
+	if conf.KeepAlive == 0 {
+		conf.KeepAlive = 30 * time.Second
+	}
+	if conf.FallbackDelay == 0 {
+		conf.FallbackDelay = 300 * time.Millisecond
+	}
+	if conf.MaxIdleConns == 0 {
+		conf.MaxIdleConns = 100
+	}
+	if conf.MaxIdleConnsPerHost == 0 {
+		conf.MaxIdleConnsPerHost = 10
+	}
+	if conf.IdleConnTimeout == 0 {
+		conf.IdleConnTimeout = 90 * time.Second
+	}
+	if conf.TLSHandshakeTimeout == 0 {
+		conf.TLSHandshakeTimeout = 10 * time.Second
+	}
+	if conf.Log.SampleRate == 0 {
+		conf.Log.SampleRate = 1
+	}
+	if conf.Log.MaxBodySize == 0 {
+		conf.Log.MaxBodySize = 1024
+	}
+	if conf.Log.TraceHeader == "" {
+		conf.Log.TraceHeader = "X-Trace-Id"
+	}
+	if conf.Retry.Backoff == 0 {
+		conf.Retry.Backoff = 100 * time.Millisecond
+	}
+}
+
+func newTransport(conf Config, maxIdleConnsPerHost, maxConnsPerHost int) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:       conf.DialTimeout,
+		KeepAlive:     conf.KeepAlive,
+		FallbackDelay: conf.FallbackDelay,
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: conf.InsecureSkipVerify}
+	if conf.TLSSessionCacheSize > 0 {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(conf.TLSSessionCacheSize)
+	}
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          conf.MaxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		MaxConnsPerHost:       maxConnsPerHost,
+		IdleConnTimeout:       conf.IdleConnTimeout,
+		TLSHandshakeTimeout:   conf.TLSHandshakeTimeout,
+		TLSClientConfig:       tlsConfig,
+		ResponseHeaderTimeout: conf.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	if conf.DNSCacheTTL > 0 {
+		t.DialContext = newCachedDialer(dialer, conf.DNSCacheTTL).DialContext
+	}
+	return t
+}
+
+// hostTransport routes the requests to the transport of the host
+type hostTransport struct {
+	hosts    map[string]http.RoundTripper
+	fallback http.RoundTripper
+}
+
+func (h *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt, ok := h.hosts[req.URL.Host]; ok {
+		return rt.RoundTrip(req)
+	}
+	if rt, ok := h.hosts[req.URL.Hostname()]; ok {
+		return rt.RoundTrip(req)
+	}
+	return h.fallback.RoundTrip(req)
+}
@@ -0,0 +1,164 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// cachedDialer caches the resolved addresses, and races them in the manner of Happy Eyeballs
+type cachedDialer struct {
+	dialer *net.Dialer
+	ttl    time.Duration
+	mu     sync.RWMutex
+	cache  map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs    []string
+	expireAt time.Time
+}
+
+func newCachedDialer(dialer *net.Dialer, ttl time.Duration) *cachedDialer {
+	return &cachedDialer{dialer: dialer, ttl: ttl, cache: make(map[string]dnsEntry)}
+}
+
+func (c *cachedDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, address)
+	}
+	addrs, err := c.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.race(ctx, network, addrs, port)
+	if err != nil {
+		// the cached addresses may be stale
+		c.mu.Lock()
+		delete(c.cache, host)
+		c.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (c *cachedDialer) resolve(ctx context.Context, host string) ([]string, error) {
+	c.mu.RLock()
+	entry, ok := c.cache[host]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expireAt) {
+		return entry.addrs, nil
+	}
+	resolver := c.dialer.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := interleave(ips)
+	c.mu.Lock()
+	c.cache[host] = dnsEntry{addrs: addrs, expireAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// interleave the IPv6 and IPv4 addresses, IPv6 first
+func interleave(ips []net.IPAddr) []string {
+	var v6, v4 []string
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			v4 = append(v4, ip.String())
+		} else {
+			v6 = append(v6, ip.String())
+		}
+	}
+	addrs := make([]string, 0, len(ips))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			addrs = append(addrs, v6[i])
+		}
+		if i < len(v4) {
+			addrs = append(addrs, v4[i])
+		}
+	}
+	return addrs
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+func (d dialResult) close() {
+	if d.conn != nil {
+		_ = d.conn.Close()
+	}
+}
+
+// race dial the addresses, the next address is dialed after the fallback delay or the previous one fails.
+// The first established connection wins, the others are closed.
+func (c *cachedDialer) race(ctx context.Context, network string, addrs []string, port string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dialResult, len(addrs))
+	dial := func(addr string) {
+		go func() {
+			conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			results <- dialResult{conn, err}
+		}()
+	}
+	delay := c.dialer.FallbackDelay
+	if delay < 0 {
+		delay = c.dialer.Timeout
+	}
+	next, pending := 0, 0
+	var lastErr error
+	for {
+		if pending == 0 {
+			if next >= len(addrs) {
+				if lastErr == nil {
+					lastErr = errors.New("no address to dial")
+				}
+				return nil, lastErr
+			}
+			dial(addrs[next])
+			next++
+			pending++
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case r := <-results:
+			timer.Stop()
+			pending--
+			if r.err == nil {
+				go closeLate(results, pending)
+				return r.conn, nil
+			}
+			lastErr = r.err
+		case <-timer.C:
+			if next < len(addrs) {
+				dial(addrs[next])
+				next++
+				pending++
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			go closeLate(results, pending)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// close the connections established after the race is over
+func closeLate(results <-chan dialResult, n int) {
+	for i := 0; i < n; i++ {
+		(<-results).close()
+	}
+}
@@ -0,0 +1,214 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/plugin/metrics"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	durationOnce    sync.Once
+	requestDuration *metrics.Histogram
+)
+
+// LatencyBuckets the buckets of the outbound request latency in seconds
+var LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// LogConfig the outbound request logging configuration
+type LogConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`       // Log the outbound requests, default false
+	SampleRate  float64 `mapstructure:"sample_rate"`   // Sample rate of the successful requests within [0, 1], default 1. The failed requests are always logged
+	CaptureBody bool    `mapstructure:"capture_body"`  // Log the request and response bodies, default false
+	MaxBodySize int     `mapstructure:"max_body_size"` // Maximum logged body bytes, default 1024
+	TraceHeader string  `mapstructure:"trace_header"`  // Header to propagate the trace id, default X-Trace-Id
+}
+
+// RetryConfig the retry configuration, only the idempotent requests are retried
+type RetryConfig struct {
+	Max     int           `mapstructure:"max"`     // Maximum retries, default 0 means no retry
+	Backoff time.Duration `mapstructure:"backoff"` // Backoff between the retries, doubled on each retry, default 100ms
+}
+
+type traceKey struct{}
+
+// WithTrace Attach the trace id to the context, the outbound requests with the context carry it
+func WithTrace(ctx context.Context, traceId string) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceId)
+}
+
+// TraceId Get the trace id of the context. The *gin.Context can be used directly, its "trace_id" key is read
+func TraceId(ctx context.Context) string {
+	if v, ok := ctx.Value(traceKey{}).(string); ok {
+		return v
+	}
+	v, _ := ctx.Value("trace_id").(string)
+	return v
+}
+
+// callTransport propagates the trace id, retries the idempotent requests and logs every outbound call
+type callTransport struct {
+	next  http.RoundTripper
+	log   LogConfig
+	retry RetryConfig
+}
+
+func (c *callTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	traceId := TraceId(req.Context())
+	if traceId != "" && req.Header.Get(c.log.TraceHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(c.log.TraceHeader, traceId)
+	}
+	var reqBody []byte
+	if c.log.CaptureBody {
+		reqBody = peekBody(req, c.log.MaxBodySize)
+	}
+	start := time.Now()
+	res, retries, err := c.do(req)
+	latency := time.Since(start)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(res.StatusCode)
+	}
+	durationOnce.Do(func() {
+		requestDuration = metrics.NewHistogram("http_client_request_duration_seconds",
+			"Latency of the outbound http requests.", LatencyBuckets, "host", "method", "status")
+	})
+	requestDuration.Observe(latency.Seconds(), req.URL.Host, req.Method, status)
+	failed := err != nil || res.StatusCode >= http.StatusInternalServerError
+	if !c.log.Enabled || (!failed && rand.Float64() >= c.log.SampleRate) {
+		return res, err
+	}
+	entry := &callLog{traceId: traceId, req: req, reqBody: reqBody, latency: latency, retries: retries, status: status, err: err, failed: failed}
+	if err == nil && c.log.CaptureBody {
+		res.Body = &capturedBody{ReadCloser: res.Body, max: c.log.MaxBodySize, entry: entry}
+		return res, nil
+	}
+	entry.write()
+	return res, err
+}
+
+func (c *callTransport) do(req *http.Request) (*http.Response, int, error) {
+	backoff := c.retry.Backoff
+	for retries := 0; ; retries++ {
+		res, err := c.next.RoundTrip(req)
+		if retries >= c.retry.Max || !retryable(req, res, err) {
+			return res, retries, err
+		}
+		if res != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4<<10))
+			_ = res.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, retries, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, retries, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func retryable(req *http.Request, res *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// read the request body from GetBody, the body itself is left untouched
+func peekBody(req *http.Request, max int) []byte {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	b, _ := io.ReadAll(io.LimitReader(body, int64(max)))
+	return b
+}
+
+type callLog struct {
+	traceId string
+	req     *http.Request
+	reqBody []byte
+	resBody []byte
+	latency time.Duration
+	retries int
+	status  string
+	err     error
+	failed  bool
+}
+
+func (l *callLog) write() {
+	var buf bytes.Buffer
+	buf.WriteString("[http-client] trace_id=" + l.traceId + " " + l.req.Method + " " + l.req.URL.Redacted() +
+		" status=" + l.status + " latency=" + l.latency.String() + " retries=" + strconv.Itoa(l.retries))
+	if l.err != nil {
+		buf.WriteString(" error=" + l.err.Error())
+	}
+	if l.reqBody != nil {
+		buf.WriteString(" request_body=" + strconv.Quote(string(l.reqBody)))
+	}
+	if l.resBody != nil {
+		buf.WriteString(" response_body=" + strconv.Quote(string(l.resBody)))
+	}
+	if l.failed {
+		logger.Log.Warn(buf.String())
+		return
+	}
+	logger.Log.Info(buf.String())
+}
+
+// capturedBody keeps the head of the response body, the call is logged when the body is closed
+type capturedBody struct {
+	io.ReadCloser
+	max   int
+	buf   []byte
+	entry *callLog
+	done  bool
+}
+
+func (c *capturedBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if rest := c.max - len(c.buf); rest > 0 {
+		c.buf = append(c.buf, p[:min(n, rest)]...)
+	}
+	return n, err
+}
+
+func (c *capturedBody) Close() error {
+	if !c.done {
+		c.done = true
+		c.entry.resBody = c.buf
+		c.entry.write()
+	}
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,319 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/auth"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+IdempotentAnnotation Declares the api method requires the idempotency key, the retries with the same key
+replay the saved response instead of calling the api method again. The ttl is optional, default the configuration.
+
+	// CreatePayment
+	// @POST(path="/payment") create payment
+	// @Idempotent(ttl="48h")
+	func (p *PaymentController) CreatePayment(ctx *gin.Context) {}
+*/
+const IdempotentAnnotation = "Idempotent"
+
+// ReplayedHeader the response header declares the response is replayed from the saved one
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Config the idempotency of the unsafe requests
+type Config struct {
+	Enabled     bool          `mapstructure:"enabled"`       // Whether to honor the idempotency key of the requests of the paths, default false. @Idempotent works regardless
+	Header      string        `mapstructure:"header"`        // Header of the idempotency key, default Idempotency-Key
+	Methods     []string      `mapstructure:"methods"`       // Methods honoring the key, default POST and PATCH
+	Paths       []string      `mapstructure:"paths"`         // Ant-style patterns of the route templates, default all routes
+	Required    bool          `mapstructure:"required"`      // Reject the requests of the paths without the key, default false
+	TTL         time.Duration `mapstructure:"ttl"`           // How long the responses are replayed, default 24h
+	LockTTL     time.Duration `mapstructure:"lock_ttl"`      // How long the key is locked by the processing request, default 1m. It must be longer than the timeouts of the routes
+	MaxBodySize int           `mapstructure:"max_body_size"` // Max bytes of the saved response body, the larger response isn't replayed, default 1MB
+	MaxRequest  int64         `mapstructure:"max_request"`   // Max bytes of the request body read for the fingerprint, the larger request is responded with 413, default 10MB
+	Store       StoreConfig   `mapstructure:"store"`         // Storage of the records, default memory
+}
+
+// ScopeResolver resolve the scope of the idempotency keys, the same key of different scopes belongs to different
+// requests. The tenant and the subject of the current principal as default, empty means the key is shared
+var ScopeResolver func(ctx *gin.Context) string = principalScope
+
+func principalScope(ctx *gin.Context) string {
+	if p, ok := auth.Current(ctx); ok {
+		return p.Tenant + "/" + p.Subject
+	}
+	return ""
+}
+
+// the context key of the request handled by the middleware
+const handledKey = "gin-plus/idempotency"
+
+// configuration of @Idempotent
+var defaults = withDefaults(Config{})
+
+func init() {
+	mvc.RegisterOrderedAnnotationHandler(IdempotentAnnotation, mvc.CacheOrder, idempotentHandler)
+}
+
+// SetConfig Set the configuration of @Idempotent
+func SetConfig(conf Config) {
+	defaults = withDefaults(conf)
+}
+
+func withDefaults(conf Config) Config {
+	if conf.Header == "" {
+		conf.Header = "Idempotency-Key"
+	}
+	if len(conf.Methods) == 0 {
+		conf.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = 24 * time.Hour
+	}
+	if conf.LockTTL <= 0 {
+		conf.LockTTL = time.Minute
+	}
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = 1 << 20
+	}
+	if conf.MaxRequest <= 0 {
+		conf.MaxRequest = 10 << 20
+	}
+	return conf
+}
+
+func idempotentHandler(val string) gin.HandlerFunc {
+	conf := defaults
+	conf.Required, conf.Paths = true, nil
+	if v := strings.Trim(mvc.ParseAnnotationArgs(val)["ttl"], `"`); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Log.Fatalf("invalid ttl of @%s(%s), %s", IdempotentAnnotation, val, err.Error())
+		}
+		conf.TTL = ttl
+	}
+	return Middleware(conf)
+}
+
+/*
+Middleware The gin middleware honors the idempotency key of the unsafe requests, the keys are scoped by ScopeResolver.
+The first request of the key is handled and its response is saved, the retries with the same key replay the saved
+status, headers and body within the ttl. The retries while the first request is processing are responded with http
+status 409 until the lock ttl elapses, so the lock ttl should be longer than the timeout of the routes, see CheckLockTTL.
+The lock is owned by the request acquiring it, the request whose lock is acquired by another one after it expires
+neither saves nor releases the key. The key reused by a different request is responded with http status 422.
+The requests failed with http status 5xx or the panics release the key, so they can be retried, so do the requests
+rejected as unauthenticated, forbidden or rate limited, so they can't take the key of its owner. It should run after
+the authentication, so the keys are scoped by the principal. The response body exceeding the max size isn't saved,
+its retries are responded with http status 409 instead of handling the request again.
+*/
+func Middleware(conf Config) gin.HandlerFunc {
+	conf = withDefaults(conf)
+	var paths func(ctx *gin.Context) bool
+	if len(conf.Paths) > 0 {
+		paths = mvc.PathPredicate(conf.Paths...)
+	}
+	return func(ctx *gin.Context) {
+		if ctx.GetBool(handledKey) || !matchMethod(conf.Methods, ctx.Request.Method) || (paths != nil && !paths(ctx)) {
+			ctx.Next()
+			return
+		}
+		key := ctx.GetHeader(conf.Header)
+		if key == "" {
+			if conf.Required {
+				resp.DirectBadRequest(ctx, "缺少请求头 %s", conf.Header)
+				ctx.Abort()
+				return
+			}
+			ctx.Next()
+			return
+		}
+		if len(key) > 255 {
+			resp.DirectBadRequest(ctx, "请求头 %s 过长", conf.Header)
+			ctx.Abort()
+			return
+		}
+		ctx.Set(handledKey, true)
+		fingerprint, err := fingerprintOf(ctx, conf.MaxRequest)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				resp.InitResp(ctx).WithBasic(resp.BadRequestCode, "请求体过大", nil).To(http.StatusRequestEntityTooLarge)
+			} else {
+				resp.DirectBadRequest(ctx, "读取请求体失败")
+			}
+			ctx.Abort()
+			return
+		}
+		storeKey := ctx.Request.Method + ":" + ctx.Request.URL.Path + ":" + ScopeResolver(ctx) + ":" + key
+		exist, token, err := Store.Acquire(storeKey, fingerprint, conf.LockTTL)
+		if err != nil {
+			logger.Log.Errorf("Acquire idempotency key error, %s", err.Error())
+			resp.ServiceUnavailable(ctx, 0)
+			ctx.Abort()
+			return
+		}
+		if exist != nil {
+			replay(ctx, exist, fingerprint)
+			ctx.Abort()
+			return
+		}
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer, limit: conf.MaxBodySize}
+		ctx.Writer = recorder
+		completed := false
+		defer func() {
+			ctx.Writer = recorder.ResponseWriter
+			if !completed || recorder.Status() >= http.StatusInternalServerError || rejected(ctx, recorder.Status()) {
+				if err := Store.Release(storeKey, token); err != nil {
+					logger.Log.Warnf("Release idempotency key error, %s", err.Error())
+				}
+				return
+			}
+			record := &Record{Fingerprint: fingerprint, Done: true, Status: recorder.Status(), Header: replayHeader(recorder.Header())}
+			if recorder.overflow {
+				record.Oversized = true
+				logger.Log.Warnf("The response of idempotency key %s exceeds %d bytes, it's not replayed", key, conf.MaxBodySize)
+			} else {
+				record.Body = recorder.body.Bytes()
+			}
+			if err := Store.Save(storeKey, token, record, conf.TTL); errors.Is(err, ErrLockLost) {
+				logger.Log.Warnf("The lock of idempotency key %s expired after %s while processing and is acquired by another request, "+
+					"increase the lock ttl", key, conf.LockTTL)
+			} else if err != nil {
+				logger.Log.Warnf("Save idempotency record error, %s", err.Error())
+			}
+		}()
+		ctx.Next()
+		completed = true
+	}
+}
+
+// respond the saved response of the key
+func replay(ctx *gin.Context, record *Record, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		resp.InitResp(ctx).WithBasic(resp.BadRequestCode, "幂等键已被其他请求使用", nil).To(http.StatusUnprocessableEntity)
+		return
+	}
+	if !record.Done {
+		resp.Conflict(ctx, time.Second)
+		return
+	}
+	ctx.Header(ReplayedHeader, "true")
+	if record.Oversized {
+		resp.InitResp(ctx).WithBasic(resp.ConflictCode, "请求已处理，响应过大无法回放", nil).To(http.StatusConflict)
+		return
+	}
+	for k, v := range record.Header {
+		ctx.Writer.Header()[k] = v
+	}
+	ctx.Data(record.Status, record.Header.Get("Content-Type"), record.Body)
+}
+
+// the headers of the saved response, the ones of the connection and the length are set by the server again
+func replayHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range []string{"Connection", "Content-Length", "Date", "Transfer-Encoding", ReplayedHeader} {
+		h.Del(k)
+	}
+	return h
+}
+
+// the request is rejected as unauthenticated, forbidden or rate limited, by the http status or the business code
+func rejected(ctx *gin.Context, status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	switch ctx.GetInt("bcode") {
+	case resp.NonLoginCode, resp.TokenExpiredCode, resp.ForbiddenCode, resp.TooManyRequestsCode:
+		return true
+	}
+	return false
+}
+
+func matchMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// the hash of the method, path, query and body of the request up to the max bytes, the body is still readable by the
+// handlers
+func fingerprintOf(ctx *gin.Context, max int64) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(ctx.Request.Method + " " + ctx.Request.URL.RequestURI() + "\n"))
+	if ctx.Request.Body != nil && ctx.Request.Body != http.NoBody {
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, max))
+		if err != nil {
+			return "", err
+		}
+		_ = ctx.Request.Body.Close()
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// responseRecorder records the response body up to the limit while writing it to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (r *responseRecorder) record(b []byte) {
+	if r.overflow {
+		return
+	}
+	if r.body.Len()+len(b) > r.limit {
+		r.overflow = true
+		r.body = bytes.Buffer{}
+		return
+	}
+	r.body.Write(b)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.record(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.record([]byte(s))
+	return r.ResponseWriter.WriteString(s)
+}
+
+// CheckLockTTL Check the lock ttl covers the timeouts of the routes mounted by Apply which honor the idempotency key,
+// otherwise the retries acquire the key again and run the request twice while the first one is processing
+func CheckLockTTL(conf Config, timeouts mvc.TimeoutConfig) error {
+	conf = withDefaults(conf)
+	paths := func(string) bool { return true }
+	if len(conf.Paths) > 0 {
+		paths = mvc.PathMatcher(conf.Paths...)
+	}
+	for _, r := range mvc.Routes() {
+		_, annotated := mvc.RouteAnnotations(r.Method, r.Path)[IdempotentAnnotation]
+		if !annotated && (!conf.Enabled || !matchMethod(conf.Methods, r.Method) || !paths(r.Path)) {
+			continue
+		}
+		if d := mvc.TimeoutOf(timeouts, r.Method, r.Path); d > conf.LockTTL {
+			return fmt.Errorf("idempotency: the lock ttl %s is shorter than the timeout %s of [%s %s]", conf.LockTTL, d, r.Method, r.Path)
+		}
+	}
+	return nil
+}
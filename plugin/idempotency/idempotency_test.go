@@ -0,0 +1,107 @@
+package idempotency
+
+import (
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEngine(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger.Log = &logger.DefaultLog{}
+	Store = NewMemoryStore()
+	e := gin.New()
+	e.Use(Middleware(Config{}))
+	e.POST("/payment", handler)
+	return e
+}
+
+func post(e *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/payment", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestReplayAndConflict(t *testing.T) {
+	runs := 0
+	e := newEngine(func(ctx *gin.Context) {
+		runs++
+		ctx.Header("X-Payment-Id", "p1")
+		ctx.String(http.StatusCreated, "created")
+	})
+	first := post(e, "k1", `{"amount":1}`)
+	if first.Code != http.StatusCreated || first.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("first response = %d %v, want 201 not replayed", first.Code, first.Header())
+	}
+	replayed := post(e, "k1", `{"amount":1}`)
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != "created" {
+		t.Errorf("replayed response = %d %q, want 201 created", replayed.Code, replayed.Body.String())
+	}
+	if replayed.Header().Get(ReplayedHeader) != "true" || replayed.Header().Get("X-Payment-Id") != "p1" {
+		t.Errorf("replayed headers = %v, want the replayed and saved headers", replayed.Header())
+	}
+	if reused := post(e, "k1", `{"amount":2}`); reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key response = %d, want 422", reused.Code)
+	}
+	if other := post(e, "k2", `{"amount":1}`); other.Code != http.StatusCreated {
+		t.Errorf("other key response = %d, want 201", other.Code)
+	}
+	if runs != 2 {
+		t.Errorf("handler runs = %d, want 2", runs)
+	}
+}
+
+func TestProcessingAndFailedRequests(t *testing.T) {
+	status := http.StatusInternalServerError
+	var retry *httptest.ResponseRecorder
+	var e *gin.Engine
+	e = newEngine(func(ctx *gin.Context) {
+		if retry == nil {
+			// the retry while the first request is processing
+			retry = post(e, "k1", "")
+		}
+		ctx.Status(status)
+	})
+	post(e, "k1", "")
+	if retry.Code != http.StatusConflict {
+		t.Errorf("retry while processing = %d, want 409", retry.Code)
+	}
+	// the failed request releases the key
+	status = http.StatusOK
+	if w := post(e, "k1", ""); w.Code != http.StatusOK || w.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("retry after failure = %d %v, want 200 not replayed", w.Code, w.Header())
+	}
+}
+
+func TestMemoryStoreOwner(t *testing.T) {
+	s := NewMemoryStore()
+	_, first, err := s.Acquire("k", "f", time.Millisecond)
+	if err != nil || first == "" {
+		t.Fatalf("Acquire = %q, %v, want the token", first, err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	_, second, _ := s.Acquire("k", "f", time.Minute)
+	if second == "" || second == first {
+		t.Fatalf("Acquire after the lock expired = %q, want a new token", second)
+	}
+	if err = s.Save("k", first, &Record{Fingerprint: "f", Done: true}, time.Minute); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Save by the expired owner error = %v, want %v", err, ErrLockLost)
+	}
+	_ = s.Release("k", first)
+	if exist, _, _ := s.Acquire("k", "f", time.Minute); exist == nil || exist.Done {
+		t.Fatalf("Release by the expired owner removed the processing record, got %+v", exist)
+	}
+	if err = s.Save("k", second, &Record{Fingerprint: "f", Done: true}, time.Minute); err != nil {
+		t.Errorf("Save by the owner error = %v", err)
+	}
+	if exist, _, _ := s.Acquire("k", "f", time.Minute); exist == nil || !exist.Done {
+		t.Errorf("saved record = %+v, want the completed one", exist)
+	}
+}
@@ -0,0 +1,125 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// StoreConfig the storage of the idempotency records
+type StoreConfig struct {
+	Type     string `mapstructure:"type"`     // memory or redis, default memory. Redis shares the records between the instances
+	Addr     string `mapstructure:"addr"`     // Redis address, default the client of the redis configuration, or 127.0.0.1:6379 without it
+	Username string `mapstructure:"username"` // Redis username
+	Password string `mapstructure:"password"` // Redis password
+	DB       int    `mapstructure:"db"`       // Redis database
+	Prefix   string `mapstructure:"prefix"`   // Prefix of the keys, default gin-plus:idempotency:
+}
+
+// save the record unless the key is acquired by another request, the expired key is saved as well
+var saveScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if v and cjson.decode(v).token ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// release the key of the owner
+var releaseScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if v and cjson.decode(v).token == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+end
+return 1
+`)
+
+// RedisStore the record storage shared by the instances, the key is acquired by SET NX and saved or released
+// by its owner atomically
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore Create the redis record storage, the client is closed with the storage
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "gin-plus:idempotency:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// NewStore Create the record storage from the configuration
+func NewStore(conf StoreConfig) RecordStore {
+	if conf.Type != "redis" {
+		return NewMemoryStore()
+	}
+	if conf.Addr == "" {
+		conf.Addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: conf.Addr, Username: conf.Username, Password: conf.Password, DB: conf.DB})
+	return NewRedisStore(client, conf.Prefix)
+}
+
+func (r *RedisStore) Acquire(key string, fingerprint string, lockTTL time.Duration) (*Record, string, error) {
+	token := newToken()
+	b, err := json.Marshal(&Record{Fingerprint: fingerprint, Token: token})
+	if err != nil {
+		return nil, "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		ok, err := r.client.SetNX(ctx, r.prefix+key, b, lockTTL).Result()
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			return nil, token, nil
+		}
+		exist, err := r.client.Get(ctx, r.prefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// expired between the commands
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		var record Record
+		if err = json.Unmarshal(exist, &record); err != nil {
+			return nil, "", err
+		}
+		return &record, "", nil
+	}
+	return nil, "", errors.New("idempotency: the key is acquired and released repeatedly")
+}
+
+func (r *RedisStore) Save(key string, token string, record *Record, ttl time.Duration) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	saved, err := saveScript.Run(ctx, r.client, []string{r.prefix + key}, token, b, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if saved == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (r *RedisStore) Release(key string, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return releaseScript.Run(ctx, r.client, []string{r.prefix + key}, token).Err()
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
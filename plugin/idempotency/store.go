@@ -0,0 +1,111 @@
+package idempotency
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	Store RecordStore = NewMemoryStore() // Store the storage of the idempotency records, memory storage as default
+)
+
+// ErrLockLost the lock of the key expired and is acquired by another request, the record of the owner is kept
+var ErrLockLost = errors.New("idempotency: the lock of the key is acquired by another request")
+
+// Record the state of an idempotency key, the response is saved when the request is completed
+type Record struct {
+	Fingerprint string      `json:"fingerprint"` // Hash of the method, route and body of the request
+	Done        bool        `json:"done"`        // Whether the request is completed, otherwise it's processing
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`    // Headers of the response
+	Body        []byte      `json:"body,omitempty"`      // Body of the response, empty when it's oversized
+	Oversized   bool        `json:"oversized,omitempty"` // Whether the body exceeds the max size, the response isn't replayed
+	Token       string      `json:"token,omitempty"`     // Owner of the processing record, empty when it's completed
+}
+
+// RecordStore the storage of the idempotency records
+type RecordStore interface {
+	// Acquire the key for the processing request, the record of the key is returned when it is acquired by another request.
+	// The processing record expires after the lock ttl, so the key is released when the instance crashes.
+	// The token owns the lock, it's required to save or release the key
+	Acquire(key string, fingerprint string, lockTTL time.Duration) (exist *Record, token string, err error)
+
+	// Save the completed record of the key unless it's acquired by another request, ErrLockLost is returned then
+	Save(key string, token string, record *Record, ttl time.Duration) error
+
+	// Release the key of the failed request, so it can be retried. The key acquired by another request is kept
+	Release(key string, token string) error
+}
+
+// MemoryStore in-process record storage, expired keys are removed periodically
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]memoryRecord
+	lastSweep time.Time
+}
+
+type memoryRecord struct {
+	record *Record
+	expire time.Time
+}
+
+// NewMemoryStore Create a memory record storage
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord), lastSweep: time.Now()}
+}
+
+func (m *MemoryStore) Acquire(key string, fingerprint string, lockTTL time.Duration) (*Record, string, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	if r, ok := m.records[key]; ok && now.Before(r.expire) {
+		return r.record, "", nil
+	}
+	token := newToken()
+	m.records[key] = memoryRecord{record: &Record{Fingerprint: fingerprint, Token: token}, expire: now.Add(lockTTL)}
+	return nil, token, nil
+}
+
+func (m *MemoryStore) Save(key string, token string, record *Record, ttl time.Duration) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.records[key]; ok && now.Before(r.expire) && r.record.Token != token {
+		return ErrLockLost
+	}
+	m.records[key] = memoryRecord{record: record, expire: now.Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Release(key string, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.records[key]; ok && r.record.Token == token {
+		delete(m.records, key)
+	}
+	return nil
+}
+
+// remove the expired records, at most once a minute
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for k, r := range m.records {
+		if !now.Before(r.expire) {
+			delete(m.records, k)
+		}
+	}
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,427 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"io"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Default the job queue of the application, the workers are started after the application started and drained
+// when it stops
+var Default = New(Config{})
+
+// Config the job queue
+type Config struct {
+	Workers      int           `mapstructure:"workers"`       // Workers handling the jobs, default 4
+	MaxRetries   int           `mapstructure:"max_retries"`   // Retries of the failed job before it's dead, default 3
+	Backoff      time.Duration `mapstructure:"backoff"`       // Delay of the first retry, it's doubled for each retry, default 1s
+	MaxBackoff   time.Duration `mapstructure:"max_backoff"`   // Max delay of the retries, default 5m
+	PollInterval time.Duration `mapstructure:"poll_interval"` // Interval checking the ready jobs when the queue is empty, default 1s
+	Store        StoreConfig   `mapstructure:"store"`         // Storage of the jobs, default memory
+}
+
+/*
+Handler the handler of the jobs whose payload is the type, it's created by Handle(). The payload is decoded from json,
+so only its exported fields are kept.
+*/
+type Handler struct {
+	typ     string
+	name    string
+	retries int
+	timeout time.Duration
+	handle  func(ctx context.Context, payload json.RawMessage) error
+}
+
+// HandlerOption the option of the handler
+type HandlerOption func(h *Handler)
+
+// Retries Set the retries of the failed jobs of the handler, 0 means the failed job is dead immediately
+func Retries(n int) HandlerOption {
+	return func(h *Handler) {
+		h.retries = n
+	}
+}
+
+// Timeout Cancel the context of the job when it's not handled in time, the job is retried as failed
+func Timeout(d time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.timeout = d
+	}
+}
+
+// Handle Create the handler of the jobs whose payload is T, T and *T are the same type of the job
+//
+//	jobs.Handle(m.SendWelcomeMail, jobs.Retries(5), jobs.Timeout(30*time.Second))
+func Handle[T any](handler func(ctx context.Context, payload T) error, opts ...HandlerOption) Handler {
+	h := Handler{
+		typ:     typeOf(reflect.TypeOf((*T)(nil)).Elem()),
+		name:    runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name(),
+		retries: -1,
+		handle: func(ctx context.Context, payload json.RawMessage) error {
+			var v T
+			if err := json.Unmarshal(payload, &v); err != nil {
+				return fmt.Errorf("decode payload error, %w", err)
+			}
+			return handler(ctx, v)
+		},
+	}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	return h
+}
+
+/*
+HandlerBean the bean handling the jobs, the handlers are added when the application starts
+
+	func (m *MailService) JobHandlers() []jobs.Handler {
+	    return []jobs.Handler{
+	        jobs.Handle(m.SendWelcomeMail),
+	    }
+	}
+
+	func (m *MailService) SendWelcomeMail(ctx context.Context, mail *WelcomeMail) error {}
+*/
+type HandlerBean interface {
+	JobHandlers() []Handler
+}
+
+// SubmitOption the option of the submitted job
+type SubmitOption func(job *Job)
+
+// Delay Handle the job after the delay
+func Delay(d time.Duration) SubmitOption {
+	return func(job *Job) {
+		job.RunAt = job.RunAt.Add(d)
+	}
+}
+
+// Queue the job queue handling the jobs by the worker pool, the failed jobs are retried with the exponential backoff
+// and saved as dead after all the retries
+type Queue struct {
+	mu       sync.RWMutex
+	conf     Config
+	store    Store
+	handlers map[string]Handler
+	onDead   func(job *Job, err error)
+	stopping chan struct{}   // Closed when the queue stops, the workers exit after the ready jobs are handled
+	jobCtx   context.Context // Context of the jobs, canceled when the jobs don't finish in time after stopping
+	stopJob  context.CancelFunc
+	workers  sync.WaitGroup
+	started  bool
+}
+
+// New Create the job queue, the storage is created from the configuration
+func New(conf Config) *Queue {
+	q := &Queue{handlers: make(map[string]Handler)}
+	q.SetConfig(conf)
+	return q
+}
+
+// SetConfig Set the configuration and replace the storage, it should be set before the jobs are submitted
+func (q *Queue) SetConfig(conf Config) {
+	if conf.Workers <= 0 {
+		conf.Workers = 4
+	}
+	if conf.MaxRetries < 0 {
+		conf.MaxRetries = 0
+	}
+	if conf.Backoff <= 0 {
+		conf.Backoff = time.Second
+	}
+	if conf.MaxBackoff <= 0 {
+		conf.MaxBackoff = 5 * time.Minute
+	}
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = time.Second
+	}
+	q.SetStore(NewStore(conf.Store))
+	q.mu.Lock()
+	q.conf = conf
+	q.mu.Unlock()
+}
+
+// SetStore Replace the storage of the jobs, the previous one is closed when it implements io.Closer
+func (q *Queue) SetStore(store Store) {
+	q.mu.Lock()
+	prev := q.store
+	q.store = store
+	q.mu.Unlock()
+	if c, ok := prev.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+// AddHandlers Add the handlers, the handler added later replaces the one of the same payload type
+func (q *Queue) AddHandlers(handlers ...Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, h := range handlers {
+		if prev, ok := q.handlers[h.typ]; ok {
+			logger.Log.Warnf("Job handler %s of %s is replaced by %s", prev.name, h.typ, h.name)
+		}
+		q.handlers[h.typ] = h
+	}
+}
+
+// Register Add the handlers of the beans implementing HandlerBean, the other beans are ignored
+func (q *Queue) Register(beans ...any) {
+	for _, bean := range beans {
+		if hb, ok := bean.(HandlerBean); ok {
+			q.AddHandlers(hb.JobHandlers()...)
+		}
+	}
+}
+
+// OnDead Set the function called when the job is dead, such as alerting
+func (q *Queue) OnDead(fn func(job *Job, err error)) {
+	q.mu.Lock()
+	q.onDead = fn
+	q.mu.Unlock()
+}
+
+/*
+Submit the job handled by the handler of the payload type, the id of the job is returned. The payload is encoded as
+json, the job is handled after the application started even when it's submitted before.
+*/
+func (q *Queue) Submit(ctx context.Context, payload any, opts ...SubmitOption) (string, error) {
+	if payload == nil {
+		return "", errors.New("job payload is nil")
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("encode job payload error, %w", err)
+	}
+	now := time.Now()
+	job := &Job{Id: newId(), Type: typeOf(reflect.TypeOf(payload)), Payload: b, RunAt: now, CreatedAt: now}
+	for _, opt := range opts {
+		opt(job)
+	}
+	q.mu.RLock()
+	store := q.store
+	q.mu.RUnlock()
+	if err = store.Push(ctx, job); err != nil {
+		return "", fmt.Errorf("submit job error, %w", err)
+	}
+	return job.Id, nil
+}
+
+// DeadJobs Get the latest dead jobs
+func (q *Queue) DeadJobs(ctx context.Context, limit int) ([]*Job, error) {
+	q.mu.RLock()
+	store := q.store
+	q.mu.RUnlock()
+	return store.DeadJobs(ctx, limit)
+}
+
+// Start the workers, it does nothing when no handler is added or the queue is started
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started || len(q.handlers) == 0 {
+		return
+	}
+	q.started = true
+	q.stopping = make(chan struct{})
+	q.jobCtx, q.stopJob = context.WithCancel(context.Background())
+	for i := 0; i < q.conf.Workers; i++ {
+		q.workers.Add(1)
+		go q.work(q.stopping, q.jobCtx)
+	}
+	logger.Log.Debugf("Job queue started with %d workers and %d handlers", q.conf.Workers, len(q.handlers))
+}
+
+// handle the ready jobs until the queue stops and no job is ready, or the context of the jobs is canceled
+func (q *Queue) work(stopping <-chan struct{}, ctx context.Context) {
+	defer q.workers.Done()
+	q.mu.RLock()
+	store, interval := q.store, q.conf.PollInterval
+	q.mu.RUnlock()
+	var notify <-chan struct{}
+	if n, ok := store.(notifier); ok {
+		notify = n.Notify()
+	}
+	for ctx.Err() == nil {
+		job, err := store.Pop(ctx)
+		if err != nil {
+			logger.Log.Errorf("Pop job error, %s", err.Error())
+		}
+		if job != nil {
+			q.handle(ctx, store, job)
+			continue
+		}
+		select {
+		case <-stopping:
+			return
+		default:
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-stopping:
+		case <-ctx.Done():
+		case <-notify:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+/*
+handle the job, the failed job is pushed back with the backoff or saved as dead after all the retries.
+The job without the handler is pushed back with the max backoff, the instance handling it may be still starting
+*/
+func (q *Queue) handle(ctx context.Context, store Store, job *Job) {
+	q.mu.RLock()
+	h, ok := q.handlers[job.Type]
+	conf, onDead := q.conf, q.onDead
+	q.mu.RUnlock()
+	// the job is saved with the detached context, so it's not lost when the queue stops
+	saveCtx := context.WithoutCancel(ctx)
+	if !ok {
+		job.RunAt = time.Now().Add(conf.MaxBackoff)
+		logger.Log.Warnf("No handler of the job type %s, job %s is delayed %s", job.Type, job.Id, conf.MaxBackoff)
+		if e := store.Push(saveCtx, job); e != nil {
+			logger.Log.Errorf("Delay job %s error, %s", job.Id, e.Error())
+		}
+		if e := store.Ack(saveCtx, job); e != nil {
+			logger.Log.Errorf("Ack job %s error, %s", job.Id, e.Error())
+		}
+		return
+	}
+	retries := conf.MaxRetries
+	if h.retries >= 0 {
+		retries = h.retries
+	}
+	err := call(ctx, h, job)
+	if err == nil {
+		if err = store.Ack(ctx, job); err != nil {
+			logger.Log.Errorf("Ack job %s error, %s", job.Id, err.Error())
+		}
+		return
+	}
+	job.Attempts++
+	job.LastError = err.Error()
+	if job.Attempts > retries {
+		logger.Log.Errorf("Job %s of %s is dead after %d attempts, %s", job.Id, job.Type, job.Attempts, err.Error())
+		if e := store.Dead(saveCtx, job); e != nil {
+			logger.Log.Errorf("Save dead job %s error, %s", job.Id, e.Error())
+		}
+		if onDead != nil {
+			onDead(job, err)
+		}
+	} else {
+		backoff := retryBackoff(conf, job.Attempts)
+		job.RunAt = time.Now().Add(backoff)
+		logger.Log.Warnf("Job %s of %s failed, retry %d in %s, %s", job.Id, job.Type, job.Attempts, backoff, err.Error())
+		if e := store.Push(saveCtx, job); e != nil {
+			logger.Log.Errorf("Retry job %s error, %s", job.Id, e.Error())
+		}
+	}
+	if e := store.Ack(saveCtx, job); e != nil {
+		logger.Log.Errorf("Ack job %s error, %s", job.Id, e.Error())
+	}
+}
+
+// the delay of the retry after the failed attempts, it's doubled for each attempt up to the max backoff
+func retryBackoff(conf Config, attempts int) time.Duration {
+	if attempts > 32 {
+		return conf.MaxBackoff
+	}
+	return min(conf.Backoff<<(attempts-1), conf.MaxBackoff)
+}
+
+// call the handler within its timeout, the panic is returned as the error
+func call(ctx context.Context, h Handler, job *Job) (err error) {
+	if h.timeout > 0 {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithTimeout(ctx, h.timeout)
+		defer cancelFunc()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Errorf("Job %s handler %s panic, %v\n%s", job.Id, h.name, r, debug.Stack())
+			err = fmt.Errorf("panic, %v", r)
+		}
+	}()
+	return h.handle(ctx, job.Payload)
+}
+
+/*
+Stop the workers after the ready jobs are handled and wait for them until the context is done, then the context of the
+jobs still running is canceled. The delayed jobs of the memory storage are lost, the redis storage keeps them.
+*/
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return nil
+	}
+	q.started = false
+	close(q.stopping)
+	stopJob, store := q.stopJob, q.store
+	q.mu.Unlock()
+	defer stopJob()
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		if m, ok := store.(*MemoryStore); ok && m.Len() > 0 {
+			logger.Log.Warnf("%d delayed jobs are dropped, they are kept by the redis storage", m.Len())
+		}
+		return nil
+	case <-ctx.Done():
+		return errors.New("jobs are still running")
+	}
+}
+
+// Close the storage when it implements io.Closer
+func (q *Queue) Close() error {
+	q.mu.RLock()
+	store := q.store
+	q.mu.RUnlock()
+	if c, ok := store.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// the type of the job, the pointer is the same as its element
+func typeOf(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AddHandlers Add the handlers to the default queue
+func AddHandlers(handlers ...Handler) {
+	Default.AddHandlers(handlers...)
+}
+
+/*
+Submit the job to the default queue
+
+	id, err := jobs.Submit(ctx, &WelcomeMail{UserId: user.Id}, jobs.Delay(time.Minute))
+*/
+func Submit(ctx context.Context, payload any, opts ...SubmitOption) (string, error) {
+	return Default.Submit(ctx, payload, opts...)
+}
@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"testing"
+	"time"
+)
+
+type welcomeMail struct {
+	UserId int64 `json:"user_id"`
+}
+
+func TestRetryBackoff(t *testing.T) {
+	conf := Config{Backoff: time.Second, MaxBackoff: time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{6, 32 * time.Second},
+		{7, time.Minute},
+		{32, time.Minute},
+		{33, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(conf, tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestHandleRetriesAndDeadLetters(t *testing.T) {
+	logger.Log = &logger.DefaultLog{}
+	failure := errors.New("smtp is down")
+	tests := []struct {
+		name     string
+		retries  int
+		fail     bool
+		wantRuns int
+		wantDead bool
+	}{
+		{name: "succeeded", retries: 2, wantRuns: 1},
+		{name: "dead immediately without retries", retries: 0, fail: true, wantRuns: 1, wantDead: true},
+		{name: "dead after the retries", retries: 2, fail: true, wantRuns: 3, wantDead: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			q := New(Config{Backoff: time.Hour, MaxBackoff: time.Hour})
+			store := NewMemoryStore(0)
+			q.SetStore(store)
+			runs := 0
+			q.AddHandlers(Handle(func(ctx context.Context, mail *welcomeMail) error {
+				runs++
+				if tt.fail {
+					return failure
+				}
+				return nil
+			}, Retries(tt.retries)))
+			var dead *Job
+			q.OnDead(func(job *Job, err error) {
+				if !errors.Is(err, failure) {
+					t.Errorf("OnDead error = %v, want %v", err, failure)
+				}
+				dead = job
+			})
+			if _, err := q.Submit(ctx, &welcomeMail{UserId: 1}); err != nil {
+				t.Fatalf("Submit error, %v", err)
+			}
+			for i := 0; i <= tt.retries && store.Len() > 0; i++ {
+				job, _ := store.Pop(ctx)
+				if job == nil {
+					// the retry is delayed by the backoff
+					store.jobs[0].RunAt = time.Now()
+					job, _ = store.Pop(ctx)
+				}
+				before := time.Now()
+				q.handle(ctx, store, job)
+				if tt.fail && job.Attempts <= tt.retries {
+					if want := before.Add(retryBackoff(q.conf, job.Attempts)); job.RunAt.Before(want) {
+						t.Errorf("retry %d runs at %s, want after %s", job.Attempts, job.RunAt, want)
+					}
+				}
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("runs = %d, want %d", runs, tt.wantRuns)
+			}
+			if store.Len() != 0 {
+				t.Errorf("pending jobs = %d, want 0", store.Len())
+			}
+			deadJobs, _ := q.DeadJobs(ctx, 10)
+			if (len(deadJobs) == 1) != tt.wantDead || (dead != nil) != tt.wantDead {
+				t.Fatalf("dead jobs = %d, OnDead called %t, want dead %t", len(deadJobs), dead != nil, tt.wantDead)
+			}
+			if tt.wantDead {
+				if deadJobs[0].Attempts != tt.retries+1 || deadJobs[0].LastError != failure.Error() {
+					t.Errorf("dead job attempts = %d, last error = %q", deadJobs[0].Attempts, deadJobs[0].LastError)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreDeadLimit(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	for _, id := range []string{"a", "b", "c"} {
+		_ = store.Dead(ctx, &Job{Id: id})
+	}
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		{1, []string{"c"}},
+		{2, []string{"c", "b"}},
+		{10, []string{"c", "b"}},
+	}
+	for _, tt := range tests {
+		jobs, _ := store.DeadJobs(ctx, tt.limit)
+		var got []string
+		for _, job := range jobs {
+			got = append(got, job.Id)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("DeadJobs(%d) = %v, want %v", tt.limit, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("DeadJobs(%d) = %v, want %v", tt.limit, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StoreConfig the storage of the jobs
+type StoreConfig struct {
+	Type       string        `mapstructure:"type"`       // memory or redis, default memory. Redis keeps the jobs when the instances restart and shares them between the instances
+	Addr       string        `mapstructure:"addr"`       // Redis address, default the client of the redis configuration, or 127.0.0.1:6379 without it
+	Username   string        `mapstructure:"username"`   // Redis username
+	Password   string        `mapstructure:"password"`   // Redis password
+	DB         int           `mapstructure:"db"`         // Redis database
+	Prefix     string        `mapstructure:"prefix"`     // Prefix of the keys, default {gin-plus:jobs}:, the prefix without the hash tag is wrapped in it
+	Visibility time.Duration `mapstructure:"visibility"` // The popped job not acknowledged in it is redelivered, it should be longer than the handling, default 5m
+	DeadLimit  int           `mapstructure:"dead_limit"` // Number of the latest dead jobs kept, default 1000
+}
+
+// NewStore Create the job storage from the configuration
+func NewStore(conf StoreConfig) Store {
+	if conf.Type != "redis" {
+		return NewMemoryStore(conf.DeadLimit)
+	}
+	if conf.Addr == "" {
+		conf.Addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: conf.Addr, Username: conf.Username, Password: conf.Password, DB: conf.DB})
+	return NewRedisStore(client, conf)
+}
+
+// move the first ready job to the processing set, scored by its visibility deadline
+var popScript = redis.NewScript(`
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #jobs == 0 then
+	return false
+end
+redis.call('ZREM', KEYS[1], jobs[1])
+redis.call('ZADD', KEYS[2], ARGV[2], jobs[1])
+return jobs[1]
+`)
+
+// move the processing jobs whose visibility deadline passed back to the queue
+var requeueScript = redis.NewScript(`
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, job in ipairs(jobs) do
+	redis.call('ZREM', KEYS[2], job)
+	redis.call('ZADD', KEYS[1], ARGV[1], job)
+end
+return #jobs
+`)
+
+/*
+RedisStore the durable job storage, the ready jobs are in a sorted set scored by the RunAt. The popped job is moved to
+the processing set until it's acknowledged, the jobs not acknowledged in the visibility are moved back to the queue.
+*/
+type RedisStore struct {
+	client     redis.UniversalClient
+	queue      string
+	processing string
+	dead       string
+	visibility time.Duration
+	deadLimit  int64
+}
+
+// NewRedisStore Create the redis job storage, the client is closed with the storage
+func NewRedisStore(client redis.UniversalClient, conf StoreConfig) *RedisStore {
+	if conf.Prefix == "" {
+		conf.Prefix = "{gin-plus:jobs}:"
+	}
+	// the scripts touch the queue and the processing set, they must be in the same slot of the redis cluster
+	if !hashTagged(conf.Prefix) {
+		conf.Prefix = "{" + strings.TrimSuffix(conf.Prefix, ":") + "}:"
+	}
+	if conf.Visibility <= 0 {
+		conf.Visibility = 5 * time.Minute
+	}
+	if conf.DeadLimit <= 0 {
+		conf.DeadLimit = 1000
+	}
+	return &RedisStore{
+		client:     client,
+		queue:      conf.Prefix + "queue",
+		processing: conf.Prefix + "processing",
+		dead:       conf.Prefix + "dead",
+		visibility: conf.Visibility,
+		deadLimit:  int64(conf.DeadLimit),
+	}
+}
+
+// whether the key has the non-empty hash tag, only the tag is hashed by the redis cluster
+func hashTagged(key string) bool {
+	start := strings.IndexByte(key, '{')
+	return start >= 0 && strings.IndexByte(key[start+1:], '}') > 0
+}
+
+func (r *RedisStore) Push(ctx context.Context, job *Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return r.client.ZAdd(ctx, r.queue, redis.Z{Score: float64(job.RunAt.UnixMilli()), Member: string(b)}).Err()
+}
+
+func (r *RedisStore) Pop(ctx context.Context) (*Job, error) {
+	now := time.Now()
+	nowScore := strconv.FormatInt(now.UnixMilli(), 10)
+	if err := requeueScript.Run(ctx, r.client, []string{r.queue, r.processing}, nowScore).Err(); err != nil {
+		return nil, err
+	}
+	deadline := strconv.FormatInt(now.Add(r.visibility).UnixMilli(), 10)
+	raw, err := popScript.Run(ctx, r.client, []string{r.queue, r.processing}, nowScore, deadline).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{}
+	if err = json.Unmarshal([]byte(raw), job); err != nil {
+		// the broken job can't be handled, it's removed
+		_ = r.client.ZRem(ctx, r.processing, raw).Err()
+		return nil, err
+	}
+	job.raw = raw
+	return job, nil
+}
+
+func (r *RedisStore) Ack(ctx context.Context, job *Job) error {
+	if job.raw == "" {
+		return nil
+	}
+	return r.client.ZRem(ctx, r.processing, job.raw).Err()
+}
+
+func (r *RedisStore) Dead(ctx context.Context, job *Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.LPush(ctx, r.dead, b)
+		p.LTrim(ctx, r.dead, 0, r.deadLimit-1)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStore) DeadJobs(ctx context.Context, limit int) ([]*Job, error) {
+	items, err := r.client.LRange(ctx, r.dead, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(items))
+	for _, item := range items {
+		job := &Job{}
+		if err = json.Unmarshal([]byte(item), job); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Close the redis client
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Job the submitted work, the payload is encoded as json so it can be stored out of the process
+type Job struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"` // Type of the payload, the job is handled by the handler of the type
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"` // Number of the failed attempts
+	LastError string          `json:"last_error,omitempty"`
+	RunAt     time.Time       `json:"run_at"` // The job is not handled before it
+	CreatedAt time.Time       `json:"created_at"`
+	raw       string          // The stored form of the job, used by the redis store to acknowledge it
+}
+
+// Store the storage of the jobs
+type Store interface {
+	// Push the job, it's ready at the RunAt
+	Push(ctx context.Context, job *Job) error
+
+	// Pop a ready job, nil when no job is ready. The job popped from the durable store is redelivered when it's not
+	// acknowledged in time, such as the instance crashed
+	Pop(ctx context.Context) (*Job, error)
+
+	// Ack the popped job is handled, retried or dead
+	Ack(ctx context.Context, job *Job) error
+
+	// Dead Save the job failed after all the retries
+	Dead(ctx context.Context, job *Job) error
+
+	// DeadJobs Get the dead jobs, the latest first
+	DeadJobs(ctx context.Context, limit int) ([]*Job, error)
+}
+
+// notifier the store notifying the workers when a job is pushed, so they don't poll
+type notifier interface {
+	Notify() <-chan struct{}
+}
+
+// MemoryStore the in-process job storage, the jobs are lost when the process exits
+type MemoryStore struct {
+	mu        sync.Mutex
+	jobs      jobHeap
+	dead      []*Job
+	deadLimit int
+	notify    chan struct{}
+}
+
+// NewMemoryStore Create the memory job storage keeping the latest dead jobs up to the limit, default 1000
+func NewMemoryStore(deadLimit int) *MemoryStore {
+	if deadLimit <= 0 {
+		deadLimit = 1000
+	}
+	return &MemoryStore{deadLimit: deadLimit, notify: make(chan struct{}, 1)}
+}
+
+func (m *MemoryStore) Push(_ context.Context, job *Job) error {
+	m.mu.Lock()
+	heap.Push(&m.jobs, job)
+	m.mu.Unlock()
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (m *MemoryStore) Pop(context.Context) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.jobs) == 0 || m.jobs[0].RunAt.After(time.Now()) {
+		return nil, nil
+	}
+	return heap.Pop(&m.jobs).(*Job), nil
+}
+
+func (m *MemoryStore) Ack(context.Context, *Job) error {
+	return nil
+}
+
+func (m *MemoryStore) Dead(_ context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dead = append(m.dead, job)
+	if len(m.dead) > m.deadLimit {
+		m.dead = m.dead[len(m.dead)-m.deadLimit:]
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeadJobs(_ context.Context, limit int) ([]*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]*Job, 0, min(limit, len(m.dead)))
+	for i := len(m.dead) - 1; i >= 0 && len(jobs) < limit; i-- {
+		jobs = append(jobs, m.dead[i])
+	}
+	return jobs, nil
+}
+
+// Notify the channel receiving when a job is pushed
+func (m *MemoryStore) Notify() <-chan struct{} {
+	return m.notify
+}
+
+// Len the number of the pending jobs
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.jobs)
+}
+
+// jobHeap the jobs ordered by the RunAt
+type jobHeap []*Job
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h[i].RunAt.Before(h[j].RunAt) }
+func (h jobHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x any)        { *h = append(*h, x.(*Job)) }
+func (h *jobHeap) Pop() any {
+	old := *h
+	job := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return job
+}
@@ -0,0 +1,358 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported algorithms
+const (
+	RS256 = "RS256"
+	RS384 = "RS384"
+	RS512 = "RS512"
+	HS256 = "HS256"
+	HS384 = "HS384"
+	HS512 = "HS512"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	ES512 = "ES512"
+)
+
+// the hash of each algorithm
+var hashes = map[string]crypto.Hash{
+	RS256: crypto.SHA256, RS384: crypto.SHA384, RS512: crypto.SHA512,
+	HS256: crypto.SHA256, HS384: crypto.SHA384, HS512: crypto.SHA512,
+	ES256: crypto.SHA256, ES384: crypto.SHA384, ES512: crypto.SHA512,
+}
+
+// the curve of each ECDSA algorithm
+var curves = map[string]elliptic.Curve{ES256: elliptic.P256(), ES384: elliptic.P384(), ES512: elliptic.P521()}
+
+var (
+	ErrMalformed      = errors.New("jwt: malformed token")
+	ErrSignature      = errors.New("jwt: invalid signature")
+	ErrExpired        = errors.New("jwt: token is expired")
+	ErrNotValidYet    = errors.New("jwt: token is not valid yet")
+	ErrUnknownKey     = errors.New("jwt: unknown key")
+	ErrUnsupportedAlg = errors.New("jwt: unsupported algorithm")
+	ErrNoSigningKey   = errors.New("jwt: the key can't sign")
+)
+
+// tolerated clock difference of the exp and nbf claims
+const clockSkew = 30 * time.Second
+
+var encoding = base64.RawURLEncoding
+
+// Claims the claims of the token
+type Claims map[string]any
+
+// String Get the string claim
+func (c Claims) String(name string) string {
+	v, _ := c[name].(string)
+	return v
+}
+
+// Time Get the numeric date claim, such as exp
+func (c Claims) Time(name string) (time.Time, bool) {
+	switch v := c[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	case int:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		n, err := v.Int64()
+		return time.Unix(n, 0), err == nil
+	}
+	return time.Time{}, false
+}
+
+// Key the signing or verification key
+type Key struct {
+	ID           string            // Key id, written to the kid header
+	Alg          string            // One of the supported algorithms, such as RS256, HS256 and ES256
+	PrivateKey   *rsa.PrivateKey   // Private key of RS*, only required for signing
+	PublicKey    *rsa.PublicKey    // Public key of RS*, derived from the private key when nil
+	Secret       []byte            // Secret of HS*
+	ECPrivateKey *ecdsa.PrivateKey // Private key of ES*, only required for signing
+	ECPublicKey  *ecdsa.PublicKey  // Public key of ES*, derived from the private key when nil
+}
+
+func (k *Key) publicKey() *rsa.PublicKey {
+	if k.PublicKey == nil && k.PrivateKey != nil {
+		return &k.PrivateKey.PublicKey
+	}
+	return k.PublicKey
+}
+
+func (k *Key) ecPublicKey() *ecdsa.PublicKey {
+	if k.ECPublicKey == nil && k.ECPrivateKey != nil {
+		return &k.ECPrivateKey.PublicKey
+	}
+	return k.ECPublicKey
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Sign the claims with the key
+func Sign(claims Claims, key *Key) (string, error) {
+	h, err := json.Marshal(header{Alg: key.Alg, Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := encoding.EncodeToString(h) + "." + encoding.EncodeToString(c)
+	sig, err := sign(signing, key)
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + encoding.EncodeToString(sig), nil
+}
+
+func sign(signing string, key *Key) ([]byte, error) {
+	hash, ok := hashes[key.Alg]
+	if !ok {
+		return nil, ErrUnsupportedAlg
+	}
+	switch key.Alg[:2] {
+	case "RS":
+		if key.PrivateKey == nil {
+			return nil, ErrNoSigningKey
+		}
+		return rsa.SignPKCS1v15(rand.Reader, key.PrivateKey, hash, digest(hash, signing))
+	case "HS":
+		if len(key.Secret) == 0 {
+			return nil, ErrNoSigningKey
+		}
+		mac := hmac.New(hash.New, key.Secret)
+		mac.Write([]byte(signing))
+		return mac.Sum(nil), nil
+	default:
+		if key.ECPrivateKey == nil || key.ECPrivateKey.Curve != curves[key.Alg] {
+			return nil, ErrNoSigningKey
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, key.ECPrivateKey, digest(hash, signing))
+		if err != nil {
+			return nil, err
+		}
+		// the fixed size r || s of RFC 7518, not the ASN.1 signature
+		size := curveSize(key.ECPrivateKey.Curve)
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig, nil
+	}
+}
+
+func digest(hash crypto.Hash, signing string) []byte {
+	h := hash.New()
+	h.Write([]byte(signing))
+	return h.Sum(nil)
+}
+
+func curveSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+// KeyFunc find the verification key by the kid and alg of the token header
+type KeyFunc func(kid, alg string) (*Key, error)
+
+// Parse Verify the signature and the exp, nbf claims, then return the claims
+func Parse(token string, keyFunc KeyFunc) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	var h header
+	if err := decodeJSON(parts[0], &h); err != nil {
+		return nil, ErrMalformed
+	}
+	sig, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	key, err := keyFunc(h.Kid, h.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if key.Alg != h.Alg {
+		return nil, ErrUnsupportedAlg
+	}
+	if err = verify(parts[0]+"."+parts[1], sig, key); err != nil {
+		return nil, err
+	}
+	var claims Claims
+	if err = decodeJSON(parts[1], &claims); err != nil {
+		return nil, ErrMalformed
+	}
+	now := time.Now()
+	if exp, ok := claims.Time("exp"); ok && now.After(exp.Add(clockSkew)) {
+		return claims, ErrExpired
+	}
+	if nbf, ok := claims.Time("nbf"); ok && now.Add(clockSkew).Before(nbf) {
+		return claims, ErrNotValidYet
+	}
+	return claims, nil
+}
+
+func verify(signing string, sig []byte, key *Key) error {
+	hash, ok := hashes[key.Alg]
+	if !ok {
+		return ErrUnsupportedAlg
+	}
+	switch key.Alg[:2] {
+	case "RS":
+		pub := key.publicKey()
+		if pub == nil {
+			return ErrUnknownKey
+		}
+		if rsa.VerifyPKCS1v15(pub, hash, digest(hash, signing), sig) != nil {
+			return ErrSignature
+		}
+		return nil
+	case "HS":
+		// the empty secret is known by everyone
+		if len(key.Secret) == 0 {
+			return ErrUnknownKey
+		}
+		mac := hmac.New(hash.New, key.Secret)
+		mac.Write([]byte(signing))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return ErrSignature
+		}
+		return nil
+	default:
+		pub := key.ecPublicKey()
+		if pub == nil || pub.Curve != curves[key.Alg] {
+			return ErrUnknownKey
+		}
+		size := curveSize(pub.Curve)
+		if len(sig) != 2*size {
+			return ErrSignature
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest(hash, signing), r, s) {
+			return ErrSignature
+		}
+		return nil
+	}
+}
+
+func decodeJSON(part string, v any) error {
+	b, err := encoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// JWK the json web key of the RSA or EC public key
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS the json web key set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK Convert the RS* or ES* key to the json web key
+func (k *Key) PublicJWK() (JWK, error) {
+	if pub := k.publicKey(); strings.HasPrefix(k.Alg, "RS") && pub != nil {
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: k.Alg,
+			Kid: k.ID,
+			N:   encoding.EncodeToString(pub.N.Bytes()),
+			E:   encoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	}
+	if pub := k.ecPublicKey(); strings.HasPrefix(k.Alg, "ES") && pub != nil {
+		size := curveSize(pub.Curve)
+		return JWK{
+			Kty: "EC",
+			Use: "sig",
+			Alg: k.Alg,
+			Kid: k.ID,
+			Crv: pub.Curve.Params().Name,
+			X:   encoding.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+			Y:   encoding.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+		}, nil
+	}
+	return JWK{}, fmt.Errorf("jwt: key [%s] has no public key", k.ID)
+}
+
+// Key Convert the json web key to the verification key, the algorithm defaults to RS256 of RSA and the one of the EC curve
+func (j JWK) Key() (*Key, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := encoding.DecodeString(j.N)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		e, err := encoding.DecodeString(j.E)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		alg := j.Alg
+		if alg == "" {
+			alg = RS256
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return &Key{ID: j.Kid, Alg: alg, PublicKey: pub}, nil
+	case "EC":
+		alg := j.Alg
+		if alg == "" {
+			for a, c := range curves {
+				if c.Params().Name == j.Crv {
+					alg = a
+				}
+			}
+		}
+		curve, ok := curves[alg]
+		if !ok || curve.Params().Name != j.Crv {
+			return nil, ErrUnsupportedAlg
+		}
+		x, err := encoding.DecodeString(j.X)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		y, err := encoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		return &Key{ID: j.Kid, Alg: alg, ECPublicKey: pub}, nil
+	}
+	return nil, ErrUnsupportedAlg
+}
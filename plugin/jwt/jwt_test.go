@@ -0,0 +1,114 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sign the token with the header of the alg by the hmac of the secret, regardless of the key type
+func forge(t *testing.T, alg string, claims string, secret []byte) string {
+	t.Helper()
+	signing := encoding.EncodeToString([]byte(`{"alg":"`+alg+`","typ":"JWT"}`)) + "." + encoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signing))
+	return signing + "." + encoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRSA, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rs := &Key{Alg: RS256, PrivateKey: rsaKey}
+	es := &Key{Alg: ES256, ECPrivateKey: ecKey}
+	hs := &Key{Alg: HS256, Secret: []byte("secret")}
+	now := time.Now()
+	valid := Claims{"sub": "u1", "exp": now.Add(time.Hour).Unix()}
+	mustSign := func(claims Claims, key *Key) string {
+		token, err := Sign(claims, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	pub, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	tests := []struct {
+		name  string
+		token string
+		key   *Key // the verification key found by the header
+		want  error
+	}{
+		{name: "RS256", token: mustSign(valid, rs), key: &Key{Alg: RS256, PublicKey: &rsaKey.PublicKey}},
+		{name: "ES256", token: mustSign(valid, es), key: &Key{Alg: ES256, ECPublicKey: &ecKey.PublicKey}},
+		{name: "HS256", token: mustSign(valid, hs), key: hs},
+		{name: "wrong RSA key", token: mustSign(valid, rs), key: &Key{Alg: RS256, PublicKey: &otherRSA.PublicKey}, want: ErrSignature},
+		{name: "wrong secret", token: mustSign(valid, hs), key: &Key{Alg: HS256, Secret: []byte("other")}, want: ErrSignature},
+		{name: "HS256 signed by the RSA public key", token: forge(t, HS256, `{"sub":"admin"}`, pub),
+			key: &Key{Alg: RS256, PublicKey: &rsaKey.PublicKey}, want: ErrUnsupportedAlg},
+		{name: "none algorithm", token: encoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + encoding.EncodeToString([]byte(`{"sub":"admin"}`)) + ".",
+			key: &Key{Alg: "none"}, want: ErrUnsupportedAlg},
+		{name: "empty secret", token: forge(t, HS256, `{"sub":"admin"}`, nil), key: &Key{Alg: HS256}, want: ErrUnknownKey},
+		{name: "expired", token: mustSign(Claims{"exp": now.Add(-time.Minute).Unix()}, hs), key: hs, want: ErrExpired},
+		{name: "expired within the clock skew", token: mustSign(Claims{"exp": now.Add(-10 * time.Second).Unix()}, hs), key: hs},
+		{name: "not valid yet", token: mustSign(Claims{"nbf": now.Add(time.Minute).Unix()}, hs), key: hs, want: ErrNotValidYet},
+		{name: "not valid yet within the clock skew", token: mustSign(Claims{"nbf": now.Add(10 * time.Second).Unix()}, hs), key: hs},
+		{name: "two parts", token: "a.b", key: hs, want: ErrMalformed},
+		{name: "invalid header", token: "!!.e30.sig", key: hs, want: ErrMalformed},
+		{name: "invalid signature encoding", token: strings.Join([]string{encoding.EncodeToString([]byte(`{"alg":"HS256"}`)), "e30", "x"}, ".") + "!", key: hs, want: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.token, func(kid, alg string) (*Key, error) {
+				return tt.key, nil
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Parse error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUnknownKey(t *testing.T) {
+	token, _ := Sign(Claims{"sub": "u1"}, &Key{ID: "k1", Alg: HS256, Secret: []byte("secret")})
+	_, err := Parse(token, func(kid, alg string) (*Key, error) {
+		if kid != "k1" || alg != HS256 {
+			t.Errorf("key of kid %q and alg %q is found, want k1 and HS256", kid, alg)
+		}
+		return nil, ErrUnknownKey
+	})
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Parse error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	signing := &Key{ID: "ec1", Alg: ES384, ECPrivateKey: ecKey}
+	jwk, err := signing.PublicJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := Sign(Claims{"sub": "u1"}, signing)
+	claims, err := Parse(token, func(kid, alg string) (*Key, error) { return key, nil })
+	if err != nil || claims.String("sub") != "u1" {
+		t.Errorf("Parse by the jwk = %v, %v, want the claims", claims, err)
+	}
+}
@@ -0,0 +1,111 @@
+package k8s
+
+import (
+	"crypto/subtle"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config the kubernetes integration smoothing the rolling updates
+type Config struct {
+	Enabled       bool          `mapstructure:"enabled"`        // Whether to enable the integration, default false
+	ReadinessPath string        `mapstructure:"readiness_path"` // Readiness probe endpoint, it fails while the instance is starting or stopping, default /readyz
+	PreStopPath   string        `mapstructure:"pre_stop_path"`  // Endpoint of the preStop http hook, default empty means not mounted, the SIGTERM prepares the stop as well
+	PreStopToken  string        `mapstructure:"pre_stop_token"` // Token of the X-PreStop-Token header required by the preStop endpoint, empty means only the loopback requests are accepted
+	PreStopDelay  time.Duration `mapstructure:"pre_stop_delay"` // How long to keep serving after the readiness fails, so the pod is removed from the endpoints, default 5s
+	PodLabels     bool          `mapstructure:"pod_labels"`     // Add the pod metadata to the log lines and the metric samples, default false
+	Rollout       RolloutConfig `mapstructure:"rollout"`        // Limit the instances restarting at the same time
+}
+
+var (
+	starting atomic.Bool
+	stopping atomic.Bool
+	stopOnce sync.Once
+	// Default the restart coordinator, set when the rollout is enabled
+	Default *Rollout
+)
+
+// Ready Whether the instance accepts the new traffic
+func Ready() bool {
+	return !starting.Load() && !stopping.Load()
+}
+
+// SetStarting Set whether the instance is still starting, such as the async listeners are warming up the caches.
+// The rollout lease of the restart is released when the instance becomes ready
+func SetStarting(v bool) {
+	starting.Store(v)
+	if !v && Default != nil {
+		Default.Release(CurrentPod().Name)
+	}
+}
+
+/*
+PrepareStop Wait for the rollout lease, fail the readiness and keep serving for the delay, so the endpoints controller and
+the proxies stop routing to the pod before the server shuts down. It's triggered by the preStop hook or the SIGTERM,
+only the first call waits, the delay and the lease wait count towards terminationGracePeriodSeconds.
+*/
+func PrepareStop(delay time.Duration) {
+	stopOnce.Do(func() {
+		pod := CurrentPod()
+		if Default != nil {
+			if err := Default.Acquire(pod.Name); err != nil {
+				logger.Log.Warnf("Acquire rollout lease error, stop anyway, %s", err.Error())
+			}
+		}
+		stopping.Store(true)
+		logger.Log.Debugf("Readiness failed, stop after %s", delay)
+		time.Sleep(delay)
+	})
+}
+
+// ReadinessHandler the gin handler of the readiness probe, responded with 503 while the instance is starting or stopping
+func ReadinessHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !Ready() {
+			status := "STOPPING"
+			if !stopping.Load() {
+				status = "STARTING"
+			}
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": status})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "UP", "pod": CurrentPod()})
+	}
+}
+
+// PreStopTokenHeader the request header carrying the token of the preStop endpoint
+const PreStopTokenHeader = "X-PreStop-Token"
+
+/*
+PreStopHandler the gin handler of the preStop http hook, the kubelet sends SIGTERM after it's responded.
+The request fails the readiness until the process exits, so it's only accepted with the token in the X-PreStop-Token header,
+or from the loopback address when the token is empty:
+
+	lifecycle:
+	  preStop:
+	    httpGet: { path: /prestop, port: 4006, httpHeaders: [{ name: X-PreStop-Token, value: <token> }] }
+*/
+func PreStopHandler(delay time.Duration, token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !preStopAllowed(ctx, token) {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		PrepareStop(delay)
+		ctx.Status(http.StatusOK)
+	}
+}
+
+// the forwarded headers are ignored, only the peer address is loopback when the hook runs in the pod
+func preStopAllowed(ctx *gin.Context, token string) bool {
+	if token != "" {
+		return subtle.ConstantTimeCompare([]byte(ctx.GetHeader(PreStopTokenHeader)), []byte(token)) == 1
+	}
+	ip := net.ParseIP(ctx.RemoteIP())
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,82 @@
+package k8s
+
+import (
+	"os"
+	"strings"
+)
+
+// the namespace file of the service account mounted into the pods
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+/*
+Pod the metadata of the current pod, exposed by the downward api as the environment variables:
+
+	env:
+	  - name: POD_NAME
+	    valueFrom: { fieldRef: { fieldPath: metadata.name } }
+	  - name: POD_NAMESPACE
+	    valueFrom: { fieldRef: { fieldPath: metadata.namespace } }
+	  - name: NODE_NAME
+	    valueFrom: { fieldRef: { fieldPath: spec.nodeName } }
+	  - name: POD_IP
+	    valueFrom: { fieldRef: { fieldPath: status.podIP } }
+*/
+type Pod struct {
+	Name      string `json:"name,omitempty"`      // POD_NAME, default the hostname
+	Namespace string `json:"namespace,omitempty"` // POD_NAMESPACE, default the namespace of the service account
+	Node      string `json:"node,omitempty"`      // NODE_NAME
+	IP        string `json:"ip,omitempty"`        // POD_IP
+}
+
+// InCluster Whether the application runs in the kubernetes cluster
+func InCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// CurrentPod The metadata of the current pod
+func CurrentPod() Pod {
+	p := Pod{
+		Name:      os.Getenv("POD_NAME"),
+		Namespace: os.Getenv("POD_NAMESPACE"),
+		Node:      os.Getenv("NODE_NAME"),
+		IP:        os.Getenv("POD_IP"),
+	}
+	if p.Name == "" {
+		p.Name, _ = os.Hostname()
+	}
+	if p.Namespace == "" {
+		if b, err := os.ReadFile(namespaceFile); err == nil {
+			p.Namespace = strings.TrimSpace(string(b))
+		}
+	}
+	return p
+}
+
+// Labels The non-empty metadata as the metric labels
+func (p Pod) Labels() map[string]string {
+	labels := make(map[string]string)
+	for name, value := range map[string]string{"pod": p.Name, "namespace": p.Namespace, "node": p.Node} {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// String The non-empty metadata as the log prefix, such as [pod=order-7d9f namespace=prod]
+func (p Pod) String() string {
+	var pairs []string
+	if p.Name != "" {
+		pairs = append(pairs, "pod="+p.Name)
+	}
+	if p.Namespace != "" {
+		pairs = append(pairs, "namespace="+p.Namespace)
+	}
+	if p.Node != "" {
+		pairs = append(pairs, "node="+p.Node)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(pairs, " ") + "]"
+}
@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+// RolloutConfig limits the instances restarting at the same time by the leases shared in redis
+type RolloutConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`        // Whether to coordinate the restarts, default false
+	MaxConcurrent int           `mapstructure:"max_concurrent"` // Max instances restarting at the same time, default 1
+	LeaseTTL      time.Duration `mapstructure:"lease_ttl"`      // The lease expires after it when the replacement never becomes ready, default 1m
+	WaitTimeout   time.Duration `mapstructure:"wait_timeout"`   // Max wait for the lease, the instance stops anyway when reached, it must be longer than the lease ttl, default 90s
+	GracePeriod   time.Duration `mapstructure:"grace_period"`   // The terminationGracePeriodSeconds of the pod, the wait timeout must be shorter than it, required
+	Key           string        `mapstructure:"key"`            // Redis key of the leases, default gin-plus:rollout:<namespace>
+	Addr          string        `mapstructure:"addr"`           // Redis address, default 127.0.0.1:6379
+	Username      string        `mapstructure:"username"`       // Redis username
+	Password      string        `mapstructure:"password"`       // Redis password
+	DB            int           `mapstructure:"db"`             // Redis database
+}
+
+// ErrLeaseTimeout the lease is not acquired within the wait timeout
+var ErrLeaseTimeout = errors.New("k8s: rollout lease wait timeout")
+
+// release the lease of the holder, or the earliest one when the holder has none, such as the pod of the deployment
+// whose name changes after the restart
+var releaseScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	redis.call('ZPOPMIN', KEYS[1])
+end
+return 1
+`)
+
+// the leases are the members of the sorted set scored by the expiration,
+// the expired ones are removed before counting, the holder renews its own lease
+var acquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZSCORE', KEYS[1], ARGV[3]) or redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[4]) then
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], ARGV[5])
+	return 1
+end
+return 0
+`)
+
+// Rollout coordinates the restarts of the instances, at most MaxConcurrent instances hold the leases at the same time
+type Rollout struct {
+	client  redis.UniversalClient
+	conf    RolloutConfig
+	release sync.Once
+}
+
+// NewRollout Create the restart coordinator from the configuration, the wait timeout must be between the lease ttl
+// and the grace period, otherwise the waiting instance is killed before the lease expires
+func NewRollout(conf RolloutConfig, pod Pod) (*Rollout, error) {
+	if conf.MaxConcurrent <= 0 {
+		conf.MaxConcurrent = 1
+	}
+	if conf.LeaseTTL <= 0 {
+		conf.LeaseTTL = time.Minute
+	}
+	if conf.WaitTimeout <= 0 {
+		conf.WaitTimeout = 90 * time.Second
+	}
+	if conf.WaitTimeout <= conf.LeaseTTL {
+		return nil, fmt.Errorf("k8s: rollout wait timeout %s must be longer than the lease ttl %s", conf.WaitTimeout, conf.LeaseTTL)
+	}
+	if conf.GracePeriod <= conf.WaitTimeout {
+		return nil, fmt.Errorf("k8s: rollout wait timeout %s must be shorter than the grace period %s", conf.WaitTimeout, conf.GracePeriod)
+	}
+	if conf.Key == "" {
+		conf.Key = "gin-plus:rollout:" + pod.Namespace
+	}
+	if conf.Addr == "" {
+		conf.Addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: conf.Addr, Username: conf.Username, Password: conf.Password, DB: conf.DB})
+	return &Rollout{client: client, conf: conf}, nil
+}
+
+/*
+Acquire Wait for the lease of the holder until the wait timeout. The lease isn't released when the instance stops,
+it's released when the replacement becomes ready, or expires after the lease ttl when the replacement never does.
+*/
+func (r *Rollout) Acquire(holder string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.conf.WaitTimeout)
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		ok, err := acquireScript.Run(ctx, r.client, []string{r.conf.Key}, now.UnixMilli(), now.Add(r.conf.LeaseTTL).UnixMilli(),
+			holder, r.conf.MaxConcurrent, r.conf.LeaseTTL.Milliseconds()).Int()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ErrLeaseTimeout
+			}
+			return err
+		}
+		if ok == 1 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ErrLeaseTimeout
+		case <-ticker.C:
+		}
+	}
+}
+
+// Release the lease when the instance becomes ready after the restart, only the first call releases
+func (r *Rollout) Release(holder string) {
+	r.release.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := releaseScript.Run(ctx, r.client, []string{r.conf.Key}, holder).Err(); err != nil {
+			logger.Log.Warnf("Release rollout lease error, it expires after %s, %s", r.conf.LeaseTTL, err.Error())
+		}
+	})
+}
+
+// Close the redis client
+func (r *Rollout) Close() error {
+	return r.client.Close()
+}
@@ -32,19 +32,19 @@ func (d *DefaultLog) Errorf(msg string, args ...any) {
 }
 
 func (d *DefaultLog) Info(v ...any) {
-	log.Println(v)
+	log.Println(v...)
 }
 
 func (d *DefaultLog) Warn(v ...any) {
-	log.Println(v)
+	log.Println(v...)
 }
 
 func (d *DefaultLog) Debug(v ...any) {
-	log.Println(v)
+	log.Println(v...)
 }
 
 func (d *DefaultLog) Error(v ...any) {
-	log.Println(v)
+	log.Println(v...)
 }
 
 func (d *DefaultLog) Println(v ...any) {
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"fmt"
+)
+
+// PrefixLog adds the prefix to the messages of the log collector, such as the pod metadata
+type PrefixLog struct {
+	AbstractLogger
+	Prefix string
+}
+
+// WithPrefix Wrap the log collector, the messages are logged with the prefix
+func WithPrefix(l AbstractLogger, prefix string) AbstractLogger {
+	return &PrefixLog{AbstractLogger: l, Prefix: prefix}
+}
+
+func (p *PrefixLog) Infof(msg string, args ...any) {
+	p.AbstractLogger.Infof(p.Prefix+msg, args...)
+}
+
+func (p *PrefixLog) Warnf(msg string, args ...any) {
+	p.AbstractLogger.Warnf(p.Prefix+msg, args...)
+}
+
+func (p *PrefixLog) Debugf(msg string, args ...any) {
+	p.AbstractLogger.Debugf(p.Prefix+msg, args...)
+}
+
+func (p *PrefixLog) Errorf(msg string, args ...any) {
+	p.AbstractLogger.Errorf(p.Prefix+msg, args...)
+}
+
+func (p *PrefixLog) Info(v ...any) {
+	p.AbstractLogger.Info(p.Prefix + fmt.Sprint(v...))
+}
+
+func (p *PrefixLog) Warn(v ...any) {
+	p.AbstractLogger.Warn(p.Prefix + fmt.Sprint(v...))
+}
+
+func (p *PrefixLog) Debug(v ...any) {
+	p.AbstractLogger.Debug(p.Prefix + fmt.Sprint(v...))
+}
+
+func (p *PrefixLog) Error(v ...any) {
+	p.AbstractLogger.Error(p.Prefix + fmt.Sprint(v...))
+}
+
+func (p *PrefixLog) Println(v ...any) {
+	p.AbstractLogger.Println(p.Prefix + fmt.Sprint(v...))
+}
+
+func (p *PrefixLog) Printf(format string, v ...any) {
+	p.AbstractLogger.Printf(p.Prefix+format, v...)
+}
+
+func (p *PrefixLog) Fatal(v ...any) {
+	p.AbstractLogger.Fatal(p.Prefix + fmt.Sprint(v...))
+}
+
+func (p *PrefixLog) Fatalf(format string, v ...any) {
+	p.AbstractLogger.Fatalf(p.Prefix+format, v...)
+}
@@ -0,0 +1,296 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default the listener container of the application, the listeners are started after the application started and
+// stopped when it stops
+var Default = New(Config{})
+
+// Config the messaging, the broker is configured by its driver
+type Config struct {
+	Serializer       string        `mapstructure:"serializer"`         // Codec of the payloads, json or the codec registered by RegisterCodec(), default json
+	Retries          int           `mapstructure:"retries"`            // Retries of the failed message before it's sent to the dead letter topic, default 3
+	Backoff          time.Duration `mapstructure:"backoff"`            // Delay of the first retry, it's doubled for each retry, default 1s
+	MaxBackoff       time.Duration `mapstructure:"max_backoff"`        // Max delay of the retries, default 30s
+	DeadLetterSuffix string        `mapstructure:"dead_letter_suffix"` // Suffix of the dead letter topic of the topic, default .DLT, - means the failed message is dropped
+}
+
+// Dead letter headers describing why the message failed
+const (
+	HeaderOriginalTopic = "x-original-topic"
+	HeaderException     = "x-exception"
+	HeaderAttempts      = "x-attempts"
+)
+
+// Container runs the listeners by the broker
+type Container struct {
+	mu        sync.RWMutex
+	conf      Config
+	codec     Codec
+	broker    Broker
+	listeners []Listener
+	producer  *Producer
+	cancel    context.CancelFunc // Stops consuming
+	jobCtx    context.Context    // Context of the handling messages, canceled when they don't finish in time after stopping
+	stopJob   context.CancelFunc
+	consumers sync.WaitGroup
+	started   bool
+}
+
+// New Create the listener container
+func New(conf Config) *Container {
+	c := &Container{}
+	c.producer = &Producer{c: c}
+	if err := c.SetConfig(conf); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// SetConfig Set the configuration, it should be set before the listeners are started
+func (c *Container) SetConfig(conf Config) error {
+	if conf.Serializer == "" {
+		conf.Serializer = "json"
+	}
+	if conf.Retries < 0 {
+		conf.Retries = 0
+	}
+	if conf.Backoff <= 0 {
+		conf.Backoff = time.Second
+	}
+	if conf.MaxBackoff <= 0 {
+		conf.MaxBackoff = 30 * time.Second
+	}
+	if conf.DeadLetterSuffix == "" {
+		conf.DeadLetterSuffix = ".DLT"
+	}
+	codec, err := codecOf(conf.Serializer)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conf, c.codec = conf, codec
+	return nil
+}
+
+// SetBroker Set the broker, the previous one is closed
+func (c *Container) SetBroker(broker Broker) {
+	c.mu.Lock()
+	prev := c.broker
+	c.broker = broker
+	c.mu.Unlock()
+	if prev != nil {
+		_ = prev.Close()
+	}
+}
+
+// Producer Get the producer sending by the broker of the container
+func (c *Container) Producer() *Producer {
+	return c.producer
+}
+
+func (c *Container) current() (Broker, Codec) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.broker, c.codec
+}
+
+// Add the listeners, the listeners added after starting are not started
+func (c *Container) Add(listeners ...Listener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listeners...)
+}
+
+// Register Add the listeners of the beans implementing ListenerBean and the methods annotated by the listener
+// annotations, the other beans are ignored
+func (c *Container) Register(beans ...any) error {
+	for _, bean := range beans {
+		if lb, ok := bean.(ListenerBean); ok {
+			c.Add(lb.MessageListeners()...)
+		}
+		listeners, err := annotatedListeners(bean)
+		if err != nil {
+			return err
+		}
+		c.Add(listeners...)
+	}
+	return c.validate()
+}
+
+// the listeners are rejected when the broker requires the group and they don't have it
+func (c *Container) validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	gb, ok := c.broker.(GroupedBroker)
+	if !ok || !gb.RequiresGroup() {
+		return nil
+	}
+	for _, l := range c.listeners {
+		if l.Group == "" {
+			return fmt.Errorf("listener %s of %s requires the group on %T", l.name, l.Topic, c.broker)
+		}
+	}
+	return nil
+}
+
+// Start consuming by the listeners, it does nothing when there's no broker, no listener or it's started
+func (c *Container) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started || c.broker == nil || len(c.listeners) == 0 {
+		return
+	}
+	c.started = true
+	gb, grouped := c.broker.(GroupedBroker)
+	grouped = grouped && gb.RequiresGroup()
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	c.jobCtx, c.stopJob = context.WithCancel(context.Background())
+	for _, l := range c.listeners {
+		// the listeners added after the registration are checked here
+		if grouped && l.Group == "" {
+			logger.Log.Errorf("Message listener %s of %s is not started, the group is required on %T", l.name, l.Topic, c.broker)
+			continue
+		}
+		for i := 0; i < max(l.concurrency, 1); i++ {
+			c.consumers.Add(1)
+			go c.consume(ctx, c.jobCtx, l)
+		}
+	}
+	logger.Log.Debugf("Message listeners started, %d listeners on %T", len(c.listeners), c.broker)
+}
+
+// consume the topic until the container stops, the consuming is restarted when the connection fails
+func (c *Container) consume(ctx, jobCtx context.Context, l Listener) {
+	defer c.consumers.Done()
+	broker, codec := c.current()
+	for {
+		err := broker.Consume(ctx, l.Topic, l.Group, func(msg *Message) error {
+			return c.handle(jobCtx, broker, codec, l, msg)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Log.Warnf("Message listener %s of %s stopped, restart in 1s, %s", l.name, l.Topic, err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// handle the message with the retries, the message failed after all the retries is sent to the dead letter topic
+func (c *Container) handle(ctx context.Context, broker Broker, codec Codec, l Listener, msg *Message) error {
+	c.mu.RLock()
+	conf := c.conf
+	c.mu.RUnlock()
+	retries := conf.Retries
+	if l.retries >= 0 {
+		retries = l.retries
+	}
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		if err = call(ctx, codec, l, msg); err == nil {
+			return nil
+		}
+		if attempts > retries {
+			break
+		}
+		backoff := conf.MaxBackoff
+		if attempts <= 32 {
+			backoff = min(conf.Backoff<<(attempts-1), conf.MaxBackoff)
+		}
+		logger.Log.Warnf("Message listener %s of %s failed, retry %d in %s, %s", l.name, msg.Topic, attempts, backoff, err.Error())
+		select {
+		case <-ctx.Done():
+			// the message is redelivered after the restart
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	if conf.DeadLetterSuffix == "-" {
+		logger.Log.Errorf("Message listener %s of %s failed after %d attempts, the message is dropped, %s", l.name, msg.Topic, attempts, err.Error())
+		return nil
+	}
+	dead := &Message{Topic: msg.Topic + conf.DeadLetterSuffix, Key: msg.Key, Value: msg.Value, Headers: make(map[string]string, len(msg.Headers)+3)}
+	for k, v := range msg.Headers {
+		dead.Headers[k] = v
+	}
+	dead.Headers[HeaderOriginalTopic] = msg.Topic
+	dead.Headers[HeaderException] = err.Error()
+	dead.Headers[HeaderAttempts] = strconv.Itoa(attempts)
+	logger.Log.Errorf("Message listener %s of %s failed after %d attempts, sent to %s, %s", l.name, msg.Topic, attempts, dead.Topic, err.Error())
+	if perr := broker.Publish(context.WithoutCancel(ctx), dead); perr != nil {
+		return errors.Join(fmt.Errorf("send the dead letter to %s error, %w", dead.Topic, perr), err)
+	}
+	return nil
+}
+
+// call the listener, the panic is returned as the error
+func call(ctx context.Context, codec Codec, l Listener, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Errorf("Message listener %s panic, %v\n%s", l.name, r, debug.Stack())
+			err = fmt.Errorf("panic, %v", r)
+		}
+	}()
+	return l.handle(ctx, codec, msg)
+}
+
+// Stop consuming and wait for the handling messages until the context is done, then their context is canceled
+func (c *Container) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return nil
+	}
+	c.started = false
+	c.cancel()
+	stopJob := c.stopJob
+	c.mu.Unlock()
+	defer stopJob()
+	done := make(chan struct{})
+	go func() {
+		c.consumers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("messages are still handling")
+	}
+}
+
+// Close the broker
+func (c *Container) Close() error {
+	c.mu.Lock()
+	broker := c.broker
+	c.broker = nil
+	c.mu.Unlock()
+	if broker == nil {
+		return nil
+	}
+	return broker.Close()
+}
+
+// Send the payload to the topic by the default container
+func Send(ctx context.Context, topic string, payload any, opts ...SendOption) error {
+	return Default.producer.Send(ctx, topic, payload, opts...)
+}
@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/plugin/messaging"
+	"github.com/segmentio/kafka-go"
+	"time"
+)
+
+// Config the kafka cluster
+type Config struct {
+	Brokers     []string      `mapstructure:"brokers"`      // Addresses of the brokers, default 127.0.0.1:9092
+	StartOffset string        `mapstructure:"start_offset"` // Offset of the new consumer group, earliest or latest, default latest
+	MinBytes    int           `mapstructure:"min_bytes"`    // Min bytes of the fetch, default 1
+	MaxBytes    int           `mapstructure:"max_bytes"`    // Max bytes of the fetch, default 10MB
+	MaxWait     time.Duration `mapstructure:"max_wait"`     // Max wait of the fetch, default 10s
+	BatchSize   int           `mapstructure:"batch_size"`   // Messages of the batch sent by the producer, default 1, so the message is sent immediately
+	Async       bool          `mapstructure:"async"`        // Send without waiting for the acknowledgement, the errors are logged, default false
+}
+
+// Broker the kafka broker, the messages of the same key are sent to the same partition
+type Broker struct {
+	conf   Config
+	writer *kafka.Writer
+}
+
+// New Create the kafka broker, the connections are established on the first use
+func New(conf Config) *Broker {
+	if len(conf.Brokers) == 0 {
+		conf.Brokers = []string{"127.0.0.1:9092"}
+	}
+	if conf.MinBytes <= 0 {
+		conf.MinBytes = 1
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = 10 << 20
+	}
+	if conf.MaxWait <= 0 {
+		conf.MaxWait = 10 * time.Second
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 1
+	}
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(conf.Brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchSize:    conf.BatchSize,
+		Async:        conf.Async,
+	}
+	if conf.Async {
+		writer.Completion = func(messages []kafka.Message, err error) {
+			if err != nil {
+				logger.Log.Errorf("Send %d kafka messages error, %s", len(messages), err.Error())
+			}
+		}
+	}
+	return &Broker{conf: conf, writer: writer}
+}
+
+func (b *Broker) Publish(ctx context.Context, msg *messaging.Message) error {
+	km := kafka.Message{Topic: msg.Topic, Key: msg.Key, Value: msg.Value, Time: msg.Time}
+	for k, v := range msg.Headers {
+		km.Headers = append(km.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return b.writer.WriteMessages(ctx, km)
+}
+
+// RequiresGroup The offsets are committed by the consumer group, so the listeners require the group
+func (b *Broker) RequiresGroup() bool {
+	return true
+}
+
+func (b *Broker) Consume(ctx context.Context, topic, group string, handle func(msg *messaging.Message) error) error {
+	startOffset := kafka.LastOffset
+	if b.conf.StartOffset == "earliest" {
+		startOffset = kafka.FirstOffset
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     b.conf.Brokers,
+		GroupID:     group,
+		Topic:       topic,
+		MinBytes:    b.conf.MinBytes,
+		MaxBytes:    b.conf.MaxBytes,
+		MaxWait:     b.conf.MaxWait,
+		StartOffset: startOffset,
+	})
+	defer reader.Close()
+	for {
+		km, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		msg := &messaging.Message{Topic: km.Topic, Key: km.Key, Value: km.Value, Time: km.Time, Headers: make(map[string]string, len(km.Headers))}
+		for _, h := range km.Headers {
+			msg.Headers[h.Key] = string(h.Value)
+		}
+		if err = handle(msg); err != nil {
+			return err
+		}
+		// the handled message is committed even when the consuming is stopping
+		commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		err = reader.CommitMessages(commitCtx, km)
+		cancel()
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+}
+
+// Close the producer, the buffered messages are sent
+func (b *Broker) Close() error {
+	return b.writer.Close()
+}
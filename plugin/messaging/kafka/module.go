@@ -0,0 +1,36 @@
+package kafka
+
+import (
+	"context"
+	"github.com/archine/gin-plus/v3/plugin/messaging"
+	"github.com/spf13/viper"
+)
+
+/*
+Module the application module setting the kafka broker of messaging.Default from the messaging.kafka configuration.
+
+	application.Default().Modules(kafka.NewModule()).Run()
+*/
+type Module struct {
+	conf Config
+}
+
+// NewModule Create the kafka module
+func NewModule() *Module {
+	return &Module{}
+}
+
+func (m *Module) Name() string {
+	return "kafka"
+}
+
+// Configure Read the messaging.kafka configuration
+func (m *Module) Configure(conf *viper.Viper) error {
+	return conf.UnmarshalKey("messaging.kafka", &m.conf)
+}
+
+// Init Set the broker, the connections are established on the first use
+func (m *Module) Init(context.Context) error {
+	messaging.Default.SetBroker(New(m.conf))
+	return nil
+}
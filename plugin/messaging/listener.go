@@ -0,0 +1,165 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+)
+
+// Listener annotations of the bean methods, the listener method is declared as func(ctx context.Context, payload T) error
+//
+//	// SendOrderMail
+//	// @KafkaListener(topic="orders", group="mail", concurrency="2")
+//	func (m *MailService) SendOrderMail(ctx context.Context, e *OrderCreated) error {}
+//
+//	// SyncStock
+//	// @RabbitListener(queue="stock")
+//	func (s *StockService) SyncStock(ctx context.Context, e *StockChanged) error {}
+//
+// The annotations are found in the annotations generated by gp-ast, the same as the api methods
+const (
+	KafkaListenerAnnotation  = "KafkaListener"
+	RabbitListenerAnnotation = "RabbitListener"
+)
+
+/*
+Listener consumes the messages of the topic, it's created by Listen() or the listener annotations. The failed message is
+retried with the backoff and sent to the dead letter topic after all the retries, the panic is recovered as the error.
+*/
+type Listener struct {
+	Topic       string
+	Group       string // Consumer group of kafka, it's required by kafka and ignored by rabbitmq where the consumers of the queue compete
+	name        string
+	concurrency int
+	retries     int
+	handle      func(ctx context.Context, codec Codec, msg *Message) error
+}
+
+// ListenerOption the option of the listener
+type ListenerOption func(l *Listener)
+
+// Concurrency Set the consumers of the listener, default 1
+func Concurrency(n int) ListenerOption {
+	return func(l *Listener) {
+		l.concurrency = n
+	}
+}
+
+// Retries Set the retries of the failed message, 0 means it's sent to the dead letter topic immediately
+func Retries(n int) ListenerOption {
+	return func(l *Listener) {
+		l.retries = n
+	}
+}
+
+/*
+Listen Create the listener of the topic, the payload is decoded by the codec. T can be []byte, string or *Message to
+receive the message as it is
+*/
+func Listen[T any](topic, group string, handler func(ctx context.Context, payload T) error, opts ...ListenerOption) Listener {
+	l := Listener{
+		Topic:       topic,
+		Group:       group,
+		name:        runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name(),
+		concurrency: 1,
+		retries:     -1,
+		handle: func(ctx context.Context, codec Codec, msg *Message) error {
+			var v T
+			if err := decode(codec, msg, &v); err != nil {
+				return fmt.Errorf("decode message error, %w", err)
+			}
+			return handler(ctx, v)
+		},
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	return l
+}
+
+/*
+ListenerBean the bean consuming the messages, the listeners are started after the application started
+
+	func (m *MailService) MessageListeners() []messaging.Listener {
+	    return []messaging.Listener{
+	        messaging.Listen("orders", "mail", m.SendOrderMail, messaging.Concurrency(2)),
+	    }
+	}
+
+	func (m *MailService) SendOrderMail(ctx context.Context, e *OrderCreated) error {}
+*/
+type ListenerBean interface {
+	MessageListeners() []Listener
+}
+
+var (
+	ctxType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// the listeners of the methods of the bean annotated by the listener annotations
+func annotatedListeners(bean any) ([]Listener, error) {
+	bv := reflect.ValueOf(bean)
+	if bv.Kind() != reflect.Pointer || bv.Elem().Kind() != reflect.Struct {
+		return nil, nil
+	}
+	methods := mvc.MethodAnnotations(bean)
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var listeners []Listener
+	for _, name := range names {
+		val, ok := methods[name][KafkaListenerAnnotation]
+		if !ok {
+			if val, ok = methods[name][RabbitListenerAnnotation]; !ok {
+				continue
+			}
+		}
+		args := mvc.ParseAnnotationArgs(val)
+		topic := args["topic"]
+		if topic == "" {
+			topic = args["queue"]
+		}
+		if topic == "" {
+			topic = args["value"]
+		}
+		method := bv.MethodByName(name)
+		if !method.IsValid() || topic == "" {
+			return nil, fmt.Errorf("invalid listener %T.%s, the method and the topic are required", bean, name)
+		}
+		mt := method.Type()
+		if mt.NumIn() != 2 || mt.In(0) != ctxType || mt.NumOut() != 1 || mt.Out(0) != errorType {
+			return nil, fmt.Errorf("invalid listener %T.%s, func(ctx context.Context, payload T) error is expected", bean, name)
+		}
+		l := Listener{
+			Topic:       topic,
+			Group:       args["group"],
+			name:        fmt.Sprintf("%T.%s", bean, name),
+			concurrency: 1,
+			retries:     -1,
+			handle: func(ctx context.Context, codec Codec, msg *Message) error {
+				v := reflect.New(mt.In(1))
+				if err := decode(codec, msg, v.Interface()); err != nil {
+					return fmt.Errorf("decode message error, %w", err)
+				}
+				out := method.Call([]reflect.Value{reflect.ValueOf(ctx), v.Elem()})
+				err, _ := out[0].Interface().(error)
+				return err
+			},
+		}
+		if n, err := strconv.Atoi(args["concurrency"]); err == nil {
+			l.concurrency = n
+		}
+		if n, err := strconv.Atoi(args["retries"]); err == nil {
+			l.retries = n
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,155 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Message the message sent to or received from the broker
+type Message struct {
+	Topic   string            // Topic of kafka, or the routing key of rabbitmq
+	Key     []byte            // Key of the message, the messages of the same key are in order on kafka
+	Value   []byte            // Payload encoded by the codec
+	Headers map[string]string // Headers of the message
+	Time    time.Time         // Time of the message, set by the broker
+}
+
+// Broker the driver of the message broker, such as plugin/messaging/kafka
+type Broker interface {
+	// Publish the message
+	Publish(ctx context.Context, msg *Message) error
+
+	/*
+		Consume the messages of the topic as the member of the group until the context is done or the connection fails.
+		The message is acknowledged when handle returns nil, otherwise the consuming stops without acknowledging it,
+		so it's redelivered
+	*/
+	Consume(ctx context.Context, topic, group string, handle func(msg *Message) error) error
+
+	// Close the connections of the broker
+	Close() error
+}
+
+// GroupedBroker the broker whose consumers commit the offsets by the group, such as kafka, the listeners without the
+// group are rejected, because their offsets can't be committed
+type GroupedBroker interface {
+	Broker
+	RequiresGroup() bool
+}
+
+// Codec encodes the payloads of the messages, the []byte and string payloads are sent as they are
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes the payloads as json
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+var (
+	codecMu sync.RWMutex
+	codecs  = map[string]Codec{"json": JSONCodec{}}
+)
+
+// RegisterCodec Register the codec used by the serializer configuration, such as protobuf
+func RegisterCodec(name string, codec Codec) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codecs[name] = codec
+}
+
+func codecOf(name string) (Codec, error) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown message serializer %s, register it by messaging.RegisterCodec()", name)
+	}
+	return codec, nil
+}
+
+// encode the payload, the message and the raw payloads are not encoded
+func encode(codec Codec, payload any) ([]byte, error) {
+	switch v := payload.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return codec.Marshal(payload)
+}
+
+// decode the value of the message into the pointer, the raw payloads are not decoded
+func decode(codec Codec, msg *Message, v any) error {
+	switch p := v.(type) {
+	case *[]byte:
+		*p = msg.Value
+		return nil
+	case *string:
+		*p = string(msg.Value)
+		return nil
+	case **Message:
+		*p = msg
+		return nil
+	}
+	return codec.Unmarshal(msg.Value, v)
+}
+
+// Producer sends the messages by the broker of the configuration, it's the bean injected into the services
+//
+//	type OrderService struct {
+//	    Producer *messaging.Producer
+//	}
+//
+//	err := o.Producer.Send(ctx, "orders", &OrderCreated{Id: order.Id}, messaging.WithKey(order.UserId))
+type Producer struct {
+	c *Container
+}
+
+// SendOption the option of the sent message
+type SendOption func(msg *Message)
+
+// WithKey Set the key of the message
+func WithKey(key string) SendOption {
+	return func(msg *Message) {
+		msg.Key = []byte(key)
+	}
+}
+
+// WithHeader Set the header of the message
+func WithHeader(name, value string) SendOption {
+	return func(msg *Message) {
+		if msg.Headers == nil {
+			msg.Headers = make(map[string]string)
+		}
+		msg.Headers[name] = value
+	}
+}
+
+// Send the payload encoded by the codec to the topic
+func (p *Producer) Send(ctx context.Context, topic string, payload any, opts ...SendOption) error {
+	broker, codec := p.c.current()
+	if broker == nil {
+		return fmt.Errorf("no message broker, the message to %s is not sent", topic)
+	}
+	b, err := encode(codec, payload)
+	if err != nil {
+		return fmt.Errorf("encode message error, %w", err)
+	}
+	msg := &Message{Topic: topic, Value: b}
+	for _, opt := range opts {
+		opt(msg)
+	}
+	return broker.Publish(ctx, msg)
+}
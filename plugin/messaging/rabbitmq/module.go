@@ -0,0 +1,36 @@
+package rabbitmq
+
+import (
+	"context"
+	"github.com/archine/gin-plus/v3/plugin/messaging"
+	"github.com/spf13/viper"
+)
+
+/*
+Module the application module setting the rabbitmq broker of messaging.Default from the messaging.rabbitmq configuration.
+
+	application.Default().Modules(rabbitmq.NewModule()).Run()
+*/
+type Module struct {
+	conf Config
+}
+
+// NewModule Create the rabbitmq module
+func NewModule() *Module {
+	return &Module{}
+}
+
+func (m *Module) Name() string {
+	return "rabbitmq"
+}
+
+// Configure Read the messaging.rabbitmq configuration
+func (m *Module) Configure(conf *viper.Viper) error {
+	return conf.UnmarshalKey("messaging.rabbitmq", &m.conf)
+}
+
+// Init Set the broker, the connection is established on the first use
+func (m *Module) Init(context.Context) error {
+	messaging.Default.SetBroker(New(m.conf))
+	return nil
+}
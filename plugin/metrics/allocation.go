@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	rtmetrics "runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AllocationConfig the per-request allocation diagnostics, it is designed for the development environment.
+// The allocations are measured by the process-wide runtime metrics, so the concurrent requests are counted into each other
+type AllocationConfig struct {
+	Enabled       bool    `mapstructure:"enabled"`        // Whether to record the allocations of each request, default false
+	MaxBytes      uint64  `mapstructure:"max_bytes"`      // Allocated bytes budget of a request, default 10MB, 0 means no budget
+	MaxObjects    uint64  `mapstructure:"max_objects"`    // Allocated objects budget of a request, default 0 means no budget
+	MaxGoroutines uint64  `mapstructure:"max_goroutines"` // Peak goroutines budget during a request, default 0 means no budget
+	Factor        float64 `mapstructure:"factor"`         // Flag the request allocating more than factor times the route average, default 4
+	MinSamples    int     `mapstructure:"min_samples"`    // Requests of the route required before comparing with the average, default 20
+	Path          string  `mapstructure:"path"`           // Endpoint exposing the per-route statistics as json, default empty means not exposed
+}
+
+// AllocationStat the allocation statistics of a route
+type AllocationStat struct {
+	Route         string `json:"route"`
+	Requests      int64  `json:"requests"`
+	AvgBytes      uint64 `json:"avg_bytes"`
+	MaxBytes      uint64 `json:"max_bytes"`
+	AvgObjects    uint64 `json:"avg_objects"`
+	MaxObjects    uint64 `json:"max_objects"`
+	MaxGoroutines uint64 `json:"max_goroutines"`
+	Outliers      int64  `json:"outliers"`
+}
+
+type allocationStats struct {
+	mu     sync.Mutex
+	routes map[string]*AllocationStat
+	bytes  map[string]uint64 // total bytes of each route
+	objs   map[string]uint64 // total objects of each route
+}
+
+var allocStats = &allocationStats{routes: map[string]*AllocationStat{}, bytes: map[string]uint64{}, objs: map[string]uint64{}}
+
+// goroutine peak sampler, running while any request is diagnosed
+var (
+	samplerMu   sync.Mutex
+	samplerRefs int
+	samplerPeak = map[*uint64]struct{}{}
+)
+
+/*
+Allocation The gin middleware records the allocated bytes, objects and the peak goroutines of each request,
+the requests exceeding the budgets or the route average by the factor are logged as warnings.
+*/
+func Allocation(conf AllocationConfig) gin.HandlerFunc {
+	if conf.Factor <= 0 {
+		conf.Factor = 4
+	}
+	if conf.MinSamples <= 0 {
+		conf.MinSamples = 20
+	}
+	return func(ctx *gin.Context) {
+		samples := []rtmetrics.Sample{{Name: "/gc/heap/allocs:bytes"}, {Name: "/gc/heap/allocs:objects"}, {Name: "/sched/goroutines:goroutines"}}
+		rtmetrics.Read(samples)
+		startBytes, startObjs, goroutines := samples[0].Value.Uint64(), samples[1].Value.Uint64(), samples[2].Value.Uint64()
+		peak := goroutines
+		watchGoroutines(&peak)
+		// the sampler stops watching even when the handler panics
+		defer unwatchGoroutines(&peak)
+		start := time.Now()
+		ctx.Next()
+		rtmetrics.Read(samples)
+		bytes, objs := samples[0].Value.Uint64()-startBytes, samples[1].Value.Uint64()-startObjs
+		if g := samples[2].Value.Uint64(); g > atomic.LoadUint64(&peak) {
+			atomic.StoreUint64(&peak, g)
+		}
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRouteLabel
+		}
+		if reason := allocStats.record(route, bytes, objs, atomic.LoadUint64(&peak), conf); reason != "" {
+			logger.Log.Warnf("Allocation outlier [%s %s] %s, allocated %d bytes in %d objects, peak goroutines %d, cost %s",
+				ctx.Request.Method, ctx.Request.URL.Path, reason, bytes, objs, atomic.LoadUint64(&peak), time.Since(start))
+		}
+	}
+}
+
+// AllocationHandler Respond the allocation statistics of each route as json
+func AllocationHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(200, allocStats.snapshot())
+	}
+}
+
+// record the request, returns the reason when it is an outlier
+func (s *allocationStats) record(route string, bytes, objs, goroutines uint64, conf AllocationConfig) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat, ok := s.routes[route]
+	if !ok {
+		stat = &AllocationStat{Route: route}
+		s.routes[route] = stat
+	}
+	reason := ""
+	switch {
+	case conf.MaxBytes > 0 && bytes > conf.MaxBytes:
+		reason = "exceeds the bytes budget"
+	case conf.MaxObjects > 0 && objs > conf.MaxObjects:
+		reason = "exceeds the objects budget"
+	case conf.MaxGoroutines > 0 && goroutines > conf.MaxGoroutines:
+		reason = "exceeds the goroutines budget"
+	case stat.Requests >= int64(conf.MinSamples) && float64(bytes) > conf.Factor*float64(s.bytes[route]/uint64(stat.Requests)):
+		reason = "exceeds the route average"
+	}
+	stat.Requests++
+	s.bytes[route] += bytes
+	s.objs[route] += objs
+	stat.AvgBytes = s.bytes[route] / uint64(stat.Requests)
+	stat.AvgObjects = s.objs[route] / uint64(stat.Requests)
+	stat.MaxBytes = max(stat.MaxBytes, bytes)
+	stat.MaxObjects = max(stat.MaxObjects, objs)
+	stat.MaxGoroutines = max(stat.MaxGoroutines, goroutines)
+	if reason != "" {
+		stat.Outliers++
+	}
+	return reason
+}
+
+func (s *allocationStats) snapshot() []AllocationStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]AllocationStat, 0, len(s.routes))
+	for _, route := range sortedKeys(s.routes) {
+		stats = append(stats, *s.routes[route])
+	}
+	return stats
+}
+
+// watch the peak goroutines during the request, the sampler starts with the first watcher
+func watchGoroutines(peak *uint64) {
+	samplerMu.Lock()
+	defer samplerMu.Unlock()
+	samplerPeak[peak] = struct{}{}
+	if samplerRefs++; samplerRefs == 1 {
+		go sampleGoroutines()
+	}
+}
+
+func unwatchGoroutines(peak *uint64) {
+	samplerMu.Lock()
+	defer samplerMu.Unlock()
+	delete(samplerPeak, peak)
+	samplerRefs--
+}
+
+func sampleGoroutines() {
+	samples := []rtmetrics.Sample{{Name: "/sched/goroutines:goroutines"}}
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for range ticker.C {
+		rtmetrics.Read(samples)
+		g := samples[0].Value.Uint64()
+		samplerMu.Lock()
+		if samplerRefs == 0 {
+			samplerMu.Unlock()
+			return
+		}
+		for peak := range samplerPeak {
+			if g > atomic.LoadUint64(peak) {
+				atomic.StoreUint64(peak, g)
+			}
+		}
+		samplerMu.Unlock()
+	}
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"sync"
+)
+
+// Saturation scopes
+const (
+	RouteScope = "route"
+	PoolScope  = "pool"
+)
+
+// SaturationAlert triggered when the in-flight count of a route or a worker pool reaches its threshold.
+// scope: RouteScope or PoolScope
+// name: the route pattern or the pool name
+type SaturationAlert func(scope, name string, inFlight float64, threshold int)
+
+// SaturationConfig the in-flight thresholds, 0 means no alert
+type SaturationConfig struct {
+	Route int            `mapstructure:"route"` // Threshold of each route
+	Pools map[string]int `mapstructure:"pools"` // Threshold of each worker pool, the key is the pool name
+}
+
+var (
+	concurrencyOnce  sync.Once
+	routeInFlight    *Gauge
+	poolInFlight     *Gauge
+	saturation       SaturationConfig
+	saturationAlerts []SaturationAlert
+)
+
+func initConcurrencyMetrics() {
+	concurrencyOnce.Do(func() {
+		routeInFlight = NewGauge("http_requests_in_flight", "Number of requests being served per route.", "route")
+		poolInFlight = NewGauge("worker_pool_in_flight", "Number of tasks being executed per worker pool.", "pool")
+	})
+}
+
+// SetSaturation Sets the in-flight thresholds
+func SetSaturation(conf SaturationConfig) {
+	saturation = conf
+}
+
+// OnSaturation Add the saturation alert hooks, they are triggered when the in-flight count rises to the threshold
+func OnSaturation(alerts ...SaturationAlert) {
+	saturationAlerts = append(saturationAlerts, alerts...)
+}
+
+// Concurrency The gin middleware records the in-flight requests of each route
+func Concurrency() gin.HandlerFunc {
+	initConcurrencyMetrics()
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRouteLabel
+		}
+		current := routeInFlight.Inc(route)
+		checkSaturation(RouteScope, route, current, saturation.Route)
+		defer routeInFlight.Dec(route)
+		ctx.Next()
+	}
+}
+
+// TrackPool Record a task of the worker pool is started, the returned function must be called when the task finishes.
+//
+//	done := metrics.TrackPool("email")
+//	defer done()
+func TrackPool(pool string) (done func()) {
+	initConcurrencyMetrics()
+	current := poolInFlight.Inc(pool)
+	checkSaturation(PoolScope, pool, current, saturation.Pools[pool])
+	return func() {
+		poolInFlight.Dec(pool)
+	}
+}
+
+// trigger the alerts only when the in-flight count rises to the threshold, to avoid alert storms
+func checkSaturation(scope, name string, current float64, threshold int) {
+	if threshold <= 0 || current != float64(threshold) {
+		return
+	}
+	for _, alert := range saturationAlerts {
+		alert(scope, name, current, threshold)
+	}
+}
@@ -0,0 +1,274 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registered metrics, exposed in the prometheus text format
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]collector)
+)
+
+// the labels added to all samples, such as the pod name
+var constLabels []string
+
+type collector interface {
+	write(buf *bytes.Buffer)
+}
+
+// metric basic information
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+// Counter the cumulative metric that only increases
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Gauge the metric that can go up and down
+type Gauge struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Histogram samples observations and counts them in configurable buckets
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewCounter Create and register a counter, the label values are passed in order of the label names
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{desc: desc{name, help, labelNames}, values: make(map[string]float64)}
+	register(name, c)
+	return c
+}
+
+// NewGauge Create and register a gauge
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{desc: desc{name, help, labelNames}, values: make(map[string]float64)}
+	register(name, g)
+	return g
+}
+
+// NewHistogram Create and register a histogram, the buckets are upper bounds in increasing order
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{desc: desc{name, help, labelNames}, buckets: buckets, values: make(map[string]*histogramValue)}
+	register(name, h)
+	return h
+}
+
+// register the metric, panic when the name is already registered
+func register(name string, c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("metric [%s] is already registered", name))
+	}
+	registry[name] = c
+}
+
+// Add the value to the counter, the value must be positive
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.values[labelKey(labelValues)] += v
+	c.mu.Unlock()
+}
+
+// Inc the counter by 1
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Set the gauge value
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.mu.Lock()
+	g.values[labelKey(labelValues)] = v
+	g.mu.Unlock()
+}
+
+// Add the value to the gauge, the value can be negative
+func (g *Gauge) Add(v float64, labelValues ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	k := labelKey(labelValues)
+	g.values[k] += v
+	return g.values[k]
+}
+
+// Inc the gauge by 1 and return the current value
+func (g *Gauge) Inc(labelValues ...string) float64 {
+	return g.Add(1, labelValues...)
+}
+
+// Dec the gauge by 1 and return the current value
+func (g *Gauge) Dec(labelValues ...string) float64 {
+	return g.Add(-1, labelValues...)
+}
+
+// Value the current value of the gauge
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[labelKey(labelValues)]
+}
+
+// Observe add a single observation to the histogram
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	k := labelKey(labelValues)
+	hv, ok := h.values[k]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hv
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+func (c *Counter) write(buf *bytes.Buffer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeHeader(buf, "counter")
+	for _, k := range sortedKeys(c.values) {
+		c.writeSample(buf, c.name, k, "", c.values[k])
+	}
+}
+
+func (g *Gauge) write(buf *bytes.Buffer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writeHeader(buf, "gauge")
+	for _, k := range sortedKeys(g.values) {
+		g.writeSample(buf, g.name, k, "", g.values[k])
+	}
+}
+
+func (h *Histogram) write(buf *bytes.Buffer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(buf, "histogram")
+	for _, k := range sortedKeys(h.values) {
+		hv := h.values[k]
+		for i, upper := range h.buckets {
+			h.writeSample(buf, h.name+"_bucket", k, `le="`+formatFloat(upper)+`"`, float64(hv.counts[i]))
+		}
+		h.writeSample(buf, h.name+"_bucket", k, `le="+Inf"`, float64(hv.count))
+		h.writeSample(buf, h.name+"_sum", k, "", hv.sum)
+		h.writeSample(buf, h.name+"_count", k, "", float64(hv.count))
+	}
+}
+
+func (d *desc) writeHeader(buf *bytes.Buffer, typ string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, typ)
+}
+
+func (d *desc) writeSample(buf *bytes.Buffer, name, key, extra string, v float64) {
+	buf.WriteString(name)
+	var pairs []string
+	if len(d.labelNames) > 0 {
+		values := strings.Split(key, "\xff")
+		for i, ln := range d.labelNames {
+			lv := ""
+			if i < len(values) {
+				lv = values[i]
+			}
+			pairs = append(pairs, ln+`="`+escapeLabel(lv)+`"`)
+		}
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	pairs = append(pairs, constLabels...)
+	if len(pairs) > 0 {
+		buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	buf.WriteString(" " + formatFloat(v) + "\n")
+}
+
+// SetConstLabels Sets the labels added to all samples, such as the pod name and the namespace, call it before serving
+func SetConstLabels(labels map[string]string) {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+`="`+escapeLabel(labels[name])+`"`)
+	}
+	constLabels = pairs
+}
+
+// Handler the gin handler exposing all metrics in the prometheus text format
+func Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		registryMu.RLock()
+		names := make([]string, 0, len(registry))
+		for name := range registry {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var buf bytes.Buffer
+		for _, name := range names {
+			registry[name].write(&buf)
+		}
+		registryMu.RUnlock()
+		ctx.Data(200, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+	}
+}
+
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
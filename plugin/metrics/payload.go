@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SizeBuckets the default buckets of the payload size histograms, in bytes
+var SizeBuckets = []float64{128, 512, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+
+var (
+	payloadOnce         sync.Once
+	requestSize         *Histogram
+	responseSize        *Histogram
+	responseWireSize    *Histogram
+	unmatchedRouteLabel = "unmatched"
+)
+
+// the payload statistics of a request, shared by the http handler and the gin middleware
+type payloadStat struct {
+	route     string
+	bodyBytes int64
+}
+
+// the request size, the content length is used when the body is not fully read
+func (p *payloadStat) requestSize(r *http.Request) int64 {
+	if r.ContentLength > p.bodyBytes {
+		return r.ContentLength
+	}
+	return p.bodyBytes
+}
+
+type payloadStatKey struct{}
+
+func initPayloadMetrics() {
+	payloadOnce.Do(func() {
+		requestSize = NewHistogram("http_request_size_bytes", "Size of the request body per route.", SizeBuckets, "route")
+		responseSize = NewHistogram("http_response_size_bytes", "Size of the response body per route, before compression.", SizeBuckets, "route")
+		responseWireSize = NewHistogram("http_response_wire_size_bytes", "Size of the response body per route, after compression.", SizeBuckets, "route")
+	})
+}
+
+// PayloadHandler Wrap the http handler of the server to record the request size and the response size written to the wire,
+// must be used together with the Payload middleware.
+func PayloadHandler(next http.Handler) http.Handler {
+	initPayloadMetrics()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stat := &payloadStat{route: unmatchedRouteLabel}
+		cw := &countingWriter{ResponseWriter: w}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &countingBody{ReadCloser: r.Body, stat: stat}
+		}
+		next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), payloadStatKey{}, stat)))
+		requestSize.Observe(float64(stat.requestSize(r)), stat.route)
+		responseWireSize.Observe(float64(cw.size), stat.route)
+	})
+}
+
+// Payload The gin middleware records the response size before compression, and responds the Server-Timing header
+// with the request size and the processing duration. It should run after the compression middleware.
+func Payload() gin.HandlerFunc {
+	initPayloadMetrics()
+	return func(ctx *gin.Context) {
+		stat, _ := ctx.Request.Context().Value(payloadStatKey{}).(*payloadStat)
+		if stat == nil {
+			stat = &payloadStat{}
+		}
+		if ctx.FullPath() != "" {
+			stat.route = ctx.FullPath()
+		}
+		pw := &timingWriter{ResponseWriter: ctx.Writer, start: time.Now(), reqSize: stat.requestSize(ctx.Request)}
+		ctx.Writer = pw
+		ctx.Next()
+		ctx.Writer = pw.ResponseWriter
+		responseSize.Observe(float64(pw.size), stat.route)
+	}
+}
+
+// countingWriter counts the bytes written to the connection
+type countingWriter struct {
+	http.ResponseWriter
+	size int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(b)
+	c.size += int64(n)
+	return n, err
+}
+
+func (c *countingWriter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap the original response writer, used by http.ResponseController
+func (c *countingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// countingBody counts the bytes read from the request body
+type countingBody struct {
+	io.ReadCloser
+	stat *payloadStat
+}
+
+func (c *countingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.stat.bodyBytes += int64(n)
+	return n, err
+}
+
+// timingWriter counts the bytes written by the handlers and sets the Server-Timing header before the headers are sent
+type timingWriter struct {
+	gin.ResponseWriter
+	start   time.Time
+	reqSize int64
+	size    int64
+}
+
+func (t *timingWriter) writeTiming() {
+	if t.ResponseWriter.Written() {
+		return
+	}
+	t.Header().Add("Server-Timing", fmt.Sprintf(`app;dur=%.2f, req-size;desc="%d"`,
+		float64(time.Since(t.start).Microseconds())/1000, t.reqSize))
+}
+
+func (t *timingWriter) WriteHeaderNow() {
+	t.writeTiming()
+	t.ResponseWriter.WriteHeaderNow()
+}
+
+func (t *timingWriter) Write(b []byte) (int, error) {
+	t.writeTiming()
+	n, err := t.ResponseWriter.Write(b)
+	t.size += int64(n)
+	return n, err
+}
+
+func (t *timingWriter) WriteString(s string) (int, error) {
+	t.writeTiming()
+	n, err := t.ResponseWriter.WriteString(s)
+	t.size += int64(n)
+	return n, err
+}
+
+func (t *timingWriter) Flush() {
+	t.writeTiming()
+	t.ResponseWriter.Flush()
+}
@@ -0,0 +1,461 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/auth"
+	"github.com/archine/gin-plus/v3/plugin/cache"
+	"github.com/archine/gin-plus/v3/plugin/httpclient"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SessionKey = "oidc_session" // Key of the login session in the gin context
+
+var DefaultRelyingParty *RelyingParty // Relying party created from the oidc.client configuration when the application starts
+
+// RelyingPartyConfig the login of the browser users against the OpenID Connect provider, such as Keycloak, Auth0 and Google
+type RelyingPartyConfig struct {
+	Enabled      bool               `mapstructure:"enabled"`       // Whether to enable the login, default false
+	Issuer       string             `mapstructure:"issuer"`        // Issuer url, the endpoints are discovered from its /.well-known/openid-configuration, required
+	ClientID     string             `mapstructure:"client_id"`     // Client id, required
+	ClientSecret string             `mapstructure:"client_secret"` // Client secret, empty means a public client
+	RedirectURL  string             `mapstructure:"redirect_url"`  // Absolute url of the callback endpoint registered at the provider, required
+	Scopes       []string           `mapstructure:"scopes"`        // Requested scopes, default openid, profile and email
+	BasePath     string             `mapstructure:"base_path"`     // Path prefix of the login, callback, refresh and logout endpoints, default /auth
+	SuccessURL   string             `mapstructure:"success_url"`   // Redirected after login when the login has no return_to, default /
+	LogoutURL    string             `mapstructure:"logout_url"`    // Redirected after logout, default /
+	Cookie       string             `mapstructure:"cookie"`        // Name of the session cookie, default gin_plus_session
+	SessionTTL   time.Duration      `mapstructure:"session_ttl"`   // Lifetime of the session, default 24h
+	Claims       auth.ClaimsMapping `mapstructure:"claims"`        // Claims of the id token mapped into the principal
+}
+
+// Session the login session stored in the cache, the principal is set to the context of the requests carrying its cookie
+type Session struct {
+	Principal    *auth.Principal `json:"principal"`
+	AccessToken  string          `json:"access_token"`
+	RefreshToken string          `json:"refresh_token,omitempty"`
+	IDToken      string          `json:"id_token,omitempty"`
+	Expiry       time.Time       `json:"expiry"` // Expiry of the access token, zero means unknown
+}
+
+// CurrentSession Get the login session of the current request
+func CurrentSession(ctx *gin.Context) (*Session, bool) {
+	v, ok := ctx.Get(SessionKey)
+	if !ok {
+		return nil, false
+	}
+	s, ok := v.(*Session)
+	return s, ok
+}
+
+// the endpoints of the provider metadata
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// the pending login, bound to the state
+type loginState struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	ReturnTo string `json:"return_to"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// RelyingParty logs in the users by the authorization code flow with PKCE, the sessions are stored in cache.Store
+type RelyingParty struct {
+	conf          RelyingPartyConfig
+	mu            sync.Mutex
+	meta          *discovery
+	authenticator *auth.Authenticator
+	discovering   *discoveryCall // the discovery in flight, nil without it
+}
+
+// discoveryCall the discovery shared by the concurrent callers, err is set before done is closed
+type discoveryCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRelyingParty Create the relying party, the provider metadata is discovered on the first login
+func NewRelyingParty(conf RelyingPartyConfig) (*RelyingParty, error) {
+	if conf.Issuer == "" || conf.ClientID == "" || conf.RedirectURL == "" {
+		return nil, errors.New("oidc: issuer, client_id and redirect_url of the client are required")
+	}
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid", "profile", "email"}
+	}
+	if conf.BasePath == "" {
+		conf.BasePath = "/auth"
+	}
+	if conf.SuccessURL == "" {
+		conf.SuccessURL = "/"
+	}
+	if conf.LogoutURL == "" {
+		conf.LogoutURL = "/"
+	}
+	if conf.Cookie == "" {
+		conf.Cookie = "gin_plus_session"
+	}
+	if conf.SessionTTL <= 0 {
+		conf.SessionTTL = 24 * time.Hour
+	}
+	return &RelyingParty{conf: conf}, nil
+}
+
+// Mount the login, callback, refresh and logout endpoints to the gin engine
+func (r *RelyingParty) Mount(e *gin.Engine) {
+	e.GET(r.conf.BasePath+"/login", r.login)
+	e.GET(r.conf.BasePath+"/callback", r.callback)
+	e.POST(r.conf.BasePath+"/refresh", r.refresh)
+	// the logout changes the state, so it isn't triggered by the links or the images of other sites
+	e.POST(r.conf.BasePath+"/logout", r.logout)
+}
+
+/*
+Middleware The gin middleware loads the session of the cookie, the expired access token is refreshed by the refresh token.
+The principal is set to the context, so @Auth, @RequireRole and auth.Current() work with the browser sessions too.
+*/
+func (r *RelyingParty) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := ctx.Cookie(r.conf.Cookie)
+		if err != nil || id == "" {
+			ctx.Next()
+			return
+		}
+		session, ok := r.loadSession(id)
+		if ok && session.RefreshToken != "" && !session.Expiry.IsZero() && time.Now().Add(30*time.Second).After(session.Expiry) {
+			if err = r.refreshSession(ctx.Request.Context(), id, session); err != nil {
+				logger.Log.Debugf("oidc: refresh session error, %s", err.Error())
+				cache.Store.Delete(sessionKey(id))
+				ok = false
+			}
+		}
+		if ok {
+			ctx.Set(SessionKey, session)
+			ctx.Set(auth.PrincipalKey, session.Principal)
+		}
+		ctx.Next()
+	}
+}
+
+// the provider metadata and the authenticator of the id tokens, retried on the next call when failed.
+// The concurrent callers share one discovery, it's fetched without the lock and the context of the callers,
+// so the stalled provider or the canceled request of a caller doesn't fail the others
+func (r *RelyingParty) discover(ctx context.Context) (*discovery, *auth.Authenticator, error) {
+	r.mu.Lock()
+	if r.meta != nil {
+		defer r.mu.Unlock()
+		return r.meta, r.authenticator, nil
+	}
+	call := r.discovering
+	if call == nil {
+		call = &discoveryCall{done: make(chan struct{})}
+		r.discovering = call
+		go r.discoverInBackground(call)
+	}
+	r.mu.Unlock()
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, nil, call.err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.meta, r.authenticator, nil
+}
+
+func (r *RelyingParty) discoverInBackground(call *discoveryCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	meta, authenticator, err := r.fetchDiscovery(ctx)
+	r.mu.Lock()
+	if err == nil {
+		r.meta, r.authenticator = meta, authenticator
+	}
+	call.err = err
+	r.discovering = nil
+	r.mu.Unlock()
+	close(call.done)
+}
+
+// fetch the provider metadata and create the authenticator of its keys
+func (r *RelyingParty) fetchDiscovery(ctx context.Context) (*discovery, *auth.Authenticator, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(r.conf.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := httpclient.Default.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("oidc: discovery responded %d", res.StatusCode)
+	}
+	var meta discovery
+	if err = json.NewDecoder(res.Body).Decode(&meta); err != nil {
+		return nil, nil, err
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, nil, errors.New("oidc: discovery document misses the endpoints")
+	}
+	authenticator, err := auth.New(auth.Config{JWKSURL: meta.JWKSURI, Issuer: meta.Issuer, Audience: r.conf.ClientID, Claims: r.conf.Claims})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &meta, authenticator, nil
+}
+
+// redirect to the authorization endpoint, the return_to query is the relative url redirected after login
+func (r *RelyingParty) login(ctx *gin.Context) {
+	meta, _, err := r.discover(ctx.Request.Context())
+	if err != nil {
+		logger.Log.Errorf("oidc: discovery error, %s", err.Error())
+		resp.ServiceUnavailable(ctx, 0, "认证服务不可用")
+		return
+	}
+	state := loginState{Verifier: randomToken(), Nonce: randomToken(), ReturnTo: ctx.Query("return_to")}
+	// only the relative urls, avoiding the open redirect
+	if !strings.HasPrefix(state.ReturnTo, "/") || strings.HasPrefix(state.ReturnTo, "//") || strings.HasPrefix(state.ReturnTo, "/\\") {
+		state.ReturnTo = r.conf.SuccessURL
+	}
+	b, _ := json.Marshal(state)
+	stateId := randomToken()
+	cache.Store.Set(stateKey(stateId), b, 10*time.Minute)
+	r.setCookie(ctx, r.conf.Cookie+"_state", stateId, 600)
+	sum := sha256.Sum256([]byte(state.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {r.conf.ClientID},
+		"redirect_uri":          {r.conf.RedirectURL},
+		"scope":                 {strings.Join(r.conf.Scopes, " ")},
+		"state":                 {stateId},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	ctx.Redirect(http.StatusFound, appendQuery(meta.AuthorizationEndpoint, q))
+}
+
+// exchange the code, verify the id token and establish the session
+func (r *RelyingParty) callback(ctx *gin.Context) {
+	if e := ctx.Query("error"); e != "" {
+		resp.NoLogin(ctx, true, "登录失败, "+e)
+		return
+	}
+	stateId := ctx.Query("state")
+	cookieState, _ := ctx.Cookie(r.conf.Cookie + "_state")
+	r.setCookie(ctx, r.conf.Cookie+"_state", "", -1)
+	b, ok := cache.Store.Get(stateKey(stateId))
+	if stateId == "" || !ok || cookieState != stateId {
+		resp.NoLogin(ctx, true, "登录已失效，请重新登录")
+		return
+	}
+	cache.Store.Delete(stateKey(stateId))
+	var state loginState
+	if err := json.Unmarshal(b, &state); err != nil {
+		resp.NoLogin(ctx, true, "登录已失效，请重新登录")
+		return
+	}
+	meta, authenticator, err := r.discover(ctx.Request.Context())
+	if err != nil {
+		logger.Log.Errorf("oidc: discovery error, %s", err.Error())
+		resp.ServiceUnavailable(ctx, 0, "认证服务不可用")
+		return
+	}
+	token, err := r.exchange(ctx.Request.Context(), meta, url.Values{
+		"grant_type":    {GrantAuthorizationCode},
+		"code":          {ctx.Query("code")},
+		"redirect_uri":  {r.conf.RedirectURL},
+		"code_verifier": {state.Verifier},
+	})
+	if err != nil {
+		logger.Log.Warnf("oidc: exchange code error, %s", err.Error())
+		resp.NoLogin(ctx, true, "登录失败")
+		return
+	}
+	principal, err := authenticator.Authenticate(token.IDToken)
+	if err == nil && principal.Claims.String("nonce") != state.Nonce {
+		err = errors.New("oidc: nonce mismatch")
+	}
+	if err != nil {
+		logger.Log.Warnf("oidc: invalid id token, %s", err.Error())
+		resp.NoLogin(ctx, true, "登录失败")
+		return
+	}
+	session := &Session{Principal: principal, IDToken: token.IDToken}
+	session.apply(token)
+	id := randomToken()
+	if err = r.saveSession(id, session); err != nil {
+		resp.SeverError(ctx, true)
+		return
+	}
+	r.setCookie(ctx, r.conf.Cookie, id, int(r.conf.SessionTTL.Seconds()))
+	ctx.Redirect(http.StatusFound, state.ReturnTo)
+}
+
+// refresh the access token of the session, responded with the new expiry
+func (r *RelyingParty) refresh(ctx *gin.Context) {
+	id, _ := ctx.Cookie(r.conf.Cookie)
+	session, ok := r.loadSession(id)
+	if !ok {
+		resp.NoLogin(ctx, true)
+		return
+	}
+	if session.RefreshToken == "" {
+		resp.LoginExpired(ctx, true, "会话不支持刷新")
+		return
+	}
+	if err := r.refreshSession(ctx.Request.Context(), id, session); err != nil {
+		logger.Log.Debugf("oidc: refresh session error, %s", err.Error())
+		cache.Store.Delete(sessionKey(id))
+		resp.LoginExpired(ctx, true)
+		return
+	}
+	resp.Json(ctx, gin.H{"expiry": session.Expiry})
+}
+
+// end the session, redirected to the end session endpoint of the provider when it has one
+func (r *RelyingParty) logout(ctx *gin.Context) {
+	id, _ := ctx.Cookie(r.conf.Cookie)
+	session, ok := r.loadSession(id)
+	cache.Store.Delete(sessionKey(id))
+	r.setCookie(ctx, r.conf.Cookie, "", -1)
+	meta, _, err := r.discover(ctx.Request.Context())
+	if !ok || err != nil || meta.EndSessionEndpoint == "" {
+		ctx.Redirect(http.StatusFound, r.conf.LogoutURL)
+		return
+	}
+	q := url.Values{"client_id": {r.conf.ClientID}, "id_token_hint": {session.IDToken}}
+	if strings.Contains(r.conf.LogoutURL, "://") {
+		q.Set("post_logout_redirect_uri", r.conf.LogoutURL)
+	}
+	ctx.Redirect(http.StatusFound, appendQuery(meta.EndSessionEndpoint, q))
+}
+
+func (r *RelyingParty) refreshSession(ctx context.Context, id string, session *Session) error {
+	meta, _, err := r.discover(ctx)
+	if err != nil {
+		return err
+	}
+	token, err := r.exchange(ctx, meta, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {session.RefreshToken}})
+	if err != nil {
+		return err
+	}
+	session.apply(token)
+	return r.saveSession(id, session)
+}
+
+// call the token endpoint, the client is authenticated by the basic auth
+func (r *RelyingParty) exchange(ctx context.Context, meta *discovery, form url.Values) (*tokenResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if r.conf.ClientSecret == "" {
+		form.Set("client_id", r.conf.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if r.conf.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(r.conf.ClientID), url.QueryEscape(r.conf.ClientSecret))
+	}
+	res, err := httpclient.Default.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint responded %d", res.StatusCode)
+	}
+	var token tokenResponse
+	if err = json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("oidc: token endpoint responded no access token")
+	}
+	return &token, nil
+}
+
+// apply the tokens of the token response, the refresh token is kept when not rotated
+func (s *Session) apply(token *tokenResponse) {
+	s.AccessToken = token.AccessToken
+	s.Principal.Token = token.AccessToken
+	if token.RefreshToken != "" {
+		s.RefreshToken = token.RefreshToken
+	}
+	if token.IDToken != "" {
+		s.IDToken = token.IDToken
+	}
+	s.Expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		s.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+}
+
+func (r *RelyingParty) loadSession(id string) (*Session, bool) {
+	if id == "" {
+		return nil, false
+	}
+	b, ok := cache.Store.Get(sessionKey(id))
+	if !ok {
+		return nil, false
+	}
+	var session Session
+	if json.Unmarshal(b, &session) != nil || session.Principal == nil {
+		return nil, false
+	}
+	return &session, true
+}
+
+func (r *RelyingParty) saveSession(id string, session *Session) error {
+	b, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	cache.Store.Set(sessionKey(id), b, r.conf.SessionTTL)
+	return nil
+}
+
+// the http only cookie, secure over https
+func (r *RelyingParty) setCookie(ctx *gin.Context, name, value string, maxAge int) {
+	secure := ctx.Request.TLS != nil || strings.EqualFold(ctx.GetHeader("X-Forwarded-Proto"), "https")
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(name, value, maxAge, "/", "", secure, true)
+}
+
+func sessionKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return "oidc:session:" + base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func stateKey(id string) string {
+	return "oidc:state:" + id
+}
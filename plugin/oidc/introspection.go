@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/breaker"
+	"github.com/archine/gin-plus/v3/plugin/cache"
+	"github.com/archine/gin-plus/v3/plugin/httpclient"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	IntrospectionKey        = "oidc_introspection" // Key of the introspection result in the gin context
+	IntrospectionMiddleware = "introspection"      // Name of the introspection middleware, used by @Use(introspection)
+	IntrospectionBreaker    = "oidc-introspection" // Name of the circuit breaker of the introspection calls, configured by circuit_breaker.breakers
+)
+
+var (
+	DefaultIntrospector *Introspector                                                            // Introspector created from the oidc.introspection configuration when the application starts
+	ErrCircuitOpen      = fmt.Errorf("oidc: introspection circuit is open, %w", breaker.ErrOpen) // Returned when the introspection endpoint keeps failing and the calls are suspended
+)
+
+// IntrospectionConfig the token introspection configuration, see RFC 7662
+type IntrospectionConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`         // Whether to verify the bearer tokens by introspection, default false
+	Endpoint       string        `mapstructure:"endpoint"`        // Introspection endpoint, required
+	ClientID       string        `mapstructure:"client_id"`       // Client id to authenticate to the endpoint
+	ClientSecret   string        `mapstructure:"client_secret"`   // Client secret to authenticate to the endpoint
+	RequiredScopes []string      `mapstructure:"required_scopes"` // Scopes the token must have
+	CacheTTL       time.Duration `mapstructure:"cache_ttl"`       // Lifetime of the cached results, never exceeds the token expiry. Default 1m, negative disables the cache
+	Timeout        time.Duration `mapstructure:"timeout"`         // Timeout of each call, default 3s
+}
+
+// Introspection the introspection result
+type Introspection struct {
+	Active    bool           `json:"active"`
+	Scope     string         `json:"scope,omitempty"`
+	ClientId  string         `json:"client_id,omitempty"`
+	Username  string         `json:"username,omitempty"`
+	TokenType string         `json:"token_type,omitempty"`
+	Exp       int64          `json:"exp,omitempty"`
+	Iat       int64          `json:"iat,omitempty"`
+	Sub       string         `json:"sub,omitempty"`
+	Aud       any            `json:"aud,omitempty"`
+	Iss       string         `json:"iss,omitempty"`
+	Claims    map[string]any `json:"-"` // All members of the response
+}
+
+// HasScope Whether the token has the scope
+func (i *Introspection) HasScope(scope string) bool {
+	return contains(strings.Fields(i.Scope), scope)
+}
+
+// Introspected Get the introspection result of the current request
+func Introspected(ctx *gin.Context) (*Introspection, bool) {
+	v, ok := ctx.Get(IntrospectionKey)
+	if !ok {
+		return nil, false
+	}
+	i, ok := v.(*Introspection)
+	return i, ok
+}
+
+// Introspector calls the introspection endpoint, the results are cached and the calls are circuit-broken
+type Introspector struct {
+	conf    IntrospectionConfig
+	breaker *breaker.Breaker
+}
+
+// NewIntrospector Create the introspector
+func NewIntrospector(conf IntrospectionConfig) *Introspector {
+	if conf.CacheTTL == 0 {
+		conf.CacheTTL = time.Minute
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = 3 * time.Second
+	}
+	return &Introspector{conf: conf, breaker: breaker.Get(IntrospectionBreaker)}
+}
+
+// Introspect the token, the inactive result is returned without error
+func (i *Introspector) Introspect(ctx context.Context, token string) (*Introspection, error) {
+	key := ""
+	if i.conf.CacheTTL > 0 {
+		sum := sha256.Sum256([]byte(token))
+		key = "oidc:introspect:" + hex.EncodeToString(sum[:])
+		if b, ok := cache.Store.Get(key); ok {
+			var res Introspection
+			if json.Unmarshal(b, &res.Claims) == nil && json.Unmarshal(b, &res) == nil {
+				return &res, nil
+			}
+		}
+	}
+	done, err := i.breaker.Allow()
+	if err != nil {
+		return nil, ErrCircuitOpen
+	}
+	res, raw, err := i.call(ctx, token)
+	done(err == nil)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		ttl := i.conf.CacheTTL
+		if res.Exp > 0 {
+			if untilExp := time.Until(time.Unix(res.Exp, 0)); untilExp < ttl {
+				ttl = untilExp
+			}
+		}
+		if ttl > 0 {
+			cache.Store.Set(key, raw, ttl)
+		}
+	}
+	return res, nil
+}
+
+func (i *Introspector) call(ctx context.Context, token string) (*Introspection, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, i.conf.Timeout)
+	defer cancel()
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.conf.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.conf.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(i.conf.ClientID), url.QueryEscape(i.conf.ClientSecret))
+	}
+	res, err := httpclient.Default.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("oidc: introspection endpoint responded %d", res.StatusCode)
+	}
+	var raw json.RawMessage
+	if err = json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		return nil, nil, err
+	}
+	var result Introspection
+	if err = json.Unmarshal(raw, &result.Claims); err != nil {
+		return nil, nil, err
+	}
+	if err = json.Unmarshal(raw, &result); err != nil {
+		return nil, nil, err
+	}
+	return &result, raw, nil
+}
+
+// Middleware Verify the bearer token of the request by introspection,
+// the result is set to the context and can be retrieved by Introspected()
+func (i *Introspector) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			resp.NoLogin(ctx, true)
+			ctx.Abort()
+			return
+		}
+		result, err := i.Introspect(ctx.Request.Context(), token)
+		if err != nil {
+			logger.Log.Errorf("Token introspection error, %s", err.Error())
+			resp.InitResp(ctx).WithBasic(resp.SystemErrorCode, "认证服务不可用", nil).To(http.StatusServiceUnavailable)
+			ctx.Abort()
+			return
+		}
+		if !result.Active {
+			resp.LoginExpired(ctx, true, "Token无效或已过期")
+			ctx.Abort()
+			return
+		}
+		for _, scope := range i.conf.RequiredScopes {
+			if !result.HasScope(scope) {
+				resp.Forbidden(ctx, true)
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Set(IntrospectionKey, result)
+		ctx.Next()
+	}
+}
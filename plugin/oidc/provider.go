@@ -0,0 +1,524 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/jwt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Grant types
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+)
+
+// ProviderConfig the authorization server configuration
+type ProviderConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`          // Whether to enable the authorization server, default false
+	Issuer         string        `mapstructure:"issuer"`           // Issuer url, such as https://auth.example.com, required
+	BasePath       string        `mapstructure:"base_path"`        // Path prefix of the endpoints, default /oauth2
+	KeyFile        string        `mapstructure:"key_file"`         // PEM file of the RSA private key. Empty generates a key at startup, the issued tokens become invalid after restarting
+	AccessTokenTTL time.Duration `mapstructure:"access_token_ttl"` // Lifetime of the access token, default 1h
+	IDTokenTTL     time.Duration `mapstructure:"id_token_ttl"`     // Lifetime of the id token, default 1h
+	CodeTTL        time.Duration `mapstructure:"code_ttl"`         // Lifetime of the authorization code, default 1m
+	Clients        []Client      `mapstructure:"clients"`          // Registered clients
+}
+
+// Client the registered client
+type Client struct {
+	ID           string   `mapstructure:"id"`            // Client id
+	Secret       string   `mapstructure:"secret"`        // Client secret, empty means a public client which must use PKCE
+	RedirectURIs []string `mapstructure:"redirect_uris"` // Allowed redirect uris of the authorization code flow
+	GrantTypes   []string `mapstructure:"grant_types"`   // Allowed grant types, default authorization_code
+	Scopes       []string `mapstructure:"scopes"`        // Allowed scopes, empty means any scope
+}
+
+func (c *Client) allowGrant(grant string) bool {
+	if len(c.GrantTypes) == 0 {
+		return grant == GrantAuthorizationCode
+	}
+	return contains(c.GrantTypes, grant)
+}
+
+func (c *Client) allowScopes(scopes []string) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		if s != "openid" && !contains(c.Scopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClientRegistry the registry of the clients
+type ClientRegistry interface {
+	// Client Find the client by id
+	Client(id string) (*Client, bool)
+}
+
+// MemoryClientRegistry the in-process client registry
+type MemoryClientRegistry struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+// NewMemoryClientRegistry Create a client registry with the clients
+func NewMemoryClientRegistry(clients ...Client) *MemoryClientRegistry {
+	r := &MemoryClientRegistry{clients: make(map[string]*Client, len(clients))}
+	for _, c := range clients {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register the client, the client with the same id is replaced
+func (r *MemoryClientRegistry) Register(c Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[c.ID] = &c
+}
+
+func (r *MemoryClientRegistry) Client(id string) (*Client, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.clients[id]
+	return c, ok
+}
+
+// Subject the authenticated end user
+type Subject struct {
+	ID     string         // Subject identifier, written to the sub claim
+	Claims map[string]any // Additional claims of the id token, such as name and email
+}
+
+// Authenticator authenticates the end user of the authorization request.
+// When the user is not logged in, respond it (such as redirecting to the login page) and return nil
+type Authenticator func(ctx *gin.Context) *Subject
+
+// Provider the OpenID Connect authorization server
+type Provider struct {
+	conf          ProviderConfig
+	key           *jwt.Key
+	Clients       ClientRegistry // Client registry, the clients of the configuration as default
+	Authenticator Authenticator  // Authenticator of the end user, required by the authorization code flow
+	codes         sync.Map
+}
+
+var (
+	DefaultProvider *Provider // Provider created from the oidc.provider configuration when the application starts
+)
+
+// NewProvider Create the authorization server
+func NewProvider(conf ProviderConfig) (*Provider, error) {
+	if conf.Issuer == "" {
+		return nil, errors.New("oidc: issuer is required")
+	}
+	conf.Issuer = strings.TrimRight(conf.Issuer, "/")
+	if conf.BasePath == "" {
+		conf.BasePath = "/oauth2"
+	}
+	conf.BasePath = "/" + strings.Trim(conf.BasePath, "/")
+	if conf.AccessTokenTTL == 0 {
+		conf.AccessTokenTTL = time.Hour
+	}
+	if conf.IDTokenTTL == 0 {
+		conf.IDTokenTTL = time.Hour
+	}
+	if conf.CodeTTL == 0 {
+		conf.CodeTTL = time.Minute
+	}
+	key, err := loadKey(conf.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{conf: conf, key: key, Clients: NewMemoryClientRegistry(conf.Clients...)}, nil
+}
+
+func loadKey(file string) (*jwt.Key, error) {
+	var pk *rsa.PrivateKey
+	if file == "" {
+		logger.Log.Warn("oidc: no key file is configured, a temporary key is generated")
+		var err error
+		if pk, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			return nil, err
+		}
+	} else {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("oidc: no pem block in the key file %s", file)
+		}
+		if pk, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err8 != nil {
+				return nil, fmt.Errorf("oidc: parse the key file %s error, %s", file, err.Error())
+			}
+			var ok bool
+			if pk, ok = parsed.(*rsa.PrivateKey); !ok {
+				return nil, fmt.Errorf("oidc: the key file %s is not a RSA private key", file)
+			}
+		}
+	}
+	sum := sha256.Sum256(pk.PublicKey.N.Bytes())
+	return &jwt.Key{ID: hex.EncodeToString(sum[:8]), Alg: jwt.RS256, PrivateKey: pk}, nil
+}
+
+// Mount the endpoints to the gin engine
+func (p *Provider) Mount(e *gin.Engine) {
+	e.GET("/.well-known/openid-configuration", p.discovery)
+	e.GET(p.conf.BasePath+"/jwks", p.jwks)
+	e.GET(p.conf.BasePath+"/authorize", p.authorize)
+	e.POST(p.conf.BasePath+"/token", p.token)
+	e.POST(p.conf.BasePath+"/introspect", p.introspect)
+	logger.Log.Debugf("OpenID Connect provider [%s]", p.conf.Issuer)
+}
+
+// Issue an access token to the subject directly, such as for the first-party login
+func (p *Provider) Issue(sub, clientId string, scopes []string) (string, error) {
+	now := time.Now()
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return jwt.Sign(jwt.Claims{
+		"iss":       p.conf.Issuer,
+		"sub":       sub,
+		"aud":       clientId,
+		"client_id": clientId,
+		"scope":     strings.Join(scopes, " "),
+		"iat":       now.Unix(),
+		"exp":       now.Add(p.conf.AccessTokenTTL).Unix(),
+		"jti":       hex.EncodeToString(b),
+	}, p.key)
+}
+
+// Verify the token issued by the provider, return the claims
+func (p *Provider) Verify(token string) (jwt.Claims, error) {
+	claims, err := jwt.Parse(token, func(kid, alg string) (*jwt.Key, error) {
+		if kid != p.key.ID {
+			return nil, jwt.ErrUnknownKey
+		}
+		return p.key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.String("iss") != p.conf.Issuer {
+		return nil, errors.New("oidc: issuer mismatch")
+	}
+	return claims, nil
+}
+
+func (p *Provider) discovery(ctx *gin.Context) {
+	base := p.conf.Issuer + p.conf.BasePath
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.JSON(http.StatusOK, gin.H{
+		"issuer":                                p.conf.Issuer,
+		"authorization_endpoint":                base + "/authorize",
+		"token_endpoint":                        base + "/token",
+		"introspection_endpoint":                base + "/introspect",
+		"jwks_uri":                              base + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{GrantAuthorizationCode, GrantClientCredentials},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwt.RS256},
+		"scopes_supported":                      []string{"openid"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(ctx *gin.Context) {
+	jwk, _ := p.key.PublicJWK()
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.JSON(http.StatusOK, jwt.JWKS{Keys: []jwt.JWK{jwk}})
+}
+
+// authCode the issued authorization code
+type authCode struct {
+	clientId     string
+	redirectUri  string
+	redirectSent bool // whether the redirect_uri was sent to the authorize endpoint, it must be sent again then
+	scopes       []string
+	nonce        string
+	challenge    string
+	subject      *Subject
+	expireAt     time.Time
+}
+
+func (p *Provider) authorize(ctx *gin.Context) {
+	client, ok := p.Clients.Client(ctx.Query("client_id"))
+	if !ok {
+		oauthError(ctx, http.StatusBadRequest, "invalid_client", "unknown client")
+		return
+	}
+	redirectUri := ctx.Query("redirect_uri")
+	redirectSent := redirectUri != ""
+	if !redirectSent && len(client.RedirectURIs) == 1 {
+		redirectUri = client.RedirectURIs[0]
+	}
+	// never redirect to an unregistered uri
+	if !contains(client.RedirectURIs, redirectUri) {
+		oauthError(ctx, http.StatusBadRequest, "invalid_request", "unregistered redirect_uri")
+		return
+	}
+	state := ctx.Query("state")
+	fail := func(code, desc string) {
+		q := url.Values{"error": {code}, "error_description": {desc}}
+		if state != "" {
+			q.Set("state", state)
+		}
+		ctx.Redirect(http.StatusFound, appendQuery(redirectUri, q))
+	}
+	if ctx.Query("response_type") != "code" {
+		fail("unsupported_response_type", "only code is supported")
+		return
+	}
+	if !client.allowGrant(GrantAuthorizationCode) {
+		fail("unauthorized_client", "authorization_code grant is not allowed")
+		return
+	}
+	scopes := strings.Fields(ctx.Query("scope"))
+	if !client.allowScopes(scopes) {
+		fail("invalid_scope", "scope is not allowed")
+		return
+	}
+	// only S256 is supported, the plain challenge is the verifier itself
+	challenge := ctx.Query("code_challenge")
+	if challenge == "" && client.Secret == "" {
+		fail("invalid_request", "code_challenge is required for public clients")
+		return
+	}
+	if challenge != "" && ctx.DefaultQuery("code_challenge_method", "S256") != "S256" {
+		fail("invalid_request", "code_challenge_method must be S256")
+		return
+	}
+	if p.Authenticator == nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "no authenticator")
+		return
+	}
+	subject := p.Authenticator(ctx)
+	if subject == nil {
+		ctx.Abort()
+		return
+	}
+	code := randomToken()
+	now := time.Now()
+	p.codes.Store(code, &authCode{
+		clientId:     client.ID,
+		redirectUri:  redirectUri,
+		redirectSent: redirectSent,
+		scopes:       scopes,
+		nonce:        ctx.Query("nonce"),
+		challenge:    challenge,
+		subject:      subject,
+		expireAt:     now.Add(p.conf.CodeTTL),
+	})
+	p.sweepCodes(now)
+	q := url.Values{"code": {code}}
+	if state != "" {
+		q.Set("state", state)
+	}
+	ctx.Redirect(http.StatusFound, appendQuery(redirectUri, q))
+}
+
+// remove the expired codes which are never exchanged
+func (p *Provider) sweepCodes(now time.Time) {
+	p.codes.Range(func(k, v any) bool {
+		if now.After(v.(*authCode).expireAt) {
+			p.codes.Delete(k)
+		}
+		return true
+	})
+}
+
+func (p *Provider) token(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	client, ok := p.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+	grant := ctx.PostForm("grant_type")
+	if !client.allowGrant(grant) {
+		oauthError(ctx, http.StatusBadRequest, "unauthorized_client", "grant type is not allowed")
+		return
+	}
+	switch grant {
+	case GrantAuthorizationCode:
+		p.exchangeCode(ctx, client)
+	case GrantClientCredentials:
+		if client.Secret == "" {
+			oauthError(ctx, http.StatusBadRequest, "unauthorized_client", "public clients can't use client_credentials")
+			return
+		}
+		scopes := strings.Fields(ctx.PostForm("scope"))
+		if !client.allowScopes(scopes) {
+			oauthError(ctx, http.StatusBadRequest, "invalid_scope", "scope is not allowed")
+			return
+		}
+		accessToken, err := p.Issue(client.ID, client.ID, scopes)
+		if err != nil {
+			oauthError(ctx, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		ctx.JSON(http.StatusOK, p.tokenResponse(accessToken, scopes, ""))
+	default:
+		oauthError(ctx, http.StatusBadRequest, "unsupported_grant_type", "unsupported grant type")
+	}
+}
+
+func (p *Provider) exchangeCode(ctx *gin.Context, client *Client) {
+	v, ok := p.codes.LoadAndDelete(ctx.PostForm("code"))
+	if !ok {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", "invalid code")
+		return
+	}
+	code := v.(*authCode)
+	// the redirect_uri must be identical when it was sent to the authorize endpoint, see RFC 6749 4.1.3
+	redirectUri := ctx.PostForm("redirect_uri")
+	if code.clientId != client.ID || ((code.redirectSent || redirectUri != "") && redirectUri != code.redirectUri) || time.Now().After(code.expireAt) {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", "invalid code")
+		return
+	}
+	if code.challenge != "" && !verifyChallenge(code.challenge, ctx.PostForm("code_verifier")) {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", "invalid code_verifier")
+		return
+	}
+	accessToken, err := p.Issue(code.subject.ID, client.ID, code.scopes)
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	var idToken string
+	if contains(code.scopes, "openid") {
+		now := time.Now()
+		claims := jwt.Claims{}
+		for k, v := range code.subject.Claims {
+			claims[k] = v
+		}
+		claims["iss"] = p.conf.Issuer
+		claims["sub"] = code.subject.ID
+		claims["aud"] = client.ID
+		claims["iat"] = now.Unix()
+		claims["exp"] = now.Add(p.conf.IDTokenTTL).Unix()
+		if code.nonce != "" {
+			claims["nonce"] = code.nonce
+		}
+		if idToken, err = jwt.Sign(claims, p.key); err != nil {
+			oauthError(ctx, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+	}
+	ctx.JSON(http.StatusOK, p.tokenResponse(accessToken, code.scopes, idToken))
+}
+
+func (p *Provider) tokenResponse(accessToken string, scopes []string, idToken string) gin.H {
+	res := gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int64(p.conf.AccessTokenTTL.Seconds()),
+		"scope":        strings.Join(scopes, " "),
+	}
+	if idToken != "" {
+		res["id_token"] = idToken
+	}
+	return res
+}
+
+// introspect the token, see RFC 7662, only the confidential clients are allowed
+func (p *Provider) introspect(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	client, ok := p.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+	if client.Secret == "" {
+		oauthError(ctx, http.StatusUnauthorized, "invalid_client", "public clients can't introspect the tokens")
+		return
+	}
+	claims, err := p.Verify(ctx.PostForm("token"))
+	// the id token isn't an access token
+	if err != nil || claims.String("client_id") == "" {
+		ctx.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+	res := gin.H{"active": true, "token_type": "Bearer"}
+	for _, k := range []string{"scope", "client_id", "sub", "aud", "iss", "exp", "iat", "jti"} {
+		if v, ok := claims[k]; ok {
+			res[k] = v
+		}
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// authenticate the client by the basic authorization or the form parameters
+func (p *Provider) authenticateClient(ctx *gin.Context) (*Client, bool) {
+	id, secret, ok := ctx.Request.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+	client, found := p.Clients.Client(id)
+	if !found || subtle.ConstantTimeCompare([]byte(client.Secret), []byte(secret)) != 1 {
+		ctx.Header("WWW-Authenticate", `Basic realm="oauth2"`)
+		oauthError(ctx, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return nil, false
+	}
+	return client, true
+}
+
+// verify the S256 challenge
+func verifyChallenge(challenge, verifier string) bool {
+	if verifier == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(base64.RawURLEncoding.EncodeToString(sum[:]))) == 1
+}
+
+func oauthError(ctx *gin.Context, status int, code, desc string) {
+	ctx.AbortWithStatusJSON(status, gin.H{"error": code, "error_description": desc})
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func appendQuery(uri string, q url.Values) string {
+	if strings.Contains(uri, "?") {
+		return uri + "&" + q.Encode()
+	}
+	return uri + "?" + q.Encode()
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const redirectURI = "https://app.example.com/callback"
+
+func newProvider(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger.Log = &logger.DefaultLog{}
+	p, err := NewProvider(ProviderConfig{Issuer: "https://auth.example.com", Clients: []Client{
+		{ID: "spa", RedirectURIs: []string{redirectURI, "https://app.example.com/other"}},
+		{ID: "web", Secret: "secret", RedirectURIs: []string{redirectURI}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Authenticator = func(ctx *gin.Context) *Subject {
+		return &Subject{ID: "u1"}
+	}
+	e := gin.New()
+	p.Mount(e)
+	return e
+}
+
+func challengeOf(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// request the authorize endpoint, return the status and the redirected location
+func authorize(e *gin.Engine, q url.Values) (int, *url.URL) {
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth2/authorize?"+q.Encode(), nil))
+	location, _ := url.Parse(w.Header().Get("Location"))
+	return w.Code, location
+}
+
+func exchange(e *gin.Engine, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthorize(t *testing.T) {
+	e := newProvider(t)
+	tests := []struct {
+		name      string
+		query     url.Values
+		wantCode  int
+		wantError string // error of the redirected location
+	}{
+		{name: "unregistered redirect_uri", wantCode: http.StatusBadRequest,
+			query: url.Values{"client_id": {"spa"}, "redirect_uri": {"https://evil.example.com"}, "response_type": {"code"}, "code_challenge": {challengeOf("v")}}},
+		{name: "redirect_uri omitted with multiple registered", wantCode: http.StatusBadRequest,
+			query: url.Values{"client_id": {"spa"}, "response_type": {"code"}, "code_challenge": {challengeOf("v")}}},
+		{name: "public client without the challenge", wantCode: http.StatusFound, wantError: "invalid_request",
+			query: url.Values{"client_id": {"spa"}, "redirect_uri": {redirectURI}, "response_type": {"code"}}},
+		{name: "plain challenge", wantCode: http.StatusFound, wantError: "invalid_request",
+			query: url.Values{"client_id": {"spa"}, "redirect_uri": {redirectURI}, "response_type": {"code"}, "code_challenge": {"v"}, "code_challenge_method": {"plain"}}},
+		{name: "S256 challenge", wantCode: http.StatusFound,
+			query: url.Values{"client_id": {"spa"}, "redirect_uri": {redirectURI}, "response_type": {"code"}, "code_challenge": {challengeOf("v")}, "code_challenge_method": {"S256"}}},
+		{name: "confidential client without the challenge", wantCode: http.StatusFound,
+			query: url.Values{"client_id": {"web"}, "response_type": {"code"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, location := authorize(e, tt.query)
+			if code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", code, tt.wantCode)
+			}
+			if code != http.StatusFound {
+				return
+			}
+			if !strings.HasPrefix(location.String(), redirectURI) {
+				t.Errorf("redirected to %s, want %s", location, redirectURI)
+			}
+			if got := location.Query().Get("error"); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	e := newProvider(t)
+	issue := func() string {
+		_, location := authorize(e, url.Values{"client_id": {"spa"}, "redirect_uri": {redirectURI}, "response_type": {"code"},
+			"scope": {"openid"}, "code_challenge": {challengeOf("verifier")}})
+		return location.Query().Get("code")
+	}
+	tests := []struct {
+		name string
+		form func(code string) url.Values
+		want int
+	}{
+		{name: "valid", want: http.StatusOK, form: func(code string) url.Values {
+			return url.Values{"grant_type": {GrantAuthorizationCode}, "client_id": {"spa"}, "code": {code}, "redirect_uri": {redirectURI}, "code_verifier": {"verifier"}}
+		}},
+		{name: "wrong verifier", want: http.StatusBadRequest, form: func(code string) url.Values {
+			return url.Values{"grant_type": {GrantAuthorizationCode}, "client_id": {"spa"}, "code": {code}, "redirect_uri": {redirectURI}, "code_verifier": {"other"}}
+		}},
+		{name: "missing verifier", want: http.StatusBadRequest, form: func(code string) url.Values {
+			return url.Values{"grant_type": {GrantAuthorizationCode}, "client_id": {"spa"}, "code": {code}, "redirect_uri": {redirectURI}}
+		}},
+		{name: "different redirect_uri", want: http.StatusBadRequest, form: func(code string) url.Values {
+			return url.Values{"grant_type": {GrantAuthorizationCode}, "client_id": {"spa"}, "code": {code}, "redirect_uri": {"https://app.example.com/other"}, "code_verifier": {"verifier"}}
+		}},
+		{name: "redirect_uri omitted after it was sent", want: http.StatusBadRequest, form: func(code string) url.Values {
+			return url.Values{"grant_type": {GrantAuthorizationCode}, "client_id": {"spa"}, "code": {code}, "code_verifier": {"verifier"}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := exchange(e, tt.form(issue()))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+	t.Run("code used once", func(t *testing.T) {
+		code := issue()
+		form := url.Values{"grant_type": {GrantAuthorizationCode}, "client_id": {"spa"}, "code": {code}, "redirect_uri": {redirectURI}, "code_verifier": {"verifier"}}
+		if w := exchange(e, form); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "id_token") {
+			t.Fatalf("first exchange = %d %s, want 200 with the id token", w.Code, w.Body.String())
+		}
+		if w := exchange(e, form); w.Code != http.StatusBadRequest {
+			t.Errorf("second exchange = %d, want 400", w.Code)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	Store LimitStore = NewMemoryStore() // Store the limit state storage, memory storage as default
+)
+
+// Rate limit algorithms
+const (
+	TokenBucket   = "token_bucket"   // Refill the bucket at the rate, allow the bursts up to the burst
+	SlidingWindow = "sliding_window" // Allow at most rate*window requests in any window
+)
+
+// Limit the rate limit, token bucket or sliding window with an optional quota of a fixed window
+type Limit struct {
+	Algorithm   string        `mapstructure:"algorithm"`    // token_bucket or sliding_window, default token_bucket
+	Rate        float64       `mapstructure:"rate"`         // Requests per second, 0 means unlimited
+	Burst       int           `mapstructure:"burst"`        // Maximum burst requests of the token bucket, default equal to the rate
+	Window      time.Duration `mapstructure:"window"`       // Window of the sliding window, default 1s
+	Quota       int64         `mapstructure:"quota"`        // Maximum requests within the quota window, 0 means unlimited
+	QuotaWindow time.Duration `mapstructure:"quota_window"` // Quota window, default 24h
+}
+
+// Unlimited the limit does not restrict any request
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0 && l.Quota <= 0
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(l.Rate, 1)
+}
+
+func (l Limit) slidingWindow() bool {
+	return l.Algorithm == SlidingWindow
+}
+
+func (l Limit) window() time.Duration {
+	if l.Window > 0 {
+		return l.Window
+	}
+	return time.Second
+}
+
+// requests allowed in the sliding window
+func (l Limit) windowLimit() float64 {
+	return math.Max(math.Floor(l.Rate*l.window().Seconds()), 1)
+}
+
+func (l Limit) quotaWindow() time.Duration {
+	if l.QuotaWindow > 0 {
+		return l.QuotaWindow
+	}
+	return 24 * time.Hour
+}
+
+// LimitStore the storage of the limit state
+type LimitStore interface {
+	// Take a request of the key, when it is limited, return false and the duration to wait before retrying
+	Take(key string, limit Limit) (allowed bool, retryAfter time.Duration)
+}
+
+// MemoryStore in-process limit state storage, idle keys are removed periodically
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens      float64
+	last        time.Time
+	quotaUsed   int64
+	quotaEnd    time.Time
+	windowStart time.Time
+	current     float64 // requests of the current window
+	previous    float64 // requests of the previous window
+}
+
+// idle keys older than it are removed
+const idleTimeout = 10 * time.Minute
+
+// NewMemoryStore Create a memory limit storage
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+func (m *MemoryStore) Take(key string, limit Limit) (bool, time.Duration) {
+	if limit.Unlimited() {
+		return true, 0
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.burst(), last: now}
+		m.buckets[key] = b
+	}
+	if limit.Quota > 0 {
+		if !now.Before(b.quotaEnd) {
+			b.quotaEnd = now.Add(limit.quotaWindow())
+			b.quotaUsed = 0
+		}
+		if b.quotaUsed >= limit.Quota {
+			return false, b.quotaEnd.Sub(now)
+		}
+	}
+	if limit.Rate > 0 && limit.slidingWindow() {
+		if ok, retryAfter := b.takeWindow(now, limit); !ok {
+			return false, retryAfter
+		}
+	} else if limit.Rate > 0 {
+		b.tokens = math.Min(limit.burst(), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+		b.last = now
+		if b.tokens < 1 {
+			return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		}
+		b.tokens--
+	}
+	b.last = now
+	b.quotaUsed++
+	return true, 0
+}
+
+// take a request of the sliding window, the requests of the previous window are weighted by its overlap
+func (b *bucket) takeWindow(now time.Time, limit Limit) (bool, time.Duration) {
+	window := limit.window()
+	start := now.Truncate(window)
+	if !start.Equal(b.windowStart) {
+		if start.Sub(b.windowStart) == window {
+			b.previous = b.current
+		} else {
+			b.previous = 0
+		}
+		b.windowStart, b.current = start, 0
+	}
+	ms := float64(window.Milliseconds())
+	elapsed := float64(now.Sub(start).Milliseconds())
+	ok, wait := windowAllow(b.previous, b.current, elapsed, ms, limit.windowLimit())
+	if !ok {
+		return false, time.Duration(wait) * time.Millisecond
+	}
+	b.current++
+	return true, 0
+}
+
+// whether the request is allowed in the sliding window, otherwise the milliseconds to wait
+func windowAllow(previous, current, elapsed, window, limit float64) (bool, float64) {
+	if previous*(window-elapsed)/window+current+1 <= limit {
+		return true, 0
+	}
+	// wait until the weight of the previous window drops enough, or the next window
+	if previous > 0 && current+1 <= limit {
+		return false, math.Ceil(window-(limit-current-1)*window/previous-elapsed) + 1
+	}
+	return false, math.Ceil(window-elapsed) + 1
+}
+
+// remove the idle buckets, at most once a minute
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for k, b := range m.buckets {
+		if now.Sub(b.last) > idleTimeout && now.After(b.quotaEnd) {
+			delete(m.buckets, k)
+		}
+	}
+}
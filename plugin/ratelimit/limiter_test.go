@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Rate: 10, Burst: 3}
+	for i := 0; i < 3; i++ {
+		if ok, _ := s.Take("k", limit); !ok {
+			t.Fatalf("request %d of the burst is limited", i+1)
+		}
+	}
+	ok, retryAfter := s.Take("k", limit)
+	if ok {
+		t.Fatal("request exceeding the burst is allowed")
+	}
+	if retryAfter <= 0 || retryAfter > 100*time.Millisecond {
+		t.Errorf("retryAfter = %s, want (0, 100ms] to refill a token at 10/s", retryAfter)
+	}
+	// refill a token
+	s.buckets["k"].last = s.buckets["k"].last.Add(-100 * time.Millisecond)
+	if ok, _ = s.Take("k", limit); !ok {
+		t.Error("request after the refill is limited")
+	}
+	if ok, _ = s.Take("other", limit); !ok {
+		t.Error("request of another key is limited")
+	}
+}
+
+func TestQuota(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Quota: 2, QuotaWindow: time.Hour}
+	s.Take("k", limit)
+	s.Take("k", limit)
+	ok, retryAfter := s.Take("k", limit)
+	if ok || retryAfter <= 59*time.Minute {
+		t.Errorf("Take after the quota = %v, %s, want limited until the window ends", ok, retryAfter)
+	}
+}
+
+func TestWindowAllow(t *testing.T) {
+	tests := []struct {
+		name                               string
+		previous, current, elapsed, window float64
+		limit                              float64
+		want                               bool
+		wantWait                           float64
+	}{
+		{name: "empty windows", window: 1000, limit: 10, want: true},
+		{name: "current window full", current: 10, elapsed: 300, window: 1000, limit: 10, wantWait: 701},
+		// 10*0.5 + 4 + 1 = 10
+		{name: "previous window weighted by the overlap", previous: 10, current: 4, elapsed: 500, window: 1000, limit: 10, want: true},
+		// 10*0.5 + 5 + 1 = 11, allowed once the weight of the previous window drops to 4 at 600ms
+		{name: "wait the previous window to drop", previous: 10, current: 5, elapsed: 500, window: 1000, limit: 10, wantWait: 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, wait := windowAllow(tt.previous, tt.current, tt.elapsed, tt.window, tt.limit)
+			if ok != tt.want || wait != tt.wantWait {
+				t.Errorf("windowAllow = %v, %v, want %v, %v", ok, wait, tt.want, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Algorithm: SlidingWindow, Rate: 1, Window: time.Minute}
+	for i := 0; i < 60; i++ {
+		if ok, _ := s.Take("k", limit); !ok {
+			t.Fatalf("request %d of the window is limited", i+1)
+		}
+	}
+	if ok, retryAfter := s.Take("k", limit); ok || retryAfter <= 0 {
+		t.Errorf("Take exceeding the window = %v, %s, want limited", ok, retryAfter)
+	}
+}
+
+func TestRulesByAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Store = NewMemoryStore()
+	AuthenticatedKey = func(ctx *gin.Context) string {
+		return ctx.GetString("key_id")
+	}
+	defer func() { AuthenticatedKey = nil }()
+	e := gin.New()
+	e.Use(func(ctx *gin.Context) {
+		// the key authenticated by the api key middleware
+		if ctx.GetHeader("X-Api-Key") == "valid" {
+			ctx.Set("key_id", "k1")
+		}
+	})
+	e.Use(Rules([]Rule{{Strategy: ByAPIKey, Limit: Limit{Rate: 1, Burst: 1}}}))
+	e.GET("/open", func(ctx *gin.Context) {})
+	get := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/open", nil)
+		req.Header.Set("X-Api-Key", key)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := get("valid"); code != http.StatusOK {
+		t.Fatalf("first request of the key = %d, want 200", code)
+	}
+	if code := get("valid"); code != http.StatusTooManyRequests {
+		t.Errorf("second request of the key = %d, want 429", code)
+	}
+	for i := 0; i < 3; i++ {
+		get("random" + strconv.Itoa(i))
+	}
+	if n := len(Store.(*MemoryStore).buckets); n != 1 {
+		t.Errorf("buckets = %d, want 1, the unauthenticated keys have no bucket", n)
+	}
+}
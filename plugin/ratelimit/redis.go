@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// StoreConfig the storage of the limit state
+type StoreConfig struct {
+	Type     string `mapstructure:"type"`     // memory or redis, default memory. Redis shares the limits between the instances
+	Addr     string `mapstructure:"addr"`     // Redis address, default the client of the redis configuration, or 127.0.0.1:6379 without it
+	Username string `mapstructure:"username"` // Redis username
+	Password string `mapstructure:"password"` // Redis password
+	DB       int    `mapstructure:"db"`       // Redis database
+	Prefix   string `mapstructure:"prefix"`   // Prefix of the keys, default gin-plus:ratelimit:
+}
+
+// the state is a hash of the token bucket (t, l), the quota (qu, qe) and the sliding window (ws, wc, wp),
+// the time is in milliseconds
+var takeScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local sliding = ARGV[2] == '1'
+local rate = tonumber(ARGV[3])
+local burst = tonumber(ARGV[4])
+local window = tonumber(ARGV[5])
+local limit = tonumber(ARGV[6])
+local quota = tonumber(ARGV[7])
+local qwindow = tonumber(ARGV[8])
+local s = redis.call('HMGET', KEYS[1], 't', 'l', 'qu', 'qe', 'ws', 'wc', 'wp')
+local qu = tonumber(s[3]) or 0
+local qe = tonumber(s[4]) or 0
+if quota > 0 then
+  if now >= qe then
+    qe = now + qwindow
+    qu = 0
+  end
+  if qu >= quota then
+    return {0, qe - now}
+  end
+end
+local ttl = 600000
+if rate > 0 and sliding then
+  local start = now - now % window
+  local ws = tonumber(s[5]) or start
+  local wc = tonumber(s[6]) or 0
+  local wp = tonumber(s[7]) or 0
+  if start ~= ws then
+    if start - ws == window then wp = wc else wp = 0 end
+    ws = start
+    wc = 0
+  end
+  local elapsed = now - start
+  if wp * (window - elapsed) / window + wc + 1 > limit then
+    if wp > 0 and wc + 1 <= limit then
+      return {0, math.ceil(window - (limit - wc - 1) * window / wp - elapsed) + 1}
+    end
+    return {0, math.ceil(window - elapsed) + 1}
+  end
+  redis.call('HSET', KEYS[1], 'ws', ws, 'wc', wc + 1, 'wp', wp)
+  ttl = math.max(ttl, window * 2)
+elseif rate > 0 then
+  local t = tonumber(s[1]) or burst
+  local l = tonumber(s[2]) or now
+  t = math.min(burst, t + (now - l) / 1000 * rate)
+  if t < 1 then
+    return {0, math.ceil((1 - t) / rate * 1000)}
+  end
+  redis.call('HSET', KEYS[1], 't', tostring(t - 1), 'l', now)
+end
+if quota > 0 then
+  redis.call('HSET', KEYS[1], 'qu', qu + 1, 'qe', qe)
+  ttl = math.max(ttl, qe - now)
+end
+redis.call('PEXPIRE', KEYS[1], math.ceil(ttl))
+return {1, 0}
+`)
+
+// RedisStore the limit state storage shared by the instances, the state is updated atomically by the lua script
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore Create the redis limit storage, the client is closed with the storage
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "gin-plus:ratelimit:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// NewStore Create the limit storage from the configuration
+func NewStore(conf StoreConfig) LimitStore {
+	if conf.Type != "redis" {
+		return NewMemoryStore()
+	}
+	if conf.Addr == "" {
+		conf.Addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: conf.Addr, Username: conf.Username, Password: conf.Password, DB: conf.DB})
+	return NewRedisStore(client, conf.Prefix)
+}
+
+// Take the request is allowed when redis fails, so the outage of redis doesn't take down the service
+func (r *RedisStore) Take(key string, limit Limit) (bool, time.Duration) {
+	if limit.Unlimited() {
+		return true, 0
+	}
+	sliding := "0"
+	if limit.slidingWindow() {
+		sliding = "1"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := takeScript.Run(ctx, r.client, []string{r.prefix + key}, time.Now().UnixMilli(), sliding, limit.Rate,
+		limit.burst(), limit.window().Milliseconds(), limit.windowLimit(), limit.Quota, limit.quotaWindow().Milliseconds()).Int64Slice()
+	if err != nil || len(res) != 2 {
+		if err != nil {
+			logger.Log.Warnf("Rate limit by redis error, %s", err.Error())
+		}
+		return true, 0
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+// Rule strategies
+const (
+	ByIP     = "ip"      // Limit the requests of each client ip
+	ByRoute  = "route"   // Limit the requests of each route of all clients
+	ByAPIKey = "api_key" // Limit the requests of each authenticated api key, the requests without it are not limited
+)
+
+// AuthenticatedKey resolve the id of the api key authenticated by the request, it's set by the auth package
+var AuthenticatedKey func(ctx *gin.Context) string
+
+// Rule the rate limit rule, the request is rejected with http status 429 when any matching rule is exceeded
+type Rule struct {
+	Name     string   `mapstructure:"name"`     // Name of the rule, part of the limit key, default rule{index}
+	Strategy string   `mapstructure:"strategy"` // ip, route or api_key, default ip
+	Paths    []string `mapstructure:"paths"`    // Ant-style patterns of the route templates, prefixed with ! to exclude, default all routes
+	Limit    `mapstructure:",squash"`
+}
+
+type rule struct {
+	Rule
+	key   string
+	match func(ctx *gin.Context) bool
+}
+
+// Rules The gin middleware limits the requests by the rules, such as 100 requests per second of each ip
+// and 10 requests per second of the /order/create route. The api keys are the ones authenticated by the api key
+// authentication, so it should run after it, the keys sent by the clients are not trusted, otherwise rotating the
+// keys skips the limit
+func Rules(rules []Rule) gin.HandlerFunc {
+	compiled := make([]rule, 0, len(rules))
+	for i, r := range rules {
+		if r.Unlimited() {
+			continue
+		}
+		c := rule{Rule: r, key: r.Name}
+		if c.key == "" {
+			c.key = "rule" + strconv.Itoa(i)
+		}
+		if c.Strategy == "" {
+			c.Strategy = ByIP
+		}
+		if len(r.Paths) > 0 {
+			c.match = mvc.PathPredicate(r.Paths...)
+		}
+		compiled = append(compiled, c)
+	}
+	return func(ctx *gin.Context) {
+		for _, r := range compiled {
+			if r.match != nil && !r.match(ctx) {
+				continue
+			}
+			var subject string
+			switch r.Strategy {
+			case ByRoute:
+				subject = ctx.FullPath()
+			case ByAPIKey:
+				if AuthenticatedKey != nil {
+					subject = AuthenticatedKey(ctx)
+				}
+			default:
+				subject = ctx.ClientIP()
+			}
+			if subject == "" {
+				continue
+			}
+			if allowed, retryAfter := Store.Take(r.key+":"+r.Strategy+":"+subject, r.Limit); !allowed {
+				resp.TooManyRequests(ctx, retryAfter)
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	TenantProvider  TenantLimitProvider           // TenantProvider provide the limits of tenants, takes precedence over the configuration
+	TenantResolver  func(ctx *gin.Context) string // TenantResolver resolve the tenant of the request, nil means the configured header or the principal
+	PrincipalTenant func(ctx *gin.Context) string // PrincipalTenant resolve the tenant of the authenticated principal, it's set by the auth package
+)
+
+// TenantConfig the rate limits of tenants
+type TenantConfig struct {
+	Enabled bool             `mapstructure:"enabled"` // Whether to limit the requests of each tenant, default false
+	Header  string           `mapstructure:"header"`  // Header carrying the tenant id set by the trusted gateway, default empty means the tenant of the authenticated principal
+	Default Limit            `mapstructure:"default"` // Limit of the tenants without specific configuration, the unknown and the empty tenants included
+	Tenants map[string]Limit `mapstructure:"tenants"` // Limit of each tenant, the key is the tenant id
+}
+
+// TenantLimitProvider provide the limits of tenants, such as loading from the database
+type TenantLimitProvider interface {
+	// TenantLimit the limit of the tenant, ok is false means the configured limit is used
+	TenantLimit(tenant string) (limit Limit, ok bool)
+}
+
+/*
+Tenant The gin middleware limits the requests of each tenant, so that a noisy tenant can't consume the whole instance.
+The tenant is the one of the authenticated principal by default, so it should run after the authentication, the
+header set by the client is trusted only when it's configured. The unknown tenants are limited by the default limit,
+the requests without the tenant share the default limit of the empty tenant, so omitting or rotating the tenant
+doesn't skip the limit.
+*/
+func Tenant(conf TenantConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var tenant string
+		switch {
+		case TenantResolver != nil:
+			tenant = TenantResolver(ctx)
+		case conf.Header != "":
+			tenant = ctx.GetHeader(conf.Header)
+		case PrincipalTenant != nil:
+			tenant = PrincipalTenant(ctx)
+		}
+		limit, ok := Limit{}, false
+		if TenantProvider != nil {
+			limit, ok = TenantProvider.TenantLimit(tenant)
+		}
+		if !ok {
+			if limit, ok = conf.Tenants[tenant]; !ok || tenant == "" {
+				limit = conf.Default
+			}
+		}
+		if allowed, retryAfter := Store.Take("tenant:"+tenant, limit); !allowed {
+			resp.TooManyRequests(ctx, retryAfter)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/metrics"
+	goredis "github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+var (
+	metricsOnce     sync.Once
+	commandDuration *metrics.Histogram
+	poolConns       *metrics.Gauge
+	poolEvents      *metrics.Gauge
+)
+
+// LatencyBuckets the buckets of the command latency in seconds
+var LatencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+func initMetrics() {
+	metricsOnce.Do(func() {
+		commandDuration = metrics.NewHistogram("redis_command_duration_seconds",
+			"Latency of the redis commands, the pipeline is recorded as one command.", LatencyBuckets, "command", "status")
+		poolConns = metrics.NewGauge("redis_pool_connections", "Connections of the redis pool by state.", "state")
+		poolEvents = metrics.NewGauge("redis_pool_events", "Cumulative events of the redis pool, such as the hits and the timeouts.", "event")
+	})
+}
+
+// metricsHook records the latency of the commands
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (metricsHook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		observe(cmd.Name(), start, err)
+		return err
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		observe("pipeline", start, err)
+		return err
+	}
+}
+
+func observe(command string, start time.Time, err error) {
+	initMetrics()
+	status := "ok"
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		status = "error"
+	}
+	commandDuration.Observe(time.Since(start).Seconds(), command, status)
+}
+
+// record the stats of the pool
+func recordPool(stats *goredis.PoolStats) {
+	initMetrics()
+	poolConns.Set(float64(stats.TotalConns), "total")
+	poolConns.Set(float64(stats.IdleConns), "idle")
+	poolEvents.Set(float64(stats.Hits), "hits")
+	poolEvents.Set(float64(stats.Misses), "misses")
+	poolEvents.Set(float64(stats.Timeouts), "timeouts")
+	poolEvents.Set(float64(stats.StaleConns), "stale")
+}
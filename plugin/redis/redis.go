@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Config the redis connection
+type Config struct {
+	Mode             string        `mapstructure:"mode"`              // standalone, sentinel or cluster, default standalone
+	Addrs            []string      `mapstructure:"addrs"`             // Addresses of the server, the sentinels or the cluster nodes, empty means no redis client
+	MasterName       string        `mapstructure:"master_name"`       // Name of the master monitored by the sentinels, required in sentinel mode
+	Username         string        `mapstructure:"username"`          // Redis username
+	Password         string        `mapstructure:"password"`          // Redis password
+	SentinelUsername string        `mapstructure:"sentinel_username"` // Username of the sentinels
+	SentinelPassword string        `mapstructure:"sentinel_password"` // Password of the sentinels
+	DB               int           `mapstructure:"db"`                // Redis database, not supported in cluster mode
+	PoolSize         int           `mapstructure:"pool_size"`         // Max connections of each node, default 10 per cpu
+	MinIdleConns     int           `mapstructure:"min_idle_conns"`    // Min idle connections of each node
+	DialTimeout      time.Duration `mapstructure:"dial_timeout"`      // Timeout of establishing the connection, default 5s
+	ReadTimeout      time.Duration `mapstructure:"read_timeout"`      // Timeout of the reads, default 3s
+	WriteTimeout     time.Duration `mapstructure:"write_timeout"`     // Timeout of the writes, default the read timeout
+}
+
+// Default the redis client created from the redis configuration when the application starts, nil when no address
+var Default goredis.UniversalClient
+
+/*
+New Create the redis client of the mode, the commands are recorded by the metrics:
+
+	redis_command_duration_seconds{command="get",status="ok"}
+*/
+func New(conf Config) (goredis.UniversalClient, error) {
+	opts := &goredis.UniversalOptions{
+		Addrs:            conf.Addrs,
+		MasterName:       conf.MasterName,
+		Username:         conf.Username,
+		Password:         conf.Password,
+		SentinelUsername: conf.SentinelUsername,
+		SentinelPassword: conf.SentinelPassword,
+		DB:               conf.DB,
+		PoolSize:         conf.PoolSize,
+		MinIdleConns:     conf.MinIdleConns,
+		DialTimeout:      conf.DialTimeout,
+		ReadTimeout:      conf.ReadTimeout,
+		WriteTimeout:     conf.WriteTimeout,
+	}
+	var client goredis.UniversalClient
+	switch conf.Mode {
+	case "", "standalone":
+		client = goredis.NewClient(opts.Simple())
+	case "sentinel":
+		if conf.MasterName == "" {
+			return nil, errors.New("redis master_name is required in sentinel mode")
+		}
+		client = goredis.NewFailoverClient(opts.Failover())
+	case "cluster":
+		client = goredis.NewClusterClient(opts.Cluster())
+	default:
+		return nil, fmt.Errorf("unknown redis mode %s, standalone, sentinel or cluster is expected", conf.Mode)
+	}
+	client.AddHook(metricsHook{})
+	return client, nil
+}
+
+// Shared Get the default client shared by the stores of the plugins, its Close does nothing, because the default
+// client is closed when the application stops. nil when there's no default client
+func Shared() goredis.UniversalClient {
+	if Default == nil {
+		return nil
+	}
+	return sharedClient{Default}
+}
+
+// sharedClient the client whose lifecycle is owned by the application
+type sharedClient struct {
+	goredis.UniversalClient
+}
+
+func (sharedClient) Close() error {
+	return nil
+}
+
+// Ping the default client, it's the health indicator of redis and refreshes the pool metrics
+func Ping(ctx context.Context) error {
+	if Default == nil {
+		return errors.New("no redis client")
+	}
+	recordPool(Default.PoolStats())
+	return Default.Ping(ctx).Err()
+}
+
+// Close the default client
+func Close() error {
+	if Default == nil {
+		return nil
+	}
+	return Default.Close()
+}
@@ -0,0 +1,159 @@
+package rewrite
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Trailing slash policies
+const (
+	TrailingSlashStrip = "strip" // Redirect /a/ to /a
+	TrailingSlashAdd   = "add"   // Redirect /a to /a/
+)
+
+// Config the redirect and rewrite configuration, evaluated before routing
+type Config struct {
+	Watch         bool   `mapstructure:"watch"`          // Reload the rules when the configuration file changes, default false
+	HttpsRedirect bool   `mapstructure:"https_redirect"` // Redirect http to https, the X-Forwarded-Proto header is respected, default false
+	TrailingSlash string `mapstructure:"trailing_slash"` // Trailing slash policy, strip or add, default empty means keep it
+	Rules         []Rule `mapstructure:"rules"`          // Rules evaluated in order, the first matched rule wins
+}
+
+// Rule the redirect or rewrite rule.
+// The redirect and rewrite target can reference the submatches of the path pattern, such as $1 or ${name}
+type Rule struct {
+	Path       string            `mapstructure:"path"`        // Regexp of the request path, such as ^/old/(.*)$
+	Host       string            `mapstructure:"host"`        // Request host, empty means any host
+	Headers    map[string]string `mapstructure:"headers"`     // Regexps of the request headers, all of them must match
+	Redirect   string            `mapstructure:"redirect"`    // Redirect target, path or absolute url. The query is kept when it has no query
+	Status     int               `mapstructure:"status"`      // Redirect status, default 301
+	Rewrite    string            `mapstructure:"rewrite"`     // Rewritten path, the request is routed with it
+	SetHeaders map[string]string `mapstructure:"set_headers"` // Headers set to the request when it is rewritten
+}
+
+type compiledRule struct {
+	Rule
+	path    *regexp.Regexp
+	headers map[string]*regexp.Regexp
+}
+
+type ruleSet struct {
+	conf  Config
+	rules []compiledRule
+}
+
+var current atomic.Pointer[ruleSet]
+
+// Load Compile and apply the configuration, the previous rules are kept when it is invalid
+func Load(conf Config) error {
+	set := &ruleSet{conf: conf, rules: make([]compiledRule, 0, len(conf.Rules))}
+	if conf.TrailingSlash != "" && conf.TrailingSlash != TrailingSlashStrip && conf.TrailingSlash != TrailingSlashAdd {
+		return fmt.Errorf("invalid trailing slash policy [%s], must be strip or add", conf.TrailingSlash)
+	}
+	for i, r := range conf.Rules {
+		if (r.Redirect == "") == (r.Rewrite == "") {
+			return fmt.Errorf("rewrite rule %d must have either redirect or rewrite", i)
+		}
+		c := compiledRule{Rule: r, headers: make(map[string]*regexp.Regexp, len(r.Headers))}
+		if c.Status == 0 {
+			c.Status = http.StatusMovedPermanently
+		}
+		var err error
+		if c.path, err = regexp.Compile(r.Path); err != nil {
+			return fmt.Errorf("invalid path of rewrite rule %d, %s", i, err.Error())
+		}
+		for name, pattern := range r.Headers {
+			if c.headers[name], err = regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid header [%s] of rewrite rule %d, %s", name, i, err.Error())
+			}
+		}
+		set.rules = append(set.rules, c)
+	}
+	current.Store(set)
+	return nil
+}
+
+// Handler Wrap the http handler, the redirects and rewrites are evaluated before routing
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set := current.Load()
+		if set == nil || set.apply(w, r) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// apply the rules, return false when the request is redirected
+func (s *ruleSet) apply(w http.ResponseWriter, r *http.Request) bool {
+	if s.conf.HttpsRedirect && !isHttps(r) {
+		http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		return false
+	}
+	p := r.URL.Path
+	switch {
+	case s.conf.TrailingSlash == TrailingSlashStrip && len(p) > 1 && strings.HasSuffix(p, "/"):
+		http.Redirect(w, r, withQuery(strings.TrimRight(p, "/"), r), http.StatusMovedPermanently)
+		return false
+	case s.conf.TrailingSlash == TrailingSlashAdd && !strings.HasSuffix(p, "/") && !strings.Contains(p[strings.LastIndex(p, "/")+1:], "."):
+		http.Redirect(w, r, withQuery(p+"/", r), http.StatusMovedPermanently)
+		return false
+	}
+	for i := range s.rules {
+		rule := &s.rules[i]
+		match := rule.match(r)
+		if match == nil {
+			continue
+		}
+		if rule.Redirect != "" {
+			target := string(rule.path.ExpandString(nil, rule.Redirect, p, match))
+			http.Redirect(w, r, withQuery(target, r), rule.Status)
+			return false
+		}
+		r.URL.Path = string(rule.path.ExpandString(nil, rule.Rewrite, p, match))
+		r.URL.RawPath = ""
+		for name, value := range rule.SetHeaders {
+			r.Header.Set(name, value)
+		}
+		logger.Log.Debugf("Rewrite [%s] -> [%s]", p, r.URL.Path)
+		return true
+	}
+	return true
+}
+
+// match the rule, return the submatch indexes of the path
+func (c *compiledRule) match(r *http.Request) []int {
+	if c.Host != "" && !strings.EqualFold(c.Host, hostname(r.Host)) {
+		return nil
+	}
+	for name, pattern := range c.headers {
+		if !pattern.MatchString(r.Header.Get(name)) {
+			return nil
+		}
+	}
+	return c.path.FindStringSubmatchIndex(r.URL.Path)
+}
+
+func isHttps(r *http.Request) bool {
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		return strings.EqualFold(proto, "https")
+	}
+	return r.TLS != nil
+}
+
+func hostname(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i > strings.LastIndexByte(host, ']') {
+		return host[:i]
+	}
+	return host
+}
+
+func withQuery(target string, r *http.Request) string {
+	if r.URL.RawQuery == "" || strings.Contains(target, "?") {
+		return target
+	}
+	return target + "?" + r.URL.RawQuery
+}
@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule the times a task runs at
+type Schedule interface {
+	// Next the first time after t, zero means never
+	Next(t time.Time) time.Time
+}
+
+// every runs at the fixed interval
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cron the fields of the cron expression as bit sets
+type cron struct {
+	second, minute, hour, dom, month, dow uint64
+	loc                                   *time.Location
+}
+
+type bounds struct {
+	min, max int
+}
+
+var (
+	secondBounds = bounds{0, 59}
+	minuteBounds = bounds{0, 59}
+	hourBounds   = bounds{0, 23}
+	domBounds    = bounds{1, 31}
+	monthBounds  = bounds{1, 12}
+	dowBounds    = bounds{0, 6}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse the schedule in the location, nil means the local time. The spec is one of:
+//
+//	the cron expression with seconds: second minute hour day-of-month month day-of-week, such as 0 */5 * * * *
+//	the cron expression without seconds, it runs at the second 0, such as */5 * * * *
+//	the descriptors: @yearly, @monthly, @weekly, @daily, @hourly
+//	the fixed interval: @every 1m30s
+//
+// The fields support *, ?, lists (1,15), ranges (1-5) and steps (*/5, 10-40/10), the day-of-week 7 is sunday too.
+// The task runs when both the day-of-month and the day-of-week match, unless one of them is * or ?
+func Parse(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if loc == nil {
+		loc = time.Local
+	}
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %s, %w", d, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("interval %s is less than 1s", interval)
+		}
+		return every(interval), nil
+	}
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron expression %q, 5 or 6 fields are expected", spec)
+	}
+	c := &cron{loc: loc}
+	var err error
+	targets := []*uint64{&c.second, &c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, b := range []bounds{secondBounds, minuteBounds, hourBounds, domBounds, monthBounds, dowBounds} {
+		if *targets[i], err = parseField(fields[i], b, i == 5); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q, %w", spec, err)
+		}
+	}
+	// * and ? of the day fields match any day, so the other day field decides alone
+	if isAny(fields[3]) && !isAny(fields[5]) {
+		c.dom = 0
+	} else if isAny(fields[5]) && !isAny(fields[3]) {
+		c.dow = 0
+	}
+	return c, nil
+}
+
+func isAny(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parse the field to the bit set of the matched values
+func parseField(field string, b bounds, dow bool) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %s", item)
+			}
+			step = n
+		}
+		lo, hi := b.min, b.max
+		if rng != "*" && rng != "?" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("invalid value %s", item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("invalid value %s", item)
+				}
+			} else if hasStep {
+				hi = b.max
+			}
+		}
+		if dow && hi == 7 && lo >= b.min {
+			// 7 is sunday too
+			if (7-lo)%step == 0 {
+				bits |= 1
+			}
+			if lo == 7 {
+				continue
+			}
+			hi = 6
+		}
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("value %s out of range [%d, %d]", item, b.min, b.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+// Next the first time matching all the fields after t, searched within 5 years
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.In(c.loc).Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !has(c.month, int(t.Month())) {
+			t = c.date(t, t.Year(), t.Month()+1, 1, 0)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = c.date(t, t.Year(), t.Month(), t.Day()+1, 0)
+			continue
+		}
+		if !has(c.hour, t.Hour()) {
+			t = c.date(t, t.Year(), t.Month(), t.Day(), t.Hour()+1)
+			continue
+		}
+		if !has(c.minute, t.Minute()) {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if !has(c.second, t.Second()) {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// the start of the hour in the location after t. The hour skipped by the daylight saving is normalized to the hour
+// before it, such as 2:00 to 1:00 in America/New_York, so it's moved forward to keep the search going
+func (c *cron) date(t time.Time, year int, month time.Month, day, hour int) time.Time {
+	d := time.Date(year, month, day, hour, 0, 0, 0, c.loc)
+	if !d.After(t) {
+		d = d.Add(time.Hour)
+	}
+	return d
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	switch {
+	case c.dom == 0:
+		return has(c.dow, int(t.Weekday()))
+	case c.dow == 0:
+		return has(c.dom, t.Day())
+	}
+	// both are restricted, or both are any
+	return has(c.dom, t.Day()) && has(c.dow, int(t.Weekday()))
+}
@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"0 */5 * * * *", false},
+		{"*/5 * * * *", false},
+		{"0 0 9 ? * 1-5", false},
+		{"0 0 0 * * 7", false},
+		{"0 0 0 * * 5-7", false},
+		{"@daily", false},
+		{"@every 1m30s", false},
+		{"@every 500ms", true},
+		{"@every soon", true},
+		{"* * * *", true},
+		{"60 * * * * *", true},
+		{"0 0 24 * * *", true},
+		{"0 0 0 0 * *", true},
+		{"0 0 0 * 13 *", true},
+		{"0 0 0 * * 8", true},
+		{"0 0 0 * * 5-3", true},
+		{"*/0 * * * * *", true},
+		{"a * * * * *", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec, time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database is absent, %v", err)
+	}
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skipf("time zone database is absent, %v", err)
+	}
+	tests := []struct {
+		name string
+		spec string
+		loc  *time.Location
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "every 5 minutes",
+			spec: "0 */5 * * * *",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 10, 3, 20, 0, time.UTC),
+			want: time.Date(2026, 1, 1, 10, 5, 0, 0, time.UTC),
+		},
+		{
+			name: "without seconds runs at the second 0",
+			spec: "30 2 * * *",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 2, 30, 0, 0, time.UTC),
+			want: time.Date(2026, 1, 2, 2, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "day-of-week 7 is sunday",
+			spec: "0 0 0 * * 7",
+			loc:  time.UTC,
+			from: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day-of-week range ending at 7 includes sunday",
+			spec: "0 0 0 * * 6-7",
+			loc:  time.UTC,
+			from: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "both day fields restricted",
+			spec: "0 0 0 13 * 5",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 2, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leap day",
+			spec: "0 0 0 29 2 *",
+			loc:  time.UTC,
+			from: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "february 30 never runs",
+			spec: "0 0 0 30 2 *",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "april 31 never runs",
+			spec: "0 0 0 31 4 *",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "time skipped by daylight saving is skipped",
+			spec: "0 30 2 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 9, 2, 30, 0, 0, newYork),
+		},
+		{
+			name: "hour after daylight saving starts",
+			spec: "0 0 3 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 8, 3, 0, 0, 0, newYork),
+		},
+		{
+			name: "midnight skipped by daylight saving is skipped",
+			spec: "0 0 0 * * *",
+			loc:  santiago,
+			from: time.Date(2026, 9, 5, 12, 0, 0, 0, santiago),
+			want: time.Date(2026, 9, 7, 0, 0, 0, 0, santiago),
+		},
+		{
+			name: "day after the midnight skipped by daylight saving",
+			spec: "0 0 12 * * *",
+			loc:  santiago,
+			from: time.Date(2026, 9, 5, 13, 0, 0, 0, santiago),
+			want: time.Date(2026, 9, 6, 12, 0, 0, 0, santiago),
+		},
+		{
+			name: "interval across daylight saving ends",
+			spec: "0 */30 * * * *",
+			loc:  newYork,
+			from: time.Date(2026, 11, 1, 5, 45, 0, 0, time.UTC), // 01:45 EDT
+			want: time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC),  // 01:00 EST
+		},
+		{
+			name: "location of the schedule",
+			spec: "0 0 9 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 6, 1, 13, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec, tt.loc)
+			if err != nil {
+				t.Fatalf("Parse(%q) error, %v", tt.spec, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddRejectsNeverRunning(t *testing.T) {
+	job := func(ctx context.Context) error { return nil }
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"0 0 0 * * *", false},
+		{"0 0 0 29 2 *", false},
+		{"0 0 0 30 2 *", true},
+		{"0 0 0 31 11 *", true},
+		{"0 0 0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s := New(Config{Location: "UTC"})
+			if err := s.Add(Cron(tt.spec, job)); (err != nil) != tt.wantErr {
+				t.Errorf("Add(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
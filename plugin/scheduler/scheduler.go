@@ -0,0 +1,312 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Default the scheduler of the application, it's started after the application started and stopped when it stops
+var Default = New(Config{Enabled: true})
+
+// Config the scheduled tasks
+type Config struct {
+	Enabled  bool          `mapstructure:"enabled"`  // Whether to run the scheduled tasks, such as disabled on the replicas not running the jobs, default true
+	Location string        `mapstructure:"location"` // Time zone of the cron expressions, such as Asia/Shanghai, default the local time zone
+	Jitter   time.Duration `mapstructure:"jitter"`   // Max random delay of each run of the tasks without their own jitter, default 0
+}
+
+/*
+Task the scheduled job, it's created by Cron() or Every(). The run is skipped when the previous run of the task is still
+running, unless AllowOverlap() is set. The panic of the job is recovered and logged as the error.
+*/
+type Task struct {
+	Name         string
+	Spec         string
+	Job          func(ctx context.Context) error
+	Jitter       time.Duration // Max random delay of each run, so the replicas don't hit the dependencies at the same time
+	AllowOverlap bool          // Whether the run starts when the previous run is still running
+}
+
+// Option the option of the task
+type Option func(t *Task)
+
+// WithName Set the name of the task printed in the logs, default the name of the job function
+func WithName(name string) Option {
+	return func(t *Task) {
+		t.Name = name
+	}
+}
+
+// WithJitter Delay each run of the task randomly up to the max
+func WithJitter(max time.Duration) Option {
+	return func(t *Task) {
+		t.Jitter = max
+	}
+}
+
+// AllowOverlap Start the run even when the previous run is still running
+func AllowOverlap() Option {
+	return func(t *Task) {
+		t.AllowOverlap = true
+	}
+}
+
+// Cron Create the task running at the times of the cron expression, see Parse()
+//
+//	scheduler.Cron("0 */5 * * * *", s.SyncOrders, scheduler.WithJitter(10*time.Second))
+func Cron(spec string, job func(ctx context.Context) error, opts ...Option) Task {
+	t := Task{Name: runtime.FuncForPC(reflect.ValueOf(job).Pointer()).Name(), Spec: spec, Job: job}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	return t
+}
+
+// Every Create the task running at the fixed interval, the first run is after the interval
+func Every(interval time.Duration, job func(ctx context.Context) error, opts ...Option) Task {
+	return Cron("@every "+interval.String(), job, opts...)
+}
+
+// ScheduledBean the bean declaring the scheduled tasks, the tasks are added when the application starts
+//
+//	func (s *OrderService) Tasks() []scheduler.Task {
+//	    return []scheduler.Task{
+//	        scheduler.Cron("0 */5 * * * *", s.CloseExpiredOrders),
+//	    }
+//	}
+//
+//	func (s *OrderService) CloseExpiredOrders(ctx context.Context) error {}
+type ScheduledBean interface {
+	Tasks() []Task
+}
+
+// TaskStatus the status of the scheduled task
+type TaskStatus struct {
+	Name     string    `json:"name"`
+	Spec     string    `json:"spec"`
+	Running  bool      `json:"running"`
+	LastRun  time.Time `json:"last_run,omitempty"`
+	LastErr  string    `json:"last_error,omitempty"`
+	NextRun  time.Time `json:"next_run,omitempty"`
+	Runs     int64     `json:"runs"`
+	Failures int64     `json:"failures"`
+}
+
+// the task with its schedule and status
+type entry struct {
+	Task
+	schedule Schedule
+	mu       sync.Mutex
+	status   TaskStatus
+}
+
+// Scheduler runs the scheduled tasks
+type Scheduler struct {
+	mu      sync.Mutex
+	conf    Config
+	loc     *time.Location
+	entries []*entry
+	ctx     context.Context // Canceled when the scheduler stops
+	cancel  context.CancelFunc
+	jobCtx  context.Context // Context of the jobs, canceled when the jobs don't finish in time after stopping
+	stopJob context.CancelFunc
+	loops   sync.WaitGroup // the goroutines waiting for the next runs
+	runs    sync.WaitGroup // the running jobs
+	started bool
+}
+
+// New Create the scheduler
+func New(conf Config) *Scheduler {
+	s := &Scheduler{}
+	if err := s.SetConfig(conf); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// SetConfig Set the configuration, it should be set before the tasks are added
+func (s *Scheduler) SetConfig(conf Config) error {
+	loc := time.Local
+	if conf.Location != "" {
+		l, err := time.LoadLocation(conf.Location)
+		if err != nil {
+			return fmt.Errorf("invalid scheduler location %s, %w", conf.Location, err)
+		}
+		loc = l
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conf, s.loc = conf, loc
+	return nil
+}
+
+// Add the tasks, returns the error when the spec is invalid or never runs, such as 0 0 0 30 2 *. The tasks added
+// after starting run immediately
+func (s *Scheduler) Add(tasks ...Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range tasks {
+		schedule, err := Parse(t.Spec, s.loc)
+		if err != nil {
+			return fmt.Errorf("task %s, %w", t.Name, err)
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			return fmt.Errorf("task %s, cron expression %q never runs", t.Name, t.Spec)
+		}
+		if t.Jitter == 0 {
+			t.Jitter = s.conf.Jitter
+		}
+		e := &entry{Task: t, schedule: schedule, status: TaskStatus{Name: t.Name, Spec: t.Spec}}
+		s.entries = append(s.entries, e)
+		if s.started {
+			s.loop(s.ctx, s.jobCtx, e)
+		}
+	}
+	return nil
+}
+
+// Register Add the tasks of the beans implementing ScheduledBean, the other beans are ignored
+func (s *Scheduler) Register(beans ...any) error {
+	for _, bean := range beans {
+		if sb, ok := bean.(ScheduledBean); ok {
+			if err := s.Add(sb.Tasks()...); err != nil {
+				return fmt.Errorf("%T, %w", bean, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Start running the tasks, it does nothing when the scheduler is disabled or started
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || !s.conf.Enabled {
+		return
+	}
+	s.started = true
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.jobCtx, s.stopJob = context.WithCancel(context.Background())
+	for _, e := range s.entries {
+		s.loop(s.ctx, s.jobCtx, e)
+	}
+	if len(s.entries) > 0 {
+		logger.Log.Debugf("Scheduler started with %d tasks", len(s.entries))
+	}
+}
+
+// wait for the next runs of the task until the scheduler stops
+func (s *Scheduler) loop(ctx, jobCtx context.Context, e *entry) {
+	s.loops.Add(1)
+	go func() {
+		defer s.loops.Done()
+		for {
+			now := time.Now()
+			next := e.schedule.Next(now)
+			if next.IsZero() {
+				return
+			}
+			if e.Jitter > 0 {
+				next = next.Add(time.Duration(rand.Int63n(int64(e.Jitter))))
+			}
+			e.mu.Lock()
+			e.status.NextRun = next
+			e.mu.Unlock()
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				s.run(jobCtx, e)
+			}
+		}
+	}()
+}
+
+// run the job of the task, it's skipped when the previous run is still running and the overlap is not allowed
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	e.mu.Lock()
+	if e.status.Running && !e.AllowOverlap {
+		e.mu.Unlock()
+		logger.Log.Warnf("Scheduled task %s is skipped, the previous run is still running", e.Name)
+		return
+	}
+	e.status.Running = true
+	e.status.LastRun = time.Now()
+	e.status.Runs++
+	e.mu.Unlock()
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		err := call(ctx, e)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.status.Running = false
+		e.status.LastErr = ""
+		if err != nil {
+			e.status.Failures++
+			e.status.LastErr = err.Error()
+			logger.Log.Errorf("Scheduled task %s failed, %s", e.Name, err.Error())
+		}
+	}()
+}
+
+// call the job, the panic is returned as the error
+func call(ctx context.Context, e *entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Errorf("Scheduled task %s panic, %v\n%s", e.Name, r, debug.Stack())
+			err = fmt.Errorf("panic, %v", r)
+		}
+	}()
+	return e.Job(ctx)
+}
+
+// Status Get the status of the tasks, in order of the registration
+func (s *Scheduler) Status() []TaskStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := make([]TaskStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		e.mu.Lock()
+		status = append(status, e.status)
+		e.mu.Unlock()
+	}
+	return status
+}
+
+// Stop scheduling the tasks and wait for the running jobs until the context is done,
+// then the context of the jobs still running is canceled
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	s.started = false
+	s.cancel()
+	stopJob := s.stopJob
+	s.mu.Unlock()
+	defer stopJob()
+	s.loops.Wait()
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("scheduled tasks are still running")
+	}
+}
@@ -0,0 +1,422 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter operators
+const (
+	OpEq = "eq"
+	OpNe = "ne"
+	OpCo = "co"
+	OpSw = "sw"
+	OpEw = "ew"
+	OpGt = "gt"
+	OpGe = "ge"
+	OpLt = "lt"
+	OpLe = "le"
+	OpPr = "pr"
+)
+
+// Filter the parsed filter expression, see RFC 7644 3.4.2.2
+type Filter interface {
+	// Match Whether the resource matches the filter, the attribute names and the string values are case-insensitive
+	Match(r Resource) bool
+}
+
+// AttrExpr the attribute expression, such as userName eq "bjensen"
+type AttrExpr struct {
+	Path  string // Attribute path, such as name.familyName
+	Op    string // Operator, such as eq
+	Value any    // Compared value, string, float64, bool or nil. Nil for the pr operator
+}
+
+// LogicalExpr the logical expression, and or or
+type LogicalExpr struct {
+	Op          string // and or or
+	Left, Right Filter
+}
+
+// NotExpr the negated expression
+type NotExpr struct {
+	Filter Filter
+}
+
+// ValuePathExpr the filter of the multi-valued attribute, such as emails[type eq "work"]
+type ValuePathExpr struct {
+	Path   string
+	Filter Filter
+}
+
+// ParseFilter Parse the filter expression
+func ParseFilter(expr string) (Filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, invalidFilter("unexpected %q", p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+func invalidFilter(format string, args ...any) *Error {
+	return &Error{Status: 400, ScimType: "invalidFilter", Detail: fmt.Sprintf(format, args...)}
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{tokenLBracket, "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{tokenRBracket, "]"})
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(expr) && expr[j] != '"'; j++ {
+				if expr[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(expr) {
+				return nil, invalidFilter("unterminated string")
+			}
+			var s string
+			if err := json.Unmarshal([]byte(expr[i:j+1]), &s); err != nil {
+				return nil, invalidFilter("invalid string %s", expr[i:j+1])
+			}
+			tokens = append(tokens, token{tokenString, s})
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(expr) && !strings.ContainsRune(" \t()[]\"", rune(expr[j])); j++ {
+			}
+			tokens = append(tokens, token{tokenWord, expr[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) peek() (token, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return token{}, false
+}
+
+func (p *filterParser) keyword(word string) bool {
+	t, ok := p.peek()
+	if ok && t.kind == tokenWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(kind tokenKind, text string) error {
+	t, ok := p.peek()
+	if !ok || t.kind != kind {
+		return invalidFilter("%q is expected", text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	if p.keyword("not") {
+		if err := p.expect(tokenLParen, "("); err != nil {
+			return nil, err
+		}
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return &NotExpr{Filter: f}, nil
+	}
+	t, ok := p.peek()
+	if !ok {
+		return nil, invalidFilter("unexpected end of the filter")
+	}
+	if t.kind == tokenLParen {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if t.kind != tokenWord {
+		return nil, invalidFilter("attribute path is expected, got %q", t.text)
+	}
+	p.pos++
+	path := t.text
+	if next, ok := p.peek(); ok && next.kind == tokenLBracket {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(tokenRBracket, "]"); err != nil {
+			return nil, err
+		}
+		return &ValuePathExpr{Path: path, Filter: f}, nil
+	}
+	opToken, ok := p.peek()
+	if !ok || opToken.kind != tokenWord {
+		return nil, invalidFilter("operator is expected after %s", path)
+	}
+	p.pos++
+	op := strings.ToLower(opToken.text)
+	switch op {
+	case OpPr:
+		return &AttrExpr{Path: path, Op: op}, nil
+	case OpEq, OpNe, OpCo, OpSw, OpEw, OpGt, OpGe, OpLt, OpLe:
+	default:
+		return nil, invalidFilter("unsupported operator %s", opToken.text)
+	}
+	vt, ok := p.peek()
+	if !ok {
+		return nil, invalidFilter("value is expected after %s %s", path, op)
+	}
+	p.pos++
+	if vt.kind == tokenString {
+		return &AttrExpr{Path: path, Op: op, Value: vt.text}, nil
+	}
+	if vt.kind != tokenWord {
+		return nil, invalidFilter("value is expected after %s %s", path, op)
+	}
+	switch strings.ToLower(vt.text) {
+	case "true":
+		return &AttrExpr{Path: path, Op: op, Value: true}, nil
+	case "false":
+		return &AttrExpr{Path: path, Op: op, Value: false}, nil
+	case "null":
+		return &AttrExpr{Path: path, Op: op, Value: nil}, nil
+	}
+	n, err := strconv.ParseFloat(vt.text, 64)
+	if err != nil {
+		return nil, invalidFilter("invalid value %s", vt.text)
+	}
+	return &AttrExpr{Path: path, Op: op, Value: n}, nil
+}
+
+func (e *LogicalExpr) Match(r Resource) bool {
+	if e.Op == "and" {
+		return e.Left.Match(r) && e.Right.Match(r)
+	}
+	return e.Left.Match(r) || e.Right.Match(r)
+}
+
+func (e *NotExpr) Match(r Resource) bool {
+	return !e.Filter.Match(r)
+}
+
+func (e *ValuePathExpr) Match(r Resource) bool {
+	for _, v := range r.values(e.Path) {
+		if sub, ok := v.(map[string]any); ok && e.Filter.Match(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *AttrExpr) Match(r Resource) bool {
+	values := r.values(e.Path)
+	if e.Op == OpPr {
+		for _, v := range values {
+			if present(v) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(values) == 0 {
+		// the missing attribute only equals null
+		return (e.Op == OpEq && e.Value == nil) || (e.Op == OpNe && e.Value != nil)
+	}
+	for _, v := range values {
+		// the multi-valued complex attribute is compared by its value sub-attribute
+		if m, ok := v.(map[string]any); ok {
+			v = Resource(m).lookup("value")
+		}
+		if compare(v, e.Op, e.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func present(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case string:
+		return t != ""
+	case []any:
+		return len(t) > 0
+	case map[string]any:
+		return len(t) > 0
+	}
+	return true
+}
+
+func compare(actual any, op string, expected any) bool {
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return op == OpNe
+		}
+		if at, err := time.Parse(time.RFC3339, a); err == nil {
+			if et, err := time.Parse(time.RFC3339, e); err == nil {
+				return order(at.Compare(et), op)
+			}
+		}
+		a, e = strings.ToLower(a), strings.ToLower(e)
+		switch op {
+		case OpCo:
+			return strings.Contains(a, e)
+		case OpSw:
+			return strings.HasPrefix(a, e)
+		case OpEw:
+			return strings.HasSuffix(a, e)
+		}
+		return order(strings.Compare(a, e), op)
+	case float64, int, int64:
+		e, ok := expected.(float64)
+		if !ok {
+			return op == OpNe
+		}
+		f := toFloat(a)
+		switch {
+		case f < e:
+			return order(-1, op)
+		case f > e:
+			return order(1, op)
+		}
+		return order(0, op)
+	case bool:
+		e, ok := expected.(bool)
+		if !ok {
+			return op == OpNe
+		}
+		switch op {
+		case OpEq:
+			return a == e
+		case OpNe:
+			return a != e
+		}
+	case nil:
+		return (op == OpEq && expected == nil) || (op == OpNe && expected != nil)
+	}
+	return false
+}
+
+func order(c int, op string) bool {
+	switch op {
+	case OpEq:
+		return c == 0
+	case OpNe:
+		return c != 0
+	case OpGt:
+		return c > 0
+	case OpGe:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLe:
+		return c <= 0
+	}
+	return false
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore the in-memory store, userName of the users and displayName of the groups are unique.
+// It is suitable for the development and the tests
+type MemoryStore struct {
+	mu        sync.RWMutex
+	resources map[string]map[string]Resource
+	order     map[string][]string
+}
+
+// NewMemoryStore Create the in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{resources: map[string]map[string]Resource{}, order: map[string][]string{}}
+}
+
+func (s *MemoryStore) List(_ context.Context, resourceType string, q *Query) ([]Resource, int, error) {
+	s.mu.RLock()
+	var matched []Resource
+	for _, id := range s.order[resourceType] {
+		r := s.resources[resourceType][id]
+		if q.Filter == nil || q.Filter.Match(r) {
+			matched = append(matched, clone(r))
+		}
+	}
+	s.mu.RUnlock()
+	if q.SortBy != "" {
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := sortKey(matched[i], q.SortBy), sortKey(matched[j], q.SortBy)
+			if q.Descending {
+				return a > b
+			}
+			return a < b
+		})
+	}
+	total := len(matched)
+	start := min(q.StartIndex-1, total)
+	end := min(start+q.Count, total)
+	return matched[start:end], total, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, resourceType, id string) (Resource, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.resources[resourceType][id]
+	if !ok {
+		return nil, NotFound(resourceType, id)
+	}
+	return clone(r), nil
+}
+
+func (s *MemoryStore) Create(_ context.Context, resourceType string, r Resource) (Resource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkUnique(resourceType, "", r); err != nil {
+		return nil, err
+	}
+	r = clone(r)
+	id := newId()
+	r["id"] = id
+	if s.resources[resourceType] == nil {
+		s.resources[resourceType] = map[string]Resource{}
+	}
+	s.resources[resourceType][id] = r
+	s.order[resourceType] = append(s.order[resourceType], id)
+	return clone(r), nil
+}
+
+func (s *MemoryStore) Replace(_ context.Context, resourceType, id string, r Resource) (Resource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.resources[resourceType][id]
+	if !ok {
+		return nil, NotFound(resourceType, id)
+	}
+	if err := s.checkUnique(resourceType, id, r); err != nil {
+		return nil, err
+	}
+	r = clone(r)
+	r["id"] = id
+	// the creation time is kept
+	if created, ok := existing.Get("meta.created").(string); ok {
+		if meta, ok := r.lookup("meta").(map[string]any); ok {
+			meta["created"] = created
+		}
+	}
+	s.resources[resourceType][id] = r
+	return clone(r), nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, resourceType, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.resources[resourceType][id]; !ok {
+		return NotFound(resourceType, id)
+	}
+	delete(s.resources[resourceType], id)
+	ids := s.order[resourceType]
+	for i, v := range ids {
+		if v == id {
+			s.order[resourceType] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) checkUnique(resourceType, id string, r Resource) error {
+	attr := "userName"
+	if resourceType == GroupResource {
+		attr = "displayName"
+	}
+	value, _ := r.lookup(attr).(string)
+	if value == "" {
+		return &Error{Status: 400, ScimType: "invalidValue", Detail: attr + " is required"}
+	}
+	for otherId, other := range s.resources[resourceType] {
+		if v, _ := other.lookup(attr).(string); otherId != id && strings.EqualFold(v, value) {
+			return Conflict(fmt.Sprintf("%s %s already exists", attr, value))
+		}
+	}
+	return nil
+}
+
+func sortKey(r Resource, path string) string {
+	values := r.values(path)
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.ToLower(fmt.Sprint(values[0]))
+}
+
+// clone the resource deeply, so the callers can't modify the stored one
+func clone(r Resource) Resource {
+	b, _ := json.Marshal(r)
+	var c Resource
+	_ = json.Unmarshal(b, &c)
+	return c
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,253 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PatchOperation the operation of the patch request, see RFC 7644 3.5.2
+type PatchOperation struct {
+	Op    string `json:"op"`             // add, replace or remove, case-insensitive
+	Path  string `json:"path,omitempty"` // Attribute path, such as emails[type eq "work"].value
+	Value any    `json:"value,omitempty"`
+}
+
+// PatchRequest the patch request body
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// patch target, such as attr[filter].sub
+type patchPath struct {
+	attr   string
+	filter Filter
+	sub    string
+}
+
+func invalidPatch(scimType, format string, args ...any) *Error {
+	return &Error{Status: http.StatusBadRequest, ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+func parsePatchPath(path string) (*patchPath, error) {
+	i := strings.IndexByte(path, '[')
+	if i < 0 {
+		return &patchPath{attr: path}, nil
+	}
+	j := strings.LastIndexByte(path, ']')
+	if i == 0 || j < i {
+		return nil, invalidPatch("invalidPath", "invalid path %s", path)
+	}
+	f, err := ParseFilter(path[i+1 : j])
+	if err != nil {
+		return nil, invalidPatch("invalidPath", "invalid path %s, %s", path, err.(*Error).Detail)
+	}
+	p := &patchPath{attr: path[:i], filter: f}
+	if rest := path[j+1:]; rest != "" {
+		if !strings.HasPrefix(rest, ".") || len(rest) == 1 {
+			return nil, invalidPatch("invalidPath", "invalid path %s", path)
+		}
+		p.sub = rest[1:]
+	}
+	return p, nil
+}
+
+// ApplyPatch Apply the patch operations to the resource in order, the resource is modified in place.
+// The returned error is *Error when the operations are invalid
+func ApplyPatch(r Resource, ops []PatchOperation) error {
+	for _, op := range ops {
+		var err error
+		switch strings.ToLower(op.Op) {
+		case "add":
+			err = patchAdd(r, op)
+		case "replace":
+			err = patchReplace(r, op)
+		case "remove":
+			err = patchRemove(r, op)
+		default:
+			err = invalidPatch("invalidSyntax", "unsupported patch operation %s", op.Op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func patchAdd(r Resource, op PatchOperation) error {
+	if op.Path == "" {
+		values, ok := op.Value.(map[string]any)
+		if !ok {
+			return invalidPatch("invalidValue", "value of the add operation without path must be an object")
+		}
+		for k, v := range values {
+			if mergeExtension(r, k, v) {
+				continue
+			}
+			if err := patchAdd(r, PatchOperation{Path: k, Value: v}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	p, err := parsePatchPath(op.Path)
+	if err != nil {
+		return err
+	}
+	m, name := r.container(p.attr, true)
+	if m == nil {
+		return invalidPatch("invalidPath", "invalid path %s", op.Path)
+	}
+	if p.filter != nil {
+		return patchMatched(m, name, p, op, func(elem map[string]any) {
+			if p.sub != "" {
+				elem[Resource(elem).key(p.sub)] = op.Value
+			} else if values, ok := op.Value.(map[string]any); ok {
+				merge(elem, values)
+			}
+		})
+	}
+	key := m.key(name)
+	switch existing := m[key].(type) {
+	case []any:
+		if values, ok := op.Value.([]any); ok {
+			m[key] = append(existing, values...)
+		} else {
+			m[key] = append(existing, op.Value)
+		}
+	case map[string]any:
+		if values, ok := op.Value.(map[string]any); ok {
+			merge(existing, values)
+		} else {
+			m[key] = op.Value
+		}
+	default:
+		m[key] = op.Value
+	}
+	return nil
+}
+
+func patchReplace(r Resource, op PatchOperation) error {
+	if op.Path == "" {
+		values, ok := op.Value.(map[string]any)
+		if !ok {
+			return invalidPatch("invalidValue", "value of the replace operation without path must be an object")
+		}
+		for k, v := range values {
+			if mergeExtension(r, k, v) {
+				continue
+			}
+			if err := patchReplace(r, PatchOperation{Path: k, Value: v}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	p, err := parsePatchPath(op.Path)
+	if err != nil {
+		return err
+	}
+	m, name := r.container(p.attr, true)
+	if m == nil {
+		return invalidPatch("invalidPath", "invalid path %s", op.Path)
+	}
+	if p.filter != nil {
+		return patchMatched(m, name, p, op, func(elem map[string]any) {
+			if p.sub != "" {
+				elem[Resource(elem).key(p.sub)] = op.Value
+			} else if values, ok := op.Value.(map[string]any); ok {
+				for k := range elem {
+					delete(elem, k)
+				}
+				merge(elem, values)
+			}
+		})
+	}
+	key := m.key(name)
+	if existing, ok := m[key].(map[string]any); ok {
+		if values, ok := op.Value.(map[string]any); ok {
+			merge(existing, values)
+			return nil
+		}
+	}
+	m[key] = op.Value
+	return nil
+}
+
+func patchRemove(r Resource, op PatchOperation) error {
+	if op.Path == "" {
+		return invalidPatch("noTarget", "path of the remove operation is required")
+	}
+	p, err := parsePatchPath(op.Path)
+	if err != nil {
+		return err
+	}
+	m, name := r.container(p.attr, false)
+	if m == nil {
+		return nil
+	}
+	key := m.key(name)
+	if p.filter == nil {
+		delete(m, key)
+		return nil
+	}
+	elems, _ := m[key].([]any)
+	if p.sub != "" {
+		for _, e := range elems {
+			if elem, ok := e.(map[string]any); ok && p.filter.Match(elem) {
+				delete(elem, Resource(elem).key(p.sub))
+			}
+		}
+		return nil
+	}
+	kept := elems[:0]
+	for _, e := range elems {
+		if elem, ok := e.(map[string]any); !ok || !p.filter.Match(elem) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m, key)
+	} else {
+		m[key] = kept
+	}
+	return nil
+}
+
+// apply the fn to the elements of the multi-valued attribute matched by the filter
+func patchMatched(m Resource, name string, p *patchPath, op PatchOperation, fn func(elem map[string]any)) error {
+	elems, _ := m.lookup(name).([]any)
+	matched := false
+	for _, e := range elems {
+		if elem, ok := e.(map[string]any); ok && p.filter.Match(elem) {
+			fn(elem)
+			matched = true
+		}
+	}
+	if !matched {
+		return invalidPatch("noTarget", "no value matches the path %s", op.Path)
+	}
+	return nil
+}
+
+// merge the extension object of the operation without path, such as {"urn:...:enterprise:2.0:User": {...}}
+func mergeExtension(r Resource, k string, v any) bool {
+	values, ok := v.(map[string]any)
+	if !ok || !strings.HasPrefix(strings.ToLower(k), "urn:") {
+		return false
+	}
+	key := r.key(k)
+	if ext, ok := r[key].(map[string]any); ok {
+		merge(ext, values)
+	} else {
+		r[key] = values
+	}
+	return true
+}
+
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		dst[Resource(dst).key(k)] = v
+	}
+}
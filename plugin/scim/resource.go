@@ -0,0 +1,179 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Schema URNs
+const (
+	UserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	EnterpriseSchema   = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	ListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// Resource types
+const (
+	UserResource  = "User"
+	GroupResource = "Group"
+)
+
+// Resource the scim resource in json form, such as the user and the group.
+// The core attributes are top-level members, the extension attributes are nested under the schema urn.
+type Resource map[string]any
+
+// ID Get the id of the resource
+func (r Resource) ID() string {
+	id, _ := r.lookup("id").(string)
+	return id
+}
+
+// Get the value of the attribute path, such as userName, name.givenName or emails
+func (r Resource) Get(path string) any {
+	m, name := r.container(path, false)
+	if m == nil {
+		return nil
+	}
+	return m.lookup(name)
+}
+
+// lookup the member case-insensitively
+func (r Resource) lookup(name string) any {
+	if v, ok := r[name]; ok {
+		return v
+	}
+	for k, v := range r {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// key of the member case-insensitively, the name itself when missing
+func (r Resource) key(name string) string {
+	if _, ok := r[name]; ok {
+		return name
+	}
+	for k := range r {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+// values of the attribute path, the multi-valued attributes are flattened
+func (r Resource) values(path string) []any {
+	r, path = r.resolveSchema(path, false)
+	current := []any{map[string]any(r)}
+	for _, name := range strings.Split(path, ".") {
+		var next []any
+		for _, c := range current {
+			m, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch v := Resource(m).lookup(name).(type) {
+			case nil:
+			case []any:
+				next = append(next, v...)
+			default:
+				next = append(next, v)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// container of the last attribute of the path, it is nil when the parent is missing.
+// The missing parents are created when create is true
+func (r Resource) container(path string, create bool) (Resource, string) {
+	r, path = r.resolveSchema(path, create)
+	if r == nil {
+		return nil, ""
+	}
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		m, ok := r.lookup(name).(map[string]any)
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			m = map[string]any{}
+			r[r.key(name)] = m
+		}
+		r = m
+	}
+	return r, names[len(names)-1]
+}
+
+// resolve the schema urn prefix of the path, such as urn:ietf:params:scim:schemas:core:2.0:User:userName
+func (r Resource) resolveSchema(path string, create bool) (Resource, string) {
+	if !strings.HasPrefix(strings.ToLower(path), "urn:") {
+		return r, path
+	}
+	for k, v := range r {
+		if len(path) > len(k) && strings.EqualFold(path[:len(k)], k) && path[len(k)] == ':' {
+			ext, ok := v.(map[string]any)
+			if !ok && create {
+				ext = map[string]any{}
+				r[k] = ext
+			}
+			return ext, path[len(k)+1:]
+		}
+	}
+	for _, schema := range []string{UserSchema, GroupSchema} {
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)], schema) && path[len(schema)] == ':' {
+			return r, path[len(schema)+1:]
+		}
+	}
+	// the extension schema missing in the resource
+	if i := strings.LastIndex(path, ":"); i > 0 {
+		if !create {
+			return nil, path[i+1:]
+		}
+		ext := map[string]any{}
+		r[path[:i]] = ext
+		return ext, path[i+1:]
+	}
+	return r, path
+}
+
+// ListResponse the response of the query
+type ListResponse struct {
+	Schemas      []string   `json:"schemas"`
+	TotalResults int        `json:"totalResults"`
+	StartIndex   int        `json:"startIndex"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []Resource `json:"Resources"`
+}
+
+// Error the scim error, the stores can return it to respond the specific status and scim type
+type Error struct {
+	Status   int    // Http status
+	ScimType string // Scim error type, such as uniqueness, invalidFilter, invalidPath, noTarget, invalidValue, mutability
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	if e.ScimType != "" {
+		return fmt.Sprintf("scim: %s, %s", e.ScimType, e.Detail)
+	}
+	return "scim: " + e.Detail
+}
+
+// NotFound Create the error of the missing resource
+func NotFound(resourceType, id string) *Error {
+	return &Error{Status: http.StatusNotFound, Detail: fmt.Sprintf("%s %s not found", resourceType, id)}
+}
+
+// Conflict Create the error of the uniqueness violation
+func Conflict(detail string) *Error {
+	return &Error{Status: http.StatusConflict, ScimType: "uniqueness", Detail: detail}
+}
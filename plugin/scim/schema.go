@@ -0,0 +1,100 @@
+package scim
+
+// resource types served by the /ResourceTypes endpoint
+var resourceTypes = []Resource{
+	{
+		"schemas":     []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+		"id":          UserResource,
+		"name":        UserResource,
+		"endpoint":    "/Users",
+		"description": "User Account",
+		"schema":      UserSchema,
+		"schemaExtensions": []map[string]any{
+			{"schema": EnterpriseSchema, "required": false},
+		},
+	},
+	{
+		"schemas":     []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+		"id":          GroupResource,
+		"name":        GroupResource,
+		"endpoint":    "/Groups",
+		"description": "Group",
+		"schema":      GroupSchema,
+	},
+}
+
+// schemas served by the /Schemas endpoint, the common attributes are described
+var schemas = []Resource{
+	{
+		"id":          UserSchema,
+		"name":        "User",
+		"description": "User Account",
+		"attributes": []map[string]any{
+			attribute("userName", "string", false, true, "server"),
+			attribute("externalId", "string", false, false, "none"),
+			complexAttribute("name", false,
+				attribute("formatted", "string", false, false, "none"),
+				attribute("familyName", "string", false, false, "none"),
+				attribute("givenName", "string", false, false, "none"),
+			),
+			attribute("displayName", "string", false, false, "none"),
+			attribute("active", "boolean", false, false, "none"),
+			complexAttribute("emails", true,
+				attribute("value", "string", false, false, "none"),
+				attribute("type", "string", false, false, "none"),
+				attribute("primary", "boolean", false, false, "none"),
+			),
+			complexAttribute("groups", true,
+				attribute("value", "string", false, false, "none"),
+				attribute("display", "string", false, false, "none"),
+			),
+		},
+	},
+	{
+		"id":          GroupSchema,
+		"name":        "Group",
+		"description": "Group",
+		"attributes": []map[string]any{
+			attribute("displayName", "string", false, true, "none"),
+			attribute("externalId", "string", false, false, "none"),
+			complexAttribute("members", true,
+				attribute("value", "string", false, false, "none"),
+				attribute("display", "string", false, false, "none"),
+				attribute("type", "string", false, false, "none"),
+			),
+		},
+	},
+	{
+		"id":          EnterpriseSchema,
+		"name":        "EnterpriseUser",
+		"description": "Enterprise User",
+		"attributes": []map[string]any{
+			attribute("employeeNumber", "string", false, false, "none"),
+			attribute("department", "string", false, false, "none"),
+			attribute("organization", "string", false, false, "none"),
+			complexAttribute("manager", false,
+				attribute("value", "string", false, false, "none"),
+				attribute("displayName", "string", false, false, "none"),
+			),
+		},
+	},
+}
+
+func attribute(name, typ string, multiValued, required bool, uniqueness string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"type":        typ,
+		"multiValued": multiValued,
+		"required":    required,
+		"caseExact":   false,
+		"mutability":  "readWrite",
+		"returned":    "default",
+		"uniqueness":  uniqueness,
+	}
+}
+
+func complexAttribute(name string, multiValued bool, subAttributes ...map[string]any) map[string]any {
+	a := attribute(name, "complex", multiValued, false, "none")
+	a["subAttributes"] = subAttributes
+	return a
+}
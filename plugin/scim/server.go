@@ -0,0 +1,368 @@
+package scim
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType of the scim requests and responses
+const ContentType = "application/scim+json"
+
+// Config the scim server configuration
+type Config struct {
+	BasePath   string `mapstructure:"base_path"`   // Base path of the endpoints, default /scim/v2
+	Token      string `mapstructure:"token"`       // Bearer token of the provisioning client, the authentication middlewares are required when empty
+	MaxResults int    `mapstructure:"max_results"` // Max resources of a query, default 100
+}
+
+// Query the query of the resources, see RFC 7644 3.4.2
+type Query struct {
+	Filter     Filter // Nil when the request has no filter
+	StartIndex int    // 1-based index of the first resource
+	Count      int    // Max resources to return, 0 means only the total is wanted
+	SortBy     string // Attribute path to sort by, empty means unsorted
+	Descending bool
+}
+
+/*
+Store the storage hooks of the scim resources, the resource type is User or Group.
+The missing resource is reported by NotFound(), the uniqueness violation is reported by Conflict().
+
+The store can implement Patcher to apply the patch natively, otherwise the resource is patched in memory and replaced.
+*/
+type Store interface {
+	// List the resources matched by the query, returns the page and the total
+	List(ctx context.Context, resourceType string, q *Query) ([]Resource, int, error)
+
+	// Get the resource by id
+	Get(ctx context.Context, resourceType, id string) (Resource, error)
+
+	// Create the resource and assign the id
+	Create(ctx context.Context, resourceType string, r Resource) (Resource, error)
+
+	// Replace the resource by id
+	Replace(ctx context.Context, resourceType, id string, r Resource) (Resource, error)
+
+	// Delete the resource by id
+	Delete(ctx context.Context, resourceType, id string) error
+}
+
+// Patcher the store which applies the patch operations natively
+type Patcher interface {
+	Patch(ctx context.Context, resourceType, id string, ops []PatchOperation) (Resource, error)
+}
+
+type server struct {
+	conf  Config
+	store Store
+}
+
+// Mount the scim endpoints to the router, the provisioning client is authenticated by the bearer token of the
+// configuration or the middlewares. The endpoints are never served unauthenticated, the error is returned without both
+func Mount(router gin.IRouter, conf Config, store Store, auth ...gin.HandlerFunc) error {
+	if conf.Token == "" && len(auth) == 0 {
+		return errors.New("scim: neither the token nor the authentication middleware is set")
+	}
+	if conf.BasePath == "" {
+		conf.BasePath = "/scim/v2"
+	}
+	if conf.MaxResults <= 0 {
+		conf.MaxResults = 100
+	}
+	s := &server{conf: conf, store: store}
+	g := router.Group(conf.BasePath, auth...)
+	if conf.Token != "" {
+		g.Use(s.authenticate)
+	}
+	g.GET("/ServiceProviderConfig", s.serviceProviderConfig)
+	g.GET("/ResourceTypes", func(ctx *gin.Context) {
+		write(ctx, http.StatusOK, ListResponse{
+			Schemas:      []string{ListResponseSchema},
+			TotalResults: len(resourceTypes),
+			StartIndex:   1,
+			ItemsPerPage: len(resourceTypes),
+			Resources:    resourceTypes,
+		})
+	})
+	g.GET("/Schemas", func(ctx *gin.Context) {
+		write(ctx, http.StatusOK, ListResponse{
+			Schemas:      []string{ListResponseSchema},
+			TotalResults: len(schemas),
+			StartIndex:   1,
+			ItemsPerPage: len(schemas),
+			Resources:    schemas,
+		})
+	})
+	for endpoint, resourceType := range map[string]string{"/Users": UserResource, "/Groups": GroupResource} {
+		g.GET(endpoint, s.list(resourceType, endpoint))
+		g.POST(endpoint, s.create(resourceType, endpoint))
+		g.GET(endpoint+"/:id", s.get(resourceType, endpoint))
+		g.PUT(endpoint+"/:id", s.replace(resourceType, endpoint))
+		g.PATCH(endpoint+"/:id", s.patch(resourceType, endpoint))
+		g.DELETE(endpoint+"/:id", s.delete(resourceType))
+	}
+	return nil
+}
+
+func (s *server) authenticate(ctx *gin.Context) {
+	// the scheme is case-insensitive, the token without it is rejected
+	scheme, token, ok := strings.Cut(ctx.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(token), []byte(s.conf.Token)) != 1 {
+		writeError(ctx, &Error{Status: http.StatusUnauthorized, Detail: "authorization failure"})
+		ctx.Abort()
+		return
+	}
+	ctx.Next()
+}
+
+func (s *server) list(resourceType, endpoint string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		q := &Query{StartIndex: 1, Count: s.conf.MaxResults, SortBy: ctx.Query("sortBy"), Descending: ctx.Query("sortOrder") == "descending"}
+		if expr := ctx.Query("filter"); expr != "" {
+			f, err := ParseFilter(expr)
+			if err != nil {
+				writeError(ctx, err)
+				return
+			}
+			q.Filter = f
+		}
+		if v, err := strconv.Atoi(ctx.Query("startIndex")); err == nil && v > 1 {
+			q.StartIndex = v
+		}
+		if v, err := strconv.Atoi(ctx.Query("count")); err == nil && v < q.Count {
+			q.Count = max(v, 0)
+		}
+		resources, total, err := s.store.List(ctx.Request.Context(), resourceType, q)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		for i, r := range resources {
+			setMeta(r, resourceType, map[string]any{"location": s.location(ctx, endpoint, r.ID())})
+			resources[i] = project(ctx, r)
+		}
+		if resources == nil {
+			resources = []Resource{}
+		}
+		write(ctx, http.StatusOK, ListResponse{
+			Schemas:      []string{ListResponseSchema},
+			TotalResults: total,
+			StartIndex:   q.StartIndex,
+			ItemsPerPage: len(resources),
+			Resources:    resources,
+		})
+	}
+}
+
+func (s *server) get(resourceType, endpoint string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		r, err := s.store.Get(ctx.Request.Context(), resourceType, ctx.Param("id"))
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		setMeta(r, resourceType, map[string]any{"location": s.location(ctx, endpoint, r.ID())})
+		write(ctx, http.StatusOK, project(ctx, r))
+	}
+}
+
+func (s *server) create(resourceType, endpoint string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		r, ok := readResource(ctx)
+		if !ok {
+			return
+		}
+		delete(r, r.key("id"))
+		now := time.Now().UTC().Format(time.RFC3339)
+		setMeta(r, resourceType, map[string]any{"created": now, "lastModified": now})
+		created, err := s.store.Create(ctx.Request.Context(), resourceType, r)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		location := s.location(ctx, endpoint, created.ID())
+		setMeta(created, resourceType, map[string]any{"location": location})
+		ctx.Header("Location", location)
+		write(ctx, http.StatusCreated, project(ctx, created))
+	}
+}
+
+func (s *server) replace(resourceType, endpoint string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		r, ok := readResource(ctx)
+		if !ok {
+			return
+		}
+		s.save(ctx, resourceType, endpoint, r)
+	}
+}
+
+func (s *server) patch(resourceType, endpoint string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req PatchRequest
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+			writeError(ctx, &Error{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: err.Error()})
+			return
+		}
+		if p, ok := s.store.(Patcher); ok {
+			r, err := p.Patch(ctx.Request.Context(), resourceType, ctx.Param("id"), req.Operations)
+			if err != nil {
+				writeError(ctx, err)
+				return
+			}
+			setMeta(r, resourceType, map[string]any{"location": s.location(ctx, endpoint, r.ID())})
+			write(ctx, http.StatusOK, project(ctx, r))
+			return
+		}
+		r, err := s.store.Get(ctx.Request.Context(), resourceType, ctx.Param("id"))
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		if err = ApplyPatch(r, req.Operations); err != nil {
+			writeError(ctx, err)
+			return
+		}
+		s.save(ctx, resourceType, endpoint, r)
+	}
+}
+
+// save the replaced or patched resource, the id and the creation time can't be changed by the client
+func (s *server) save(ctx *gin.Context, resourceType, endpoint string, r Resource) {
+	id := ctx.Param("id")
+	r[r.key("id")] = id
+	setMeta(r, resourceType, map[string]any{"lastModified": time.Now().UTC().Format(time.RFC3339)})
+	saved, err := s.store.Replace(ctx.Request.Context(), resourceType, id, r)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	setMeta(saved, resourceType, map[string]any{"location": s.location(ctx, endpoint, id)})
+	write(ctx, http.StatusOK, project(ctx, saved))
+}
+
+func (s *server) delete(resourceType string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := s.store.Delete(ctx.Request.Context(), resourceType, ctx.Param("id")); err != nil {
+			writeError(ctx, err)
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+func (s *server) serviceProviderConfig(ctx *gin.Context) {
+	write(ctx, http.StatusOK, map[string]any{
+		"schemas":               []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":                 map[string]any{"supported": true},
+		"bulk":                  map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":                map[string]any{"supported": true, "maxResults": s.conf.MaxResults},
+		"changePassword":        map[string]any{"supported": false},
+		"sort":                  map[string]any{"supported": true},
+		"etag":                  map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{{"type": "oauthbearertoken", "name": "OAuth Bearer Token", "description": "Authentication scheme using the OAuth Bearer Token Standard"}},
+	})
+}
+
+// location of the resource, the scheme and host of the request are used
+func (s *server) location(ctx *gin.Context, endpoint, id string) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + ctx.Request.Host + strings.TrimSuffix(s.conf.BasePath, "/") + endpoint + "/" + id
+}
+
+func setMeta(r Resource, resourceType string, values map[string]any) {
+	meta, ok := r.lookup("meta").(map[string]any)
+	if !ok {
+		meta = map[string]any{}
+		r["meta"] = meta
+	}
+	meta["resourceType"] = resourceType
+	merge(meta, values)
+	if r.lookup("schemas") == nil {
+		schema := UserSchema
+		if resourceType == GroupResource {
+			schema = GroupSchema
+		}
+		r["schemas"] = []any{schema}
+	}
+}
+
+// project the resource by the attributes and excludedAttributes parameters, only the top-level attributes are supported
+func project(ctx *gin.Context, r Resource) Resource {
+	if attrs := ctx.Query("attributes"); attrs != "" {
+		projected := Resource{}
+		for _, k := range []string{"id", "schemas", "meta"} {
+			if v := r.lookup(k); v != nil {
+				projected[k] = v
+			}
+		}
+		for _, attr := range strings.Split(attrs, ",") {
+			m, name := r.container(strings.SplitN(strings.TrimSpace(attr), ".", 2)[0], false)
+			if m != nil {
+				if v := m.lookup(name); v != nil {
+					projected[m.key(name)] = v
+				}
+			}
+		}
+		return projected
+	}
+	if attrs := ctx.Query("excludedAttributes"); attrs != "" {
+		for _, attr := range strings.Split(attrs, ",") {
+			if m, name := r.container(strings.TrimSpace(attr), false); m != nil {
+				delete(m, m.key(name))
+			}
+		}
+	}
+	return r
+}
+
+func readResource(ctx *gin.Context) (Resource, bool) {
+	var r Resource
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&r); err != nil || r == nil {
+		detail := "request body must be an object"
+		if err != nil {
+			detail = err.Error()
+		}
+		writeError(ctx, &Error{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: detail})
+		return nil, false
+	}
+	return r, true
+}
+
+func write(ctx *gin.Context, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Data(status, ContentType, b)
+}
+
+// write the scim error, the errors other than *Error are responded as 500
+func writeError(ctx *gin.Context, err error) {
+	var se *Error
+	if !errors.As(err, &se) {
+		logger.Log.Errorf("scim: %s %s error, %s", ctx.Request.Method, ctx.Request.URL.Path, err.Error())
+		se = &Error{Status: http.StatusInternalServerError, Detail: "internal server error"}
+	}
+	body := map[string]any{"schemas": []string{ErrorSchema}, "status": strconv.Itoa(se.Status), "detail": se.Detail}
+	if se.ScimType != "" {
+		body["scimType"] = se.ScimType
+	}
+	b, _ := json.Marshal(body)
+	ctx.Data(se.Status, ContentType, b)
+}
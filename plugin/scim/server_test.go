@@ -0,0 +1,73 @@
+package scim
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMountAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := Mount(gin.New(), Config{}, NewMemoryStore()); err == nil {
+		t.Error("Mount without the token and the middlewares error = nil, want refused")
+	}
+	e := gin.New()
+	if err := Mount(e, Config{Token: "provisioning"}, NewMemoryStore()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{name: "valid token", authorization: "Bearer provisioning", want: http.StatusOK},
+		{name: "wrong token", authorization: "Bearer other", want: http.StatusUnauthorized},
+		{name: "token without the scheme", authorization: "provisioning", want: http.StatusUnauthorized},
+		{name: "no token", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	user := Resource{"userName": "bjensen", "active": true, "emails": []any{map[string]any{"type": "work", "value": "bjensen@example.com"}}}
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: `userName eq "bjensen"`, want: true},
+		{expr: `USERNAME eq "BJENSEN"`, want: true},
+		{expr: `userName sw "bj" and active eq true`, want: true},
+		{expr: `userName eq "other" or not (active eq false)`, want: true},
+		{expr: `emails[type eq "work" and value co "@example.com"]`, want: true},
+		{expr: `emails[type eq "home"]`, want: false},
+		{expr: `title pr`, want: false},
+		{expr: `userName eq`, wantErr: true},
+		{expr: `userName eq "bjensen" and`, wantErr: true},
+		{expr: `(userName eq "bjensen"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && f.Match(user) != tt.want {
+				t.Errorf("Match = %v, want %v", !tt.want, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Signer signs the outbound requests by the same scheme as the Middleware
+type Signer struct {
+	KeyId  string // Key id, sent when not empty
+	Secret string // Secret of the key
+	Scheme Scheme // Headers of the signature, the defaults are the same as the Middleware
+}
+
+// Sign the request, the body is still readable by the transport
+func (s *Signer) Sign(req *http.Request) error {
+	scheme := s.Scheme.withDefaults()
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(b)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(scheme.TimestampHeader, timestamp)
+	req.Header.Set(scheme.NonceHeader, nonce)
+	if s.KeyId != "" {
+		req.Header.Set(scheme.KeyIdHeader, s.KeyId)
+	}
+	req.Header.Set(scheme.SignatureHeader, scheme.Compute(s.Secret, timestamp, nonce, req.Method, req.URL.RequestURI(), body))
+	return nil
+}
+
+/*
+Transport Sign the requests of the http client, such as calling the partner apis:
+
+	client := &http.Client{Transport: signature.Transport(httpclient.Default.Transport, &signature.Signer{KeyId: "shop", Secret: secret})}
+*/
+func Transport(next http.RoundTripper, signer *Signer) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next, signer: signer}
+}
+
+type transport struct {
+	next   http.RoundTripper
+	signer *Signer
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the request must not be modified by the transport
+	req = req.Clone(req.Context())
+	if err := t.signer.Sign(req); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
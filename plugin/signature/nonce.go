@@ -0,0 +1,95 @@
+package signature
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+var (
+	Store NonceStore = NewMemoryStore() // Store the storage of the used nonces, memory storage as default
+)
+
+// NonceStore the storage of the used nonces, rejecting the replayed requests
+type NonceStore interface {
+	// Use the nonce, false means it's already used within the ttl
+	Use(nonce string, ttl time.Duration) (bool, error)
+}
+
+// StoreConfig the storage of the nonces
+type StoreConfig struct {
+	Type     string `mapstructure:"type"`     // memory or redis, default memory. Redis shares the nonces between the instances
+	Addr     string `mapstructure:"addr"`     // Redis address, default the client of the redis configuration, or 127.0.0.1:6379 without it
+	Username string `mapstructure:"username"` // Redis username
+	Password string `mapstructure:"password"` // Redis password
+	DB       int    `mapstructure:"db"`       // Redis database
+	Prefix   string `mapstructure:"prefix"`   // Prefix of the keys, default gin-plus:nonce:
+}
+
+// NewStore Create the nonce storage from the configuration
+func NewStore(conf StoreConfig) NonceStore {
+	if conf.Type != "redis" {
+		return NewMemoryStore()
+	}
+	if conf.Addr == "" {
+		conf.Addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: conf.Addr, Username: conf.Username, Password: conf.Password, DB: conf.DB})
+	return NewRedisStore(client, conf.Prefix)
+}
+
+// NewRedisStore Create the redis nonce storage, the client is closed with the storage
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "gin-plus:nonce:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// MemoryStore in-process nonce storage, expired nonces are removed periodically
+type MemoryStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore Create a memory nonce storage
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nonces: make(map[string]time.Time), lastSweep: time.Now()}
+}
+
+func (m *MemoryStore) Use(nonce string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.lastSweep) > time.Minute {
+		for n, expire := range m.nonces {
+			if now.After(expire) {
+				delete(m.nonces, n)
+			}
+		}
+		m.lastSweep = now
+	}
+	if expire, ok := m.nonces[nonce]; ok && now.Before(expire) {
+		return false, nil
+	}
+	m.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// RedisStore the nonce storage shared by the instances, the nonce is used by SET NX
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func (r *RedisStore) Use(nonce string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return r.client.SetNX(ctx, r.prefix+nonce, 1, ttl).Result()
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,226 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"hash"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+SignedAnnotation Declares the api method requires the signed request, such as the webhook receivers
+
+	// ReceivePayment
+	// @POST(path="/webhook/payment") receive payment notification
+	// @Signed
+	func (w *WebhookController) ReceivePayment(ctx *gin.Context) {
+	    partner := ctx.GetString(signature.KeyIdKey)
+	}
+*/
+const (
+	SignedAnnotation = "Signed"
+	KeyIdKey         = "signature_key_id" // Key of the key id of the verified request in the gin context
+)
+
+var (
+	ErrNoSignature = errors.New("signature: missing signature")
+	ErrUnknownKey  = errors.New("signature: unknown key")
+	ErrExpired     = errors.New("signature: timestamp out of the allowed skew")
+	ErrMismatch    = errors.New("signature: signature mismatch")
+	ErrReplayed    = errors.New("signature: nonce is used")
+)
+
+/*
+Scheme how the signature is carried by the headers. The signed payload is:
+
+	{timestamp}\n{nonce}\n{METHOD}\n{request uri}\n{body}
+*/
+type Scheme struct {
+	SignatureHeader string `mapstructure:"signature_header"` // Header of the signature, default X-Signature
+	TimestampHeader string `mapstructure:"timestamp_header"` // Header of the unix seconds, default X-Timestamp
+	NonceHeader     string `mapstructure:"nonce_header"`     // Header of the nonce, default X-Nonce
+	KeyIdHeader     string `mapstructure:"key_id_header"`    // Header of the key id, default X-Key-Id, used when multiple keys are configured
+	Algorithm       string `mapstructure:"algorithm"`        // sha1, sha256 or sha512, default sha256
+	Encoding        string `mapstructure:"encoding"`         // hex or base64, default hex
+	Prefix          string `mapstructure:"prefix"`           // Prefix of the signature value, such as sha256=
+}
+
+// Config the verification of the signed requests
+type Config struct {
+	Enabled     bool              `mapstructure:"enabled"`       // Whether to verify the requests of the paths, default false. @Signed works regardless
+	Paths       []string          `mapstructure:"paths"`         // Ant-style patterns of the route templates, default all routes
+	Secret      string            `mapstructure:"secret"`        // Secret of the requests without the key id
+	Keys        map[string]string `mapstructure:"keys"`          // Secrets of the key ids, such as the partners
+	Scheme      Scheme            `mapstructure:",squash"`       // Headers of the signature
+	MaxSkew     time.Duration     `mapstructure:"max_skew"`      // Max difference between the timestamp and now, default 5m
+	MaxBodySize int64             `mapstructure:"max_body_size"` // Larger bodies are rejected, default 10M
+	Store       StoreConfig       `mapstructure:"store"`         // Storage of the nonces, default memory
+}
+
+// configuration of @Signed
+var defaults = withDefaults(Config{})
+
+func init() {
+	mvc.RegisterOrderedAnnotationHandler(SignedAnnotation, mvc.AuthenticationOrder, func(string) gin.HandlerFunc {
+		conf := defaults
+		conf.Paths = nil
+		return Middleware(conf)
+	})
+}
+
+// SetConfig Set the configuration of @Signed
+func SetConfig(conf Config) {
+	defaults = withDefaults(conf)
+}
+
+func withDefaults(conf Config) Config {
+	conf.Scheme = conf.Scheme.withDefaults()
+	if conf.MaxSkew <= 0 {
+		conf.MaxSkew = 5 * time.Minute
+	}
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = 10 << 20
+	}
+	return conf
+}
+
+func (s Scheme) withDefaults() Scheme {
+	if s.SignatureHeader == "" {
+		s.SignatureHeader = "X-Signature"
+	}
+	if s.TimestampHeader == "" {
+		s.TimestampHeader = "X-Timestamp"
+	}
+	if s.NonceHeader == "" {
+		s.NonceHeader = "X-Nonce"
+	}
+	if s.KeyIdHeader == "" {
+		s.KeyIdHeader = "X-Key-Id"
+	}
+	if s.Algorithm == "" {
+		s.Algorithm = "sha256"
+	}
+	if s.Encoding == "" {
+		s.Encoding = "hex"
+	}
+	return s
+}
+
+// Compute the signature value of the payload, including the prefix
+func (s Scheme) Compute(secret, timestamp, nonce, method, requestURI string, body []byte) string {
+	var h func() hash.Hash
+	switch s.Algorithm {
+	case "sha1":
+		h = sha1.New
+	case "sha512":
+		h = sha512.New
+	default:
+		h = sha256.New
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n" + strings.ToUpper(method) + "\n" + requestURI + "\n"))
+	mac.Write(body)
+	if s.Encoding == "base64" {
+		return s.Prefix + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return s.Prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+Middleware The gin middleware verifies the hmac signature of the requests of the paths, the timestamp must be within
+the max skew and each nonce is accepted once within twice the max skew, so the captured requests can't be replayed.
+The failures are responded with http status 401, the key id of the verified request is set to the context.
+*/
+func Middleware(conf Config) gin.HandlerFunc {
+	conf = withDefaults(conf)
+	var paths func(ctx *gin.Context) bool
+	if len(conf.Paths) > 0 {
+		paths = mvc.PathPredicate(conf.Paths...)
+	}
+	return func(ctx *gin.Context) {
+		if _, ok := ctx.Get(KeyIdKey); ok || ctx.FullPath() == "" || (paths != nil && !paths(ctx)) {
+			ctx.Next()
+			return
+		}
+		keyId, err := verify(ctx, conf)
+		if err != nil {
+			logger.Log.Debugf("[signature] %s %s rejected, %s", ctx.Request.Method, ctx.Request.URL.Path, err.Error())
+			msg := "签名无效"
+			switch {
+			case errors.Is(err, ErrExpired):
+				msg = "签名已过期"
+			case errors.Is(err, ErrReplayed):
+				msg = "重复的请求"
+			}
+			resp.StatusFailed(ctx, exception.NewStatusErr(http.StatusUnauthorized, resp.NonLoginCode, msg))
+			ctx.Abort()
+			return
+		}
+		ctx.Set(KeyIdKey, keyId)
+		ctx.Next()
+	}
+}
+
+// verify the signature of the request, the body is still readable by the handlers
+func verify(ctx *gin.Context, conf Config) (string, error) {
+	s := conf.Scheme
+	sig := ctx.GetHeader(s.SignatureHeader)
+	timestamp := ctx.GetHeader(s.TimestampHeader)
+	nonce := ctx.GetHeader(s.NonceHeader)
+	if sig == "" || timestamp == "" || nonce == "" {
+		return "", ErrNoSignature
+	}
+	keyId := ctx.GetHeader(s.KeyIdHeader)
+	secret := conf.Secret
+	if keyId != "" {
+		secret = conf.Keys[keyId]
+	}
+	if secret == "" {
+		return "", ErrUnknownKey
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || math.Abs(time.Since(time.Unix(ts, 0)).Seconds()) > conf.MaxSkew.Seconds() {
+		return "", ErrExpired
+	}
+	var body []byte
+	if ctx.Request.Body != nil && ctx.Request.Body != http.NoBody {
+		body, err = io.ReadAll(io.LimitReader(ctx.Request.Body, conf.MaxBodySize+1))
+		if err != nil {
+			return "", err
+		}
+		if int64(len(body)) > conf.MaxBodySize {
+			return "", errors.New("signature: body too large")
+		}
+		_ = ctx.Request.Body.Close()
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	expected := s.Compute(secret, timestamp, nonce, ctx.Request.Method, ctx.Request.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return "", ErrMismatch
+	}
+	// the nonce is used after the signature is verified, so the forged requests can't burn the nonces
+	fresh, err := Store.Use(keyId+":"+nonce, 2*conf.MaxSkew)
+	if err != nil {
+		return "", err
+	}
+	if !fresh {
+		return "", ErrReplayed
+	}
+	return keyId, nil
+}
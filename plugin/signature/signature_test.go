@@ -0,0 +1,111 @@
+package signature
+
+import (
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger.Log = &logger.DefaultLog{}
+	Store = NewMemoryStore()
+	e := gin.New()
+	e.Use(Middleware(Config{Keys: map[string]string{"shop": "secret"}, MaxSkew: time.Minute}))
+	e.POST("/webhook", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.GetString(KeyIdKey))
+	})
+	return e
+}
+
+// the request signed by the scheme at the time, the nonce and the secret
+func signed(at time.Time, nonce, secret, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhook?id=1", strings.NewReader(body))
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set("X-Key-Id", "shop")
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Nonce", nonce)
+	req.Header.Set("X-Signature", Scheme{}.withDefaults().Compute(secret, timestamp, nonce, req.Method, req.URL.RequestURI(), []byte(body)))
+	return req
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+	}{
+		{name: "signed", req: func() *http.Request { return signed(now, "n1", "secret", `{"paid":true}`) }, want: http.StatusOK},
+		{name: "wrong secret", req: func() *http.Request { return signed(now, "n1", "other", `{"paid":true}`) }, want: http.StatusUnauthorized},
+		{name: "tampered body", req: func() *http.Request {
+			req := signed(now, "n1", "secret", `{"paid":true}`)
+			tampered := signed(now, "n1", "secret", `{"paid":false}`)
+			tampered.Header = req.Header
+			return tampered
+		}, want: http.StatusUnauthorized},
+		{name: "unknown key", req: func() *http.Request {
+			req := signed(now, "n1", "secret", "")
+			req.Header.Set("X-Key-Id", "other")
+			return req
+		}, want: http.StatusUnauthorized},
+		{name: "missing signature", req: func() *http.Request {
+			req := signed(now, "n1", "secret", "")
+			req.Header.Del("X-Signature")
+			return req
+		}, want: http.StatusUnauthorized},
+		{name: "timestamp within the skew", req: func() *http.Request { return signed(now.Add(-50*time.Second), "n1", "secret", "") }, want: http.StatusOK},
+		{name: "timestamp out of the skew", req: func() *http.Request { return signed(now.Add(-2*time.Minute), "n1", "secret", "") }, want: http.StatusUnauthorized},
+		{name: "timestamp in the future", req: func() *http.Request { return signed(now.Add(2*time.Minute), "n1", "secret", "") }, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newEngine().ServeHTTP(w, tt.req())
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	e := newEngine()
+	now := time.Now()
+	serve := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w.Code
+	}
+	// the forged request doesn't burn the nonce
+	if code := serve(signed(now, "n1", "forged", "")); code != http.StatusUnauthorized {
+		t.Fatalf("forged request = %d, want 401", code)
+	}
+	if code := serve(signed(now, "n1", "secret", "")); code != http.StatusOK {
+		t.Fatalf("first request = %d, want 200", code)
+	}
+	if code := serve(signed(now, "n1", "secret", "")); code != http.StatusUnauthorized {
+		t.Errorf("replayed request = %d, want 401", code)
+	}
+	if code := serve(signed(now, "n2", "secret", "")); code != http.StatusOK {
+		t.Errorf("request of another nonce = %d, want 200", code)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	e := newEngine()
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"paid":true}`))
+	if err := (&Signer{KeyId: "shop", Secret: "secret"}).Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "shop" {
+		t.Errorf("request signed by the signer = %d %q, want 200 shop", w.Code, w.Body.String())
+	}
+}
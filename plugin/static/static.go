@@ -0,0 +1,171 @@
+package static
+
+import (
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config the static assets configuration
+type Config struct {
+	Prefix string        `mapstructure:"prefix"`  // Url prefix, default /
+	Dir    string        `mapstructure:"dir"`     // Local directory of the assets, only used in the configuration file
+	MaxAge time.Duration `mapstructure:"max_age"` // Cache-Control max-age of the assets, default 0 means no-cache. The index file is never cached
+	Spa    bool          `mapstructure:"spa"`     // Fallback to the index file when the asset is not found, for single page applications
+	Index  string        `mapstructure:"index"`   // Index file, default index.html
+	// Serve the pre-compressed siblings such as app.js.br and app.js.gz to the clients accepting the encodings, default false
+	Precompressed bool `mapstructure:"precompressed"`
+}
+
+// encodings of the pre-compressed siblings in order of preference, and their file extensions
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{{"br", ".br"}, {"gzip", ".gz"}}
+
+// Site the static assets mounted under the prefix
+type Site struct {
+	Config
+	FS fs.FS // The assets, os.DirFS or embed.FS
+}
+
+/*
+Mount the sites to the gin engine. The assets are served only when no route matches the request,
+so the api routes always take precedence, and the prefix "/" doesn't conflict with them.
+*/
+func Mount(e *gin.Engine, sites []Site) {
+	for i := range sites {
+		s := &sites[i]
+		if s.Prefix == "" {
+			s.Prefix = "/"
+		}
+		s.Prefix = "/" + strings.Trim(s.Prefix, "/")
+		if s.Index == "" {
+			s.Index = "index.html"
+		}
+		logger.Log.Debugf("Static assets [%s]", s.Prefix)
+	}
+	// the longest prefix first
+	sort.SliceStable(sites, func(i, j int) bool {
+		return len(sites[i].Prefix) > len(sites[j].Prefix)
+	})
+	e.NoRoute(func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+			return
+		}
+		for i := range sites {
+			if sites[i].serve(ctx) {
+				ctx.Abort()
+				return
+			}
+		}
+	})
+}
+
+// serve the asset of the request, return false when the request isn't under the prefix or the asset is not found
+func (s *Site) serve(ctx *gin.Context) bool {
+	p := ctx.Request.URL.Path
+	if s.Prefix != "/" {
+		if p != s.Prefix && !strings.HasPrefix(p, s.Prefix+"/") {
+			return false
+		}
+		p = strings.TrimPrefix(p, s.Prefix)
+	}
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		name = s.Index
+	}
+	if s.open(ctx, name) || s.open(ctx, path.Join(name, s.Index)) {
+		return true
+	}
+	// the missing asset with an extension is a real 404, the others are the routes of the frontend
+	if s.Spa && path.Ext(name) == "" {
+		return s.open(ctx, s.Index)
+	}
+	return false
+}
+
+func (s *Site) open(ctx *gin.Context, name string) bool {
+	f, err := s.FS.Open(name)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Log.Warnf("Open static asset [%s] error, %s", name, err.Error())
+		}
+		return false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if s.Precompressed {
+		if cf, cstat, ok := s.openPrecompressed(ctx, name); ok {
+			defer cf.Close()
+			f, stat = cf, cstat
+			ctx.Header("Content-Type", contentType)
+		}
+	}
+	if path.Base(name) == s.Index || s.MaxAge <= 0 {
+		ctx.Header("Cache-Control", "no-cache")
+	} else {
+		ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(s.MaxAge.Seconds())))
+	}
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(ctx.Writer, ctx.Request, stat.Name(), stat.ModTime(), rs)
+		return true
+	}
+	ctx.DataFromReader(http.StatusOK, stat.Size(), contentType, f, nil)
+	return true
+}
+
+// open the pre-compressed sibling of the asset accepted by the client, the Content-Encoding and Vary are set when it exists
+func (s *Site) openPrecompressed(ctx *gin.Context, name string) (fs.File, fs.FileInfo, bool) {
+	accept := ctx.GetHeader("Accept-Encoding")
+	vary := false
+	for _, pe := range precompressedEncodings {
+		f, err := s.FS.Open(name + pe.ext)
+		if err != nil {
+			continue
+		}
+		// the response varies with the Accept-Encoding once any sibling exists
+		if !vary {
+			ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+			vary = true
+		}
+		stat, err := f.Stat()
+		if err != nil || stat.IsDir() || !acceptsEncoding(accept, pe.encoding) {
+			_ = f.Close()
+			continue
+		}
+		ctx.Header("Content-Encoding", pe.encoding)
+		return f, stat, true
+	}
+	return nil, nil, false
+}
+
+// whether the Accept-Encoding accepts the encoding, q=0 means not acceptable
+func acceptsEncoding(accept, encoding string) bool {
+	for _, item := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, encoding) && name != "*" {
+			continue
+		}
+		q := strings.ReplaceAll(strings.TrimSpace(params), " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
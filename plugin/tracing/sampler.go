@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/archine/gin-plus/v3/mvc"
+	"math"
+)
+
+// SamplingParams the information of the request to decide the sampling
+type SamplingParams struct {
+	TraceId       string
+	Method        string
+	Route         string // Route template, such as /user/:id
+	HasParent     bool   // Whether the trace is continued from the caller by the traceparent header
+	ParentSampled bool   // Whether the caller sampled the trace
+}
+
+// Sampler decides whether the trace of the request is sampled
+type Sampler interface {
+	ShouldSample(p SamplingParams) bool
+}
+
+// SamplerFunc the function sampler
+type SamplerFunc func(p SamplingParams) bool
+
+func (f SamplerFunc) ShouldSample(p SamplingParams) bool {
+	return f(p)
+}
+
+// RateSampler Sample the fraction of the traces, the decision is derived from the trace id,
+// so the services sampling at the same rate make the same decision of a trace
+func RateSampler(rate float64) Sampler {
+	if rate >= 1 {
+		return SamplerFunc(func(SamplingParams) bool { return true })
+	}
+	if rate <= 0 {
+		return SamplerFunc(func(SamplingParams) bool { return false })
+	}
+	threshold := uint64(rate * math.MaxUint64)
+	return SamplerFunc(func(p SamplingParams) bool {
+		return traceIdBits(p.TraceId) < threshold
+	})
+}
+
+// the lower 64 bits of the trace id, the traces without the hex id are hashed by FNV-1a
+func traceIdBits(traceId string) uint64 {
+	if len(traceId) == 32 {
+		if b, err := hex.DecodeString(traceId[16:]); err == nil {
+			return binary.BigEndian.Uint64(b)
+		}
+	}
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(traceId); i++ {
+		h ^= uint64(traceId[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// RouteRate the sampling rate of the routes
+type RouteRate struct {
+	Path string  `mapstructure:"path"` // Ant-style pattern of the route templates, such as /order/**
+	Rate float64 `mapstructure:"rate"` // Sampling rate, 0 to 1
+}
+
+// RouteSampler Sample by the rate of the first matching route, the other routes are sampled by the fallback
+func RouteSampler(routes []RouteRate, fallback Sampler) Sampler {
+	samplers := make([]Sampler, len(routes))
+	for i, r := range routes {
+		samplers[i] = RateSampler(r.Rate)
+	}
+	return SamplerFunc(func(p SamplingParams) bool {
+		for i, r := range routes {
+			if mvc.MatchPath(r.Path, p.Route) {
+				return samplers[i].ShouldSample(p)
+			}
+		}
+		return fallback.ShouldSample(p)
+	})
+}
+
+// ParentBasedSampler Follow the decision of the caller, the root traces are sampled by the root sampler
+func ParentBasedSampler(root Sampler) Sampler {
+	return SamplerFunc(func(p SamplingParams) bool {
+		if p.HasParent {
+			return p.ParentSampled
+		}
+		return root.ShouldSample(p)
+	})
+}
@@ -0,0 +1,181 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Context keys of the trace, the trace id key is read by the responses and the http client
+const (
+	TraceIdKey = "trace_id"
+	sampledKey = "gin-plus/trace-sampled"
+)
+
+// Config the request tracing
+type Config struct {
+	Enabled      bool        `mapstructure:"enabled"`       // Whether to trace the requests, default false
+	Rate         float64     `mapstructure:"rate"`          // Sampling rate of the root traces, 0 to 1, default 1
+	Routes       []RouteRate `mapstructure:"routes"`        // Sampling rates of the routes, the first matching one is used
+	ParentBased  bool        `mapstructure:"parent_based"`  // Follow the decision of the caller declared by the traceparent header, default true
+	SampleErrors bool        `mapstructure:"sample_errors"` // Export the unsampled traces of the requests failed with http status 5xx or the errors, default true
+	Header       string      `mapstructure:"header"`        // Response header carrying the trace id, default X-Trace-Id
+}
+
+// Span the traced request
+type Span struct {
+	TraceId      string
+	SpanId       string
+	ParentSpanId string
+	Method       string
+	Route        string
+	Path         string
+	Status       int
+	Error        string
+	Start        time.Time
+	Duration     time.Duration
+	Sampled      bool // Whether the trace is sampled by the sampler
+	ErrorSampled bool // Whether the unsampled trace is exported because the request failed
+}
+
+// Exporter exports the sampled spans, such as sending them to the tracing backend
+type Exporter interface {
+	Export(span Span)
+}
+
+// ExporterFunc the function exporter
+type ExporterFunc func(span Span)
+
+func (f ExporterFunc) Export(span Span) {
+	f(span)
+}
+
+var (
+	exportersMu sync.RWMutex
+	exporters   []Exporter
+)
+
+// AddExporter Add the exporter of the spans, the spans are logged at debug level when no exporter is added
+func AddExporter(e Exporter) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	exporters = append(exporters, e)
+}
+
+// NewSampler Create the sampler of the configuration, the route rates take precedence over the rate,
+// the decision of the caller takes precedence over both when it is parent based
+func NewSampler(conf Config) Sampler {
+	var s = RateSampler(conf.Rate)
+	if len(conf.Routes) > 0 {
+		s = RouteSampler(conf.Routes, s)
+	}
+	if conf.ParentBased {
+		s = ParentBasedSampler(s)
+	}
+	return s
+}
+
+// Sampled Whether the trace of the request is sampled
+func Sampled(ctx *gin.Context) bool {
+	return ctx.GetBool(sampledKey)
+}
+
+/*
+Middleware The gin middleware traces the requests. The trace is continued from the W3C traceparent header of the caller,
+then the trace id header, otherwise a new trace is started. The trace id is set to the context, so it's carried by the
+responses and the outbound calls of the http client.
+
+The sampled spans are exported after the request is completed, the unsampled spans of the failed requests are exported
+too when SampleErrors is enabled, so the errors are always traced while the cost of the successful requests stays low.
+*/
+func Middleware(conf Config) gin.HandlerFunc {
+	if conf.Header == "" {
+		conf.Header = "X-Trace-Id"
+	}
+	sampler := NewSampler(conf)
+	return func(ctx *gin.Context) {
+		span := Span{Method: ctx.Request.Method, Route: ctx.FullPath(), Path: ctx.Request.URL.Path, Start: time.Now()}
+		params := SamplingParams{Method: span.Method, Route: span.Route}
+		if traceId, parentId, sampled, ok := parseTraceparent(ctx.GetHeader("traceparent")); ok {
+			span.TraceId, span.ParentSpanId = traceId, parentId
+			params.HasParent, params.ParentSampled = true, sampled
+		} else if traceId = ctx.GetHeader(conf.Header); traceId != "" && len(traceId) <= 128 {
+			span.TraceId = traceId
+		} else {
+			span.TraceId = randomHex(16)
+		}
+		span.SpanId = randomHex(8)
+		params.TraceId = span.TraceId
+		span.Sampled = sampler.ShouldSample(params)
+		ctx.Set(TraceIdKey, span.TraceId)
+		ctx.Set(sampledKey, span.Sampled)
+		ctx.Header(conf.Header, span.TraceId)
+		defer func() {
+			r := recover()
+			span.Duration = time.Since(span.Start)
+			span.Status = ctx.Writer.Status()
+			if r != nil {
+				span.Status = http.StatusInternalServerError
+				span.Error = fmt.Sprint(r)
+			} else if len(ctx.Errors) > 0 {
+				span.Error = ctx.Errors.Last().Error()
+			}
+			if !span.Sampled && conf.SampleErrors && (span.Status >= http.StatusInternalServerError || span.Error != "") {
+				span.ErrorSampled = true
+			}
+			if span.Sampled || span.ErrorSampled {
+				export(span)
+			}
+			if r != nil {
+				// the panic is handled by the global exception interceptor
+				panic(r)
+			}
+		}()
+		ctx.Next()
+	}
+}
+
+func export(span Span) {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	if len(exporters) == 0 {
+		logger.Log.Debugf("[trace] %s %s %s %d %s", span.TraceId, span.Method, span.Path, span.Status, span.Duration)
+		return
+	}
+	for _, e := range exporters {
+		e.Export(span)
+	}
+}
+
+// parse the W3C traceparent header, such as 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceparent(header string) (traceId, parentId string, sampled, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return
+	}
+	for _, p := range parts[:4] {
+		if _, err := hex.DecodeString(p); err != nil {
+			return
+		}
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	return strings.ToLower(parts[1]), strings.ToLower(parts[2]), flags[0]&1 == 1, true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,127 @@
+package wellknown
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"io/fs"
+	"net/http"
+	"path"
+	"time"
+)
+
+// Config the robots.txt, favicon, security.txt and /.well-known/* documents.
+// The inline contents take precedence over the files of the directory
+type Config struct {
+	Dir         string            `mapstructure:"dir"`          // Directory of robots.txt, favicon.ico, security.txt and .well-known/*
+	Robots      string            `mapstructure:"robots"`       // Content of /robots.txt
+	SecurityTxt string            `mapstructure:"security_txt"` // Content of /.well-known/security.txt
+	Documents   map[string]string `mapstructure:"documents"`    // Contents of /.well-known/{name}, the key is the name
+	MaxAge      time.Duration     `mapstructure:"max_age"`      // Cache-Control max-age, default 24h
+}
+
+// Empty nothing is configured
+func (c Config) Empty() bool {
+	return c.Dir == "" && c.Robots == "" && c.SecurityTxt == "" && len(c.Documents) == 0
+}
+
+type document struct {
+	name    string
+	content []byte
+	etag    string
+	modTime time.Time
+}
+
+/*
+Mount the documents to the gin engine, the files are read from the fs once.
+The document whose path is already mounted, such as the oidc discovery, fails the startup.
+The fs may be nil, such as an embed.FS containing:
+
+	robots.txt
+	favicon.ico
+	security.txt
+	.well-known/apple-app-site-association
+*/
+func Mount(e *gin.Engine, conf Config, fsys fs.FS) {
+	docs := make(map[string]*document)
+	if fsys != nil {
+		for _, f := range []string{"robots.txt", "favicon.ico"} {
+			addFile(docs, fsys, f, "/"+f)
+		}
+		addFile(docs, fsys, "security.txt", "/.well-known/security.txt")
+		entries, err := fs.ReadDir(fsys, ".well-known")
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Log.Fatalf("Read well-known documents error, %s", err.Error())
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				addFile(docs, fsys, path.Join(".well-known", entry.Name()), "/.well-known/"+entry.Name())
+			}
+		}
+	}
+	if conf.Robots != "" {
+		docs["/robots.txt"] = newDocument("robots.txt", []byte(conf.Robots), time.Time{})
+	}
+	if conf.SecurityTxt != "" {
+		docs["/.well-known/security.txt"] = newDocument("security.txt", []byte(conf.SecurityTxt), time.Time{})
+	}
+	for name, content := range conf.Documents {
+		docs["/.well-known/"+name] = newDocument(name, []byte(content), time.Time{})
+	}
+	cacheControl := fmt.Sprintf("public, max-age=%d", int64(conf.MaxAge.Seconds()))
+	mounted := make(map[string]bool)
+	for _, r := range e.Routes() {
+		if r.Method == http.MethodGet {
+			mounted[r.Path] = true
+		}
+	}
+	for p, doc := range docs {
+		// such as /.well-known/openid-configuration served by the oidc provider
+		if mounted[p] {
+			logger.Log.Fatalf("Well-known document [%s] conflicts with the route already mounted, remove the document", p)
+		}
+		handler := doc.handler(cacheControl)
+		e.GET(p, handler)
+		e.HEAD(p, handler)
+		logger.Log.Debugf("Well-known document [%s]", p)
+	}
+}
+
+func addFile(docs map[string]*document, fsys fs.FS, name, urlPath string) {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Log.Fatalf("Read well-known document [%s] error, %s", name, err.Error())
+		}
+		return
+	}
+	var modTime time.Time
+	if stat, err := fs.Stat(fsys, name); err == nil {
+		modTime = stat.ModTime()
+	}
+	docs[urlPath] = newDocument(path.Base(name), content, modTime)
+}
+
+func newDocument(name string, content []byte, modTime time.Time) *document {
+	sum := sha256.Sum256(content)
+	return &document{name: name, content: content, etag: `"` + hex.EncodeToString(sum[:8]) + `"`, modTime: modTime}
+}
+
+func (d *document) handler(cacheControl string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", cacheControl)
+		ctx.Header("ETag", d.etag)
+		if path.Ext(d.name) == "" {
+			// the documents without extension are mostly json, such as apple-app-site-association
+			ctx.Header("Content-Type", http.DetectContentType(d.content))
+			if trimmed := bytes.TrimSpace(d.content); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+				ctx.Header("Content-Type", "application/json")
+			}
+		}
+		http.ServeContent(ctx.Writer, ctx.Request, d.name, d.modTime, bytes.NewReader(d.content))
+	}
+}
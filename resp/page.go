@@ -0,0 +1,43 @@
+package resp
+
+/*
+Page the page of the items, responded by Json() with the X-Total-Count header.
+
+	// @GET(path="/user")
+	func (u *UserController) ListUser(ctx *gin.Context, page mvc.Pageable) (*resp.Page[User], error) {
+	    users, total, err := u.repo.List(page.Offset(), page.Limit(), page.OrderBy())
+	    return resp.NewPage(users, total, page.Page, page.Size), err
+	}
+*/
+type Page[T any] struct {
+	Items []T   `json:"items"` // Items of the page, empty array instead of null
+	Total int64 `json:"total"` // Total number of the items
+	Page  int   `json:"page"`  // Page number, starts from 1
+	Size  int   `json:"size"`  // Page size
+	Pages int   `json:"pages"` // Total number of the pages
+}
+
+// NewPage Create the page of the items
+func NewPage[T any](items []T, total int64, page, size int) *Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	p := &Page[T]{Items: items, Total: total, Page: page, Size: size}
+	if size > 0 {
+		p.Pages = int((total + int64(size) - 1) / int64(size))
+	}
+	return p
+}
+
+// TotalCount the total number of the items, responded as the X-Total-Count header
+func (p *Page[T]) TotalCount() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.Total
+}
+
+// paged the page recognized by Json()
+type paged interface {
+	TotalCount() int64
+}
@@ -4,23 +4,32 @@ import (
 	"errors"
 	"fmt"
 	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/i18n"
 	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/validation"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
+	"math"
 	"net/http"
-	"reflect"
+	"strconv"
 	"sync"
+	"time"
 )
 
 // Respond to the client assistant and return quickly
 
 const (
-	BadRequestCode      = 40000
-	NonLoginCode        = 40001
+	BadRequestCode      = exception.BadRequestCode
+	NonLoginCode        = exception.NonLoginCode
 	TokenExpiredCode    = 40002
-	ForbiddenCode       = 40003
+	ForbiddenCode       = exception.ForbiddenCode
+	NotFoundCode        = exception.NotFoundCode
+	ConflictCode        = exception.ConflictCode
 	ParamValidationCode = 40010
+	ChecksumCode        = 40022
+	TooManyRequestsCode = 40029
 	SystemErrorCode     = 50000
+	UnavailableCode     = exception.UnavailableCode
+	TimeoutCode         = 50004
 )
 
 // ResultPool result pool
@@ -47,6 +56,13 @@ type PaginationResult struct {
 	Data      interface{} `json:"data"`       // Response data
 }
 
+// CursorPage Keyset paging result, the next cursor is empty when there are no more items
+type CursorPage[T any] struct {
+	Data       []T    `json:"data"`                  // Response data
+	NextCursor string `json:"next_cursor,omitempty"` // Opaque cursor of the next page
+	HasMore    bool   `json:"has_more"`              // Whether there are more items
+}
+
 // Result Return result
 type Result struct {
 	ctx     *gin.Context `json:"-"`
@@ -122,6 +138,9 @@ func ParamInvalid(ctx *gin.Context, condition bool, msg ...string) bool {
 }
 
 // ParamValidation parameter validation, return false means that the validation failed
+//
+// Deprecated: the binding is lenient and only the first error is responded, use mvc.Bind or the parameters
+// of the api method instead, which respond the errors of each field and support the strict json binding
 func ParamValidation(ctx *gin.Context, obj interface{}) bool {
 	err := ctx.ShouldBind(obj)
 	if err == nil {
@@ -131,6 +150,23 @@ func ParamValidation(ctx *gin.Context, obj interface{}) bool {
 	return false
 }
 
+// ParamBindError Respond the parameter binding error with http status 400
+// obj: the bound object, used to get the custom validation message. can be nil
+func ParamBindError(ctx *gin.Context, err error, obj interface{}) {
+	ValidationFailed(ctx, exception.NewValidationErr(err, obj))
+}
+
+// ValidationFailed Respond the validation exception with http status 400, the errors of each field are returned as data.
+// The messages are localized by the configured language, default the language accepted by the client
+func ValidationFailed(ctx *gin.Context, ex *exception.ValidationException) {
+	lang := validation.Locale()
+	if lang == "" {
+		lang = acceptLanguage(ctx)
+	}
+	ex = ex.Localize(lang)
+	InitResp(ctx).WithBasic(ParamValidationCode, ex.Msg, ex.Errors).To(http.StatusBadRequest)
+}
+
 // Forbidden Insufficient permission error.
 // Return true means the condition is true
 func Forbidden(ctx *gin.Context, condition bool, msg ...string) bool {
@@ -170,13 +206,59 @@ func LoginExpired(ctx *gin.Context, condition bool, msg ...string) bool {
 	return condition
 }
 
+// ChecksumMismatch The received body does not match the checksum, respond with http status 422
+func ChecksumMismatch(ctx *gin.Context, msg string) {
+	InitResp(ctx).WithBasic(ChecksumCode, msg, nil).To(http.StatusUnprocessableEntity)
+}
+
+// TooManyRequests The request is rate limited, respond with http status 429 and the Retry-After header
+func TooManyRequests(ctx *gin.Context, retryAfter time.Duration, msg ...string) {
+	message := "请求过于频繁,请稍后再试"
+	if len(msg) > 0 {
+		message = msg[0]
+	}
+	if retryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	InitResp(ctx).WithBasic(TooManyRequestsCode, message, nil).To(http.StatusTooManyRequests)
+}
+
+// ServiceUnavailable The service is temporarily unavailable, such as the circuit is open,
+// respond with http status 503 and the Retry-After header
+func ServiceUnavailable(ctx *gin.Context, retryAfter time.Duration, msg ...string) {
+	message := "服务暂不可用,请稍后再试"
+	if len(msg) > 0 {
+		message = msg[0]
+	}
+	if retryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	InitResp(ctx).WithBasic(UnavailableCode, message, nil).To(http.StatusServiceUnavailable)
+}
+
+// Conflict The request conflicts with the request in progress, such as the retry with the same idempotency key,
+// respond with http status 409 and the Retry-After header
+func Conflict(ctx *gin.Context, retryAfter time.Duration, msg ...string) {
+	message := "请求正在处理中,请稍后再试"
+	if len(msg) > 0 {
+		message = msg[0]
+	}
+	if retryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	InitResp(ctx).WithBasic(ConflictCode, message, nil).To(http.StatusConflict)
+}
+
 // Ok Normal request with no data returned
 func Ok(ctx *gin.Context) {
 	InitResp(ctx).To()
 }
 
-// Json Normal request with data returned
+// Json Normal request with data returned, the total count of the Page is set to the X-Total-Count header
 func Json(ctx *gin.Context, data interface{}) {
+	if p, ok := data.(paged); ok {
+		ctx.Header("X-Total-Count", strconv.FormatInt(p.TotalCount(), 10))
+	}
 	InitResp(ctx).WithBasic(0, "ok", data).To()
 }
 
@@ -198,15 +280,116 @@ func DirectRespWithCode(ctx *gin.Context, bCode int, format string, args ...any)
 	InitResp(ctx).WithBasic(bCode, fmt.Sprintf(format, args...), nil).To()
 }
 
-// DirectRespErr Respond directly with any err
+// DirectRespErr Respond directly with any err, the response is described by ErrorOf()
 func DirectRespErr(ctx *gin.Context, err error) {
+	ex, known := ErrorOf(ctx, err)
+	if !known {
+		exception.PrintStack(err)
+	}
+	InitResp(ctx).WithBasic(ex.Code, ex.Msg, ex.Data).To(ex.Status)
+}
+
+// ErrorMapped Respond the error registered by exception.RegisterError or exception.RegisterErrorType,
+// false means the error is not registered
+func ErrorMapped(ctx *gin.Context, err error) bool {
+	mapping, ok := exception.MapError(err)
+	if !ok {
+		return false
+	}
+	StatusFailed(ctx, mapping.Exception(err, acceptLanguage(ctx)))
+	return true
+}
+
+/*
+ErrorOf Get the http status, the business code, the localized message and the data of the error, the same as they're
+responded by DirectRespErr(). It's used by the protocols not responding the result, such as graphql.
+False means the error is unknown, it's described as the server error
+*/
+func ErrorOf(ctx *gin.Context, err error) (*exception.StatusException, bool) {
 	var businessErr *exception.BusinessException
 	if errors.As(err, &businessErr) {
-		DirectRespWithCode(ctx, businessErr.Code, businessErr.Msg)
+		code, ok := exception.LookupError(businessErr.Code)
+		if !ok {
+			return &exception.StatusException{Status: http.StatusOK, Code: businessErr.Code, Msg: localize(ctx, businessErr.Msg, businessErr.Args...)}, true
+		}
+		msg := businessErr.Msg
+		if businessErr.Declared() {
+			msg = code.Message(acceptLanguage(ctx), businessErr.Args...)
+		}
+		return &exception.StatusException{Status: max(code.Status, http.StatusOK), Code: businessErr.Code, Msg: msg}, true
+	}
+	var validationErr *exception.ValidationException
+	if errors.As(err, &validationErr) {
+		lang := validation.Locale()
+		if lang == "" {
+			lang = acceptLanguage(ctx)
+		}
+		validationErr = validationErr.Localize(lang)
+		return &exception.StatusException{Status: http.StatusBadRequest, Code: ParamValidationCode, Msg: validationErr.Msg, Data: validationErr.Errors}, true
+	}
+	var checksumErr *exception.ChecksumException
+	if errors.As(err, &checksumErr) {
+		return &exception.StatusException{Status: http.StatusUnprocessableEntity, Code: ChecksumCode, Msg: checksumErr.Msg}, true
+	}
+	statusErr := &exception.StatusException{}
+	if !errors.As(err, &statusErr) {
+		mapping, ok := exception.MapError(err)
+		if !ok {
+			return &exception.StatusException{Status: http.StatusOK, Code: SystemErrorCode, Msg: "服务器异常,请联系管理员!"}, false
+		}
+		statusErr = mapping.Exception(err, acceptLanguage(ctx))
+	}
+	return &exception.StatusException{Status: max(statusErr.Status, http.StatusOK), Code: statusErr.Code, Msg: localize(ctx, statusErr.Msg), Data: statusErr.Data}, true
+}
+
+// BusinessFailed Respond the business exception, the exception created from the error declared in the catalog
+// is responded with the declared http status and the message of the language accepted by the client.
+// The message of other exceptions is translated when it's the key of the i18n bundle
+func BusinessFailed(ctx *gin.Context, ex *exception.BusinessException) {
+	code, ok := exception.LookupError(ex.Code)
+	if !ok {
+		InitResp(ctx).WithBasic(ex.Code, localize(ctx, ex.Msg, ex.Args...), nil).To()
 		return
 	}
-	SeverError(ctx, true)
-	exception.PrintStack(err)
+	msg := ex.Msg
+	if ex.Declared() {
+		msg = code.Message(acceptLanguage(ctx), ex.Args...)
+	}
+	status := code.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	InitResp(ctx).WithBasic(ex.Code, msg, nil).To(status)
+}
+
+// StatusFailed Respond the status exception with its http status, business code and data,
+// the message is translated when it's the key of the i18n bundle
+func StatusFailed(ctx *gin.Context, ex *exception.StatusException) {
+	status := ex.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	InitResp(ctx).WithBasic(ex.Code, localize(ctx, ex.Msg), ex.Data).To(status)
+}
+
+// translate the message key by the language accepted by the client, the message is kept when it's not a key
+func localize(ctx *gin.Context, msg string, args ...any) string {
+	if translated, ok := i18n.Default.Message(acceptLanguage(ctx), msg, args...); ok {
+		return translated
+	}
+	return msg
+}
+
+// ErrorCatalogHandler Respond the error codes declared in the catalog as json
+func ErrorCatalogHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, exception.Catalog())
+	}
+}
+
+// the language of the highest weight in the Accept-Language header, default exception.DefaultLanguage
+func acceptLanguage(ctx *gin.Context) string {
+	return i18n.ParseAcceptLanguage(ctx.GetHeader("Accept-Language"), exception.DefaultLanguage)
 }
 
 // ChangeResultType Change the result type
@@ -224,29 +407,6 @@ func Recycle(resp Resp) {
 }
 
 func getValidMsg(err error, obj interface{}) string {
-	if obj == nil {
-		return err.Error()
-	}
-	var errs validator.ValidationErrors
-	if errors.As(err, &errs) {
-		logger.Log.Error(err.Error())
-		getObj := reflect.TypeOf(obj)
-		if getObj.Kind() == reflect.Ptr {
-			getObj = getObj.Elem()
-		}
-		for _, e := range errs {
-			if f, exist := getObj.FieldByName(e.Field()); exist {
-				message := f.Tag.Get(e.Tag() + "Msg")
-				if message == "" {
-					message = f.Tag.Get("msg")
-					if message == "" {
-						return e.Error()
-					}
-				}
-				return message
-			}
-		}
-	}
 	logger.Log.Error(err.Error())
-	return "参数错误"
+	return validation.Translate(err, obj)[0].Message
 }
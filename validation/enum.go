@@ -0,0 +1,86 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// EnumTag the validation tag checks the value is one of the values of the Enum
+//
+//	type Status int
+//
+//	func (s Status) Values() []any {
+//	    return []any{Status(1), Status(2)}
+//	}
+//
+//	type Query struct {
+//	    Status Status `form:"status" binding:"enum"`
+//	}
+const EnumTag = "enum"
+
+// Enum the enumeration type, the allowed values are exposed in validation errors and api documents
+type Enum interface {
+	// Values the allowed values
+	Values() []any
+}
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		_ = v.RegisterValidation(EnumTag, validateEnum)
+	}
+}
+
+func validateEnum(fl validator.FieldLevel) bool {
+	values, ok := EnumValues(fl.Field())
+	if !ok {
+		return false
+	}
+	current := fl.Field().Interface()
+	for _, v := range values {
+		if v == current {
+			return true
+		}
+	}
+	return false
+}
+
+// EnumValues the allowed values of the enumeration, ok is false when the value is not an Enum
+func EnumValues(v reflect.Value) (values []any, ok bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+	if e, is := v.Interface().(Enum); is {
+		return e.Values(), true
+	}
+	if v.CanAddr() {
+		if e, is := v.Addr().Interface().(Enum); is {
+			return e.Values(), true
+		}
+	}
+	if e, is := reflect.New(v.Type()).Interface().(Enum); is {
+		return e.Values(), true
+	}
+	return nil, false
+}
+
+// AllowedValues the allowed values of the enum and oneof validation errors, ok is false for other errors
+func AllowedValues(fe validator.FieldError) (allowed string, ok bool) {
+	switch fe.Tag() {
+	case "oneof":
+		return strings.Join(strings.Fields(fe.Param()), ", "), true
+	case EnumTag:
+		values, is := EnumValues(reflect.ValueOf(fe.Value()))
+		if !is {
+			return "", false
+		}
+		items := make([]string, 0, len(values))
+		for _, v := range values {
+			items = append(items, fmt.Sprint(v))
+		}
+		return strings.Join(items, ", "), true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package validation
+
+import (
+	"errors"
+	"github.com/archine/gin-plus/v3/i18n"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// FormatRule the rule of the errors that the parameters cannot be parsed, such as malformed json
+const FormatRule = "format"
+
+// Rules of the errors of the strict json binding
+const (
+	UnknownRule = "unknown" // The field is not declared by the struct
+	TypeRule    = "type"    // The value is not of the type of the field
+)
+
+// FieldError the validation error of a field
+type FieldError struct {
+	Field   string `json:"field,omitempty"` // Field name, empty when the error is not related to a field
+	Rule    string `json:"rule"`            // Validation rule, such as required, min
+	Param   string `json:"-"`               // Parameter of the rule, such as the min value, the allowed values of the enum
+	Message string `json:"message"`         // Error message
+}
+
+func init() {
+	// the messages of the bundle key validation.{rule}, {field} and {param} are replaced by the field and the parameter
+	i18n.Default.Add("zh-CN", map[string]string{
+		"validation.format": "参数错误",
+		"validation.oneof":  "{field} 的取值必须为 [{param}]",
+		"validation.enum":   "{field} 的取值必须为 [{param}]",
+	})
+	i18n.Default.Add("en", map[string]string{
+		"validation.format":  "invalid parameters",
+		"validation.oneof":   "{field} must be one of [{param}]",
+		"validation.enum":    "{field} must be one of [{param}]",
+		"validation.unknown": "unsupported field {field}",
+		"validation.type":    "the type of {field} must be {param}",
+	})
+}
+
+// Translate the binding error into the field errors with the messages of the fallback language
+// obj: the bound object, can be nil
+func Translate(err error, obj any) []FieldError {
+	return TranslateLang(err, obj, i18n.Default.Fallback())
+}
+
+/*
+TranslateLang Translate the binding error into the field errors with the messages of the language.
+The message of each field is taken in order from:
+
+	the tag named rule + "Msg", such as minMsg
+	the msg tag
+	the bundle key validation.{rule}, such as validation.required
+	the default english message of the validator
+
+The tag messages are the bundle keys too, so they are translated when the key exists.
+obj: the bound object, can be nil
+*/
+func TranslateLang(err error, obj any, lang string) []FieldError {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		if obj == nil {
+			return []FieldError{{Rule: FormatRule, Message: err.Error()}}
+		}
+		return []FieldError{{Rule: FormatRule, Message: i18n.Default.Translate(lang, "validation."+FormatRule)}}
+	}
+	var objType reflect.Type
+	if obj != nil {
+		objType = reflect.TypeOf(obj)
+		if objType.Kind() == reflect.Ptr {
+			objType = objType.Elem()
+		}
+	}
+	result := make([]FieldError, 0, len(errs))
+	for _, e := range errs {
+		param, ok := AllowedValues(e)
+		if !ok {
+			param = e.Param()
+		}
+		result = append(result, FieldError{Field: e.Field(), Rule: e.Tag(), Param: param, Message: fieldMessage(objType, e, param, lang)})
+	}
+	return result
+}
+
+// Localize the messages of the errors by the bundle key validation.{rule} of the language,
+// the messages are kept when the language has no message of the rule
+func Localize(errs []FieldError, lang string) []FieldError {
+	result := make([]FieldError, len(errs))
+	for i, e := range errs {
+		if msg, ok := i18n.Default.Lookup(lang, "validation."+e.Rule); ok {
+			e.Message = render(msg, e.Field, e.Param)
+		}
+		result[i] = e
+	}
+	return result
+}
+
+func fieldMessage(objType reflect.Type, e validator.FieldError, param, lang string) string {
+	if objType != nil && objType.Kind() == reflect.Struct {
+		if f, exist := objType.FieldByName(e.StructField()); exist {
+			if message := f.Tag.Get(e.Tag() + "Msg"); message != "" {
+				return i18n.Default.Translate(lang, message)
+			}
+			if message := f.Tag.Get("msg"); message != "" {
+				return i18n.Default.Translate(lang, message)
+			}
+		}
+	}
+	if msg, ok := i18n.Default.Message(lang, "validation."+e.Tag()); ok {
+		return render(msg, e.Field(), param)
+	}
+	return e.Error()
+}
+
+func render(msg, field, param string) string {
+	return strings.NewReplacer("{field}", field, "{param}", param).Replace(msg)
+}
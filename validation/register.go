@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"github.com/archine/gin-plus/v3/i18n"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// Config the validation of the parameters
+type Config struct {
+	FieldName string `mapstructure:"field_name"` // Name of the fields in the errors, json, form or the struct field name by default
+	Locale    string `mapstructure:"locale"`     // Language of the messages, empty means the language accepted by the client
+}
+
+var locale string
+
+// SetConfig Set the validation configuration
+func SetConfig(conf Config) {
+	locale = conf.Locale
+	if conf.FieldName != "" {
+		UseFieldName(conf.FieldName)
+	}
+}
+
+// Locale Get the configured language of the messages, empty means the language accepted by the client
+func Locale() string {
+	return locale
+}
+
+// Engine Get the validator of the gin binding, panic when binding.Validator is not based on go-playground/validator
+func Engine() *validator.Validate {
+	return binding.Validator.Engine().(*validator.Validate)
+}
+
+/*
+RegisterValidation Register the validation of the tag, the messages of each language are added to the i18n bundle
+with the key validation.{tag}, {field} and {param} are replaced by the field and the parameter of the tag.
+
+	validation.RegisterValidation("mobile", func(fl validator.FieldLevel) bool {
+	    return mobileRegexp.MatchString(fl.Field().String())
+	}, map[string]string{"zh-CN": "{field} 不是有效的手机号", "en": "{field} is not a valid mobile number"})
+
+	type User struct {
+	    Mobile string `json:"mobile" binding:"required,mobile"`
+	}
+*/
+func RegisterValidation(tag string, fn validator.Func, messages ...map[string]string) error {
+	if err := Engine().RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	for _, m := range messages {
+		RegisterMessages(tag, m)
+	}
+	return nil
+}
+
+// RegisterMessages Add the messages of each language of the tag to the i18n bundle, such as the tags reported by
+// the struct level validations
+func RegisterMessages(tag string, messages map[string]string) {
+	for lang, msg := range messages {
+		i18n.Default.Add(lang, map[string]string{"validation." + tag: msg})
+	}
+}
+
+/*
+RegisterStructValidation Register the struct level validation of the types, usually validating the fields depending on each other.
+
+	validation.RegisterStructValidation(func(sl validator.StructLevel) {
+	    q := sl.Current().Interface().(TimeRange)
+	    if q.End.Before(q.Start) {
+	        sl.ReportError(q.End, "End", "end", "after_start", "")
+	    }
+	}, TimeRange{})
+	validation.RegisterMessages("after_start", map[string]string{"zh-CN": "结束时间必须晚于开始时间"})
+*/
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...any) {
+	Engine().RegisterStructValidation(fn, types...)
+}
+
+// RegisterTagNameFunc Register the function getting the name of the fields in the errors
+func RegisterTagNameFunc(fn validator.TagNameFunc) {
+	Engine().RegisterTagNameFunc(fn)
+}
+
+// RegisterAlias Register the alias of the tags, such as RegisterAlias("username", "required,min=3,max=32")
+func RegisterAlias(alias, tags string) {
+	Engine().RegisterAlias(alias, tags)
+}
+
+// UseFieldName Use the name of the tag as the name of the fields in the errors, such as json, the struct field name
+// is used when the tag is absent
+func UseFieldName(tag string) {
+	RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return f.Name
+		}
+		return name
+	})
+}